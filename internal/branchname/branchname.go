@@ -0,0 +1,307 @@
+package branchname
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Template placeholders
+const (
+	PlaceholderIssueKey = "{issue-key}"
+	PlaceholderSlug     = "{slug}"
+	PlaceholderTaskID   = "{task_id}"
+	PlaceholderUser     = "{user}"
+	PlaceholderType     = "{type}"
+	PlaceholderDate     = "{date}"
+
+	// PlaceholderTaskName is the workspace naming placeholder; it keeps the
+	// task name's dots and separators instead of slugging the title
+	PlaceholderTaskName = "{task_name}"
+)
+
+const (
+	// DefaultName is used when no valid name can be derived from the input
+	DefaultName = "task"
+
+	// DefaultType is the {type} value when no label maps to a branch type
+	DefaultType = "task"
+
+	// DefaultSlugLength is a readable {slug} length for callers without their own limit
+	DefaultSlugLength = 40
+
+	// DateFormat is the layout used for the {date} placeholder
+	DateFormat = "20060102"
+
+	// MaxRefLength is the longest branch name cowork will create
+	MaxRefLength = 255
+
+	// MaxCollisionSuffix is the highest numeric suffix tried when resolving collisions
+	MaxCollisionSuffix = 99
+)
+
+// placeholderPattern matches any {placeholder} in a template
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// labelTypes maps issue labels to branch types
+var labelTypes = map[string]string{
+	"bug":           "fix",
+	"bugfix":        "fix",
+	"fix":           "fix",
+	"feature":       "feature",
+	"enhancement":   "feature",
+	"docs":          "docs",
+	"documentation": "docs",
+	"chore":         "chore",
+	"maintenance":   "chore",
+	"dependencies":  "chore",
+	"refactor":      "refactor",
+	"test":          "test",
+	"tests":         "test",
+}
+
+// Data holds the values substituted into a branch name template
+type Data struct {
+	// Issue key, such as the issue number
+	IssueKey string
+
+	// Issue title used for {slug}
+	Title string
+
+	// Task name used for {task_name}; falls back to Title
+	TaskName string
+
+	// Cowork task ID
+	TaskID int
+
+	// Login of the user the work is for
+	User string
+
+	// Issue labels used to derive {type}
+	Labels []string
+
+	// Date used for {date}; the current time when zero
+	Date time.Time
+
+	// Maximum length of {slug} and {task_name}; unlimited when zero
+	SlugLength int
+}
+
+// ExistsFunc reports whether a branch name is already taken
+type ExistsFunc func(name string) (bool, error)
+
+// Render expands a branch name template and validates the result against
+// git's ref naming rules
+func Render(template string, data *Data) (string, error) {
+	if strings.TrimSpace(template) == "" {
+		return "", fmt.Errorf("branch name template cannot be empty")
+	}
+
+	var unknown []string
+	name := placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		value, ok := data.value(placeholder)
+		if !ok {
+			unknown = append(unknown, placeholder)
+		}
+		return value
+	})
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("unknown placeholder %s in branch name template %q", strings.Join(unknown, ", "), template)
+	}
+
+	name = tidy(name)
+	if err := Validate(name); err != nil {
+		return "", fmt.Errorf("template %q produced an invalid branch name: %w", template, err)
+	}
+
+	return name, nil
+}
+
+// value returns the substitution for a placeholder
+func (d *Data) value(placeholder string) (string, bool) {
+	switch placeholder {
+	case PlaceholderIssueKey:
+		return Slug(d.IssueKey, 0, ""), true
+	case PlaceholderSlug:
+		return Slug(d.Title, d.SlugLength, DefaultName), true
+	case PlaceholderTaskName:
+		taskName := d.TaskName
+		if taskName == "" {
+			taskName = d.Title
+		}
+		return Sanitize(taskName, d.SlugLength), true
+	case PlaceholderTaskID:
+		if d.TaskID <= 0 {
+			return "", true
+		}
+		return fmt.Sprintf("%d", d.TaskID), true
+	case PlaceholderUser:
+		return Slug(d.User, 0, ""), true
+	case PlaceholderType:
+		return TypeFromLabels(d.Labels), true
+	case PlaceholderDate:
+		date := d.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		return date.Format(DateFormat), true
+	}
+	return "", false
+}
+
+// tidy cleans up separators left behind by empty placeholders
+func tidy(name string) string {
+	for _, pair := range [][2]string{{"//", "/"}, {"-/", "/"}, {"/-", "/"}, {"--", "-"}} {
+		for strings.Contains(name, pair[0]) {
+			name = strings.ReplaceAll(name, pair[0], pair[1])
+		}
+	}
+	return strings.Trim(name, "-/")
+}
+
+// Slug converts text into a lowercase, hyphen separated slug containing only
+// letters, numbers and hyphens. Returns fallback if nothing is left.
+func Slug(text string, maxLen int, fallback string) string {
+	slug := strings.ToLower(text)
+	slug = strings.ReplaceAll(slug, "_", "-")
+	slug = strings.Join(strings.Fields(slug), "-")
+
+	// Keep only letters, numbers, and hyphens
+	slug = strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
+			return r
+		}
+		return -1
+	}, slug)
+
+	for strings.Contains(slug, "--") {
+		slug = strings.ReplaceAll(slug, "--", "-")
+	}
+	slug = strings.Trim(slug, "-")
+
+	if maxLen > 0 && len(slug) > maxLen {
+		slug = strings.TrimRight(slug[:maxLen], "-")
+	}
+
+	if slug == "" {
+		return fallback
+	}
+	return slug
+}
+
+// Sanitize converts a task name into a branch name component, replacing
+// characters git does not allow with hyphens
+func Sanitize(text string, maxLen int) string {
+	sanitized := strings.ToLower(text)
+
+	invalidChars := []string{" ", "/", "\\", ":", "*", "?", "\"", "<", ">", "|", "..", "#", "&", "(", ")", "[", "]", "{", "}", "!", "@", "$", "%", "^", "+", "=", "~", "`", "_"}
+	for _, char := range invalidChars {
+		sanitized = strings.ReplaceAll(sanitized, char, "-")
+	}
+
+	sanitized = strings.Trim(sanitized, "-.")
+	if sanitized == "" {
+		sanitized = DefaultName
+	}
+
+	if maxLen > 0 && len(sanitized) > maxLen {
+		sanitized = strings.TrimRight(sanitized[:maxLen], "-.")
+	}
+
+	for strings.Contains(sanitized, "--") {
+		sanitized = strings.ReplaceAll(sanitized, "--", "-")
+	}
+
+	return sanitized
+}
+
+// TypeFromLabels derives the branch type from issue labels. The first label
+// with a known type wins; "kind/bug" style prefixes are ignored.
+func TypeFromLabels(labels []string) string {
+	for _, label := range labels {
+		name := strings.ToLower(strings.TrimSpace(label))
+		if i := strings.LastIndexAny(name, "/:"); i >= 0 {
+			name = strings.TrimSpace(name[i+1:])
+		}
+		if branchType, ok := labelTypes[name]; ok {
+			return branchType
+		}
+	}
+	return DefaultType
+}
+
+// Validate checks a branch name against the rules of git check-ref-format
+func Validate(name string) error {
+	switch {
+	case name == "":
+		return fmt.Errorf("branch name cannot be empty")
+	case name == "@":
+		return fmt.Errorf("branch name cannot be '@'")
+	case len(name) > MaxRefLength:
+		return fmt.Errorf("branch name is longer than %d characters", MaxRefLength)
+	case strings.HasPrefix(name, "-"):
+		return fmt.Errorf("branch name %q cannot start with '-'", name)
+	case strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/"):
+		return fmt.Errorf("branch name %q cannot start or end with '/'", name)
+	case strings.HasSuffix(name, "."):
+		return fmt.Errorf("branch name %q cannot end with '.'", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("branch name %q cannot contain '..'", name)
+	case strings.Contains(name, "//"):
+		return fmt.Errorf("branch name %q cannot contain '//'", name)
+	case strings.Contains(name, "@{"):
+		return fmt.Errorf("branch name %q cannot contain '@{'", name)
+	}
+
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(" ~^:?*[\\", r) {
+			return fmt.Errorf("branch name %q contains invalid character %q", name, r)
+		}
+	}
+
+	for _, component := range strings.Split(name, "/") {
+		if strings.HasPrefix(component, ".") {
+			return fmt.Errorf("branch name %q has a component starting with '.'", name)
+		}
+		if strings.HasSuffix(component, ".lock") {
+			return fmt.Errorf("branch name %q has a component ending with '.lock'", name)
+		}
+	}
+
+	return nil
+}
+
+// Resolve returns name, or name with the lowest free numeric suffix if it
+// is already taken
+func Resolve(name string, exists ExistsFunc) (string, error) {
+	if exists == nil {
+		return name, nil
+	}
+
+	for n := 1; n <= MaxCollisionSuffix; n++ {
+		candidate := name
+		if n > 1 {
+			candidate = fmt.Sprintf("%s-%d", name, n)
+		}
+
+		taken, err := exists(candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check branch %s: %w", candidate, err)
+		}
+		if !taken {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("branch %s and its %d suffixed variants already exist", name, MaxCollisionSuffix-1)
+}
+
+// IssueKey extracts the issue key from a ticket ID such as
+// "github:owner/repo#123", returning the ticket ID itself if it has no '#'
+func IssueKey(ticketID string) string {
+	if i := strings.LastIndex(ticketID, "#"); i >= 0 {
+		return ticketID[i+1:]
+	}
+	return ticketID
+}
@@ -0,0 +1,169 @@
+package branchname
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRender tests expanding branch name templates
+func TestRender(t *testing.T) {
+	// Test case: Each placeholder is substituted and separators left by empty
+	// values are cleaned up
+	data := &Data{
+		IssueKey:   "123",
+		Title:      "Fix login crash on Safari!",
+		TaskName:   "Fix login crash",
+		TaskID:     7,
+		User:       "Jane_Doe",
+		Labels:     []string{"priority: high", "bug"},
+		Date:       time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC),
+		SlugLength: 20,
+	}
+
+	testCases := []struct {
+		template string
+		expected string
+		desc     string
+	}{
+		{"feature/{issue-key}-{slug}", "feature/123-fix-login-crash-on-s", "default workflow template"},
+		{"{type}/{issue-key}-{slug}", "fix/123-fix-login-crash-on-s", "type from labels"},
+		{"{user}/{date}-{task_id}", "jane-doe/20240309-7", "user, date and task id"},
+		{"task/{task_name}", "task/fix-login-crash", "legacy workspace pattern"},
+		{"{slug}", "fix-login-crash-on-s", "bare slug"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := Render(tc.template, data)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
+
+// TestRender_EmptyValues tests templates whose placeholders have no value
+func TestRender_EmptyValues(t *testing.T) {
+	// Test case: Missing issue key, task ID and user must not leave dangling separators
+	data := &Data{Title: "Add cache"}
+
+	result, err := Render("{user}/task/{slug}-{issue-key}-{task_id}", data)
+	require.NoError(t, err)
+	assert.Equal(t, "task/add-cache", result)
+
+	result, err = Render("feature/{slug}", &Data{})
+	require.NoError(t, err)
+	assert.Equal(t, "feature/task", result)
+}
+
+// TestRender_Errors tests invalid templates
+func TestRender_Errors(t *testing.T) {
+	// Test case: Empty templates, unknown placeholders and templates producing
+	// invalid refs are rejected
+	data := &Data{IssueKey: "1", Title: "Add cache"}
+
+	testCases := []struct {
+		template string
+		errMsg   string
+	}{
+		{"", "cannot be empty"},
+		{"feature/{ticket}-{slug}", "unknown placeholder {ticket}"},
+		{"feature/{slug}.lock", "ending with '.lock'"},
+		{"feature..{slug}", "cannot contain '..'"},
+		{"{slug}~1", "invalid character"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			_, err := Render(tc.template, data)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+// TestTypeFromLabels tests deriving the branch type from labels
+func TestTypeFromLabels(t *testing.T) {
+	// Test case: Known labels map to a type, prefixes are ignored and unknown
+	// labels fall back to the default
+	testCases := []struct {
+		labels   []string
+		expected string
+	}{
+		{[]string{"bug"}, "fix"},
+		{[]string{"Enhancement"}, "feature"},
+		{[]string{"kind/documentation"}, "docs"},
+		{[]string{"type: refactor"}, "refactor"},
+		{[]string{"high-priority", "dependencies"}, "chore"},
+		{[]string{"question"}, DefaultType},
+		{nil, DefaultType},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.Join(tc.labels, ","), func(t *testing.T) {
+			assert.Equal(t, tc.expected, TypeFromLabels(tc.labels))
+		})
+	}
+}
+
+// TestValidate tests git ref name validation
+func TestValidate(t *testing.T) {
+	// Test case: Names follow the rules of git check-ref-format
+	valid := []string{"main", "feature/123-add-cache", "fix/v1.2", "user@host"}
+	for _, name := range valid {
+		assert.NoError(t, Validate(name), name)
+	}
+
+	invalid := []string{
+		"", "@", "-leading", "/leading", "trailing/", "trailing.", "a..b", "a//b",
+		"a@{b", "has space", "tilde~", "caret^", "colon:", "question?", "star*",
+		"bracket[", "back\\slash", "ctrl\x01", "feature/.hidden", "feature/x.lock",
+		strings.Repeat("a", MaxRefLength+1),
+	}
+	for _, name := range invalid {
+		assert.Error(t, Validate(name), name)
+	}
+}
+
+// TestResolve tests resolving collisions with existing branches
+func TestResolve(t *testing.T) {
+	// Test case: A free name is kept, taken names get the lowest free suffix
+	existing := map[string]bool{"feature/1-cache": true, "feature/1-cache-2": true}
+	exists := func(name string) (bool, error) { return existing[name], nil }
+
+	name, err := Resolve("feature/2-cache", exists)
+	require.NoError(t, err)
+	assert.Equal(t, "feature/2-cache", name)
+
+	name, err = Resolve("feature/1-cache", exists)
+	require.NoError(t, err)
+	assert.Equal(t, "feature/1-cache-3", name)
+
+	name, err = Resolve("feature/1-cache", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "feature/1-cache", name)
+}
+
+// TestResolve_Errors tests lookup failures and exhausted suffixes
+func TestResolve_Errors(t *testing.T) {
+	// Test case: Lookup errors are returned and a fully taken name fails
+	_, err := Resolve("feature/1", func(name string) (bool, error) { return false, fmt.Errorf("rate limited") })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "rate limited")
+
+	_, err = Resolve("feature/1", func(name string) (bool, error) { return true, nil })
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already exist")
+}
+
+// TestIssueKey tests extracting the issue key from ticket IDs
+func TestIssueKey(t *testing.T) {
+	// Test case: Provider ticket IDs yield the issue number, plain keys are kept
+	assert.Equal(t, "123", IssueKey("github:owner/repo#123"))
+	assert.Equal(t, "PROJ-42", IssueKey("PROJ-42"))
+	assert.Equal(t, "", IssueKey(""))
+}
@@ -1,14 +1,16 @@
 package cli
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 
-	"github.com/hlfshell/cowork/internal/auth"
 	"github.com/hlfshell/cowork/internal/config"
 	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
 	"github.com/hlfshell/cowork/internal/task"
 	"github.com/hlfshell/cowork/internal/types"
 	"github.com/hlfshell/cowork/internal/workflow"
@@ -87,6 +89,9 @@ func (app *App) setupCommands() {
 
 	// Add go command for workflow automation
 	app.addGoCommand()
+
+	// Add serve commands for webhook receivers
+	app.addServeCommands()
 }
 
 // addVersionCommand adds a detailed version command
@@ -216,10 +221,21 @@ func (app *App) addTaskCommands() {
 		},
 	}
 
+	// Approve command
+	approveCmd := &cobra.Command{
+		Use:   "approve [task-id-or-name]",
+		Short: "Approve a task's implementation plan",
+		Long:  "Approve the plan the agent proposed for a task in plan-first mode so it can start implementing",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.approveTask(cmd, args[0])
+		},
+	}
+
 	// Add tail flag to logs command
 	logsCmd.Flags().BoolP("tail", "t", false, "Continuously show logs")
 
-	taskCmd.AddCommand(listCmd, syncCmd, describeCmd, priorityCmd, startCmd, stopCmd, killCmd, logsCmd)
+	taskCmd.AddCommand(listCmd, syncCmd, describeCmd, priorityCmd, startCmd, stopCmd, killCmd, logsCmd, approveCmd)
 	app.rootCmd.AddCommand(taskCmd)
 }
 
@@ -235,9 +251,11 @@ func (app *App) addGoCommand() {
 	}
 
 	// Add flags for workflow configuration
-	goCmd.Flags().IntP("max-agents", "n", 1, "Maximum number of concurrent agents")
+	goCmd.Flags().IntP("max-agents", "n", 1, "Maximum number of concurrent agents (overrides max_concurrent in the repositories file)")
 	goCmd.Flags().String("provider", "github", "Git provider to use (github, gitlab, bitbucket)")
-	goCmd.Flags().String("repo", "", "Repository to monitor (default: current repo)")
+	goCmd.Flags().String("repo", "", "Repository to monitor as owner/repo (default: current repo)")
+	goCmd.Flags().String("repos", "", "File listing repositories to monitor (default: .cw/repositories.yaml if present)")
+	goCmd.Flags().Duration("interval", workflow.DefaultPollInterval, "Interval between polls for workflow updates")
 
 	app.rootCmd.AddCommand(goCmd)
 }
@@ -520,6 +538,60 @@ func (app *App) describeTask(cmd *cobra.Command, identifier string) error {
 		cmd.Printf("%s\n", task.Description)
 	}
 
+	if plan := task.Metadata[workflow.MetadataImplementationPlan]; plan != "" {
+		cmd.Printf("\nImplementation Plan:\n")
+		cmd.Printf("--------------------\n")
+		cmd.Printf("%s\n", plan)
+		if approvedBy := task.Metadata[workflow.MetadataPlanApprovedBy]; approvedBy != "" {
+			cmd.Printf("\n✅ Approved by %s\n", approvedBy)
+		} else {
+			cmd.Printf("\n⏳ Awaiting approval (cw task approve %d)\n", task.ID)
+		}
+	}
+
+	return nil
+}
+
+// approveTask approves the implementation plan of a task in plan-first mode
+func (app *App) approveTask(cmd *cobra.Command, identifier string) error {
+	if app.taskManager == nil {
+		return fmt.Errorf("task manager not initialized")
+	}
+
+	// Try to find task by ID or name
+	task, err := app.taskManager.GetTask(identifier)
+	if err != nil {
+		// If not found by ID, try by name
+		task, err = app.taskManager.GetTaskByName(identifier)
+		if err != nil {
+			return fmt.Errorf("task not found: %s", identifier)
+		}
+	}
+
+	if task.Metadata[workflow.MetadataImplementationPlan] == "" {
+		return fmt.Errorf("task '%s' has no implementation plan to approve", task.Name)
+	}
+	if approvedBy := task.Metadata[workflow.MetadataPlanApprovedBy]; approvedBy != "" {
+		cmd.Printf("ℹ️  Plan for task '%s' was already approved by %s\n", task.Name, approvedBy)
+		return nil
+	}
+
+	approvedBy := os.Getenv("USER")
+	if approvedBy == "" {
+		approvedBy = "cli"
+	}
+
+	metadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		metadata[key] = value
+	}
+	metadata[workflow.MetadataPlanApprovedBy] = approvedBy
+
+	if _, err := app.taskManager.UpdateTask(&types.UpdateTaskRequest{TaskID: task.ID, Metadata: &metadata}); err != nil {
+		return fmt.Errorf("failed to approve plan: %w", err)
+	}
+
+	cmd.Printf("✅ Approved the plan for task '%s'; implementation starts on the next workflow run\n", task.Name)
 	return nil
 }
 
@@ -604,16 +676,6 @@ func (app *App) showTaskLogs(cmd *cobra.Command, identifier string) error {
 	return nil
 }
 
-// Workflow command implementation
-func (app *App) startWorkflow(cmd *cobra.Command) error {
-	maxAgents, _ := cmd.Flags().GetInt("max-agents")
-	provider, _ := cmd.Flags().GetString("provider")
-	repo, _ := cmd.Flags().GetString("repo")
-
-	cmd.Printf("Start workflow with max %d agents, provider %s, repo %s - not yet implemented\n", maxAgents, provider, repo)
-	return nil
-}
-
 // Helper functions
 func getStatusIcon(status types.TaskStatus) string {
 	switch status {
@@ -693,55 +755,106 @@ func (app *App) addWorkflowCommands() {
 		},
 	}
 
+	// Retry command
+	retryCmd := &cobra.Command{
+		Use:   "retry <workflow-id>",
+		Short: "Retry a failed workflow",
+		Long:  "Requeue a workflow that exhausted its retries, resuming from the state it failed in",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.retryWorkflow(cmd, args[0])
+		},
+	}
+
+	// Pause command
+	pauseCmd := &cobra.Command{
+		Use:   "pause <workflow-id>",
+		Short: "Pause a workflow",
+		Long:  "Pause an active workflow and stop its agent container; it resumes in the state it was paused in",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.pauseWorkflow(cmd, args[0])
+		},
+	}
+
+	// Resume command
+	resumeCmd := &cobra.Command{
+		Use:   "resume <workflow-id>",
+		Short: "Resume a paused workflow",
+		Long:  "Resume a paused workflow in the state it was paused in, restarting the agent container if the agent was working",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.resumeWorkflow(cmd, args[0])
+		},
+	}
+
+	// Abort command
+	abortCmd := &cobra.Command{
+		Use:   "abort <workflow-id>",
+		Short: "Abort a workflow",
+		Long:  "Stop a workflow for good, stopping its agent container and cancelling its task",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.abortWorkflow(cmd, args[0])
+		},
+	}
+
+	// History command
+	historyCmd := &cobra.Command{
+		Use:   "history <workflow-id>",
+		Short: "Show workflow state history",
+		Long:  "Display the state transitions of a workflow as a timeline with the time spent in each phase",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.showWorkflowHistory(cmd, args[0])
+		},
+	}
+
+	// Replay command
+	replayCmd := &cobra.Command{
+		Use:   "replay <workflow-id>",
+		Short: "Rebuild a workflow from its log",
+		Long:  "Rebuild a workflow's state from the workflow log, verify it matches the workflows file cache, and recover a corrupted snapshot or workflows file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			repair, _ := cmd.Flags().GetBool("repair")
+			return app.replayWorkflow(cmd, args[0], repair)
+		},
+	}
+
+	// Graph command
+	graphCmd := &cobra.Command{
+		Use:   "graph",
+		Short: "Show workflow dependencies",
+		Long:  "Display the dependency graph of workflows, showing which issues wait on which blockers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.showWorkflowGraph(cmd)
+		},
+	}
+
 	// Add flags
 	scanCmd.Flags().String("provider", "github", "Git provider to use (github, gitlab, bitbucket)")
 	scanCmd.Flags().String("owner", "", "Repository owner (defaults to auto-detected from current repository)")
 	scanCmd.Flags().String("repo", "", "Repository name (defaults to auto-detected from current repository)")
+	scanCmd.Flags().Bool("dry-run", false, "Show what the scan would create without creating it")
 
-	startCmd.Flags().IntP("max-concurrent", "n", 1, "Maximum number of concurrent workflow processors")
+	startCmd.Flags().IntP("max-concurrent", "n", 1, "Maximum number of concurrent workflow processors (overrides max_concurrent in the repositories file)")
 	startCmd.Flags().Bool("daemon", false, "Run as daemon process")
+	startCmd.Flags().String("provider", "github", "Git provider to use (github, gitlab, bitbucket)")
+	startCmd.Flags().Duration("interval", workflow.DefaultPollInterval, "Interval between polls when running as daemon")
+	startCmd.Flags().Bool("dry-run", false, "Run one pass that prints the plan per workflow instead of pushing or changing anything on the provider")
+	startCmd.Flags().String("repos", "", "File listing repositories to orchestrate (default: .cw/repositories.yaml if present)")
 
 	listCmd.Flags().String("state", "", "Filter by workflow state (queued, implementing, pr_open, etc.)")
 	listCmd.Flags().Bool("active-only", false, "Show only active (non-terminal) workflows")
+	replayCmd.Flags().Bool("repair", false, "Replace a cached workflow that differs with the replayed one")
+	listCmd.Flags().StringSlice("repo", nil, "Only show workflows for these repositories (owner/repo, or owner/* for every repository of an owner)")
 
-	workflowCmd.AddCommand(scanCmd, startCmd, listCmd, statusCmd)
+	workflowCmd.AddCommand(scanCmd, startCmd, listCmd, statusCmd, retryCmd, pauseCmd, resumeCmd, abortCmd, historyCmd, replayCmd, graphCmd)
 	app.rootCmd.AddCommand(workflowCmd)
 }
 
-// validateProviderAuth validates that provider authentication is configured
-func (app *App) validateProviderAuth(providerName string) error {
-	// Create auth manager
-	authManager, err := auth.NewManager(app.configManager)
-	if err != nil {
-		return fmt.Errorf("failed to create auth manager: %w", err)
-	}
-
-	// Convert provider name to type
-	var providerType git.ProviderType
-	switch strings.ToLower(providerName) {
-	case "github":
-		providerType = git.ProviderGitHub
-	case "gitlab":
-		providerType = git.ProviderGitLab
-	case "bitbucket":
-		providerType = git.ProviderBitbucket
-	default:
-		return fmt.Errorf("unsupported provider: %s", providerName)
-	}
-
-	// Try to get auth config (check project first, then global)
-	_, err = authManager.GetAuthConfig(providerType, auth.AuthScopeProject)
-	if err != nil {
-		// Fallback to global scope
-		_, err = authManager.GetAuthConfig(providerType, auth.AuthScopeGlobal)
-		if err != nil {
-			return fmt.Errorf("no authentication configured for %s. Run 'cw config provider %s login' first", providerName, providerName)
-		}
-	}
-
-	return nil
-}
-
 // detectRepositoryInfo auto-detects repository information from current directory
 func (app *App) detectRepositoryInfo() (owner, repo string, err error) {
 	repoInfo, err := git.GetRepositoryInfo(".")
@@ -781,6 +894,7 @@ func (app *App) scanWorkflows(cmd *cobra.Command) error {
 	provider, _ := cmd.Flags().GetString("provider")
 	owner, _ := cmd.Flags().GetString("owner")
 	repo, _ := cmd.Flags().GetString("repo")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 	// Auto-detect repository info if not provided
 	if owner == "" || repo == "" {
@@ -798,100 +912,62 @@ func (app *App) scanWorkflows(cmd *cobra.Command) error {
 
 	cmd.Printf("🔍 Scanning issues from %s provider for %s/%s...\n", provider, owner, repo)
 
-	// Validate provider authentication
-	if err := app.validateProviderAuth(provider); err != nil {
-		return fmt.Errorf("provider authentication validation failed: %w", err)
-	}
-
-	// For now, we'll create placeholder tasks to demonstrate the workflow
-	// TODO: Implement full issue scanning when GitProvider interface is ready
-
-	cmd.Printf("⚠️  Issue scanning is temporarily simplified\n")
-	cmd.Printf("📋 Creating example task to demonstrate workflow functionality\n")
-
-	// Create an example task to demonstrate workflow
-	ticketID := fmt.Sprintf("%s:%s/%s#example", provider, owner, repo)
-	req := &types.CreateTaskRequest{
-		Name:        fmt.Sprintf("Example workflow task for %s/%s", owner, repo),
-		Description: "This is an example task created to demonstrate workflow functionality. Replace with actual issue scanning when the provider interface is ready.",
-		TicketID:    ticketID,
-		URL:         fmt.Sprintf("https://github.com/%s/%s", owner, repo),
-		Priority:    1, // Default priority
-		Tags:        []string{provider, "workflow", "example"},
-		Metadata: map[string]string{
-			"provider":     provider,
-			"owner":        owner,
-			"repo":         repo,
-			"issue_number": "example",
-			"issue_title":  "Example workflow task",
-			"created_at":   "2024-01-01T00:00:00Z",
-		},
-	}
-
-	// Check if task already exists
-	existingTasks, err := app.taskManager.ListTasks(nil)
+	prTemplate, err := prbody.LoadTemplate(".cw")
 	if err != nil {
-		return fmt.Errorf("failed to list existing tasks: %w", err)
+		return err
 	}
 
-	taskExists := false
-	for _, task := range existingTasks {
-		if task.TicketID == ticketID {
-			taskExists = true
-			cmd.Printf("✅ Example task already exists: %s (Task ID: %d)\n", task.Name, task.ID)
-			break
-		}
+	coworkProvider, workspaceManager, err := app.newCoworkProvider(provider, "", prTemplate)
+	if err != nil {
+		return err
 	}
 
-	if !taskExists {
-		task, err := app.taskManager.CreateTask(req)
-		if err != nil {
-			return fmt.Errorf("failed to create example task: %w", err)
-		}
-
-		cmd.Printf("✅ Created example task: %s (Task ID: %d)\n", task.Name, task.ID)
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
 	}
+	defer workflowManager.Close()
 
-	cmd.Printf("✅ Issue scanning completed successfully\n")
-	return nil
+	manager := workflow.NewManager(coworkProvider, app.taskManager, workspaceManager, owner, repo)
+	manager.SetWorkflowManager(workflowManager)
+	return app.scanIssues(cmd, manager, dryRun)
 }
 
-func (app *App) startWorkflows(cmd *cobra.Command) error {
-	maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
-	daemon, _ := cmd.Flags().GetBool("daemon")
-
-	// Auto-detect repository info
-	owner, repo, err := app.detectRepositoryInfo()
+// scanIssues creates tasks and workflows for the issues the repository's
+// workflow config lets cowork pick up, or lists them on a dry run. The
+// workflows keep a snapshot of that config.
+func (app *App) scanIssues(cmd *cobra.Command, manager *workflow.Manager, dryRun bool) error {
+	workflowConfig, err := app.loadWorkflowConfig()
 	if err != nil {
-		return fmt.Errorf("failed to auto-detect repository info: %w", err)
+		return err
 	}
+	manager.SetConfig(workflowConfig)
 
-	cmd.Printf("🚀 Starting workflow automation for %s/%s...\n", owner, repo)
-	cmd.Printf("📊 Max concurrent processors: %d\n", maxConcurrent)
-
-	if daemon {
-		cmd.Printf("🔄 Running in daemon mode...\n")
-		// TODO: Implement daemon mode
-		return fmt.Errorf("daemon mode not yet implemented")
+	ctx := context.Background()
+	if dryRun {
+		issues, err := manager.ScanIssues(ctx)
+		if err != nil {
+			return err
+		}
+		for _, issue := range issues {
+			cmd.Printf("📝 [dry-run] Would pick up issue #%d: %s\n", issue.Number, issue.Title)
+		}
+		cmd.Printf("✅ Found %d issues to pick up\n", len(issues))
+		return nil
 	}
 
-	// For now, we'll provide a simplified workflow start implementation
-	// TODO: Implement full workflow automation when CoworkProvider is ready
-
-	cmd.Printf("⚠️  Full workflow automation not yet implemented\n")
-	cmd.Printf("📋 You can use the following commands to manage workflows manually:\n")
-	cmd.Printf("   • cw workflow scan - Scan for issues and create tasks\n")
-	cmd.Printf("   • cw task list - View created tasks\n")
-	cmd.Printf("   • cw workflow list - View workflow status\n")
-	cmd.Printf("\n💡 Full automation will be available once the workflow engine is complete\n")
+	if err := manager.ScanAndCreateTasks(ctx); err != nil {
+		return err
+	}
 
-	cmd.Printf("✅ Workflow automation completed successfully\n")
+	cmd.Printf("✅ Issue scanning completed successfully\n")
 	return nil
 }
 
 func (app *App) listWorkflows(cmd *cobra.Command) error {
 	stateFilter, _ := cmd.Flags().GetString("state")
 	activeOnly, _ := cmd.Flags().GetBool("active-only")
+	repoFilters, _ := cmd.Flags().GetStringSlice("repo")
 
 	// Create workflow manager
 	coworkDir := filepath.Join(".", ".cowork")
@@ -928,6 +1004,13 @@ func (app *App) listWorkflows(cmd *cobra.Command) error {
 		workflows = activeWorkflows
 	}
 
+	if len(repoFilters) > 0 {
+		workflows, err = filterWorkflowsByRepo(workflows, repoFilters)
+		if err != nil {
+			return err
+		}
+	}
+
 	if len(workflows) == 0 {
 		cmd.Println("No workflows found.")
 		return nil
@@ -941,7 +1024,13 @@ func (app *App) listWorkflows(cmd *cobra.Command) error {
 		if workflow.BranchName != "" {
 			cmd.Printf("   Branch: %s\n", workflow.BranchName)
 		}
-		if workflow.PRNumber != nil {
+		if numbers := workflow.PRNumbers(); len(numbers) > 1 {
+			refs := make([]string, len(numbers))
+			for i, number := range numbers {
+				refs[i] = fmt.Sprintf("#%d", number)
+			}
+			cmd.Printf("   PRs: %s\n", strings.Join(refs, ", "))
+		} else if workflow.PRNumber != nil {
 			cmd.Printf("   PR: #%d\n", *workflow.PRNumber)
 		}
 		cmd.Printf("   Created: %s\n", workflow.CreatedAt.Format("2006-01-02 15:04:05"))
@@ -983,6 +1072,19 @@ func (app *App) showWorkflowStatus(cmd *cobra.Command, workflowID string) error
 		cmd.Printf("Pull Request: #%d\n", *workflow.PRNumber)
 	}
 
+	if len(workflow.StackedPRs) > 0 {
+		cmd.Printf("Stacked PRs:\n")
+		for i, part := range workflow.StackedPRs {
+			status := "open"
+			if part.Merged {
+				status = "merged"
+			} else if part.Number == 0 {
+				status = "not opened"
+			}
+			cmd.Printf("  %d. #%d %s → %s (%d file(s), %s)\n", i+1, part.Number, part.Branch, part.Base, len(part.Files), status)
+		}
+	}
+
 	if workflow.TaskID != 0 {
 		cmd.Printf("Task ID: %d\n", workflow.TaskID)
 	}
@@ -991,6 +1093,10 @@ func (app *App) showWorkflowStatus(cmd *cobra.Command, workflowID string) error
 		cmd.Printf("Workspace ID: %d\n", workflow.WorkspaceID)
 	}
 
+	if len(workflow.Config.RequiredChecks) > 0 {
+		cmd.Printf("Required Checks: %s (%s)\n", strings.Join(workflow.Config.RequiredChecks, ", "), requiredChecksState(workflow))
+	}
+
 	cmd.Printf("\nTimestamps:\n")
 	cmd.Printf("Created: %s\n", workflow.CreatedAt.Format("2006-01-02 15:04:05"))
 	cmd.Printf("Updated: %s\n", workflow.UpdatedAt.Format("2006-01-02 15:04:05"))
@@ -1003,18 +1109,30 @@ func (app *App) showWorkflowStatus(cmd *cobra.Command, workflowID string) error
 		cmd.Printf("Ended: %s\n", workflow.EndedAt.Format("2006-01-02 15:04:05"))
 	}
 
-	if workflow.ErrorCount > 0 {
-		cmd.Printf("\nErrors: %d\n", workflow.ErrorCount)
+	if workflow.ErrorCount > 0 || workflow.LastError != "" {
+		cmd.Printf("\nErrors: %d (max retries: %d)\n", workflow.ErrorCount, workflow.Config.MaxRetries)
 		if workflow.LastError != "" {
 			cmd.Printf("Last Error: %s\n", workflow.LastError)
 		}
+		if workflow.NextRetryAt != nil {
+			cmd.Printf("Next Retry: %s\n", workflow.NextRetryAt.Format("2006-01-02 15:04:05"))
+		}
+		if workflow.State == types.WorkflowStateFailed {
+			cmd.Printf("💡 Run 'cw workflow retry %d' to try again\n", workflow.ID)
+		}
+		if workflow.State == types.WorkflowStatePaused {
+			cmd.Printf("💡 Run 'cw workflow resume %d' to continue\n", workflow.ID)
+		}
 	}
 
 	// Check if workflow is locked
 	locked, lock := workflowManager.IsWorkflowLocked(workflowID)
 	if locked {
-		cmd.Printf("\n🔒 Locked by: %s\n", lock.LockedBy)
+		cmd.Printf("\n🔒 Locked by: %s (pid %d)\n", lock.LockedBy, lock.ProcessID)
 		cmd.Printf("Lock timeout: %s\n", lock.LockTimeout.Format("2006-01-02 15:04:05"))
+		if !lock.HeartbeatAt.IsZero() {
+			cmd.Printf("Last heartbeat: %s\n", lock.HeartbeatAt.Format("2006-01-02 15:04:05"))
+		}
 	}
 
 	return nil
@@ -1089,10 +1207,16 @@ func getWorkflowStatusIcon(state types.WorkflowState) string {
 	switch state {
 	case types.WorkflowStateQueued:
 		return "⏳"
+	case types.WorkflowStateAwaitingClarification:
+		return "🤔"
 	case types.WorkflowStateWorkspaceReady:
 		return "🏗️"
+	case types.WorkflowStatePlanReview:
+		return "📝"
 	case types.WorkflowStateImplementing:
 		return "💻"
+	case types.WorkflowStateReviewing:
+		return "🧐"
 	case types.WorkflowStatePROpen:
 		return "🔍"
 	case types.WorkflowStateRevising:
@@ -1103,6 +1227,10 @@ func getWorkflowStatusIcon(state types.WorkflowState) string {
 		return "❌"
 	case types.WorkflowStateAborted:
 		return "🚫"
+	case types.WorkflowStateFailed:
+		return "💥"
+	case types.WorkflowStatePaused:
+		return "⏸️"
 	default:
 		return "❓"
 	}
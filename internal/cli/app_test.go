@@ -139,7 +139,7 @@ func TestApp_VersionCommand(t *testing.T) {
 // TestApp_TaskCommands tests that task commands are properly structured
 func TestApp_TaskCommands(t *testing.T) {
 	// Test case: The task command should have the expected subcommands
-	// including list, sync, describe, priority, start, stop, kill, and logs commands
+	// including list, sync, describe, priority, start, stop, kill, logs, and approve commands
 	app := NewApp("1.0.0", "2024-01-01", "test")
 
 	// Find the task command
@@ -165,7 +165,7 @@ func TestApp_TaskCommands(t *testing.T) {
 	taskSubcommands := taskCmd.Commands()
 
 	// Define expected subcommand names
-	expectedSubcommands := []string{"list", "sync", "describe", "priority", "start", "stop", "kill", "logs"}
+	expectedSubcommands := []string{"list", "sync", "describe", "priority", "start", "stop", "kill", "logs", "approve"}
 
 	// Create a map of found subcommands for easy lookup
 	foundSubcommands := make(map[string]bool)
@@ -1,10 +1,17 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/hlfshell/cowork/internal/auth"
+	"github.com/hlfshell/cowork/internal/git"
+	gitprovider "github.com/hlfshell/cowork/internal/git/providers"
+	"github.com/hlfshell/cowork/internal/types"
+	"github.com/hlfshell/cowork/internal/workflow"
 	"github.com/spf13/cobra"
 )
 
@@ -35,7 +42,20 @@ func addGitCommands(app *App) *cobra.Command {
 	// Add provider commands
 	providerCmd := addGitProviderCommands(app)
 
-	gitCmd.AddCommand(authCmd, providerCmd)
+	// Add branch garbage collection command
+	gcBranchesCmd := &cobra.Command{
+		Use:   "gc-branches",
+		Short: "Delete orphaned cowork branches",
+		Long:  "Find remote branches cowork created that no active workflow uses anymore and delete them after confirmation. Branches cowork did not create are never touched.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.gcBranches(cmd)
+		},
+	}
+	gcBranchesCmd.Flags().String("repo", "", "Repository as owner/repo (auto-detected if not provided)")
+	gcBranchesCmd.Flags().String("provider", "github", "Git provider hosting the repository")
+	gcBranchesCmd.Flags().BoolP("yes", "y", false, "Delete without asking for confirmation")
+
+	gitCmd.AddCommand(authCmd, providerCmd, gcBranchesCmd)
 
 	return gitCmd
 }
@@ -203,3 +223,112 @@ func (app *App) setGitBasicAuth(cmd *cobra.Command, username, password string, s
 	authManager.SetGitBasicAuth(username, password, scope)
 	return nil
 }
+
+// gcBranches deletes the branches cowork created that no active workflow uses
+func (app *App) gcBranches(cmd *cobra.Command) error {
+	repoFlag, _ := cmd.Flags().GetString("repo")
+	providerName, _ := cmd.Flags().GetString("provider")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	var owner, repo string
+	var err error
+	if repoFlag != "" {
+		owner, repo, err = parseRepoFlag(repoFlag)
+	} else {
+		owner, repo, err = app.detectRepositoryInfo()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to determine repository: %w", err)
+	}
+
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	orphaned, err := workflowManager.OrphanedBranches(owner, repo)
+	if err != nil {
+		return fmt.Errorf("failed to find orphaned branches: %w", err)
+	}
+	if len(orphaned) == 0 {
+		cmd.Printf("✨ No orphaned cowork branches in %s/%s\n", owner, repo)
+		return nil
+	}
+
+	provider, err := app.newGitProvider(providerName)
+	if err != nil {
+		return err
+	}
+
+	// Branches already gone from the remote only need dropping from the registry
+	ctx := context.Background()
+	var stale []*types.CreatedBranch
+	for _, branch := range orphaned {
+		exists, err := provider.BranchExists(ctx, owner, repo, branch.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to check branch %s: %w", branch.Branch, err)
+		}
+		if !exists {
+			if err := workflowManager.ForgetCreatedBranch(owner, repo, branch.Branch); err != nil {
+				return fmt.Errorf("failed to update branch registry: %w", err)
+			}
+			continue
+		}
+		stale = append(stale, branch)
+	}
+	if len(stale) == 0 {
+		cmd.Printf("✨ No orphaned cowork branches in %s/%s\n", owner, repo)
+		return nil
+	}
+
+	cmd.Printf("🌿 Orphaned cowork branches in %s/%s:\n", owner, repo)
+	for _, branch := range stale {
+		cmd.Printf("  %s (workflow %d, created %s)\n", branch.Branch, branch.WorkflowID, branch.CreatedAt.Format("2006-01-02"))
+	}
+
+	if !yes {
+		var answer string
+		cmd.Printf("Delete %d branch(es)? [y/N]: ", len(stale))
+		fmt.Scanln(&answer)
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			cmd.Printf("Aborted, no branches deleted\n")
+			return nil
+		}
+	}
+
+	deleted := 0
+	for _, branch := range stale {
+		ok, err := workflow.DeleteCreatedBranch(ctx, workflowManager, provider, owner, repo, branch.Branch)
+		if err != nil {
+			cmd.Printf("❌ %s: %v\n", branch.Branch, err)
+			continue
+		}
+		if ok {
+			deleted++
+			cmd.Printf("🗑️  Deleted %s\n", branch.Branch)
+		}
+	}
+
+	cmd.Printf("✅ Deleted %d of %d orphaned branch(es)\n", deleted, len(stale))
+	return nil
+}
+
+// newGitProvider creates a provider client for the named provider from its stored credentials
+func (app *App) newGitProvider(providerName string) (git.GitProvider, error) {
+	authConfig, err := app.getProviderAuthConfig(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	switch authConfig.ProviderType {
+	case git.ProviderGitHub:
+		return gitprovider.NewGitHubProvider(authConfig.Token, authConfig.BaseURL)
+	case git.ProviderGitLab:
+		return gitprovider.NewGitLabProvider(authConfig.Token, authConfig.BaseURL)
+	case git.ProviderBitbucket:
+		return gitprovider.NewBitbucketProvider(authConfig.Token, authConfig.BaseURL)
+	default:
+		return nil, fmt.Errorf("unsupported provider: %s", providerName)
+	}
+}
@@ -0,0 +1,152 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hlfshell/cowork/internal/types"
+	"github.com/hlfshell/cowork/internal/webhook"
+	"github.com/hlfshell/cowork/internal/workflow"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// githubWebhookSecretEnv is the environment variable holding the GitHub webhook secret
+	githubWebhookSecretEnv = "CW_GITHUB_WEBHOOK_SECRET"
+
+	// gitlabWebhookSecretEnv is the environment variable holding the GitLab webhook secret
+	gitlabWebhookSecretEnv = "CW_GITLAB_WEBHOOK_SECRET"
+)
+
+// addServeCommands adds commands that run long-lived servers
+func (app *App) addServeCommands() {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run cowork servers",
+		Long:  "Run long-lived cowork servers such as the webhook receiver",
+	}
+
+	webhooksCmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Receive provider webhooks and drive workflows",
+		Long: `Start an HTTP server that receives GitHub and GitLab webhooks for issues,
+comments, pull requests, reviews and check suites. Each verified delivery is
+recorded as a workflow event and the workflow daemon is woken immediately so
+new feedback is acted on within seconds.
+
+Routes:
+  POST ` + webhook.GitHubPath + `   (verified with X-Hub-Signature-256)
+  POST ` + webhook.GitLabPath + `   (verified with X-Gitlab-Token)
+
+Secrets default to the ` + githubWebhookSecretEnv + ` and ` + gitlabWebhookSecretEnv + `
+environment variables.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.serveWebhooks(cmd)
+		},
+	}
+
+	webhooksCmd.Flags().String("addr", ":8787", "Address to listen on")
+	webhooksCmd.Flags().String("github-secret", "", "GitHub webhook secret (default: $"+githubWebhookSecretEnv+")")
+	webhooksCmd.Flags().String("gitlab-secret", "", "GitLab webhook secret token (default: $"+gitlabWebhookSecretEnv+")")
+	webhooksCmd.Flags().Bool("record-only", false, "Only record events; leave processing to a separate 'cw workflow start --daemon'")
+	webhooksCmd.Flags().String("provider", "github", "Git provider used to process workflows (github, gitlab, bitbucket)")
+	webhooksCmd.Flags().IntP("max-concurrent", "n", 1, "Maximum number of concurrent workflow processors (overrides max_concurrent in the repositories file)")
+	webhooksCmd.Flags().Duration("interval", workflow.DefaultPollInterval, "Interval between fallback polls for workflow updates")
+	webhooksCmd.Flags().String("repos", "", "File listing repositories to orchestrate (default: .cw/repositories.yaml if present)")
+
+	serveCmd.AddCommand(webhooksCmd)
+	app.rootCmd.AddCommand(serveCmd)
+}
+
+// serveWebhooks runs the webhook receiver, and the workflow daemon unless record-only
+func (app *App) serveWebhooks(cmd *cobra.Command) error {
+	addr, _ := cmd.Flags().GetString("addr")
+	githubSecret, _ := cmd.Flags().GetString("github-secret")
+	gitlabSecret, _ := cmd.Flags().GetString("gitlab-secret")
+	recordOnly, _ := cmd.Flags().GetBool("record-only")
+	provider, _ := cmd.Flags().GetString("provider")
+	maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	reposFlag, _ := cmd.Flags().GetString("repos")
+
+	if githubSecret == "" {
+		githubSecret = os.Getenv(githubWebhookSecretEnv)
+	}
+	if gitlabSecret == "" {
+		gitlabSecret = os.Getenv(gitlabWebhookSecretEnv)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var recorder *workflow.WorkflowManager
+	var daemon *workflow.Daemon
+	if recordOnly {
+		workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+		if err != nil {
+			return fmt.Errorf("failed to create workflow manager: %w", err)
+		}
+		defer workflowManager.Close()
+		recorder = workflowManager
+	} else {
+		repositories, err := app.loadRepositoriesConfig(reposFlag)
+		if err != nil {
+			return err
+		}
+
+		opts := workflowRunOptions{
+			provider:      provider,
+			maxConcurrent: maxConcurrent,
+			pollInterval:  interval,
+			daemon:        true,
+			repositories:  repositories,
+		}
+		workflowDaemon, workflowManager, err := app.newWorkflowDaemon(cmd, &opts)
+		if err != nil {
+			return err
+		}
+		defer workflowManager.Close()
+		recorder = workflowManager
+		daemon = workflowDaemon
+	}
+
+	server, err := webhook.NewServer(recorder, webhook.Config{
+		GitHubSecret: githubSecret,
+		GitLabSecret: gitlabSecret,
+	}, func(event *types.WorkflowEvent) {
+		if daemon != nil {
+			daemon.Wake()
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook server: %w", err)
+	}
+
+	daemonDone := make(chan error, 1)
+	if daemon != nil {
+		go func() {
+			daemonDone <- daemon.Run(ctx)
+		}()
+	} else {
+		daemonDone <- nil
+	}
+
+	cmd.Printf("📡 Listening for webhooks on %s (press Ctrl+C to stop)...\n", addr)
+	serveErr := server.ListenAndServe(ctx, addr)
+
+	// Make sure the daemon stops even if the server failed on its own
+	stop()
+	if err := <-daemonDone; err != nil {
+		return fmt.Errorf("workflow daemon failed: %w", err)
+	}
+	if serveErr != nil {
+		return serveErr
+	}
+
+	cmd.Printf("✅ Webhook server stopped\n")
+	return nil
+}
@@ -0,0 +1,959 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/auth"
+	"github.com/hlfshell/cowork/internal/git"
+	gitprovider "github.com/hlfshell/cowork/internal/git/providers"
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/task"
+	"github.com/hlfshell/cowork/internal/types"
+	"github.com/hlfshell/cowork/internal/workflow"
+	"github.com/hlfshell/cowork/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+// workflowRunOptions contains the settings for running workflow automation
+type workflowRunOptions struct {
+	provider      string
+	owner         string
+	repo          string
+	maxConcurrent int
+	pollInterval  time.Duration
+	daemon        bool
+
+	// Repositories to orchestrate from one daemon; nil runs a single repository
+	repositories *workflow.RepositoriesConfig
+
+	// Run one pass that records pushes and provider changes instead of making them
+	dryRun bool
+
+	// Collects the recorded changes of a dry run
+	recorder *workflow.DryRunRecorder
+
+	// Directory holding the workflow state (default: .cowork)
+	stateDir string
+}
+
+// startWorkflow implements `cw go`, running the workflow daemon for a repository
+func (app *App) startWorkflow(cmd *cobra.Command) error {
+	maxAgents, _ := cmd.Flags().GetInt("max-agents")
+	provider, _ := cmd.Flags().GetString("provider")
+	repoFlag, _ := cmd.Flags().GetString("repo")
+	reposFlag, _ := cmd.Flags().GetString("repos")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	opts := workflowRunOptions{
+		provider:      provider,
+		maxConcurrent: maxAgents,
+		pollInterval:  interval,
+		daemon:        true,
+	}
+
+	if repoFlag != "" {
+		owner, repo, err := parseRepoFlag(repoFlag)
+		if err != nil {
+			return err
+		}
+		opts.owner = owner
+		opts.repo = repo
+	} else {
+		repositories, err := app.loadRepositoriesConfig(reposFlag)
+		if err != nil {
+			return err
+		}
+		opts.repositories = repositories
+	}
+
+	return app.runWorkflows(cmd, opts)
+}
+
+// startWorkflows implements `cw workflow start`
+func (app *App) startWorkflows(cmd *cobra.Command) error {
+	maxConcurrent, _ := cmd.Flags().GetInt("max-concurrent")
+	daemon, _ := cmd.Flags().GetBool("daemon")
+	provider, _ := cmd.Flags().GetString("provider")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	reposFlag, _ := cmd.Flags().GetString("repos")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	repositories, err := app.loadRepositoriesConfig(reposFlag)
+	if err != nil {
+		return err
+	}
+
+	return app.runWorkflows(cmd, workflowRunOptions{
+		provider:      provider,
+		maxConcurrent: maxConcurrent,
+		pollInterval:  interval,
+		daemon:        daemon,
+		repositories:  repositories,
+		dryRun:        dryRun,
+	})
+}
+
+// workflowStateDir returns the directory holding the workflow state
+func (opts *workflowRunOptions) workflowStateDir() string {
+	if opts.stateDir != "" {
+		return opts.stateDir
+	}
+	return filepath.Join(".", ".cowork")
+}
+
+// prepareDryRun points a run at a scratch copy of the workflow and task state
+// and sets up the recorder for its changes. The returned cleanup function
+// restores the task manager and removes the copy.
+func (app *App) prepareDryRun(cmd *cobra.Command, opts *workflowRunOptions) (func(), error) {
+	scratchDir, err := workflow.CopyStateForDryRun(opts.workflowStateDir())
+	if err != nil {
+		return nil, err
+	}
+
+	taskManager, err := task.NewManager(scratchDir, 300)
+	if err != nil {
+		os.RemoveAll(scratchDir)
+		return nil, fmt.Errorf("failed to create dry run task manager: %w", err)
+	}
+
+	realTaskManager := app.taskManager
+	app.taskManager = taskManager
+	opts.stateDir = scratchDir
+	opts.recorder = workflow.NewDryRunRecorder()
+
+	cmd.Printf("🧪 Dry run: nothing will be pushed or changed on the provider, and workflow state is left as is\n")
+
+	return func() {
+		app.taskManager = realTaskManager
+		os.RemoveAll(scratchDir)
+	}, nil
+}
+
+// runDryRun runs a single processing pass and prints, per workflow, the state
+// it would reach and the changes it would make
+func (app *App) runDryRun(cmd *cobra.Command, daemon *workflow.Daemon, workflowManager *workflow.WorkflowManager, recorder *workflow.DryRunRecorder) error {
+	before := make(map[string]types.WorkflowState)
+	workflows, err := workflowManager.ListWorkflows()
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+	for _, wf := range workflows {
+		before[fmt.Sprintf("%d", wf.ID)] = wf.State
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := daemon.RunOnce(ctx); err != nil {
+		return fmt.Errorf("failed to process workflows: %w", err)
+	}
+
+	plan := recorder.Plan()
+	workflows, err = workflowManager.ListWorkflows()
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].ID < workflows[j].ID })
+
+	cmd.Printf("\n📋 Dry run plan:\n")
+	planned := 0
+	for _, wf := range workflows {
+		workflowID := fmt.Sprintf("%d", wf.ID)
+		mutations := plan[workflowID]
+		if len(mutations) == 0 && wf.State == before[workflowID] {
+			continue
+		}
+		planned++
+
+		cmd.Printf("\n%s Workflow %d: %s/%s#%d\n", getWorkflowStatusIcon(wf.State), wf.ID, wf.Owner, wf.Repo, wf.IssueID)
+		if wf.State != before[workflowID] {
+			cmd.Printf("   State: %s → %s\n", before[workflowID], wf.State)
+		}
+		for _, mutation := range mutations {
+			cmd.Printf("   • %s: %s\n", mutation.Kind, mutation.Description)
+		}
+	}
+
+	if other := plan[""]; len(other) > 0 {
+		planned++
+		cmd.Printf("\n🔧 Outside any workflow:\n")
+		for _, mutation := range other {
+			cmd.Printf("   • %s on %s: %s\n", mutation.Kind, mutation.Repository, mutation.Description)
+		}
+	}
+
+	if planned == 0 {
+		cmd.Printf("\nNo workflow would change.\n")
+	}
+
+	return nil
+}
+
+// loadRepositoriesConfig reads the repositories to orchestrate from path, or
+// from the repository's repositories file when path is empty. It returns nil
+// when there is nothing to load, meaning only the current repository is run.
+func (app *App) loadRepositoriesConfig(path string) (*workflow.RepositoriesConfig, error) {
+	if path == "" {
+		path = filepath.Join(".cw", workflow.RepositoriesFileName)
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil
+		}
+	}
+
+	return workflow.LoadRepositoriesConfig(path)
+}
+
+// runWorkflows processes active workflows once, or continuously when running as a daemon
+func (app *App) runWorkflows(cmd *cobra.Command, opts workflowRunOptions) error {
+	if opts.dryRun {
+		cleanup, err := app.prepareDryRun(cmd, &opts)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+	}
+
+	daemon, workflowManager, err := app.newWorkflowDaemon(cmd, &opts)
+	if err != nil {
+		return err
+	}
+	defer workflowManager.Close()
+
+	if opts.dryRun {
+		return app.runDryRun(cmd, daemon, workflowManager, opts.recorder)
+	}
+
+	// Stop cleanly on SIGINT/SIGTERM, letting in-flight workflows finish
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !opts.daemon {
+		if err := daemon.RunOnce(ctx); err != nil {
+			return fmt.Errorf("failed to process workflows: %w", err)
+		}
+		cmd.Printf("✅ Workflow processing pass completed\n")
+		return nil
+	}
+
+	cmd.Printf("🔄 Running in daemon mode (poll interval: %s, press Ctrl+C to stop)...\n", opts.pollInterval)
+	if err := daemon.Run(ctx); err != nil {
+		return fmt.Errorf("workflow daemon failed: %w", err)
+	}
+
+	cmd.Printf("✅ Workflow automation stopped\n")
+	return nil
+}
+
+// newWorkflowDaemon creates the workflow manager, engine, and daemon described
+// by opts, filling in the repository from the current directory if unset.
+// The caller must close the returned workflow manager.
+func (app *App) newWorkflowDaemon(cmd *cobra.Command, opts *workflowRunOptions) (*workflow.Daemon, *workflow.WorkflowManager, error) {
+	if opts.repositories != nil {
+		return app.newMultiRepoDaemon(cmd, opts)
+	}
+
+	if opts.maxConcurrent <= 0 {
+		return nil, nil, fmt.Errorf("max concurrent must be positive, got %d", opts.maxConcurrent)
+	}
+
+	// Auto-detect repository info if not provided
+	if opts.owner == "" || opts.repo == "" {
+		owner, repo, err := app.detectRepositoryInfo()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to auto-detect repository info: %w", err)
+		}
+		opts.owner = owner
+		opts.repo = repo
+	}
+
+	workflowConfig, err := app.loadWorkflowConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd.Printf("🚀 Starting workflow automation for %s/%s...\n", opts.owner, opts.repo)
+	cmd.Printf("📊 Max concurrent processors: %d\n", opts.maxConcurrent)
+
+	workflowManager, err := workflow.NewWorkflowManager(opts.workflowStateDir())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	workflowManager.SetDefaultConfig(workflowConfig)
+
+	engine, err := app.newWorkflowEngine(workflowManager, workflowConfig, opts.provider, "", opts.owner, opts.repo)
+	if err != nil {
+		workflowManager.Close()
+		return nil, nil, err
+	}
+	if opts.recorder != nil {
+		engine.SetDryRun(opts.recorder)
+	}
+
+	// Abort agents that run past the job timeout
+	workflowManager.SetJobTimeoutHandler(func(wf *types.Workflow) error {
+		return engine.HandleJobTimeout(context.Background(), wf)
+	})
+
+	daemon, err := workflow.NewDaemon(workflowManager, engine, workflow.DaemonConfig{
+		MaxConcurrent: opts.maxConcurrent,
+		PollInterval:  opts.pollInterval,
+		Owner:         opts.owner,
+		Repo:          opts.repo,
+	})
+	if err != nil {
+		workflowManager.Close()
+		return nil, nil, fmt.Errorf("failed to create workflow daemon: %w", err)
+	}
+
+	return daemon, workflowManager, nil
+}
+
+// newMultiRepoDaemon creates a daemon driving one engine per repository listed
+// in opts.repositories, sharing one concurrency budget. The caller must close
+// the returned workflow manager.
+func (app *App) newMultiRepoDaemon(cmd *cobra.Command, opts *workflowRunOptions) (*workflow.Daemon, *workflow.WorkflowManager, error) {
+	// An explicit flag wins over the repositories file
+	if opts.repositories.MaxConcurrent > 0 && !concurrencyFlagChanged(cmd) {
+		opts.maxConcurrent = opts.repositories.MaxConcurrent
+	}
+	if opts.maxConcurrent <= 0 {
+		return nil, nil, fmt.Errorf("max concurrent must be positive, got %d", opts.maxConcurrent)
+	}
+
+	workflowConfig, err := app.loadWorkflowConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cmd.Printf("🚀 Starting workflow automation for %d repositories...\n", len(opts.repositories.Repositories))
+	cmd.Printf("📊 Max concurrent processors: %d\n", opts.maxConcurrent)
+
+	workflowManager, err := workflow.NewWorkflowManager(opts.workflowStateDir())
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	workflowManager.SetDefaultConfig(workflowConfig)
+
+	router := workflow.NewRepositoryRouter(workflowManager)
+	for _, repository := range opts.repositories.Repositories {
+		repositoryConfig, err := repository.WorkflowConfig(workflowConfig)
+		if err != nil {
+			workflowManager.Close()
+			return nil, nil, err
+		}
+		workflowManager.SetRepositoryConfig(repository.Owner, repository.Repo, repositoryConfig)
+
+		engine, err := app.newWorkflowEngine(workflowManager, repositoryConfig, repository.Provider, repository.AuthScope, repository.Owner, repository.Repo)
+		if err != nil {
+			workflowManager.Close()
+			return nil, nil, fmt.Errorf("failed to create engine for %s: %w", repository.Key(), err)
+		}
+		if opts.recorder != nil {
+			engine.SetDryRun(opts.recorder)
+		}
+		router.AddRepository(repository.Owner, repository.Repo, engine)
+
+		if repository.MaxConcurrent > 0 {
+			cmd.Printf("   📦 %s (%s, max %d)\n", repository.Key(), repository.Provider, repository.MaxConcurrent)
+		} else {
+			cmd.Printf("   📦 %s (%s)\n", repository.Key(), repository.Provider)
+		}
+	}
+
+	// Abort agents that run past the job timeout
+	workflowManager.SetJobTimeoutHandler(func(wf *types.Workflow) error {
+		return router.HandleJobTimeout(context.Background(), wf)
+	})
+
+	daemon, err := workflow.NewDaemon(workflowManager, router, workflow.DaemonConfig{
+		MaxConcurrent:     opts.maxConcurrent,
+		PollInterval:      opts.pollInterval,
+		Repositories:      router.Repositories(),
+		RepoMaxConcurrent: opts.repositories.RepoLimits(),
+	})
+	if err != nil {
+		workflowManager.Close()
+		return nil, nil, fmt.Errorf("failed to create workflow daemon: %w", err)
+	}
+
+	return daemon, workflowManager, nil
+}
+
+// concurrencyFlagChanged reports whether the concurrency limit was given on the command line
+func concurrencyFlagChanged(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("max-concurrent") || cmd.Flags().Changed("max-agents")
+}
+
+// retryWorkflow requeues a failed workflow
+func (app *App) retryWorkflow(cmd *cobra.Command, workflowID string) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	retried, err := workflowManager.RetryWorkflow(workflowID, currentActor())
+	if err != nil {
+		return fmt.Errorf("failed to retry workflow: %w", err)
+	}
+
+	cmd.Printf("🔁 Workflow %d requeued in state %s\n", retried.ID, retried.State)
+	return nil
+}
+
+// pauseWorkflow pauses an active workflow and stops its agent container
+func (app *App) pauseWorkflow(cmd *cobra.Command, workflowID string) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	paused, err := workflowManager.PauseWorkflow(workflowID, currentActor())
+	if err != nil {
+		return fmt.Errorf("failed to pause workflow: %w", err)
+	}
+
+	app.controlAgentContainer(cmd, paused, false)
+
+	cmd.Printf("⏸️  Workflow %d paused in state %s\n", paused.ID, paused.Metadata[workflow.MetadataPausedState])
+	cmd.Printf("💡 Run 'cw workflow resume %d' to continue\n", paused.ID)
+	return nil
+}
+
+// resumeWorkflow resumes a paused workflow, restarting its agent container
+// when the agent was working on it
+func (app *App) resumeWorkflow(cmd *cobra.Command, workflowID string) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	resumed, err := workflowManager.ResumeWorkflow(workflowID, currentActor())
+	if err != nil {
+		return fmt.Errorf("failed to resume workflow: %w", err)
+	}
+
+	if resumed.State.IsAgentRunning() {
+		app.controlAgentContainer(cmd, resumed, true)
+	}
+
+	cmd.Printf("▶️  Workflow %d resumed in state %s\n", resumed.ID, resumed.State)
+	return nil
+}
+
+// abortWorkflow stops a workflow for good, stopping its agent container and
+// cancelling its task
+func (app *App) abortWorkflow(cmd *cobra.Command, workflowID string) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	aborted, err := workflowManager.AbortWorkflow(workflowID, currentActor())
+	if err != nil {
+		return fmt.Errorf("failed to abort workflow: %w", err)
+	}
+
+	app.controlAgentContainer(cmd, aborted, false)
+
+	if aborted.TaskID != 0 && app.taskManager != nil {
+		status := types.TaskStatusCancelled
+		_, err := app.taskManager.UpdateTask(&types.UpdateTaskRequest{
+			TaskID: aborted.TaskID,
+			Status: &status,
+		})
+		if err != nil {
+			cmd.Printf("⚠️  Failed to cancel task %d: %v\n", aborted.TaskID, err)
+		}
+	}
+
+	cmd.Printf("🚫 Workflow %d aborted\n", aborted.ID)
+	return nil
+}
+
+// controlAgentContainer starts or stops the agent container of a workflow's
+// workspace. The workflow state has already changed, so failures are only reported.
+func (app *App) controlAgentContainer(cmd *cobra.Command, wf *types.Workflow, start bool) {
+	if wf.WorkspaceID == 0 {
+		return
+	}
+
+	workspaceManager, err := workspace.NewManager(300)
+	if err != nil {
+		cmd.Printf("⚠️  Failed to create workspace manager: %v\n", err)
+		return
+	}
+
+	ctx := context.Background()
+	if start {
+		if err := workspaceManager.StartContainer(ctx, wf.WorkspaceID); err != nil {
+			cmd.Printf("⚠️  Failed to start agent container: %v\n", err)
+			return
+		}
+		cmd.Printf("🐳 Agent container restarted\n")
+		return
+	}
+
+	if err := workspaceManager.StopContainer(ctx, wf.WorkspaceID, 30); err != nil {
+		cmd.Printf("⚠️  Failed to stop agent container: %v\n", err)
+		return
+	}
+	cmd.Printf("🐳 Agent container stopped\n")
+}
+
+// showWorkflowHistory renders a workflow's state transitions as a timeline
+// with the time spent in each phase
+func (app *App) showWorkflowHistory(cmd *cobra.Command, workflowID string) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	wf, err := workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("workflow not found: %w", err)
+	}
+
+	transitions, err := workflowManager.ListTransitions(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to load workflow history: %w", err)
+	}
+
+	cmd.Printf("📜 Workflow %d History (issue #%d, %s/%s)\n", wf.ID, wf.IssueID, wf.Owner, wf.Repo)
+	cmd.Printf("================================\n\n")
+
+	if len(transitions) == 0 {
+		cmd.Printf("No transitions recorded. Workflows created before the history was kept have none.\n")
+		return nil
+	}
+
+	totals := make(map[types.WorkflowState]time.Duration)
+	var order []types.WorkflowState
+	for _, span := range workflow.PhaseSpans(transitions, time.Now()) {
+		t := span.Transition
+
+		from := string(t.From)
+		if from == "" {
+			from = "(new)"
+		}
+
+		duration := formatPhaseDuration(span.Duration)
+		if span.Current {
+			duration += " so far"
+		} else if t.To.IsTerminal() {
+			duration = "-"
+		}
+
+		cmd.Printf("%s  %-15s → %-15s %-14s by %s\n", t.Timestamp.Format("2006-01-02 15:04:05"), from, t.To, duration, t.Actor)
+		if t.Reason != "" {
+			cmd.Printf("    Reason: %s\n", t.Reason)
+		}
+		if t.Error != "" {
+			cmd.Printf("    Error: %s\n", t.Error)
+		}
+
+		if !t.To.IsTerminal() {
+			if _, seen := totals[t.To]; !seen {
+				order = append(order, t.To)
+			}
+			totals[t.To] += span.Duration
+		}
+	}
+
+	cmd.Printf("\n⏱️  Time per phase:\n")
+	for _, state := range order {
+		cmd.Printf("  %-15s %s\n", state, formatPhaseDuration(totals[state]))
+	}
+
+	return nil
+}
+
+// replayWorkflow rebuilds a workflow from the workflow log and reports how
+// it compares with the cached state
+func (app *App) replayWorkflow(cmd *cobra.Command, workflowID string, repair bool) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	result, err := workflowManager.ReplayWorkflow(workflowID, repair)
+	if err != nil {
+		return fmt.Errorf("failed to replay workflow: %w", err)
+	}
+
+	cmd.Printf("🔁 Replayed workflow %s: %d records after snapshot #%d\n", workflowID, result.Records, result.SnapshotSequence)
+	if result.Workflow == nil {
+		cmd.Printf("   The log deleted this workflow\n")
+	} else {
+		cmd.Printf("   State: %s (updated %s)\n", result.Workflow.State, result.Workflow.UpdatedAt.Format("2006-01-02 15:04:05"))
+	}
+	if result.SkippedLines > 0 {
+		cmd.Printf("⚠️  Skipped %d unreadable log lines\n", result.SkippedLines)
+	}
+	if result.SnapshotRecovered {
+		cmd.Printf("♻️  Snapshot was corrupted and has been rebuilt from the log\n")
+	}
+	if result.StoreRecovered {
+		cmd.Printf("♻️  Workflows file was corrupted and has been rebuilt from the log\n")
+		return nil
+	}
+
+	if result.Matches() {
+		cmd.Printf("✅ Replayed state matches the cached workflow\n")
+		return nil
+	}
+
+	cmd.Printf("⚠️  Replayed state differs from the cached workflow in: %s\n", strings.Join(result.Differences, ", "))
+	if result.Repaired {
+		cmd.Printf("🔧 Cached workflow replaced with the replayed state\n")
+	} else {
+		cmd.Printf("💡 Run 'cw workflow replay %s --repair' to restore the replayed state\n", workflowID)
+	}
+	return nil
+}
+
+// showWorkflowGraph prints the dependency graph of each repository's workflows
+func (app *App) showWorkflowGraph(cmd *cobra.Command) error {
+	workflowManager, err := workflow.NewWorkflowManager(filepath.Join(".", ".cowork"))
+	if err != nil {
+		return fmt.Errorf("failed to create workflow manager: %w", err)
+	}
+	defer workflowManager.Close()
+
+	workflows, err := workflowManager.ListWorkflows()
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	cmd.Printf("🕸️  Workflow Dependency Graph\n")
+	cmd.Printf("============================\n")
+
+	if len(workflows) == 0 {
+		cmd.Printf("\nNo workflows found.\n")
+		return nil
+	}
+
+	byRepo := make(map[string][]*types.Workflow)
+	var repos []string
+	for _, wf := range workflows {
+		key := wf.Owner + "/" + wf.Repo
+		if _, ok := byRepo[key]; !ok {
+			repos = append(repos, key)
+		}
+		byRepo[key] = append(byRepo[key], wf)
+	}
+	sort.Strings(repos)
+
+	for _, repo := range repos {
+		cmd.Printf("\n📦 %s\n", repo)
+
+		graph := workflow.BuildDependencyGraph(byRepo[repo])
+		shown := make(map[int]bool)
+		for _, root := range graph.Roots() {
+			printGraphNode(cmd, graph, root, "", "", shown, nil)
+		}
+
+		// Issues caught in a cycle have no root to start from
+		for _, wf := range byRepo[repo] {
+			if !shown[wf.IssueID] {
+				printGraphNode(cmd, graph, wf.IssueID, "", "", shown, nil)
+			}
+		}
+	}
+
+	return nil
+}
+
+// printGraphNode prints an issue and, indented below it, the issues depending on it
+func printGraphNode(cmd *cobra.Command, graph *workflow.DependencyGraph, issueID int, prefix, branch string, shown map[int]bool, path []int) {
+	label := fmt.Sprintf("#%d", issueID)
+	if wf := graph.Workflow(issueID); wf != nil {
+		label += fmt.Sprintf(" %s (workflow %d)", wf.State, wf.ID)
+		if blockedBy := wf.Metadata[workflow.MetadataBlockedBy]; blockedBy != "" {
+			label += fmt.Sprintf(" ⏸️  waiting on %s", blockedBy)
+		}
+		if stackedOn := wf.Metadata[workflow.MetadataStackedOn]; stackedOn != "" {
+			label += fmt.Sprintf(" 🥞 stacked on #%s", stackedOn)
+		}
+	} else {
+		label += " (not tracked by cowork)"
+	}
+
+	for _, seen := range path {
+		if seen == issueID {
+			cmd.Printf("%s%s%s 🔁 cycle\n", prefix, branch, label)
+			return
+		}
+	}
+	if shown[issueID] && len(graph.Dependents(issueID)) > 0 {
+		cmd.Printf("%s%s%s (dependents shown above)\n", prefix, branch, label)
+		return
+	}
+	shown[issueID] = true
+	cmd.Printf("%s%s%s\n", prefix, branch, label)
+
+	childPrefix := prefix
+	switch branch {
+	case "├── ":
+		childPrefix += "│   "
+	case "└── ":
+		childPrefix += "    "
+	}
+
+	dependents := graph.Dependents(issueID)
+	for i, dependent := range dependents {
+		childBranch := "├── "
+		if i == len(dependents)-1 {
+			childBranch = "└── "
+		}
+		printGraphNode(cmd, graph, dependent, childPrefix, childBranch, shown, append(path, issueID))
+	}
+}
+
+// formatPhaseDuration renders a phase duration rounded to the second
+func formatPhaseDuration(d time.Duration) string {
+	return d.Round(time.Second).String()
+}
+
+// currentActor identifies the user running the CLI in the transition history
+func currentActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return "user:" + u.Username
+	}
+	return "user"
+}
+
+// newWorkflowEngine builds a workflow engine backed by the given provider,
+// authenticating with credentials of the given scope (empty for any), for a
+// repository with the given workflow configuration
+func (app *App) newWorkflowEngine(workflowManager *workflow.WorkflowManager, workflowConfig types.WorkflowConfig, providerName string, scope auth.AuthScope, owner, repo string) (*workflow.Engine, error) {
+	prTemplate, err := prbody.LoadTemplate(".cw")
+	if err != nil {
+		return nil, err
+	}
+
+	coworkProvider, workspaceManager, err := app.newCoworkProvider(providerName, scope, prTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := workflow.NewEngine(workflowManager, app.taskManager, workspaceManager, coworkProvider, owner, repo)
+
+	classifier, err := app.newFeedbackClassifier()
+	if err != nil {
+		return nil, err
+	}
+	engine.SetFeedbackClassifier(classifier)
+	engine.SetAgentRunner(app.newAgentRunner())
+	engine.SetPRTemplate(prTemplate)
+
+	if workflowConfig.ReviewerModel != "" {
+		engine.SetReviewRunner(app.newAgentRunner("--model", workflowConfig.ReviewerModel))
+	}
+
+	return engine, nil
+}
+
+// newCoworkProvider builds the cowork provider for a git provider,
+// authenticating with credentials of the given scope (empty for any), along
+// with the workspace manager it creates workspaces with
+func (app *App) newCoworkProvider(providerName string, scope auth.AuthScope, prTemplate string) (git.CoworkProvider, *workspace.Manager, error) {
+	if app.taskManager == nil {
+		return nil, nil, fmt.Errorf("task manager not initialized. Run 'cw init' first")
+	}
+
+	authConfig, err := app.getScopedProviderAuthConfig(providerName, scope)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	workspaceManager, err := workspace.NewManager(300)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create workspace manager: %w", err)
+	}
+	if app.configManager != nil {
+		if cfg, err := app.configManager.Load(); err == nil && cfg.Workspace.NamingPattern != "" {
+			workspaceManager.SetBranchNamingPattern(cfg.Workspace.NamingPattern)
+		}
+	}
+
+	switch authConfig.ProviderType {
+	case git.ProviderGitHub:
+		githubProvider, err := gitprovider.NewGitHubCoworkProvider(authConfig.Token, authConfig.BaseURL, app.taskManager, workspaceManager)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create GitHub provider: %w", err)
+		}
+		githubProvider.SetPRTemplate(prTemplate)
+		return githubProvider, workspaceManager, nil
+	default:
+		return nil, nil, fmt.Errorf("workflow automation is not yet supported for provider: %s", providerName)
+	}
+}
+
+// loadWorkflowConfig reads the repository's workflow configuration, falling
+// back to the defaults when there is none
+func (app *App) loadWorkflowConfig() (types.WorkflowConfig, error) {
+	configPath := filepath.Join(".cw", workflow.WorkflowConfigFileName)
+	if _, err := os.Stat(configPath); err != nil {
+		return types.GetDefaultWorkflowConfig(), nil
+	}
+
+	return workflow.LoadWorkflowConfig(configPath)
+}
+
+// newFeedbackClassifier builds the PR feedback classifier from the repository's
+// rules file, falling back to the built-in rules when there is none
+func (app *App) newFeedbackClassifier() (workflow.FeedbackClassifier, error) {
+	rules := workflow.DefaultFeedbackRules()
+
+	rulesPath := filepath.Join(".cw", workflow.FeedbackRulesFileName)
+	if _, err := os.Stat(rulesPath); err == nil {
+		rules, err = workflow.LoadFeedbackRules(rulesPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rulesClassifier, err := workflow.NewRulesFeedbackClassifier(rules)
+	if err != nil {
+		return nil, err
+	}
+
+	if rules.Classifier == workflow.FeedbackClassifierAgent {
+		return workflow.NewAgentFeedbackClassifier(app.newAgentRunner(), rulesClassifier), nil
+	}
+
+	return rulesClassifier, nil
+}
+
+// newAgentRunner returns a runner for the Aider agent used by workflow
+// automation, passing any extra arguments to aider
+func (app *App) newAgentRunner(extraArgs ...string) workflow.AgentRunner {
+	timeout := 30 * time.Minute
+	if app.configManager != nil {
+		if cfg, err := app.configManager.Load(); err == nil && cfg.Agent.TimeoutMinutes > 0 {
+			timeout = time.Duration(cfg.Agent.TimeoutMinutes) * time.Minute
+		}
+	}
+
+	return workflow.NewAgentRunner(func() agent.Agent { return agent.NewAiderAgent() }, agent.AgentConfig{
+		AgentType: "aider",
+		Command:   []string{"aider"},
+		Args:      append([]string{"--yes"}, extraArgs...),
+		Environment: map[string]string{
+			"OPENAI_API_KEY": os.Getenv("OPENAI_API_KEY"),
+		},
+		Timeout: timeout,
+	})
+}
+
+// getProviderAuthConfig returns the auth config for a provider, checking project scope first
+func (app *App) getProviderAuthConfig(providerName string) (*auth.AuthConfig, error) {
+	return app.getScopedProviderAuthConfig(providerName, "")
+}
+
+// getScopedProviderAuthConfig returns the auth config for a provider from the
+// given scope, or from either scope when scope is empty
+func (app *App) getScopedProviderAuthConfig(providerName string, scope auth.AuthScope) (*auth.AuthConfig, error) {
+	providerType, err := parseProviderType(providerName)
+	if err != nil {
+		return nil, err
+	}
+
+	authManager, err := auth.NewManager(app.configManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth manager: %w", err)
+	}
+
+	if scope != "" {
+		authConfig, err := authManager.GetAuthConfig(providerType, scope)
+		if err != nil {
+			return nil, fmt.Errorf("no %s authentication configured for %s. Run 'cw config provider %s login' first", scope, providerName, providerName)
+		}
+		return authConfig, nil
+	}
+
+	authConfig, err := authManager.GetAuthConfig(providerType, auth.AuthScopeProject)
+	if err != nil {
+		authConfig, err = authManager.GetAuthConfig(providerType, auth.AuthScopeGlobal)
+		if err != nil {
+			return nil, fmt.Errorf("no authentication configured for %s. Run 'cw config provider %s login' first", providerName, providerName)
+		}
+	}
+
+	return authConfig, nil
+}
+
+// parseProviderType converts a provider name to its provider type
+func parseProviderType(providerName string) (git.ProviderType, error) {
+	switch strings.ToLower(providerName) {
+	case "github":
+		return git.ProviderGitHub, nil
+	case "gitlab":
+		return git.ProviderGitLab, nil
+	case "bitbucket":
+		return git.ProviderBitbucket, nil
+	default:
+		return "", fmt.Errorf("unsupported provider: %s", providerName)
+	}
+}
+
+// parseRepoFlag parses a repository given as "owner/repo"
+func parseRepoFlag(value string) (owner, repo string, err error) {
+	parts := strings.Split(strings.TrimSpace(value), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository %q, expected owner/repo", value)
+	}
+
+	return parts[0], parts[1], nil
+}
+
+// filterWorkflowsByRepo keeps the workflows of the repositories given as
+// owner/repo, where owner/* matches every repository of that owner
+func filterWorkflowsByRepo(workflows []*types.Workflow, filters []string) ([]*types.Workflow, error) {
+	type repoFilter struct{ owner, repo string }
+
+	var parsed []repoFilter
+	for _, filter := range filters {
+		owner, repo, err := parseRepoFlag(filter)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, repoFilter{owner, repo})
+	}
+
+	var matched []*types.Workflow
+	for _, wf := range workflows {
+		for _, filter := range parsed {
+			if wf.Owner == filter.owner && (filter.repo == "*" || wf.Repo == filter.repo) {
+				matched = append(matched, wf)
+				break
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// requiredChecksState returns the last recorded required checks state of a workflow
+func requiredChecksState(wf *types.Workflow) string {
+	if state := wf.Metadata[workflow.MetadataChecksState]; state != "" {
+		return state
+	}
+	return workflow.ChecksStatePending
+}
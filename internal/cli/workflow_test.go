@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+	"github.com/hlfshell/cowork/internal/workflow"
+)
+
+// fakeScanProvider serves open issues and records the tasks created from them;
+// unimplemented methods panic via the nil embedded interface
+type fakeScanProvider struct {
+	git.CoworkProvider
+	issues  []*git.Issue
+	created []int
+}
+
+func (p *fakeScanProvider) GetProviderType() git.ProviderType {
+	return git.ProviderGitHub
+}
+
+func (p *fakeScanProvider) GetRepositoryInfo(ctx context.Context, owner, repo string) (*git.Repository, error) {
+	return &git.Repository{Owner: owner, Name: repo, DefaultBranch: "develop"}, nil
+}
+
+func (p *fakeScanProvider) ScanOpenIssues(ctx context.Context, owner, repo string) ([]*git.Issue, error) {
+	return p.issues, nil
+}
+
+func (p *fakeScanProvider) GetTaskByIssue(ctx context.Context, owner, repo string, issueNumber int) (*types.Task, error) {
+	return nil, nil
+}
+
+func (p *fakeScanProvider) GetTrackingIssues(ctx context.Context, owner, repo string, issueNumber int) ([]int, error) {
+	return nil, nil
+}
+
+func (p *fakeScanProvider) CreateTaskFromIssue(ctx context.Context, owner, repo string, issue *git.Issue) (*types.Task, error) {
+	p.created = append(p.created, issue.Number)
+	return &types.Task{ID: len(p.created), Name: issue.Title}, nil
+}
+
+// chdirRepository switches into a temporary repository holding the given
+// workflow config for the rest of the test
+func chdirRepository(t *testing.T, workflowConfig string) {
+	t.Helper()
+
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, ".cw"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".cw", workflow.WorkflowConfigFileName), []byte(workflowConfig), 0644))
+
+	previous, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(previous) })
+}
+
+// TestApp_ScanIssues tests that scanning applies the repository's label gate
+func TestApp_ScanIssues(t *testing.T) {
+	testCases := []struct {
+		name           string
+		dryRun         bool
+		expectCreated  []int
+		expectOutput   string
+		unexpectOutput string
+	}{
+		{
+			// Test case: Only the enabled issue without a disable label gets a task
+			name:          "create tasks",
+			expectCreated: []int{1},
+			expectOutput:  "Issue scanning completed",
+		},
+		{
+			// Test case: A dry run lists the enabled issue and creates nothing
+			name:           "dry run",
+			dryRun:         true,
+			expectOutput:   "Would pick up issue #1: Enabled",
+			unexpectOutput: "#2",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chdirRepository(t, "enable_labels: [\"bot:go\"]\ndisable_labels: [\"bot:stop\"]\n")
+
+			provider := &fakeScanProvider{issues: []*git.Issue{
+				{Number: 1, Title: "Enabled", Labels: []*git.Label{{Name: "bot:go"}}},
+				{Number: 2, Title: "Disabled", Labels: []*git.Label{{Name: "bot:go"}, {Name: "bot:stop"}}},
+				{Number: 3, Title: "Default label only", Labels: []*git.Label{{Name: "cowork:on"}}},
+			}}
+
+			var out bytes.Buffer
+			cmd := &cobra.Command{}
+			cmd.SetOut(&out)
+
+			app := &App{}
+			manager := workflow.NewManager(provider, nil, nil, "owner", "repo")
+			require.NoError(t, app.scanIssues(cmd, manager, tc.dryRun))
+
+			assert.Equal(t, tc.expectCreated, provider.created)
+			assert.Contains(t, out.String(), tc.expectOutput)
+			if tc.unexpectOutput != "" {
+				assert.NotContains(t, out.String(), tc.unexpectOutput)
+			}
+		})
+	}
+}
+
+// TestApp_ScanIssues_CreatesWorkflows tests that scanned issues get workflows
+// keeping a snapshot of the repository's workflow config
+func TestApp_ScanIssues_CreatesWorkflows(t *testing.T) {
+	// Test case: The queued workflow carries the repository settings, also
+	// after the workflow state is reloaded, and a second scan reuses it
+	chdirRepository(t, "sync_strategy: merge\nforce_push_disabled: true\nmax_pr_lines: 400\nstack_split: agent\nstack_dependencies: true\njob_timeout: 45m\n")
+
+	stateDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(stateDir, "tasks.json"), []byte("[]"), 0644))
+	workflowManager, err := workflow.NewWorkflowManager(stateDir)
+	require.NoError(t, err)
+
+	provider := &fakeScanProvider{issues: []*git.Issue{
+		{Number: 7, Title: "Fix the widget", Labels: []*git.Label{{Name: "cowork:on"}}},
+	}}
+	manager := workflow.NewManager(provider, nil, nil, "owner", "repo")
+	manager.SetWorkflowManager(workflowManager)
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(&bytes.Buffer{})
+	app := &App{}
+	require.NoError(t, app.scanIssues(cmd, manager, false))
+	require.NoError(t, workflowManager.Close())
+
+	reopened, err := workflow.NewWorkflowManager(stateDir)
+	require.NoError(t, err)
+	defer reopened.Close()
+
+	created, err := reopened.GetWorkflowByIssue("owner", "repo", 7)
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateQueued, created.State)
+	assert.Equal(t, "develop", created.BaseBranch)
+	assert.Equal(t, "github", created.Provider)
+	assert.Equal(t, 1, created.TaskID)
+	assert.Equal(t, "merge", created.Config.SyncStrategy)
+	assert.True(t, created.Config.ForcePushDisabled)
+	assert.Equal(t, 400, created.Config.MaxPRLines)
+	assert.Equal(t, workflow.StackSplitAgent, created.Config.StackSplit)
+	assert.True(t, created.Config.StackDependencies)
+	assert.Equal(t, 45*time.Minute, created.Config.JobTimeout)
+
+	manager.SetWorkflowManager(reopened)
+	require.NoError(t, app.scanIssues(cmd, manager, false))
+	workflows, err := reopened.ListWorkflows()
+	require.NoError(t, err)
+	assert.Len(t, workflows, 1)
+}
+
+// TestConcurrencyFlagChanged tests detecting an explicit concurrency limit
+func TestConcurrencyFlagChanged(t *testing.T) {
+	// Test case: Only a limit given on the command line overrides the
+	// repositories file, not the flag's default
+	testCases := []struct {
+		name    string
+		flag    string
+		args    []string
+		changed bool
+	}{
+		{name: "default", flag: "max-concurrent", args: nil, changed: false},
+		{name: "max concurrent", flag: "max-concurrent", args: []string{"-n", "3"}, changed: true},
+		{name: "max agents", flag: "max-agents", args: []string{"--max-agents", "2"}, changed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := &cobra.Command{Use: "start"}
+			cmd.Flags().IntP(tc.flag, "n", 1, "")
+			require.NoError(t, cmd.ParseFlags(tc.args))
+			assert.Equal(t, tc.changed, concurrencyFlagChanged(cmd))
+		})
+	}
+}
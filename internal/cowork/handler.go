@@ -3,20 +3,34 @@ package cowork
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
+	"github.com/hlfshell/cowork/internal/branchname"
 	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
 	"github.com/hlfshell/cowork/internal/task"
 	"github.com/hlfshell/cowork/internal/types"
 	"github.com/hlfshell/cowork/internal/workspace"
 )
 
+// Branch naming defaults
+const (
+	// DefaultBranchTemplate is the branch name template used when none is set
+	DefaultBranchTemplate = "task/{slug}-{issue-key}"
+
+	// MaxBranchSlugLength is the maximum length of the issue title slug
+	MaxBranchSlugLength = 30
+)
+
 // Handler implements cowork-specific operations using any GitProvider
 type Handler struct {
 	provider         git.GitProvider
 	taskManager      task.TaskManager
 	workspaceManager workspace.WorkspaceManager
+	branchTemplate   string
+	prTemplate       string
 }
 
 // NewHandler creates a new cowork handler with the given provider and managers
@@ -25,6 +39,7 @@ func NewHandler(provider git.GitProvider, taskManager task.TaskManager, workspac
 		provider:         provider,
 		taskManager:      taskManager,
 		workspaceManager: workspaceManager,
+		branchTemplate:   DefaultBranchTemplate,
 	}
 }
 
@@ -122,8 +137,14 @@ func (h *Handler) CreateWorkspaceForTask(ctx context.Context, task *types.Task,
 		return nil, fmt.Errorf("failed to get issue: %w", err)
 	}
 
-	// Generate branch name
-	branchName := h.GenerateBranchName(issue)
+	// Keep a branch name chosen earlier, otherwise generate a free one
+	branchName := task.Metadata["branch_name"]
+	if branchName == "" {
+		branchName, err = branchname.Resolve(h.GenerateBranchName(issue), git.RemoteBranchExists(ctx, h.provider, owner, repo))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve branch name: %w", err)
+		}
+	}
 
 	// Create workspace request
 	req := &types.CreateWorkspaceRequest{
@@ -141,49 +162,36 @@ func (h *Handler) CreateWorkspaceForTask(ctx context.Context, task *types.Task,
 	return h.workspaceManager.CreateWorkspace(req)
 }
 
-// GenerateBranchName generates a branch name for a task based on the issue
+// SetBranchTemplate sets the template used to name task branches
+func (h *Handler) SetBranchTemplate(template string) {
+	h.branchTemplate = template
+}
+
+// SetPRTemplate sets the template used to write pull request bodies; an empty
+// template uses prbody.DefaultTemplate
+func (h *Handler) SetPRTemplate(template string) {
+	h.prTemplate = template
+}
+
+// GenerateBranchName generates a branch name for a task based on the issue.
+// A template that cannot produce a valid name falls back to the default.
 func (h *Handler) GenerateBranchName(issue *git.Issue) string {
-	// Create a human-readable branch name
-	title := strings.ToLower(issue.Title)
-	
-	// Remove special characters and replace spaces with hyphens
-	title = strings.ReplaceAll(title, " ", "-")
-	title = strings.ReplaceAll(title, "_", "-")
-	title = strings.ReplaceAll(title, ".", "")
-	title = strings.ReplaceAll(title, ",", "")
-	title = strings.ReplaceAll(title, ":", "")
-	title = strings.ReplaceAll(title, ";", "")
-	title = strings.ReplaceAll(title, "!", "")
-	title = strings.ReplaceAll(title, "?", "")
-	title = strings.ReplaceAll(title, "(", "")
-	title = strings.ReplaceAll(title, ")", "")
-	title = strings.ReplaceAll(title, "[", "")
-	title = strings.ReplaceAll(title, "]", "")
-	title = strings.ReplaceAll(title, "{", "")
-	title = strings.ReplaceAll(title, "}", "")
-	title = strings.ReplaceAll(title, "/", "-")
-	title = strings.ReplaceAll(title, "\\", "-")
-	
-	// Remove multiple consecutive hyphens
-	for strings.Contains(title, "--") {
-		title = strings.ReplaceAll(title, "--", "-")
-	}
-	
-	// Trim hyphens from start and end
-	title = strings.Trim(title, "-")
-	
-	// Limit length
-	if len(title) > 30 {
-		title = title[:30]
-		title = strings.Trim(title, "-")
+	data := git.NewBranchNameData(issue)
+	data.SlugLength = MaxBranchSlugLength
+
+	if h.branchTemplate != "" {
+		branchName, err := branchname.Render(h.branchTemplate, data)
+		if err == nil {
+			return branchName
+		}
+		log.Printf("⚠️  Falling back to default branch name for issue #%d: %v", issue.Number, err)
 	}
-	
-	// Ensure it's not empty
-	if title == "" {
-		title = "task"
+
+	branchName, err := branchname.Render(DefaultBranchTemplate, data)
+	if err != nil {
+		return fmt.Sprintf("task/%d", issue.Number)
 	}
-	
-	return fmt.Sprintf("task/%s-%d", title, issue.Number)
+	return branchName
 }
 
 // CreatePullRequestForTask creates a pull request for a completed task
@@ -213,7 +221,7 @@ func (h *Handler) CreatePullRequestForTask(ctx context.Context, task *types.Task
 
 	prReq := &git.CreatePullRequestRequest{
 		Title:  fmt.Sprintf("Fix: %s", issue.Title),
-		Body:   h.generatePRBody(task, issue),
+		Body:   h.generatePRBody(task, issue, workspace),
 		Head:   branchName,
 		Base:   "main", // Default, could be made configurable
 		Draft:  false,
@@ -350,20 +358,6 @@ func (h *Handler) getProviderHost() string {
 	}
 }
 
-func (h *Handler) generatePRBody(task *types.Task, issue *git.Issue) string {
-	return fmt.Sprintf(`## Summary
-
-This PR addresses issue #%d: %s
-
-## Changes
-
-- [Task completed by Cowork](%s)
-
-## Related
-
-- Closes #%d
-
----
-*This PR was automatically generated by Cowork*`, 
-		issue.Number, issue.Title, task.URL, issue.Number)
+func (h *Handler) generatePRBody(task *types.Task, issue *git.Issue, workspace *types.Workspace) string {
+	return prbody.Build(h.prTemplate, issue, task, workspace.Path, "main")
 }
@@ -153,6 +153,24 @@ func (m *MockGitProvider) GetLabels(ctx context.Context, owner, repo string) ([]
 	return args.Get(0).([]*git.Label), args.Error(1)
 }
 
+func (m *MockGitProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	args := m.Called(ctx, owner, repo, ref)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*git.CommitCheck), args.Error(1)
+}
+
+func (m *MockGitProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	args := m.Called(ctx, owner, repo, branch)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockGitProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	args := m.Called(ctx, owner, repo, branch)
+	return args.Error(0)
+}
+
 // TestHandler_GenerateBranchName tests branch name generation
 func TestHandler_GenerateBranchName(t *testing.T) {
 	// Test case: Generate branch name from issue
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hlfshell/cowork/internal/branchname"
+)
+
+// NewBranchNameData builds branch name template data from an issue. The
+// {user} placeholder is the first assignee, falling back to the author.
+func NewBranchNameData(issue *Issue) *branchname.Data {
+	data := &branchname.Data{
+		IssueKey: fmt.Sprintf("%d", issue.Number),
+		Title:    issue.Title,
+		TaskName: issue.Title,
+	}
+
+	for _, assignee := range issue.Assignees {
+		if assignee != nil && assignee.Login != "" {
+			data.User = assignee.Login
+			break
+		}
+	}
+	if data.User == "" && issue.Author != nil {
+		data.User = issue.Author.Login
+	}
+
+	for _, label := range issue.Labels {
+		if label != nil {
+			data.Labels = append(data.Labels, label.Name)
+		}
+	}
+
+	return data
+}
+
+// RemoteBranchExists returns a branchname.ExistsFunc that checks the provider
+// for an existing branch
+func RemoteBranchExists(ctx context.Context, provider GitProvider, owner, repo string) branchname.ExistsFunc {
+	return func(name string) (bool, error) {
+		return provider.BranchExists(ctx, owner, repo, name)
+	}
+}
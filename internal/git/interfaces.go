@@ -91,6 +91,16 @@ type GitProvider interface {
 
 	// GetLabels retrieves available labels for a repository
 	GetLabels(ctx context.Context, owner, repo string) ([]*Label, error)
+
+	// GetCommitChecks retrieves the commit statuses and check runs reported for a commit SHA
+	GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*CommitCheck, error)
+
+	// BranchExists reports whether a branch exists in the remote repository
+	BranchExists(ctx context.Context, owner, repo, branch string) (bool, error)
+
+	// DeleteBranch deletes a branch from the remote repository; deleting a
+	// branch that does not exist is not an error
+	DeleteBranch(ctx context.Context, owner, repo, branch string) error
 }
 
 // GitOperationsInterface defines the interface for local Git operations
@@ -100,6 +110,9 @@ type GitOperationsInterface interface {
 
 	// GetRepositoryInfo retrieves information about a Git repository
 	GetRepositoryInfo(repoPath string) (*RepositoryInfo, error)
+
+	// SetBranchTemplate sets the template used to name task branches
+	SetBranchTemplate(template string)
 }
 
 // Repository contains basic information about a repository
@@ -264,6 +277,78 @@ type Comment struct {
 	UpdatedAt time.Time `json:"updated_at"`
 	// Comment URL
 	URL string `json:"url"`
+	// File and line an inline review comment is attached to; empty for conversation comments
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Review comment this one replies to, if any
+	InReplyTo int `json:"in_reply_to,omitempty"`
+}
+
+// CheckSource identifies which provider API reported a commit check
+type CheckSource string
+
+const (
+	// CheckSourceStatus is a legacy commit status
+	CheckSourceStatus CheckSource = "status"
+	// CheckSourceCheckRun is a check run (e.g. GitHub Actions job or CI pipeline job)
+	CheckSourceCheckRun CheckSource = "check_run"
+)
+
+// Check statuses and conclusions, following the GitHub check run vocabulary
+const (
+	CheckStatusQueued     = "queued"
+	CheckStatusInProgress = "in_progress"
+	CheckStatusCompleted  = "completed"
+
+	CheckConclusionSuccess        = "success"
+	CheckConclusionFailure        = "failure"
+	CheckConclusionNeutral        = "neutral"
+	CheckConclusionCancelled      = "cancelled"
+	CheckConclusionSkipped        = "skipped"
+	CheckConclusionTimedOut       = "timed_out"
+	CheckConclusionActionRequired = "action_required"
+)
+
+// CommitCheck is a single CI result reported against a commit
+type CommitCheck struct {
+	// Check or status context name (e.g. "lint", "ci/test")
+	Name string `json:"name"`
+	// Where the check was reported from
+	Source CheckSource `json:"source"`
+	// Check status (queued, in_progress, completed)
+	Status string `json:"status"`
+	// Check conclusion once completed (success, failure, ...)
+	Conclusion string `json:"conclusion,omitempty"`
+	// Short human readable summary of the result
+	Summary string `json:"summary,omitempty"`
+	// Link to the check details
+	URL string `json:"url,omitempty"`
+	// Completion time (if completed)
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// IsCompleted reports whether the check has finished
+func (cc *CommitCheck) IsCompleted() bool {
+	return cc.Status == CheckStatusCompleted
+}
+
+// IsSuccessful reports whether the check finished without failing
+func (cc *CommitCheck) IsSuccessful() bool {
+	if !cc.IsCompleted() {
+		return false
+	}
+
+	switch cc.Conclusion {
+	case CheckConclusionSuccess, CheckConclusionNeutral, CheckConclusionSkipped:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsFailed reports whether the check finished and failed
+func (cc *CommitCheck) IsFailed() bool {
+	return cc.IsCompleted() && !cc.IsSuccessful()
 }
 
 // IssueListOptions contains options for listing issues
@@ -399,6 +484,10 @@ type CoworkProvider interface {
 	// Returns nil if no task exists for the issue
 	GetTaskByIssue(ctx context.Context, owner, repo string, issueNumber int) (*types.Task, error)
 
+	// GetTrackingIssues returns the issues of the repository tracking an issue,
+	// such as the parent of a sub-issue
+	GetTrackingIssues(ctx context.Context, owner, repo string, issueNumber int) ([]int, error)
+
 	// Workspace Management
 	// CreateWorkspaceForTask creates a workspace for a task when it starts
 	// This should create a branch based on the issue and set up the workspace
@@ -430,6 +519,9 @@ type CoworkProvider interface {
 	// This includes new comments, reviews, status changes, etc.
 	GetPullRequestUpdates(ctx context.Context, owner, repo string, prNumber int, since time.Time) (*PullRequestUpdate, error)
 
+	// ReplyToReviewComment replies in the thread of an inline review comment
+	ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber, commentID int, body string) (*Comment, error)
+
 	// UpdateTaskFromPullRequest updates a task based on pull request changes
 	// This should handle new comments, review requests, etc.
 	UpdateTaskFromPullRequest(ctx context.Context, task *types.Task, pr *PullRequest, updates *PullRequestUpdate) error
@@ -455,6 +547,9 @@ type PullRequestUpdate struct {
 	// New reviews since last check
 	NewReviews []*Review `json:"new_reviews"`
 
+	// New inline review comments since last check
+	NewReviewComments []*Comment `json:"new_review_comments"`
+
 	// Status changes (mergeable, draft, etc.)
 	StatusChanges map[string]interface{} `json:"status_changes"`
 
@@ -1,12 +1,14 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 
+	"github.com/hlfshell/cowork/internal/branchname"
 	"github.com/hlfshell/cowork/internal/types"
 )
 
@@ -17,12 +19,18 @@ const (
 
 	// DefaultTaskName is the default name used when no valid task name can be generated
 	DefaultTaskName = "task"
+
+	// DefaultBranchTemplate is the branch name template used when none is set
+	DefaultBranchTemplate = "task/{task_name}-{issue-key}"
 )
 
 // GitOperations provides Git-related functionality for workspace management
 type GitOperations struct {
 	// Default timeout for Git operations in seconds
 	operationTimeoutSeconds int
+
+	// Template used to name task branches
+	branchTemplate string
 }
 
 // NewGitOperations creates a new GitOperations instance
@@ -33,9 +41,16 @@ func NewGitOperations(operationTimeoutSeconds int) *GitOperations {
 
 	return &GitOperations{
 		operationTimeoutSeconds: operationTimeoutSeconds,
+		branchTemplate:          DefaultBranchTemplate,
 	}
 }
 
+// SetBranchTemplate sets the template used to name task branches, such as
+// the workspace naming pattern from the configuration
+func (g *GitOperations) SetBranchTemplate(template string) {
+	g.branchTemplate = template
+}
+
 // CloneRepository clones a repository using full clone
 func (g *GitOperations) CloneRepository(req *types.CreateWorkspaceRequest, workspacePath string, authInfo *types.GitAuthInfo) error {
 	// Validate the request
@@ -83,12 +98,9 @@ func (g *GitOperations) performFullClone(req *types.CreateWorkspaceRequest, work
 	}
 
 	// Create a new branch for the task
-	// Use branch name from metadata if provided, otherwise generate one
-	var branchName string
-	if req.Metadata != nil && req.Metadata["branch_name"] != "" {
-		branchName = req.Metadata["branch_name"]
-	} else {
-		branchName = g.generateBranchName(req.TaskName, req.TicketID)
+	branchName, err := g.taskBranchName(workspacePath, req, authInfo)
+	if err != nil {
+		return err
 	}
 
 	if err := g.createAndCheckoutBranch(workspacePath, branchName, authInfo); err != nil {
@@ -130,7 +142,11 @@ func (g *GitOperations) cloneFromLocalPath(sourcePath, workspacePath string, req
 	}
 
 	// Create a new branch for the task
-	branchName := g.generateBranchName(req.TaskName, req.TicketID)
+	branchName, err := g.taskBranchName(workspacePath, req, nil)
+	if err != nil {
+		return err
+	}
+
 	if err := g.createAndCheckoutBranch(workspacePath, branchName, nil); err != nil {
 		return fmt.Errorf("failed to create task branch: %w", err)
 	}
@@ -299,49 +315,70 @@ func (g *GitOperations) configureHTTPSAuth(workspacePath string, authInfo *types
 	os.WriteFile(credentialFile, []byte(credentialContent), 0600) // Ignore errors
 }
 
-// generateBranchName creates a branch name from the task name and ticket ID
-func (g *GitOperations) generateBranchName(taskName, ticketID string) string {
-	// Sanitize the task name for use in branch names
-	sanitizedTaskName := g.sanitizeBranchName(taskName)
+// taskBranchName returns the branch name from the request metadata if
+// provided, otherwise generates one that does not collide with a branch on
+// the remote
+func (g *GitOperations) taskBranchName(repoPath string, req *types.CreateWorkspaceRequest, authInfo *types.GitAuthInfo) (string, error) {
+	if req.Metadata != nil && req.Metadata["branch_name"] != "" {
+		return req.Metadata["branch_name"], nil
+	}
 
-	if ticketID != "" {
-		return fmt.Sprintf("task/%s-%s", sanitizedTaskName, ticketID)
+	branchName, err := branchname.Resolve(g.generateBranchName(req.TaskName, req.TicketID), func(name string) (bool, error) {
+		return g.remoteBranchExists(repoPath, name, authInfo)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve task branch name: %w", err)
 	}
 
-	return fmt.Sprintf("task/%s", sanitizedTaskName)
+	return branchName, nil
 }
 
-// sanitizeBranchName converts a task name into a valid Git branch name
-func (g *GitOperations) sanitizeBranchName(taskName string) string {
-	// Convert to lowercase for consistency
-	sanitized := strings.ToLower(taskName)
+// remoteBranchExists checks whether a branch exists on origin
+func (g *GitOperations) remoteBranchExists(repoPath, branchName string, authInfo *types.GitAuthInfo) (bool, error) {
+	cmd := g.getAuthenticatedCommand(repoPath, authInfo, "ls-remote", "--exit-code", "--heads", "origin", branchName)
 
-	// Replace invalid characters with hyphens
-	invalidChars := []string{" ", "/", "\\", ":", "*", "?", "\"", "<", ">", "|", "..", "#", "&", "(", ")", "[", "]", "{", "}", "!", "@", "$", "%", "^", "+", "=", "~", "`", "_"}
-	for _, char := range invalidChars {
-		sanitized = strings.ReplaceAll(sanitized, char, "-")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		// ls-remote exits with 2 when no matching ref was found
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 2 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git ls-remote failed: %w, output: %s", err, string(output))
 	}
 
-	// Remove leading/trailing hyphens and dots
-	sanitized = strings.Trim(sanitized, "-.")
+	return true, nil
+}
 
-	// Ensure it's not empty
-	if sanitized == "" {
-		sanitized = DefaultTaskName
+// generateBranchName creates a branch name from the task name and ticket ID
+// using the branch template, falling back to the default template if the
+// configured one cannot produce a valid name
+func (g *GitOperations) generateBranchName(taskName, ticketID string) string {
+	data := &branchname.Data{
+		IssueKey:   branchname.IssueKey(ticketID),
+		Title:      taskName,
+		TaskName:   taskName,
+		SlugLength: MaxBranchNameLength,
 	}
 
-	// Limit length for better readability
-	if len(sanitized) > MaxBranchNameLength {
-		sanitized = sanitized[:MaxBranchNameLength]
-		sanitized = strings.TrimRight(sanitized, "-")
+	if g.branchTemplate != "" {
+		branchName, err := branchname.Render(g.branchTemplate, data)
+		if err == nil {
+			return branchName
+		}
+		fmt.Printf("Warning: falling back to default branch name: %v\n", err)
 	}
 
-	// Remove multiple consecutive hyphens
-	for strings.Contains(sanitized, "--") {
-		sanitized = strings.ReplaceAll(sanitized, "--", "-")
+	branchName, err := branchname.Render(DefaultBranchTemplate, data)
+	if err != nil {
+		return fmt.Sprintf("task/%s", DefaultTaskName)
 	}
+	return branchName
+}
 
-	return sanitized
+// sanitizeBranchName converts a task name into a valid Git branch name
+func (g *GitOperations) sanitizeBranchName(taskName string) string {
+	return branchname.Sanitize(taskName, MaxBranchNameLength)
 }
 
 // GetRepositoryInfo retrieves information about a Git repository
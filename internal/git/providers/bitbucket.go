@@ -90,13 +90,13 @@ func (bp *BitbucketProvider) GetPullRequestByIssue(ctx context.Context, owner, r
 
 // CreatePullRequest creates a new pull request in a Bitbucket repository
 func (bp *BitbucketProvider) CreatePullRequest(ctx context.Context, owner, repo string, pr *git.CreatePullRequestRequest) (*git.PullRequest, error) {
-	// TODO: Implement Bitbucket pull request creation
+	// TODO: Implement Bitbucket pull request creation, passing the draft flag through
 	return nil, fmt.Errorf("Bitbucket provider not yet implemented")
 }
 
 // UpdatePullRequest updates an existing Bitbucket pull request
 func (bp *BitbucketProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, updates *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
-	// TODO: Implement Bitbucket pull request update
+	// TODO: Implement Bitbucket pull request update, including draft changes
 	return nil, fmt.Errorf("Bitbucket provider not yet implemented")
 }
 
@@ -129,3 +129,21 @@ func (bp *BitbucketProvider) GetLabels(ctx context.Context, owner, repo string)
 	// TODO: Implement Bitbucket labels retrieval
 	return nil, fmt.Errorf("Bitbucket provider not yet implemented")
 }
+
+// GetCommitChecks retrieves commit statuses and pipeline jobs for a Bitbucket commit
+func (bp *BitbucketProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	// TODO: Implement Bitbucket commit checks retrieval
+	return nil, fmt.Errorf("Bitbucket provider not yet implemented")
+}
+
+// BranchExists reports whether a branch exists in a Bitbucket repository
+func (bp *BitbucketProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	// TODO: Implement Bitbucket branch lookup
+	return false, fmt.Errorf("Bitbucket provider not yet implemented")
+}
+
+// DeleteBranch deletes a branch from a Bitbucket repository
+func (bp *BitbucketProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	// TODO: Implement Bitbucket branch deletion
+	return fmt.Errorf("Bitbucket provider not yet implemented")
+}
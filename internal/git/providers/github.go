@@ -319,41 +319,110 @@ func (gp *GitHubProvider) CreatePullRequest(ctx context.Context, owner, repo str
 // UpdatePullRequest updates an existing GitHub pull request
 func (gp *GitHubProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, updates *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
 	githubPR := &github.PullRequest{}
+	edited := false
 
 	if updates.Title != nil {
 		githubPR.Title = updates.Title
+		edited = true
 	}
 	if updates.Body != nil {
 		githubPR.Body = updates.Body
+		edited = true
 	}
 	if updates.State != nil {
 		githubPR.State = updates.State
+		edited = true
 	}
 	if updates.Base != nil {
 		githubPR.Base = &github.PullRequestBranch{Ref: updates.Base}
+		edited = true
 	}
+
+	if edited {
+		if _, _, err := gp.client.PullRequests.Edit(ctx, owner, repo, prNumber, githubPR); err != nil {
+			return nil, fmt.Errorf("failed to update pull request %d: %w", prNumber, err)
+		}
+	}
+
+	// The REST API ignores draft changes, so they go through GraphQL
 	if updates.Draft != nil {
-		githubPR.Draft = updates.Draft
+		if err := gp.setPullRequestDraft(ctx, owner, repo, prNumber, *updates.Draft); err != nil {
+			return nil, err
+		}
 	}
 
-	updatedPR, _, err := gp.client.PullRequests.Edit(ctx, owner, repo, prNumber, githubPR)
+	updatedPR, _, err := gp.client.PullRequests.Get(ctx, owner, repo, prNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to update pull request %d: %w", prNumber, err)
+		return nil, fmt.Errorf("failed to get pull request %d: %w", prNumber, err)
 	}
 
 	return convertGitHubPullRequest(updatedPR), nil
 }
 
-// GetPullRequestReviews retrieves reviews for a GitHub pull request
-func (gp *GitHubProvider) GetPullRequestReviews(ctx context.Context, owner, repo string, prNumber int) ([]*git.Review, error) {
-	githubReviews, _, err := gp.client.PullRequests.ListReviews(ctx, owner, repo, prNumber, &github.ListOptions{PerPage: 100})
+// setPullRequestDraft converts a pull request to a draft or marks it ready
+// for review
+func (gp *GitHubProvider) setPullRequestDraft(ctx context.Context, owner, repo string, prNumber int, draft bool) error {
+	githubPR, _, err := gp.client.PullRequests.Get(ctx, owner, repo, prNumber)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pull request reviews: %w", err)
+		return fmt.Errorf("failed to get pull request %d: %w", prNumber, err)
+	}
+	if githubPR.GetDraft() == draft {
+		return nil
 	}
 
-	reviews := make([]*git.Review, len(githubReviews))
-	for i, githubReview := range githubReviews {
-		reviews[i] = convertGitHubReview(githubReview)
+	mutation := "markPullRequestReadyForReview"
+	if draft {
+		mutation = "convertPullRequestToDraft"
+	}
+
+	body := map[string]interface{}{
+		"query":     fmt.Sprintf("mutation($id: ID!) { %s(input: {pullRequestId: $id}) { clientMutationId } }", mutation),
+		"variables": map[string]interface{}{"id": githubPR.GetNodeID()},
+	}
+	req, err := gp.client.NewRequest(http.MethodPost, graphQLURL(gp.client.BaseURL), body)
+	if err != nil {
+		return fmt.Errorf("failed to build draft update request: %w", err)
+	}
+
+	var result struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := gp.client.Do(ctx, req, &result); err != nil {
+		return fmt.Errorf("failed to update draft status of pull request %d: %w", prNumber, err)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("failed to update draft status of pull request %d: %s", prNumber, result.Errors[0].Message)
+	}
+
+	return nil
+}
+
+// graphQLURL returns the GraphQL endpoint for a REST API base URL. GitHub
+// Enterprise serves REST under /api/v3 and GraphQL under /api/graphql.
+func graphQLURL(baseURL *url.URL) string {
+	endpoint := *baseURL
+	endpoint.Path = strings.TrimSuffix(strings.TrimSuffix(endpoint.Path, "/"), "/v3") + "/graphql"
+	return endpoint.String()
+}
+
+// GetPullRequestReviews retrieves reviews for a GitHub pull request
+func (gp *GitHubProvider) GetPullRequestReviews(ctx context.Context, owner, repo string, prNumber int) ([]*git.Review, error) {
+	reviews := make([]*git.Review, 0)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		githubReviews, resp, err := gp.client.PullRequests.ListReviews(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull request reviews: %w", err)
+		}
+		for _, githubReview := range githubReviews {
+			reviews = append(reviews, convertGitHubReview(githubReview))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
 	return reviews, nil
@@ -361,16 +430,20 @@ func (gp *GitHubProvider) GetPullRequestReviews(ctx context.Context, owner, repo
 
 // GetPullRequestComments retrieves comments for a GitHub pull request
 func (gp *GitHubProvider) GetPullRequestComments(ctx context.Context, owner, repo string, prNumber int) ([]*git.Comment, error) {
-	githubComments, _, err := gp.client.PullRequests.ListComments(ctx, owner, repo, prNumber, &github.PullRequestListCommentsOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pull request comments: %w", err)
-	}
-
-	comments := make([]*git.Comment, len(githubComments))
-	for i, githubComment := range githubComments {
-		comments[i] = convertGitHubComment(githubComment)
+	comments := make([]*git.Comment, 0)
+	opts := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		githubComments, resp, err := gp.client.PullRequests.ListComments(ctx, owner, repo, prNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pull request comments: %w", err)
+		}
+		for _, githubComment := range githubComments {
+			comments = append(comments, convertGitHubComment(githubComment))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
 	return comments, nil
@@ -378,16 +451,20 @@ func (gp *GitHubProvider) GetPullRequestComments(ctx context.Context, owner, rep
 
 // GetIssueComments retrieves comments for a GitHub issue
 func (gp *GitHubProvider) GetIssueComments(ctx context.Context, owner, repo string, issueNumber int) ([]*git.Comment, error) {
-	githubComments, _, err := gp.client.Issues.ListComments(ctx, owner, repo, issueNumber, &github.IssueListCommentsOptions{
-		ListOptions: github.ListOptions{PerPage: 100},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get issue comments: %w", err)
-	}
-
-	comments := make([]*git.Comment, len(githubComments))
-	for i, githubComment := range githubComments {
-		comments[i] = convertGitHubIssueComment(githubComment)
+	comments := make([]*git.Comment, 0)
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		githubComments, resp, err := gp.client.Issues.ListComments(ctx, owner, repo, issueNumber, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issue comments: %w", err)
+		}
+		for _, githubComment := range githubComments {
+			comments = append(comments, convertGitHubIssueComment(githubComment))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
 	return comments, nil
@@ -409,17 +486,88 @@ func (gp *GitHubProvider) CreateComment(ctx context.Context, owner, repo string,
 
 // GetLabels retrieves available labels for a GitHub repository
 func (gp *GitHubProvider) GetLabels(ctx context.Context, owner, repo string) ([]*git.Label, error) {
-	githubLabels, _, err := gp.client.Issues.ListLabels(ctx, owner, repo, &github.ListOptions{PerPage: 100})
+	labels := make([]*git.Label, 0)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		githubLabels, resp, err := gp.client.Issues.ListLabels(ctx, owner, repo, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get labels: %w", err)
+		}
+		for _, githubLabel := range githubLabels {
+			labels = append(labels, convertGitHubLabel(githubLabel))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return labels, nil
+}
+
+// GetCommitChecks retrieves commit statuses and check runs for a GitHub commit.
+// Every page is read so a failing check is never missed.
+func (gp *GitHubProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	var checks []*git.CommitCheck
+
+	statusOpts := &github.ListOptions{PerPage: 100}
+	for {
+		combined, resp, err := gp.client.Repositories.GetCombinedStatus(ctx, owner, repo, ref, statusOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commit statuses: %w", err)
+		}
+		for _, status := range combined.Statuses {
+			checks = append(checks, convertGitHubRepoStatus(status))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		statusOpts.Page = resp.NextPage
+	}
+
+	checkRunOpts := &github.ListCheckRunsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		checkRuns, resp, err := gp.client.Checks.ListCheckRunsForRef(ctx, owner, repo, ref, checkRunOpts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get check runs: %w", err)
+		}
+		for _, checkRun := range checkRuns.CheckRuns {
+			checks = append(checks, convertGitHubCheckRun(checkRun))
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		checkRunOpts.Page = resp.NextPage
+	}
+
+	return checks, nil
+}
+
+// BranchExists reports whether a branch exists in a GitHub repository
+func (gp *GitHubProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	_, resp, err := gp.client.Repositories.GetBranch(ctx, owner, repo, branch, 1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get labels: %w", err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to get branch %s: %w", branch, err)
 	}
 
-	labels := make([]*git.Label, len(githubLabels))
-	for i, githubLabel := range githubLabels {
-		labels[i] = convertGitHubLabel(githubLabel)
+	return true, nil
+}
+
+// DeleteBranch deletes a branch from a GitHub repository
+func (gp *GitHubProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	resp, err := gp.client.Git.DeleteRef(ctx, owner, repo, "heads/"+branch)
+	if err != nil {
+		// GitHub answers 422 for refs that do not exist
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
 	}
 
-	return labels, nil
+	return nil
 }
 
 // Helper functions to convert GitHub types to our generic types
@@ -568,6 +716,9 @@ func convertGitHubComment(githubComment *github.PullRequestComment) *git.Comment
 		CreatedAt: githubComment.GetCreatedAt().Time,
 		UpdatedAt: githubComment.GetUpdatedAt().Time,
 		URL:       githubComment.GetURL(),
+		Path:      githubComment.GetPath(),
+		Line:      githubComment.GetLine(),
+		InReplyTo: int(githubComment.GetInReplyTo()),
 	}
 }
 
@@ -616,3 +767,57 @@ func convertGitHubRepositoryInfo(githubRepo *github.Repository) *git.Repository
 		UpdatedAt:     githubRepo.GetUpdatedAt().Time,
 	}
 }
+
+func convertGitHubRepoStatus(status *github.RepoStatus) *git.CommitCheck {
+	check := &git.CommitCheck{
+		Name:    status.GetContext(),
+		Source:  git.CheckSourceStatus,
+		Status:  git.CheckStatusCompleted,
+		Summary: status.GetDescription(),
+		URL:     status.GetTargetURL(),
+	}
+
+	// Commit statuses only have a single state: pending, success, failure or error
+	switch status.GetState() {
+	case "pending":
+		check.Status = git.CheckStatusInProgress
+	case "success":
+		check.Conclusion = git.CheckConclusionSuccess
+	default:
+		check.Conclusion = git.CheckConclusionFailure
+	}
+
+	if check.IsCompleted() && status.UpdatedAt != nil {
+		completedAt := status.UpdatedAt.Time
+		check.CompletedAt = &completedAt
+	}
+
+	return check
+}
+
+func convertGitHubCheckRun(checkRun *github.CheckRun) *git.CommitCheck {
+	check := &git.CommitCheck{
+		Name:       checkRun.GetName(),
+		Source:     git.CheckSourceCheckRun,
+		Status:     checkRun.GetStatus(),
+		Conclusion: checkRun.GetConclusion(),
+		URL:        checkRun.GetHTMLURL(),
+	}
+
+	if output := checkRun.GetOutput(); output != nil {
+		check.Summary = output.GetTitle()
+		if summary := output.GetSummary(); summary != "" {
+			if check.Summary != "" {
+				check.Summary += ": "
+			}
+			check.Summary += summary
+		}
+	}
+
+	if checkRun.CompletedAt != nil {
+		completedAt := checkRun.CompletedAt.Time
+		check.CompletedAt = &completedAt
+	}
+
+	return check
+}
@@ -3,11 +3,15 @@ package gitprovider
 import (
 	"context"
 	"fmt"
+	"log"
+	"net/http"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/hlfshell/cowork/internal/branchname"
 	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
 	"github.com/hlfshell/cowork/internal/task"
 	"github.com/hlfshell/cowork/internal/types"
 	"github.com/hlfshell/cowork/internal/workspace"
@@ -20,6 +24,9 @@ const (
 
 	// DefaultTaskName is the default name used when no valid task name can be generated
 	DefaultTaskName = "task"
+
+	// DefaultBranchTemplate is the branch name template used when none is set
+	DefaultBranchTemplate = "{slug}"
 )
 
 // GitHubCoworkProvider implements the CoworkProvider interface for GitHub
@@ -28,6 +35,8 @@ type GitHubCoworkProvider struct {
 	taskManager      task.TaskManager
 	workspaceManager workspace.WorkspaceManager
 	currentUser      string
+	branchTemplate   string
+	prTemplate       string
 }
 
 // NewGitHubCoworkProvider creates a new GitHub cowork provider instance
@@ -48,6 +57,7 @@ func NewGitHubCoworkProvider(token, baseURL string, taskManager task.TaskManager
 		taskManager:      taskManager,
 		workspaceManager: workspaceManager,
 		currentUser:      *currentUser.Login,
+		branchTemplate:   DefaultBranchTemplate,
 	}, nil
 }
 
@@ -148,8 +158,14 @@ func (gcp *GitHubCoworkProvider) CreateWorkspaceForTask(ctx context.Context, tas
 		return nil, fmt.Errorf("failed to get issue: %w", err)
 	}
 
-	// Generate branch name
-	branchName := gcp.GenerateBranchName(issue)
+	// Keep a branch name chosen earlier, otherwise generate a free one
+	branchName := task.BranchName
+	if branchName == "" {
+		branchName, err = branchname.Resolve(gcp.GenerateBranchName(issue), git.RemoteBranchExists(ctx, gcp, owner, repo))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve branch name: %w", err)
+		}
+	}
 
 	// Create workspace request
 	req := &types.CreateWorkspaceRequest{
@@ -191,46 +207,35 @@ func (gcp *GitHubCoworkProvider) CreateWorkspaceForTask(ctx context.Context, tas
 	return workspace, nil
 }
 
-// GenerateBranchName generates a branch name for a task based on the issue
-func (gcp *GitHubCoworkProvider) GenerateBranchName(issue *git.Issue) string {
-	// Convert issue title to branch name
-	branchName := strings.ToLower(issue.Title)
+// SetBranchTemplate sets the template used to name task branches
+func (gcp *GitHubCoworkProvider) SetBranchTemplate(template string) {
+	gcp.branchTemplate = template
+}
 
-	// Replace underscores with hyphens
-	branchName = strings.ReplaceAll(branchName, "_", "-")
+// SetPRTemplate sets the template used to write pull request bodies; an empty
+// template uses prbody.DefaultTemplate
+func (gcp *GitHubCoworkProvider) SetPRTemplate(template string) {
+	gcp.prTemplate = template
+}
 
-	// Replace multiple spaces with single hyphens
-	branchName = strings.Join(strings.Fields(branchName), "-")
+// GenerateBranchName generates a branch name for a task based on the issue.
+// A template that cannot produce a valid name falls back to the default.
+func (gcp *GitHubCoworkProvider) GenerateBranchName(issue *git.Issue) string {
+	data := git.NewBranchNameData(issue)
+	data.SlugLength = MaxBranchNameLength
 
-	// Remove special characters that are not valid in Git branch names
-	// Keep only letters, numbers, and hyphens
-	branchName = strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '-' {
-			return r
+	if gcp.branchTemplate != "" {
+		branchName, err := branchname.Render(gcp.branchTemplate, data)
+		if err == nil {
+			return branchName
 		}
-		return -1
-	}, branchName)
-
-	// Remove multiple consecutive hyphens
-	for strings.Contains(branchName, "--") {
-		branchName = strings.ReplaceAll(branchName, "--", "-")
+		log.Printf("⚠️  Falling back to default branch name for issue #%d: %v", issue.Number, err)
 	}
 
-	// Remove leading and trailing hyphens
-	branchName = strings.Trim(branchName, "-")
-
-	// Limit length to keep it short and readable (25 characters max)
-	if len(branchName) > MaxBranchNameLength {
-		branchName = branchName[:MaxBranchNameLength]
-		// Remove trailing hyphens again after truncation
-		branchName = strings.TrimRight(branchName, "-")
-	}
-
-	// Ensure it's not empty
-	if branchName == "" {
-		branchName = DefaultTaskName
+	branchName, err := branchname.Render(DefaultBranchTemplate, data)
+	if err != nil {
+		return DefaultTaskName
 	}
-
 	return branchName
 }
 
@@ -256,7 +261,7 @@ func (gcp *GitHubCoworkProvider) CreatePullRequestForTask(ctx context.Context, t
 	// Create PR request
 	prReq := &git.CreatePullRequestRequest{
 		Title: fmt.Sprintf("Fix #%d: %s", issue.Number, issue.Title),
-		Body:  fmt.Sprintf("This PR addresses issue #%d: %s\n\nCloses #%d", issue.Number, issue.Title, issue.Number),
+		Body:  prbody.Build(gcp.prTemplate, issue, task, workspace.Path, task.BaseBranch),
 		Head:  task.BranchName,
 		Base:  task.BaseBranch,
 	}
@@ -337,6 +342,30 @@ func (gcp *GitHubCoworkProvider) ScanPullRequestsForTasks(ctx context.Context, o
 	return relevantPRs, nil
 }
 
+// GetTrackingIssues returns the parent issue tracking a sub-issue, as shown
+// in its "tracked by" links. Parents in other repositories are left out.
+func (gcp *GitHubCoworkProvider) GetTrackingIssues(ctx context.Context, owner, repo string, issueNumber int) ([]int, error) {
+	req, err := gcp.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/issues/%d/parent", owner, repo, issueNumber), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build parent issue request: %w", err)
+	}
+
+	var parent github.Issue
+	resp, err := gcp.client.Do(ctx, req, &parent)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent issue: %w", err)
+	}
+
+	if !strings.HasSuffix(strings.ToLower(parent.GetRepositoryURL()), strings.ToLower(fmt.Sprintf("/repos/%s/%s", owner, repo))) {
+		return nil, nil
+	}
+
+	return []int{parent.GetNumber()}, nil
+}
+
 // GetPullRequestUpdates retrieves recent updates for a pull request
 func (gcp *GitHubCoworkProvider) GetPullRequestUpdates(ctx context.Context, owner, repo string, prNumber int, since time.Time) (*git.PullRequestUpdate, error) {
 	// Get new comments
@@ -362,22 +391,46 @@ func (gcp *GitHubCoworkProvider) GetPullRequestUpdates(ctx context.Context, owne
 		}
 	}
 
+	// Get new inline review comments
+	reviewComments, _, err := gcp.client.PullRequests.ListComments(ctx, owner, repo, prNumber, &github.PullRequestListCommentsOptions{
+		Since: since,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get review comments: %w", err)
+	}
+
 	// Convert comments
 	var newComments []*git.Comment
 	for _, comment := range comments {
-		gitComment := gcp.convertGitHubComment(comment)
+		gitComment := convertGitHubIssueComment(comment)
 		newComments = append(newComments, gitComment)
 	}
 
+	var newReviewComments []*git.Comment
+	for _, comment := range reviewComments {
+		newReviewComments = append(newReviewComments, convertGitHubComment(comment))
+	}
+
 	return &git.PullRequestUpdate{
-		PRNumber:      prNumber,
-		NewComments:   newComments,
-		NewReviews:    newReviews,
-		StatusChanges: make(map[string]interface{}), // TODO: Track status changes
-		UpdatedAt:     time.Now(),
+		PRNumber:          prNumber,
+		NewComments:       newComments,
+		NewReviews:        newReviews,
+		NewReviewComments: newReviewComments,
+		StatusChanges:     make(map[string]interface{}), // TODO: Track status changes
+		UpdatedAt:         time.Now(),
 	}, nil
 }
 
+// ReplyToReviewComment replies in the thread of an inline review comment
+func (gcp *GitHubCoworkProvider) ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber, commentID int, body string) (*git.Comment, error) {
+	reply, _, err := gcp.client.PullRequests.CreateCommentInReplyTo(ctx, owner, repo, prNumber, body, int64(commentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to reply to review comment: %w", err)
+	}
+
+	return convertGitHubComment(reply), nil
+}
+
 // UpdateTaskFromPullRequest updates a task based on pull request changes
 func (gcp *GitHubCoworkProvider) UpdateTaskFromPullRequest(ctx context.Context, task *types.Task, pr *git.PullRequest, updates *git.PullRequestUpdate) error {
 	// Update task description with new comments/reviews
@@ -562,11 +615,6 @@ func (gcp *GitHubCoworkProvider) convertGitHubPullRequest(pr *github.PullRequest
 	return &git.PullRequest{}
 }
 
-func (gcp *GitHubCoworkProvider) convertGitHubComment(comment *github.IssueComment) *git.Comment {
-	// TODO: Implement conversion from github.IssueComment to git.Comment
-	return &git.Comment{}
-}
-
 func (gcp *GitHubCoworkProvider) convertGitHubReview(review *github.PullRequestReview) *git.Review {
 	// TODO: Implement conversion from github.PullRequestReview to git.Review
 	return &git.Review{}
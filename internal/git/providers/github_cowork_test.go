@@ -304,3 +304,21 @@ func validateGitBranchName(name string) error {
 
 	return nil
 }
+
+func TestGitHubCoworkProvider_GenerateBranchName_Template(t *testing.T) {
+	provider := &GitHubCoworkProvider{}
+	issue := &git.Issue{
+		Number:    7,
+		Title:     "Fix login bug",
+		Author:    &git.User{Login: "author"},
+		Assignees: []*git.User{{Login: "octocat"}},
+		Labels:    []*git.Label{{Name: "bug"}},
+	}
+
+	provider.SetBranchTemplate("{type}/{user}/{issue-key}-{slug}")
+	assert.Equal(t, "fix/octocat/7-fix-login-bug", provider.GenerateBranchName(issue))
+
+	// Templates that cannot render fall back to the default
+	provider.SetBranchTemplate("feature/{unknown}")
+	assert.Equal(t, "fix-login-bug", provider.GenerateBranchName(issue))
+}
@@ -2,9 +2,14 @@ package gitprovider
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/google/go-github/v57/github"
 	"github.com/hlfshell/cowork/internal/git"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -465,9 +470,125 @@ func TestGitHubProvider_Timeout(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// TestConvertGitHubChecks tests mapping GitHub statuses and check runs to commit checks
+func TestConvertGitHubChecks(t *testing.T) {
+	// Test case: Commit status states map onto check statuses and conclusions,
+	// and check run output is folded into the summary
+	testCases := []struct {
+		state      string
+		status     string
+		conclusion string
+	}{
+		{"pending", git.CheckStatusInProgress, ""},
+		{"success", git.CheckStatusCompleted, git.CheckConclusionSuccess},
+		{"failure", git.CheckStatusCompleted, git.CheckConclusionFailure},
+		{"error", git.CheckStatusCompleted, git.CheckConclusionFailure},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.state, func(t *testing.T) {
+			check := convertGitHubRepoStatus(&github.RepoStatus{
+				Context: github.String("ci/lint"),
+				State:   github.String(tc.state),
+			})
+			assert.Equal(t, "ci/lint", check.Name)
+			assert.Equal(t, git.CheckSourceStatus, check.Source)
+			assert.Equal(t, tc.status, check.Status)
+			assert.Equal(t, tc.conclusion, check.Conclusion)
+		})
+	}
+
+	check := convertGitHubCheckRun(&github.CheckRun{
+		Name:       github.String("test"),
+		Status:     github.String("completed"),
+		Conclusion: github.String("failure"),
+		Output: &github.CheckRunOutput{
+			Title:   github.String("2 tests failed"),
+			Summary: github.String("TestFoo, TestBar"),
+		},
+	})
+	assert.Equal(t, git.CheckSourceCheckRun, check.Source)
+	assert.True(t, check.IsFailed())
+	assert.Equal(t, "2 tests failed: TestFoo, TestBar", check.Summary)
+}
+
+// TestGitHubProvider_GetCommitChecks_Pagination tests reading every page of checks
+func TestGitHubProvider_GetCommitChecks_Pagination(t *testing.T) {
+	// Test case: A failing status and a failing check run on the second page
+	// are returned along with the first page
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		if page == "1" {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=2>; rel="next"`, server.URL, r.URL.Path))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/repos/owner/repo/commits/abc123/status":
+			state := "success"
+			if page == "2" {
+				state = "failure"
+			}
+			fmt.Fprintf(w, `{"statuses": [{"context": "status-%s", "state": %q}]}`, page, state)
+		case "/repos/owner/repo/commits/abc123/check-runs":
+			conclusion := "success"
+			if page == "2" {
+				conclusion = "failure"
+			}
+			fmt.Fprintf(w, `{"total_count": 2, "check_runs": [{"name": "run-%s", "status": "completed", "conclusion": %q}]}`, page, conclusion)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewGitHubProvider("test-token", server.URL+"/")
+	require.NoError(t, err)
+
+	checks, err := provider.GetCommitChecks(context.Background(), "owner", "repo", "abc123")
+	require.NoError(t, err)
+
+	var names []string
+	var failed []string
+	for _, check := range checks {
+		names = append(names, check.Name)
+		if check.IsFailed() {
+			failed = append(failed, check.Name)
+		}
+	}
+	assert.Equal(t, []string{"status-1", "status-2", "run-1", "run-2"}, names)
+	assert.Equal(t, []string{"status-2", "run-2"}, failed)
+}
+
 // Helper function to get test token from environment
 func getTestToken(t *testing.T) string {
 	// In a real implementation, this would read from environment variables
 	// For now, we'll return empty string to skip tests that require authentication
 	return ""
 }
+
+// TestGraphQLURL tests deriving the GraphQL endpoint from the REST base URL
+func TestGraphQLURL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		baseURL  string
+		expected string
+	}{
+		// Test case: github.com serves GraphQL next to the REST API
+		{"github.com", "https://api.github.com/", "https://api.github.com/graphql"},
+		// Test case: Enterprise servers drop the /v3 REST suffix
+		{"enterprise", "https://github.example.com/api/v3/", "https://github.example.com/api/graphql"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			baseURL, err := url.Parse(tc.baseURL)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, graphQLURL(baseURL))
+		})
+	}
+}
@@ -90,13 +90,13 @@ func (glp *GitLabProvider) GetPullRequestByIssue(ctx context.Context, owner, rep
 
 // CreatePullRequest creates a new merge request in a GitLab repository
 func (glp *GitLabProvider) CreatePullRequest(ctx context.Context, owner, repo string, pr *git.CreatePullRequestRequest) (*git.PullRequest, error) {
-	// TODO: Implement GitLab merge request creation
+	// TODO: Implement GitLab merge request creation; drafts are opened with a "Draft: " title prefix
 	return nil, fmt.Errorf("GitLab provider not yet implemented")
 }
 
 // UpdatePullRequest updates an existing GitLab merge request
 func (glp *GitLabProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, updates *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
-	// TODO: Implement GitLab merge request update
+	// TODO: Implement GitLab merge request update; draft changes add or remove the "Draft: " title prefix
 	return nil, fmt.Errorf("GitLab provider not yet implemented")
 }
 
@@ -129,3 +129,21 @@ func (glp *GitLabProvider) GetLabels(ctx context.Context, owner, repo string) ([
 	// TODO: Implement GitLab labels retrieval
 	return nil, fmt.Errorf("GitLab provider not yet implemented")
 }
+
+// GetCommitChecks retrieves commit statuses and pipeline jobs for a GitLab commit
+func (glp *GitLabProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	// TODO: Implement GitLab commit checks retrieval
+	return nil, fmt.Errorf("GitLab provider not yet implemented")
+}
+
+// BranchExists reports whether a branch exists in a GitLab repository
+func (glp *GitLabProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	// TODO: Implement GitLab branch lookup
+	return false, fmt.Errorf("GitLab provider not yet implemented")
+}
+
+// DeleteBranch deletes a branch from a GitLab repository
+func (glp *GitLabProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	// TODO: Implement GitLab branch deletion
+	return fmt.Errorf("GitLab provider not yet implemented")
+}
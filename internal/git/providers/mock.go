@@ -340,6 +340,11 @@ func (mp *MockProvider) UpdatePullRequest(ctx context.Context, owner, repo strin
 		title = *updates.Title
 	}
 
+	draft := false
+	if updates.Draft != nil {
+		draft = *updates.Draft
+	}
+
 	return &git.PullRequest{
 		Number:    prNumber,
 		Title:     title,
@@ -355,7 +360,7 @@ func (mp *MockProvider) UpdatePullRequest(ctx context.Context, owner, repo strin
 		UpdatedAt: time.Now().UTC(),
 		Comments:  git.Comment{ID: 1},
 		URL:       fmt.Sprintf("https://api.%s.com/repos/%s/%s/pulls/%d", mp.providerType, owner, repo, prNumber),
-		Draft:     false,
+		Draft:     draft,
 		Mergeable: &[]bool{true}[0],
 	}, nil
 }
@@ -498,6 +503,56 @@ func (mp *MockProvider) GetLabels(ctx context.Context, owner, repo string) ([]*g
 	return labels, nil
 }
 
+// GetCommitChecks retrieves commit statuses and check runs for a commit
+func (mp *MockProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	if mp.shouldFail && mp.failMethod == "GetCommitChecks" {
+		return nil, fmt.Errorf("mock commit checks retrieval failed")
+	}
+
+	completedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	checks := []*git.CommitCheck{
+		{
+			Name:        "lint",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionSuccess,
+			Summary:     "No lint errors",
+			URL:         fmt.Sprintf("https://%s.com/%s/%s/commit/%s/checks/1", mp.providerType, owner, repo, ref),
+			CompletedAt: &completedAt,
+		},
+		{
+			Name:   "test",
+			Source: git.CheckSourceStatus,
+			Status: git.CheckStatusInProgress,
+			URL:    fmt.Sprintf("https://%s.com/%s/%s/commit/%s/checks/2", mp.providerType, owner, repo, ref),
+		},
+	}
+
+	return checks, nil
+}
+
+// BranchExists reports whether a branch exists. Only the default branches exist.
+func (mp *MockProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	if mp.shouldFail && mp.failMethod == "BranchExists" {
+		return false, fmt.Errorf("mock branch lookup failed")
+	}
+
+	return branch == "main" || branch == "master", nil
+}
+
+// DeleteBranch deletes a branch. The default branches cannot be deleted.
+func (mp *MockProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	if mp.shouldFail && mp.failMethod == "DeleteBranch" {
+		return fmt.Errorf("mock branch deletion failed")
+	}
+
+	if branch == "main" || branch == "master" {
+		return fmt.Errorf("cannot delete default branch %s", branch)
+	}
+
+	return nil
+}
+
 // Helper methods to create mock data
 
 func (mp *MockProvider) mockUser(id int, login string) *git.User {
@@ -384,6 +384,11 @@ func (mbp *MockBitbucketProvider) UpdatePullRequest(ctx context.Context, owner,
 		title = *updates.Title
 	}
 
+	draft := false
+	if updates.Draft != nil {
+		draft = *updates.Draft
+	}
+
 	return &git.PullRequest{
 		Number:    prNumber,
 		Title:     title,
@@ -399,7 +404,7 @@ func (mbp *MockBitbucketProvider) UpdatePullRequest(ctx context.Context, owner,
 		UpdatedAt: time.Now().UTC(),
 		Comments:  git.Comment{ID: 1},
 		URL:       fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s/%s/pullrequests/%d", owner, repo, prNumber),
-		Draft:     false,
+		Draft:     draft,
 		Mergeable: &[]bool{true}[0],
 	}, nil
 }
@@ -564,6 +569,68 @@ func (mbp *MockBitbucketProvider) GetLabels(ctx context.Context, owner, repo str
 	return labels, nil
 }
 
+// GetCommitChecks retrieves build statuses for a Bitbucket commit
+func (mbp *MockBitbucketProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	if mbp.shouldFail && mbp.failMethod == "GetCommitChecks" {
+		return nil, fmt.Errorf("Bitbucket commit checks retrieval failed: 404 Not Found")
+	}
+	if mbp.rateLimited {
+		return nil, fmt.Errorf("Bitbucket API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	completedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	checks := []*git.CommitCheck{
+		{
+			Name:        "lint",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionSuccess,
+			Summary:     "No lint errors",
+			URL:         fmt.Sprintf("https://bitbucket.org/%s/%s/pipelines/results/%d", owner, repo, 1),
+			CompletedAt: &completedAt,
+		},
+		{
+			Name:        "test",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionFailure,
+			Summary:     "2 tests failed",
+			URL:         fmt.Sprintf("https://bitbucket.org/%s/%s/pipelines/results/%d", owner, repo, 2),
+			CompletedAt: &completedAt,
+		},
+	}
+
+	return checks, nil
+}
+
+// BranchExists reports whether a Bitbucket branch exists. Only the default branches exist.
+func (mbp *MockBitbucketProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	if mbp.shouldFail && mbp.failMethod == "BranchExists" {
+		return false, fmt.Errorf("Bitbucket branch lookup failed: 500 Internal Server Error")
+	}
+	if mbp.rateLimited {
+		return false, fmt.Errorf("Bitbucket API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	return branch == "main" || branch == "master", nil
+}
+
+// DeleteBranch deletes a Bitbucket branch. The default branches cannot be deleted.
+func (mbp *MockBitbucketProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	if mbp.shouldFail && mbp.failMethod == "DeleteBranch" {
+		return fmt.Errorf("Bitbucket branch deletion failed: 500 Internal Server Error")
+	}
+	if mbp.rateLimited {
+		return fmt.Errorf("Bitbucket API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	if branch == "main" || branch == "master" {
+		return fmt.Errorf("cannot delete default branch %s", branch)
+	}
+
+	return nil
+}
+
 // Helper methods to create Bitbucket-specific mock data
 
 func (mbp *MockBitbucketProvider) mockBitbucketUser(id int, login string) *git.User {
@@ -384,6 +384,11 @@ func (mgp *MockGitHubProvider) UpdatePullRequest(ctx context.Context, owner, rep
 		title = *updates.Title
 	}
 
+	draft := false
+	if updates.Draft != nil {
+		draft = *updates.Draft
+	}
+
 	return &git.PullRequest{
 		Number:    prNumber,
 		Title:     title,
@@ -399,7 +404,7 @@ func (mgp *MockGitHubProvider) UpdatePullRequest(ctx context.Context, owner, rep
 		UpdatedAt: time.Now().UTC(),
 		Comments:  git.Comment{ID: 1},
 		URL:       fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", owner, repo, prNumber),
-		Draft:     false,
+		Draft:     draft,
 		Mergeable: &[]bool{true}[0],
 	}, nil
 }
@@ -564,6 +569,68 @@ func (mgp *MockGitHubProvider) GetLabels(ctx context.Context, owner, repo string
 	return labels, nil
 }
 
+// GetCommitChecks retrieves commit statuses and check runs for a GitHub commit
+func (mgp *MockGitHubProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	if mgp.shouldFail && mgp.failMethod == "GetCommitChecks" {
+		return nil, fmt.Errorf("GitHub commit checks retrieval failed: 404 Not Found")
+	}
+	if mgp.rateLimited {
+		return nil, fmt.Errorf("GitHub API rate limit exceeded: 403 Forbidden")
+	}
+
+	completedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	checks := []*git.CommitCheck{
+		{
+			Name:        "lint",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionSuccess,
+			Summary:     "No lint errors",
+			URL:         fmt.Sprintf("https://github.com/%s/%s/runs/%d", owner, repo, 1),
+			CompletedAt: &completedAt,
+		},
+		{
+			Name:        "test",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionFailure,
+			Summary:     "2 tests failed",
+			URL:         fmt.Sprintf("https://github.com/%s/%s/runs/%d", owner, repo, 2),
+			CompletedAt: &completedAt,
+		},
+	}
+
+	return checks, nil
+}
+
+// BranchExists reports whether a GitHub branch exists. Only the default branches exist.
+func (mgp *MockGitHubProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	if mgp.shouldFail && mgp.failMethod == "BranchExists" {
+		return false, fmt.Errorf("GitHub branch lookup failed: 500 Internal Server Error")
+	}
+	if mgp.rateLimited {
+		return false, fmt.Errorf("GitHub API rate limit exceeded: 403 Forbidden")
+	}
+
+	return branch == "main" || branch == "master", nil
+}
+
+// DeleteBranch deletes a GitHub branch. The default branches cannot be deleted.
+func (mgp *MockGitHubProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	if mgp.shouldFail && mgp.failMethod == "DeleteBranch" {
+		return fmt.Errorf("GitHub branch deletion failed: 500 Internal Server Error")
+	}
+	if mgp.rateLimited {
+		return fmt.Errorf("GitHub API rate limit exceeded: 403 Forbidden")
+	}
+
+	if branch == "main" || branch == "master" {
+		return fmt.Errorf("cannot delete default branch %s", branch)
+	}
+
+	return nil
+}
+
 // Helper methods to create GitHub-specific mock data
 
 func (mgp *MockGitHubProvider) mockGitHubUser(id int, login string) *git.User {
@@ -533,6 +533,74 @@ func TestMockGitHubProvider_GetLabels_Failure(t *testing.T) {
 	assert.Contains(t, err.Error(), "GitHub labels retrieval failed: 404 Not Found")
 }
 
+func TestMockGitHubProvider_GetCommitChecks_Success(t *testing.T) {
+	provider := NewMockGitHubProvider()
+	ctx := context.Background()
+
+	checks, err := provider.GetCommitChecks(ctx, "testowner", "testrepo", "abc123")
+	assert.NoError(t, err)
+	assert.Len(t, checks, 2)
+
+	assert.Equal(t, "lint", checks[0].Name)
+	assert.True(t, checks[0].IsSuccessful())
+	assert.Equal(t, "test", checks[1].Name)
+	assert.True(t, checks[1].IsFailed())
+	assert.Equal(t, "2 tests failed", checks[1].Summary)
+}
+
+func TestMockGitHubProvider_GetCommitChecks_Failure(t *testing.T) {
+	provider := NewMockGitHubProviderWithFailure("GetCommitChecks")
+	ctx := context.Background()
+
+	checks, err := provider.GetCommitChecks(ctx, "testowner", "testrepo", "abc123")
+	assert.Error(t, err)
+	assert.Nil(t, checks)
+	assert.Contains(t, err.Error(), "GitHub commit checks retrieval failed: 404 Not Found")
+}
+
+func TestMockGitHubProvider_BranchExists(t *testing.T) {
+	provider := NewMockGitHubProvider()
+	ctx := context.Background()
+
+	exists, err := provider.BranchExists(ctx, "testowner", "testrepo", "main")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = provider.BranchExists(ctx, "testowner", "testrepo", "feature/new-branch")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMockGitHubProvider_BranchExists_Failure(t *testing.T) {
+	provider := NewMockGitHubProviderWithFailure("BranchExists")
+	ctx := context.Background()
+
+	exists, err := provider.BranchExists(ctx, "testowner", "testrepo", "main")
+	assert.Error(t, err)
+	assert.False(t, exists)
+	assert.Contains(t, err.Error(), "GitHub branch lookup failed")
+}
+
+func TestMockGitHubProvider_DeleteBranch(t *testing.T) {
+	provider := NewMockGitHubProvider()
+	ctx := context.Background()
+
+	assert.NoError(t, provider.DeleteBranch(ctx, "testowner", "testrepo", "feature/new-branch"))
+
+	err := provider.DeleteBranch(ctx, "testowner", "testrepo", "main")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot delete default branch")
+}
+
+func TestMockGitHubProvider_DeleteBranch_Failure(t *testing.T) {
+	provider := NewMockGitHubProviderWithFailure("DeleteBranch")
+	ctx := context.Background()
+
+	err := provider.DeleteBranch(ctx, "testowner", "testrepo", "feature/new-branch")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "GitHub branch deletion failed")
+}
+
 // Helper function to create string pointers
 func stringPtr(s string) *string {
 	return &s
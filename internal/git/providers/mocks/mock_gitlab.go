@@ -384,6 +384,11 @@ func (mlp *MockGitLabProvider) UpdatePullRequest(ctx context.Context, owner, rep
 		title = *updates.Title
 	}
 
+	draft := false
+	if updates.Draft != nil {
+		draft = *updates.Draft
+	}
+
 	return &git.PullRequest{
 		Number:    prNumber,
 		Title:     title,
@@ -399,7 +404,7 @@ func (mlp *MockGitLabProvider) UpdatePullRequest(ctx context.Context, owner, rep
 		UpdatedAt: time.Now().UTC(),
 		Comments:  git.Comment{ID: 1},
 		URL:       fmt.Sprintf("https://gitlab.com/api/v4/projects/%s%%2F%s/merge_requests/%d", owner, repo, prNumber),
-		Draft:     false,
+		Draft:     draft,
 		Mergeable: &[]bool{true}[0],
 	}, nil
 }
@@ -564,6 +569,68 @@ func (mlp *MockGitLabProvider) GetLabels(ctx context.Context, owner, repo string
 	return labels, nil
 }
 
+// GetCommitChecks retrieves pipeline jobs for a GitLab commit
+func (mlp *MockGitLabProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	if mlp.shouldFail && mlp.failMethod == "GetCommitChecks" {
+		return nil, fmt.Errorf("GitLab commit checks retrieval failed: 404 Not Found")
+	}
+	if mlp.rateLimited {
+		return nil, fmt.Errorf("GitLab API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	completedAt := time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+	checks := []*git.CommitCheck{
+		{
+			Name:        "lint",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionSuccess,
+			Summary:     "No lint errors",
+			URL:         fmt.Sprintf("https://gitlab.com/%s/%s/-/jobs/%d", owner, repo, 1),
+			CompletedAt: &completedAt,
+		},
+		{
+			Name:        "test",
+			Source:      git.CheckSourceCheckRun,
+			Status:      git.CheckStatusCompleted,
+			Conclusion:  git.CheckConclusionFailure,
+			Summary:     "2 tests failed",
+			URL:         fmt.Sprintf("https://gitlab.com/%s/%s/-/jobs/%d", owner, repo, 2),
+			CompletedAt: &completedAt,
+		},
+	}
+
+	return checks, nil
+}
+
+// BranchExists reports whether a GitLab branch exists. Only the default branches exist.
+func (mlp *MockGitLabProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	if mlp.shouldFail && mlp.failMethod == "BranchExists" {
+		return false, fmt.Errorf("GitLab branch lookup failed: 500 Internal Server Error")
+	}
+	if mlp.rateLimited {
+		return false, fmt.Errorf("GitLab API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	return branch == "main" || branch == "master", nil
+}
+
+// DeleteBranch deletes a GitLab branch. The default branches cannot be deleted.
+func (mlp *MockGitLabProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	if mlp.shouldFail && mlp.failMethod == "DeleteBranch" {
+		return fmt.Errorf("GitLab branch deletion failed: 500 Internal Server Error")
+	}
+	if mlp.rateLimited {
+		return fmt.Errorf("GitLab API rate limit exceeded: 429 Too Many Requests")
+	}
+
+	if branch == "main" || branch == "master" {
+		return fmt.Errorf("cannot delete default branch %s", branch)
+	}
+
+	return nil
+}
+
 // Helper methods to create GitLab-specific mock data
 
 func (mlp *MockGitLabProvider) mockGitLabUser(id int, login string) *git.User {
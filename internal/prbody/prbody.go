@@ -0,0 +1,272 @@
+package prbody
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// Template location inside the .cw directory
+const (
+	// TemplateDirName is the directory holding user templates
+	TemplateDirName = "templates"
+
+	// TemplateFileName is the user-overridable PR body template
+	TemplateFileName = "pr.md.tmpl"
+)
+
+// Task metadata the PR body is built from
+const (
+	// MetadataAgentSummary holds the agent's own explanation of its change
+	MetadataAgentSummary = "agent_summary"
+
+	// MetadataAgentLog holds the path of the agent's log file
+	MetadataAgentLog = "agent_log"
+
+	// MetadataVerification holds the verification results as JSON
+	MetadataVerification = "verification"
+)
+
+const (
+	// ClosingKeyword links the PR to its issue so merging closes the issue
+	ClosingKeyword = "Closes"
+
+	// LogExcerptLines is the number of trailing agent log lines included
+	LogExcerptLines = 40
+
+	// VerificationOutputLines is the number of trailing output lines kept per
+	// verification command
+	VerificationOutputLines = 20
+)
+
+// DefaultTemplate is the PR body template used when the repository has none
+const DefaultTemplate = `## Summary
+
+{{if .Summary}}{{.Summary}}{{else}}This PR addresses issue #{{.Issue.Number}}: {{.Issue.Title}}{{end}}
+
+## Changes
+
+{{if .Files}}{{len .Files}} file(s) changed, +{{.Additions}} -{{.Deletions}}
+
+| File | Added | Removed |
+|------|-------|---------|
+{{range .Files}}| ` + "`{{.Path}}`" + ` | {{if .Binary}}binary{{else}}{{.Additions}}{{end}} | {{if .Binary}}binary{{else}}{{.Deletions}}{{end}} |
+{{end}}{{else}}No file changes were detected.
+{{end}}
+## Verification
+
+{{range .Verification}}- {{if .Passed}}✅{{else}}❌{{end}} ` + "`{{.Command}}`" + `
+{{else}}No verification commands were run.
+{{end}}
+## Related
+
+- {{.ClosingKeyword}} #{{.Issue.Number}}
+{{if .Task.URL}}- [Task]({{.Task.URL}})
+{{end}}{{if .AgentLog}}
+<details>
+<summary>Agent log excerpt</summary>
+
+` + "```" + `
+{{.AgentLog}}
+` + "```" + `
+
+</details>
+{{end}}
+---
+*This PR was automatically generated by Cowork*`
+
+// FileChange is one file changed on the task branch
+type FileChange struct {
+	Path      string
+	Additions int
+	Deletions int
+	Binary    bool
+}
+
+// Verification is the result of one verification command
+type Verification struct {
+	Command string `json:"command"`
+	Passed  bool   `json:"passed"`
+	Output  string `json:"output,omitempty"`
+}
+
+// Data is what a PR body template is rendered with
+type Data struct {
+	Issue          *git.Issue
+	Task           *types.Task
+	ClosingKeyword string
+
+	// Files changed against the base branch, with the totals across them
+	Files     []FileChange
+	Additions int
+	Deletions int
+
+	// The agent's explanation of the change
+	Summary string
+
+	Verification []Verification
+
+	// Trailing lines of the agent log
+	AgentLog string
+}
+
+// Parse checks that a PR body template is valid
+func Parse(tmpl string) (*template.Template, error) {
+	parsed, err := template.New("pr").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse PR template: %w", err)
+	}
+	return parsed, nil
+}
+
+// Render renders a PR body template with data
+func Render(tmpl string, data *Data) (string, error) {
+	parsed, err := Parse(tmpl)
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	if err := parsed.Execute(&body, data); err != nil {
+		return "", fmt.Errorf("failed to render PR template: %w", err)
+	}
+
+	return strings.TrimSpace(body.String()), nil
+}
+
+// LoadTemplate reads the PR body template from a .cw directory, returning an
+// empty template when the repository does not override it
+func LoadTemplate(cwDir string) (string, error) {
+	path := filepath.Join(cwDir, TemplateDirName, TemplateFileName)
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read PR template: %w", err)
+	}
+
+	if _, err := Parse(string(content)); err != nil {
+		return "", fmt.Errorf("invalid PR template %s: %w", path, err)
+	}
+
+	return string(content), nil
+}
+
+// Build collects the work done for a task and renders its PR body. An empty
+// template or one that fails to render falls back to the default.
+func Build(tmpl string, issue *git.Issue, task *types.Task, workspacePath, baseBranch string) string {
+	data := NewData(issue, task, workspacePath, baseBranch)
+
+	if tmpl != "" {
+		body, err := Render(tmpl, data)
+		if err == nil {
+			return body
+		}
+		log.Printf("⚠️  Falling back to default PR body for issue #%d: %v", issue.Number, err)
+	}
+
+	body, err := Render(DefaultTemplate, data)
+	if err != nil {
+		return fmt.Sprintf("This PR addresses issue #%d: %s\n\n%s #%d", issue.Number, issue.Title, ClosingKeyword, issue.Number)
+	}
+	return body
+}
+
+// NewData gathers the template data for a task. Missing pieces, like an
+// unreadable agent log, are left empty.
+func NewData(issue *git.Issue, task *types.Task, workspacePath, baseBranch string) *Data {
+	data := &Data{
+		Issue:          issue,
+		Task:           task,
+		ClosingKeyword: ClosingKeyword,
+		Summary:        strings.TrimSpace(task.Metadata[MetadataAgentSummary]),
+	}
+
+	if workspacePath != "" {
+		files, err := CollectChanges(workspacePath, baseBranch)
+		if err != nil {
+			log.Printf("⚠️  Could not collect changes for task %d: %v", task.ID, err)
+		}
+		data.Files = files
+		for _, file := range files {
+			data.Additions += file.Additions
+			data.Deletions += file.Deletions
+		}
+	}
+
+	if raw := task.Metadata[MetadataVerification]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &data.Verification); err != nil {
+			log.Printf("⚠️  Ignoring malformed verification results for task %d: %v", task.ID, err)
+		}
+	}
+
+	if path := task.Metadata[MetadataAgentLog]; path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️  Could not read agent log for task %d: %v", task.ID, err)
+		} else {
+			data.AgentLog = Tail(string(content), LogExcerptLines)
+		}
+	}
+
+	return data
+}
+
+// CollectChanges lists the files the workspace branch changed since it left
+// the base branch
+func CollectChanges(workspacePath, baseBranch string) ([]FileChange, error) {
+	return collectChanges(workspacePath, baseBranch)
+}
+
+// CollectChangesWithoutRenames is CollectChanges with renamed files listed as
+// a deletion and an addition, so every path names a single file
+func CollectChangesWithoutRenames(workspacePath, baseBranch string) ([]FileChange, error) {
+	return collectChanges(workspacePath, baseBranch, "--no-renames")
+}
+
+// collectChanges parses the numstat diff of the workspace branch against the base branch
+func collectChanges(workspacePath, baseBranch string, extraArgs ...string) ([]FileChange, error) {
+	args := append([]string{"diff", "--numstat"}, extraArgs...)
+	cmd := exec.Command("git", append(args, "origin/"+baseBranch+"...HEAD")...)
+	cmd.Dir = workspacePath
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff against %s: %w", baseBranch, err)
+	}
+
+	var files []FileChange
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		// Binary files report - for both counts
+		file := FileChange{Path: fields[2], Binary: fields[0] == "-"}
+		file.Additions, _ = strconv.Atoi(fields[0])
+		file.Deletions, _ = strconv.Atoi(fields[1])
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// Tail returns the last n lines of text
+func Tail(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
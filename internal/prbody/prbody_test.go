@@ -0,0 +1,159 @@
+package prbody
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// gitTest runs a git command in dir and fails the test on error
+func gitTest(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+}
+
+// newChangedWorkspace creates a workspace whose branch changed two files
+// since it left main
+func newChangedWorkspace(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	workspace := filepath.Join(root, "workspace")
+
+	gitTest(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	gitTest(t, root, "clone", "-q", origin, workspace)
+	gitTest(t, workspace, "checkout", "-q", "-b", "main")
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n"), 0644))
+	gitTest(t, workspace, "add", ".")
+	gitTest(t, workspace, "commit", "-q", "-m", "initial")
+	gitTest(t, workspace, "push", "-q", "origin", "main")
+
+	gitTest(t, workspace, "checkout", "-q", "-b", "task-7")
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(workspace, "logo.png"), []byte{0x89, 0x50, 0x00, 0x01}, 0644))
+	gitTest(t, workspace, "add", ".")
+	gitTest(t, workspace, "commit", "-q", "-m", "fix")
+
+	return workspace
+}
+
+// TestCollectChanges tests listing the files changed on a task branch
+func TestCollectChanges(t *testing.T) {
+	// Test case: Text files report their line counts and binary files are flagged
+	workspace := newChangedWorkspace(t)
+
+	files, err := CollectChanges(workspace, "main")
+	require.NoError(t, err)
+	assert.Equal(t, []FileChange{
+		{Path: "logo.png", Binary: true},
+		{Path: "main.go", Additions: 2},
+	}, files)
+
+	_, err = CollectChanges(workspace, "missing")
+	assert.Error(t, err)
+}
+
+// TestBuild tests rendering PR bodies from the work done for a task
+func TestBuild(t *testing.T) {
+	workspace := newChangedWorkspace(t)
+	logPath := filepath.Join(t.TempDir(), "agent.log")
+	var logLines []string
+	for i := 1; i <= LogExcerptLines+5; i++ {
+		logLines = append(logLines, fmt.Sprintf("step %03d", i))
+	}
+	require.NoError(t, os.WriteFile(logPath, []byte(strings.Join(logLines, "\n")+"\n"), 0644))
+
+	issue := &git.Issue{Number: 7, Title: "Fix the widget"}
+	task := &types.Task{
+		ID:  3,
+		URL: "https://example.com/tasks/3",
+		Metadata: map[string]string{
+			MetadataAgentSummary: "Made main runnable.",
+			MetadataAgentLog:     logPath,
+			MetadataVerification: `[{"command":"go test ./...","passed":true},{"command":"golangci-lint run","passed":false}]`,
+		},
+	}
+
+	t.Run("default template", func(t *testing.T) {
+		// Test case: The default body covers the agent's explanation, the
+		// changed files, verification results, the closing keyword and the
+		// tail of the agent log
+		body := Build("", issue, task, workspace, "main")
+
+		assert.Contains(t, body, "Made main runnable.")
+		assert.Contains(t, body, "2 file(s) changed, +2 -0")
+		assert.Contains(t, body, "| `main.go` | 2 | 0 |")
+		assert.Contains(t, body, "| `logo.png` | binary | binary |")
+		assert.Contains(t, body, "- ✅ `go test ./...`")
+		assert.Contains(t, body, "- ❌ `golangci-lint run`")
+		assert.Contains(t, body, "- Closes #7")
+		assert.Contains(t, body, "<summary>Agent log excerpt</summary>")
+		assert.Contains(t, body, "step 045")
+		assert.Contains(t, body, "step 006")
+		assert.NotContains(t, body, "step 005")
+	})
+
+	t.Run("custom template", func(t *testing.T) {
+		// Test case: A repository template replaces the default
+		body := Build("{{.ClosingKeyword}} #{{.Issue.Number}} touching {{len .Files}} files", issue, task, workspace, "main")
+		assert.Equal(t, "Closes #7 touching 2 files", body)
+	})
+
+	t.Run("broken template", func(t *testing.T) {
+		// Test case: A template that fails to render falls back to the default
+		body := Build("{{.Missing}}", issue, task, workspace, "main")
+		assert.Contains(t, body, "- Closes #7")
+	})
+
+	t.Run("nothing recorded", func(t *testing.T) {
+		// Test case: Without a workspace or agent metadata the body still
+		// describes the issue
+		body := Build("", issue, &types.Task{ID: 3}, "", "main")
+		assert.Contains(t, body, "This PR addresses issue #7: Fix the widget")
+		assert.Contains(t, body, "No file changes were detected.")
+		assert.Contains(t, body, "No verification commands were run.")
+		assert.NotContains(t, body, "<details>")
+	})
+}
+
+// TestLoadTemplate tests reading the repository's PR template
+func TestLoadTemplate(t *testing.T) {
+	// Test case: A missing template is empty, a valid one is returned and an
+	// invalid one is rejected
+	cwDir := t.TempDir()
+
+	tmpl, err := LoadTemplate(cwDir)
+	require.NoError(t, err)
+	assert.Empty(t, tmpl)
+
+	path := filepath.Join(cwDir, TemplateDirName, TemplateFileName)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte("{{.ClosingKeyword}} #{{.Issue.Number}}"), 0644))
+
+	tmpl, err = LoadTemplate(cwDir)
+	require.NoError(t, err)
+	assert.Equal(t, "{{.ClosingKeyword}} #{{.Issue.Number}}", tmpl)
+
+	require.NoError(t, os.WriteFile(path, []byte("{{if}}"), 0644))
+	_, err = LoadTemplate(cwDir)
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"reflect"
+	"strings"
 	"time"
 )
 
@@ -12,12 +14,24 @@ const (
 	// WorkflowStateQueued indicates the issue is queued for processing
 	WorkflowStateQueued WorkflowState = "queued"
 
+	// WorkflowStateAwaitingClarification indicates cowork asked the issue
+	// author to clarify the issue and is waiting for a reply
+	WorkflowStateAwaitingClarification WorkflowState = "awaiting_clarification"
+
 	// WorkflowStateWorkspaceReady indicates the workspace is ready for implementation
 	WorkflowStateWorkspaceReady WorkflowState = "workspace_ready"
 
+	// WorkflowStatePlanReview indicates the agent's implementation plan is
+	// waiting for a maintainer to approve it
+	WorkflowStatePlanReview WorkflowState = "plan_review"
+
 	// WorkflowStateImplementing indicates the coding agent is implementing changes
 	WorkflowStateImplementing WorkflowState = "implementing"
 
+	// WorkflowStateReviewing indicates a reviewer agent is checking the
+	// branch before the PR is opened
+	WorkflowStateReviewing WorkflowState = "reviewing"
+
 	// WorkflowStatePROpen indicates a pull request has been opened
 	WorkflowStatePROpen WorkflowState = "pr_open"
 
@@ -32,6 +46,14 @@ const (
 
 	// WorkflowStateAborted indicates the workflow was manually stopped
 	WorkflowStateAborted WorkflowState = "aborted"
+
+	// WorkflowStateFailed indicates the workflow exhausted its retries and
+	// needs manual attention before it can be retried
+	WorkflowStateFailed WorkflowState = "failed"
+
+	// WorkflowStatePaused indicates a person paused the workflow; it resumes
+	// in the state it was paused in
+	WorkflowStatePaused WorkflowState = "paused"
 )
 
 // String returns the string representation of the workflow state
@@ -42,19 +64,21 @@ func (ws WorkflowState) String() string {
 // IsValid checks if the workflow state is valid
 func (ws WorkflowState) IsValid() bool {
 	switch ws {
-	case WorkflowStateQueued, WorkflowStateWorkspaceReady, WorkflowStateImplementing,
-		WorkflowStatePROpen, WorkflowStateRevising, WorkflowStateMerged,
-		WorkflowStateClosed, WorkflowStateAborted:
+	case WorkflowStateQueued, WorkflowStateAwaitingClarification, WorkflowStateWorkspaceReady, WorkflowStatePlanReview, WorkflowStateImplementing,
+		WorkflowStateReviewing, WorkflowStatePROpen, WorkflowStateRevising, WorkflowStateMerged,
+		WorkflowStateClosed, WorkflowStateAborted, WorkflowStateFailed,
+		WorkflowStatePaused:
 		return true
 	default:
 		return false
 	}
 }
 
-// IsTerminal checks if the workflow state is a terminal state
+// IsTerminal checks if the workflow state is a terminal state. Failed
+// workflows are terminal until they are manually retried.
 func (ws WorkflowState) IsTerminal() bool {
 	switch ws {
-	case WorkflowStateMerged, WorkflowStateClosed, WorkflowStateAborted:
+	case WorkflowStateMerged, WorkflowStateClosed, WorkflowStateAborted, WorkflowStateFailed:
 		return true
 	default:
 		return false
@@ -65,32 +89,86 @@ func (ws WorkflowState) IsTerminal() bool {
 func (ws WorkflowState) CanTransitionTo(target WorkflowState) bool {
 	validTransitions := map[WorkflowState][]WorkflowState{
 		WorkflowStateQueued: {
+			WorkflowStateAwaitingClarification,
 			WorkflowStateWorkspaceReady,
 			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
+		},
+		WorkflowStateAwaitingClarification: {
+			WorkflowStateQueued,
+			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
 		},
 		WorkflowStateWorkspaceReady: {
+			WorkflowStatePlanReview,
+			WorkflowStateImplementing,
+			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
+		},
+		WorkflowStatePlanReview: {
 			WorkflowStateImplementing,
 			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
 		},
 		WorkflowStateImplementing: {
+			WorkflowStateReviewing,
+			WorkflowStatePROpen,
+			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
+		},
+		WorkflowStateReviewing: {
+			WorkflowStateImplementing,
 			WorkflowStatePROpen,
 			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
 		},
 		WorkflowStatePROpen: {
 			WorkflowStateRevising,
 			WorkflowStateMerged,
 			WorkflowStateClosed,
 			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
 		},
 		WorkflowStateRevising: {
 			WorkflowStatePROpen,
 			WorkflowStateMerged,
 			WorkflowStateClosed,
 			WorkflowStateAborted,
+			WorkflowStateFailed,
+			WorkflowStatePaused,
+		},
+		WorkflowStatePaused: { // Resumes in the state it was paused in
+			WorkflowStateQueued,
+			WorkflowStateAwaitingClarification,
+			WorkflowStateWorkspaceReady,
+			WorkflowStatePlanReview,
+			WorkflowStateImplementing,
+			WorkflowStateReviewing,
+			WorkflowStatePROpen,
+			WorkflowStateRevising,
+			WorkflowStateAborted,
 		},
 		WorkflowStateMerged:  {}, // Terminal state
 		WorkflowStateClosed:  {}, // Terminal state
 		WorkflowStateAborted: {}, // Terminal state
+		WorkflowStateFailed: { // Terminal until retried from the state that failed
+			WorkflowStateQueued,
+			WorkflowStateAwaitingClarification,
+			WorkflowStateWorkspaceReady,
+			WorkflowStatePlanReview,
+			WorkflowStateImplementing,
+			WorkflowStateReviewing,
+			WorkflowStatePROpen,
+			WorkflowStateRevising,
+			WorkflowStateAborted,
+		},
 	}
 
 	allowed, exists := validTransitions[ws]
@@ -106,6 +184,11 @@ func (ws WorkflowState) CanTransitionTo(target WorkflowState) bool {
 	return false
 }
 
+// IsAgentRunning reports whether the coding agent works on the workflow in this state
+func (ws WorkflowState) IsAgentRunning() bool {
+	return ws == WorkflowStateImplementing || ws == WorkflowStateRevising
+}
+
 // FeedbackIntent represents the intent of feedback on a PR
 type FeedbackIntent string
 
@@ -152,10 +235,20 @@ type Workflow struct {
 	PRNumber    *int          `json:"pr_number,omitempty"`
 	LastEventTS time.Time     `json:"last_event_ts"`
 
+	// When PR feedback was last handled; only newer feedback is fetched
+	LastFeedbackTS *time.Time `json:"last_feedback_ts,omitempty"`
+
 	// Associated task and workspace
 	TaskID      int `json:"task_id,omitempty,string"`
 	WorkspaceID int `json:"workspace_id,omitempty,string"`
 
+	// Issues that must be merged before this workflow starts
+	DependsOn []int `json:"depends_on,omitempty"`
+
+	// PRs a large change was split into, bottom of the stack first. The last
+	// one is on BranchName and is the workflow's PRNumber.
+	StackedPRs []StackedPullRequest `json:"stacked_prs,omitempty"`
+
 	// Configuration
 	Provider string            `json:"provider"`
 	Config   WorkflowConfig    `json:"config"`
@@ -167,9 +260,13 @@ type Workflow struct {
 	StartedAt *time.Time `json:"started_at,omitempty"`
 	EndedAt   *time.Time `json:"ended_at,omitempty"`
 
+	// When the workflow entered its current state
+	StateChangedAt *time.Time `json:"state_changed_at,omitempty"`
+
 	// Error information
-	ErrorCount int    `json:"error_count"`
-	LastError  string `json:"last_error,omitempty"`
+	ErrorCount  int        `json:"error_count"`
+	LastError   string     `json:"last_error,omitempty"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
 
 	// Lock information
 	LockedBy    string     `json:"locked_by,omitempty"`
@@ -177,6 +274,112 @@ type Workflow struct {
 	LockTimeout time.Time  `json:"lock_timeout"`
 }
 
+// StackedPullRequest is one PR in the ordered stack a large change was split
+// into; each targets the branch of the PR below it
+type StackedPullRequest struct {
+	Number int      `json:"number,omitempty"`
+	Branch string   `json:"branch"`
+	Base   string   `json:"base"`
+	Files  []string `json:"files,omitempty"`
+	Merged bool     `json:"merged,omitempty"`
+}
+
+// Clone returns a deep copy of the workflow, so the copy can be read and
+// changed without affecting the original
+func (w *Workflow) Clone() *Workflow {
+	clone := *w
+	clone.PRNumber = cloneIntPtr(w.PRNumber)
+	clone.LastFeedbackTS = cloneTimePtr(w.LastFeedbackTS)
+	clone.StartedAt = cloneTimePtr(w.StartedAt)
+	clone.EndedAt = cloneTimePtr(w.EndedAt)
+	clone.StateChangedAt = cloneTimePtr(w.StateChangedAt)
+	clone.NextRetryAt = cloneTimePtr(w.NextRetryAt)
+	clone.LockedAt = cloneTimePtr(w.LockedAt)
+	clone.Config = w.Config.Clone()
+
+	if w.DependsOn != nil {
+		clone.DependsOn = append([]int{}, w.DependsOn...)
+	}
+	if w.StackedPRs != nil {
+		clone.StackedPRs = make([]StackedPullRequest, len(w.StackedPRs))
+		for i, pr := range w.StackedPRs {
+			clone.StackedPRs[i] = pr
+			clone.StackedPRs[i].Files = cloneStrings(pr.Files)
+		}
+	}
+	if w.Metadata != nil {
+		clone.Metadata = make(map[string]string, len(w.Metadata))
+		for key, value := range w.Metadata {
+			clone.Metadata[key] = value
+		}
+	}
+
+	return &clone
+}
+
+// cloneIntPtr copies the value an int pointer points to, keeping nil as nil
+func cloneIntPtr(value *int) *int {
+	if value == nil {
+		return nil
+	}
+	clone := *value
+	return &clone
+}
+
+// cloneTimePtr copies the value a time pointer points to, keeping nil as nil
+func cloneTimePtr(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	clone := *value
+	return &clone
+}
+
+// PRNumbers returns the numbers of every PR opened for the workflow
+func (w *Workflow) PRNumbers() []int {
+	if len(w.StackedPRs) == 0 {
+		if w.PRNumber == nil {
+			return nil
+		}
+		return []int{*w.PRNumber}
+	}
+
+	numbers := make([]int, 0, len(w.StackedPRs))
+	for _, pr := range w.StackedPRs {
+		if pr.Number != 0 {
+			numbers = append(numbers, pr.Number)
+		}
+	}
+	return numbers
+}
+
+// FeedbackSince returns the time PR feedback was last handled, or the
+// workflow's creation if none was handled yet, since its PRs come later
+func (w *Workflow) FeedbackSince() time.Time {
+	if w.LastFeedbackTS != nil {
+		return *w.LastFeedbackTS
+	}
+	return w.CreatedAt
+}
+
+// JobTimedOut reports whether the agent has been implementing or revising
+// the workflow for longer than its job timeout
+func (w *Workflow) JobTimedOut(now time.Time) bool {
+	if !w.State.IsAgentRunning() || w.Config.JobTimeout <= 0 {
+		return false
+	}
+
+	// Workflows saved before state changes were tracked fall back to older timestamps
+	enteredAt := w.UpdatedAt
+	if w.StateChangedAt != nil {
+		enteredAt = *w.StateChangedAt
+	} else if w.StartedAt != nil {
+		enteredAt = *w.StartedAt
+	}
+
+	return now.Sub(enteredAt) > w.Config.JobTimeout
+}
+
 // WorkflowConfig represents configuration for a workflow
 type WorkflowConfig struct {
 	// Branch naming template (e.g., "feature/{issue-key}-{slug}")
@@ -188,10 +391,52 @@ type WorkflowConfig struct {
 	// Sync strategy preference
 	SyncStrategy string `json:"sync_strategy" default:"rebase"` // "rebase" or "merge"
 
+	// Commands run in the workspace to verify the branch after conflicts are resolved
+	VerifyCommands []string `json:"verify_commands"`
+
 	// Labels that enable/disable Cowork
 	EnableLabels  []string `json:"enable_labels"`  // e.g., ["cowork:on"]
 	DisableLabels []string `json:"disable_labels"` // e.g., ["cowork:off"]
 
+	// Pick up issues without an enable label; disable labels still apply
+	IgnoreEnableLabels bool `json:"ignore_enable_labels"`
+
+	// Start dependent issues once their blocker has a PR open, branching from
+	// the blocker's branch instead of waiting for it to merge
+	StackDependencies bool `json:"stack_dependencies"`
+
+	// Open a draft PR once the agent's first commit lands and mark it ready
+	// for review when the task is done and the required checks pass
+	DraftPullRequests bool `json:"draft_pull_requests"`
+
+	// Have a reviewer agent check the branch against the issue before the PR
+	// is opened, handing its findings back to the implementing agent for up
+	// to SelfReviewRounds rounds
+	SelfReview       bool `json:"self_review"`
+	SelfReviewRounds int  `json:"self_review_rounds" default:"2"`
+
+	// Model the reviewer agent runs with; empty uses the agent's default
+	ReviewerModel string `json:"reviewer_model"`
+
+	// Have the agent write an implementation plan before changing any code
+	// and wait for a maintainer to approve it. Approval comes from
+	// "cw task approve" or a "/cowork approve" comment by one of PlanApprovers.
+	PlanFirst     bool     `json:"plan_first"`
+	PlanApprovers []string `json:"plan_approvers"`
+
+	// Have the agent rate how clearly an issue is described before starting,
+	// asking the author clarifying questions when it rates below MinClarity
+	// on a scale of 1 to 5
+	ClarityTriage bool `json:"clarity_triage"`
+	MinClarity    int  `json:"min_clarity" default:"3"`
+
+	// Split changes larger than this many lines into a stack of PRs; 0 never splits
+	MaxPRLines int `json:"max_pr_lines"`
+
+	// How a large change is split: "files" groups files by directory,
+	// "agent" has the agent propose the boundaries
+	StackSplit string `json:"stack_split" default:"files"`
+
 	// Timeouts and retry settings
 	MaxRetries     int           `json:"max_retries" default:"3"`
 	RetryDelay     time.Duration `json:"retry_delay" default:"5m"`
@@ -213,6 +458,22 @@ func (wc *WorkflowConfig) Validate() error {
 		return fmt.Errorf("max retries must be non-negative")
 	}
 
+	if wc.MinClarity < 0 || wc.MinClarity > 5 {
+		return fmt.Errorf("min clarity must be between 0 and 5")
+	}
+
+	if wc.MaxPRLines < 0 {
+		return fmt.Errorf("max PR lines must be non-negative")
+	}
+
+	if wc.StackSplit != "" && wc.StackSplit != "files" && wc.StackSplit != "agent" {
+		return fmt.Errorf("stack split must be 'files' or 'agent'")
+	}
+
+	if wc.SelfReviewRounds < 0 {
+		return fmt.Errorf("self review rounds must be non-negative")
+	}
+
 	if wc.RetryDelay < 0 {
 		return fmt.Errorf("retry delay must be non-negative")
 	}
@@ -221,9 +482,93 @@ func (wc *WorkflowConfig) Validate() error {
 		return fmt.Errorf("job timeout must be non-negative")
 	}
 
+	if wc.CommentTimeout < 0 {
+		return fmt.Errorf("comment timeout must be non-negative")
+	}
+
+	for _, check := range wc.RequiredChecks {
+		if strings.TrimSpace(check) == "" {
+			return fmt.Errorf("required checks must not be empty")
+		}
+	}
+
+	for _, approver := range wc.PlanApprovers {
+		if strings.TrimSpace(approver) == "" {
+			return fmt.Errorf("plan approvers must not be empty")
+		}
+	}
+
+	for _, label := range append(append([]string{}, wc.EnableLabels...), wc.DisableLabels...) {
+		if strings.TrimSpace(label) == "" {
+			return fmt.Errorf("labels must not be empty")
+		}
+	}
+
+	if label := matchLabel(wc.EnableLabels, wc.DisableLabels); label != "" {
+		return fmt.Errorf("label %q cannot both enable and disable cowork", label)
+	}
+
 	return nil
 }
 
+// IsZero reports whether the config was left unset
+func (wc *WorkflowConfig) IsZero() bool {
+	return reflect.DeepEqual(*wc, WorkflowConfig{})
+}
+
+// Clone returns a copy of the config that shares no lists with the original,
+// so a workflow's snapshot is not changed by later edits to the source
+func (wc WorkflowConfig) Clone() WorkflowConfig {
+	clone := wc
+	clone.RequiredChecks = cloneStrings(wc.RequiredChecks)
+	clone.VerifyCommands = cloneStrings(wc.VerifyCommands)
+	clone.EnableLabels = cloneStrings(wc.EnableLabels)
+	clone.DisableLabels = cloneStrings(wc.DisableLabels)
+	clone.PlanApprovers = cloneStrings(wc.PlanApprovers)
+	return clone
+}
+
+// cloneStrings copies a string slice, keeping nil as nil
+func cloneStrings(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	return append([]string{}, values...)
+}
+
+// AllowsIssue reports whether cowork may pick up an issue with the given
+// labels: it must carry an enable label, unless that requirement is skipped,
+// and no disable label
+func (wc *WorkflowConfig) AllowsIssue(labels []string) bool {
+	if wc.DisableLabel(labels) != "" {
+		return false
+	}
+
+	if wc.IgnoreEnableLabels || len(wc.EnableLabels) == 0 {
+		return true
+	}
+
+	return matchLabel(labels, wc.EnableLabels) != ""
+}
+
+// DisableLabel returns the first of the labels that disables cowork, or an
+// empty string if there is none
+func (wc *WorkflowConfig) DisableLabel(labels []string) string {
+	return matchLabel(labels, wc.DisableLabels)
+}
+
+// matchLabel returns the first label found in candidates, ignoring case
+func matchLabel(labels, candidates []string) string {
+	for _, label := range labels {
+		for _, candidate := range candidates {
+			if strings.EqualFold(strings.TrimSpace(label), strings.TrimSpace(candidate)) {
+				return label
+			}
+		}
+	}
+	return ""
+}
+
 // GetDefaultConfig returns a default workflow configuration
 func GetDefaultWorkflowConfig() WorkflowConfig {
 	return WorkflowConfig{
@@ -232,6 +577,9 @@ func GetDefaultWorkflowConfig() WorkflowConfig {
 		SyncStrategy:         "rebase",
 		EnableLabels:         []string{"cowork:on"},
 		DisableLabels:        []string{"cowork:off"},
+		SelfReviewRounds:     2,
+		MinClarity:           3,
+		StackSplit:           "files",
 		MaxRetries:           3,
 		RetryDelay:           5 * time.Minute,
 		JobTimeout:           2 * time.Hour,
@@ -241,6 +589,33 @@ func GetDefaultWorkflowConfig() WorkflowConfig {
 	}
 }
 
+// maxBackoffDoublings caps how many times the retry delay is doubled
+const maxBackoffDoublings = 10
+
+// RetryBackoff returns the delay before retry attempt n (1-based), doubling
+// the base delay for each consecutive failure
+func (wc *WorkflowConfig) RetryBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	// Cap the exponent so the delay can't overflow
+	if attempt > maxBackoffDoublings+1 {
+		attempt = maxBackoffDoublings + 1
+	}
+
+	return wc.RetryDelay * time.Duration(1<<(attempt-1))
+}
+
+// Workflow event types, normalized across providers
+const (
+	WorkflowEventIssues            = "issues"
+	WorkflowEventIssueComment      = "issue_comment"
+	WorkflowEventPullRequest       = "pull_request"
+	WorkflowEventPullRequestReview = "pull_request_review"
+	WorkflowEventCheckSuite        = "check_suite"
+)
+
 // WorkflowEvent represents an event that triggers workflow actions
 type WorkflowEvent struct {
 	// Event information
@@ -272,6 +647,8 @@ type CreateWorkflowRequest struct {
 	Provider   string         `json:"provider"`
 	Config     WorkflowConfig `json:"config"`
 	TaskID     int            `json:"task_id,omitempty,string"` // Optional: create workflow from existing task
+	DependsOn  []int          `json:"depends_on,omitempty"`     // Issues this workflow waits on
+	Actor      string         `json:"actor,omitempty"`          // Who created the workflow, for the transition history
 }
 
 // Validate checks if the create workflow request is valid
@@ -303,12 +680,30 @@ func (req *CreateWorkflowRequest) Validate() error {
 type UpdateWorkflowRequest struct {
 	WorkflowID  int            `json:"workflow_id,string"`
 	State       *WorkflowState `json:"state,omitempty"`
+	BaseBranch  *string        `json:"base_branch,omitempty"`
 	BranchName  *string        `json:"branch_name,omitempty"`
 	PRNumber    *int           `json:"pr_number,omitempty"`
 	TaskID      *int           `json:"task_id,omitempty,string"`
 	WorkspaceID *int           `json:"workspace_id,omitempty,string"`
 	ErrorCount  *int           `json:"error_count,omitempty"`
 	LastError   *string        `json:"last_error,omitempty"`
+	DependsOn   *[]int         `json:"depends_on,omitempty"`
+
+	// StackedPRs replaces the workflow's stack of PRs
+	StackedPRs *[]StackedPullRequest `json:"stacked_prs,omitempty"`
+
+	// NextRetryAt schedules the next attempt; a zero time clears it
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+
+	// LastFeedbackTS moves the PR feedback cursor once feedback is handled
+	LastFeedbackTS *time.Time `json:"last_feedback_ts,omitempty"`
+
+	// Metadata entries to merge into the workflow; empty values delete keys
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Who requested a state change and why, recorded in the workflow log
+	Actor  string `json:"actor,omitempty"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // Validate checks if the update workflow request is valid
@@ -328,6 +723,28 @@ func (req *UpdateWorkflowRequest) Validate() error {
 	return nil
 }
 
+// WorkflowTransition is a state change in a workflow's history, derived from
+// the workflow log
+type WorkflowTransition struct {
+	WorkflowID int           `json:"workflow_id,string"`
+	From       WorkflowState `json:"from,omitempty"`
+	To         WorkflowState `json:"to"`
+	Actor      string        `json:"actor"`
+	Reason     string        `json:"reason,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Timestamp  time.Time     `json:"timestamp"`
+}
+
+// CreatedBranch is a remote branch cowork created for a workflow. Only
+// branches recorded this way are ever deleted by cowork.
+type CreatedBranch struct {
+	Owner      string    `json:"owner"`
+	Repo       string    `json:"repo"`
+	Branch     string    `json:"branch"`
+	WorkflowID int       `json:"workflow_id,string"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
 // WorkflowLock represents a lock on a workflow
 type WorkflowLock struct {
 	WorkflowID  int       `json:"workflow_id,string"`
@@ -335,6 +752,12 @@ type WorkflowLock struct {
 	LockedAt    time.Time `json:"locked_at"`
 	LockTimeout time.Time `json:"lock_timeout"`
 	ProcessID   int       `json:"process_id"`
+
+	// Host the holding process runs on, so its PID is only checked locally
+	Hostname string `json:"hostname,omitempty"`
+
+	// Last time the holder renewed the lease
+	HeartbeatAt time.Time `json:"heartbeat_at,omitempty"`
 }
 
 // IsExpired checks if the lock has expired
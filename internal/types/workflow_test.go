@@ -0,0 +1,183 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestWorkflowState_Failed tests the failed state's place in the state machine
+func TestWorkflowState_Failed(t *testing.T) {
+	// Test case: Failed is a valid terminal state that every active state can
+	// reach and that can be retried into any active state
+	assert.True(t, WorkflowStateFailed.IsValid())
+	assert.True(t, WorkflowStateFailed.IsTerminal())
+
+	activeStates := []WorkflowState{
+		WorkflowStateQueued,
+		WorkflowStateWorkspaceReady,
+		WorkflowStateImplementing,
+		WorkflowStatePROpen,
+		WorkflowStateRevising,
+	}
+
+	for _, state := range activeStates {
+		t.Run(string(state), func(t *testing.T) {
+			assert.True(t, state.CanTransitionTo(WorkflowStateFailed))
+			assert.True(t, WorkflowStateFailed.CanTransitionTo(state))
+		})
+	}
+
+	assert.False(t, WorkflowStateMerged.CanTransitionTo(WorkflowStateFailed))
+	assert.False(t, WorkflowStateFailed.CanTransitionTo(WorkflowStateMerged))
+}
+
+// TestWorkflowState_Paused tests the paused state's place in the state machine
+func TestWorkflowState_Paused(t *testing.T) {
+	// Test case: Paused is a valid, non-terminal state every active state can
+	// reach and that resumes into any active state or is aborted
+	assert.True(t, WorkflowStatePaused.IsValid())
+	assert.False(t, WorkflowStatePaused.IsTerminal())
+	assert.False(t, WorkflowStatePaused.IsAgentRunning())
+
+	activeStates := []WorkflowState{
+		WorkflowStateQueued,
+		WorkflowStateWorkspaceReady,
+		WorkflowStateImplementing,
+		WorkflowStatePROpen,
+		WorkflowStateRevising,
+	}
+
+	for _, state := range activeStates {
+		t.Run(string(state), func(t *testing.T) {
+			assert.True(t, state.CanTransitionTo(WorkflowStatePaused))
+			assert.True(t, WorkflowStatePaused.CanTransitionTo(state))
+		})
+	}
+
+	assert.True(t, WorkflowStatePaused.CanTransitionTo(WorkflowStateAborted))
+	assert.False(t, WorkflowStatePaused.CanTransitionTo(WorkflowStateMerged))
+	assert.False(t, WorkflowStateFailed.CanTransitionTo(WorkflowStatePaused))
+	assert.False(t, WorkflowStateMerged.CanTransitionTo(WorkflowStatePaused))
+}
+
+// TestWorkflowConfig_RetryBackoff tests exponential backoff of the retry delay
+func TestWorkflowConfig_RetryBackoff(t *testing.T) {
+	// Test case: The delay doubles per attempt and is capped for large attempt counts
+	config := WorkflowConfig{RetryDelay: time.Minute}
+
+	testCases := []struct {
+		attempt  int
+		expected time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{11, 1024 * time.Minute},
+		{100, 1024 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, config.RetryBackoff(tc.attempt), "attempt %d", tc.attempt)
+	}
+}
+
+// TestWorkflow_JobTimedOut tests detection of agents running past the job timeout
+func TestWorkflow_JobTimedOut(t *testing.T) {
+	// Test case: Only implementing and revising workflows time out, measured
+	// from when they entered the state; a zero timeout disables the check
+	now := time.Now()
+	entered := now.Add(-3 * time.Hour)
+	recent := now.Add(-time.Hour)
+
+	testCases := []struct {
+		name     string
+		workflow Workflow
+		expected bool
+	}{
+		{"implementing too long", Workflow{State: WorkflowStateImplementing, StateChangedAt: &entered}, true},
+		{"revising too long", Workflow{State: WorkflowStateRevising, StateChangedAt: &entered}, true},
+		{"implementing within timeout", Workflow{State: WorkflowStateImplementing, StateChangedAt: &recent}, false},
+		{"waiting on the PR", Workflow{State: WorkflowStatePROpen, StateChangedAt: &entered}, false},
+		{"falls back to started at", Workflow{State: WorkflowStateImplementing, StartedAt: &entered}, true},
+		{"falls back to updated at", Workflow{State: WorkflowStateImplementing, UpdatedAt: recent}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.workflow.Config.JobTimeout = 2 * time.Hour
+			assert.Equal(t, tc.expected, tc.workflow.JobTimedOut(now))
+
+			tc.workflow.Config.JobTimeout = 0
+			assert.False(t, tc.workflow.JobTimedOut(now))
+		})
+	}
+}
+
+// TestWorkflow_Clone tests that a cloned workflow shares nothing with the original
+func TestWorkflow_Clone(t *testing.T) {
+	// Test case: Changing the pointers, slices and maps of the clone leaves
+	// the original as it was
+	prNumber := 42
+	now := time.Now()
+	original := &Workflow{
+		ID:             1,
+		State:          WorkflowStatePROpen,
+		PRNumber:       &prNumber,
+		StateChangedAt: &now,
+		DependsOn:      []int{2},
+		StackedPRs:     []StackedPullRequest{{Number: 41, Files: []string{"a.go"}}},
+		Metadata:       map[string]string{"key": "value"},
+		Config:         WorkflowConfig{RequiredChecks: []string{"test"}},
+	}
+
+	clone := original.Clone()
+	assert.Equal(t, original, clone)
+
+	*clone.PRNumber = 43
+	*clone.StateChangedAt = now.Add(time.Hour)
+	clone.DependsOn[0] = 3
+	clone.StackedPRs[0].Files[0] = "b.go"
+	clone.Metadata["key"] = "changed"
+	clone.Config.RequiredChecks[0] = "lint"
+
+	assert.Equal(t, 42, *original.PRNumber)
+	assert.Equal(t, now, *original.StateChangedAt)
+	assert.Equal(t, []int{2}, original.DependsOn)
+	assert.Equal(t, []string{"a.go"}, original.StackedPRs[0].Files)
+	assert.Equal(t, "value", original.Metadata["key"])
+	assert.Equal(t, []string{"test"}, original.Config.RequiredChecks)
+}
+
+// TestWorkflowConfig_AllowsIssue tests gating issues on enable and disable labels
+func TestWorkflowConfig_AllowsIssue(t *testing.T) {
+	// Test case: An enable label is required unless skipped, a disable label
+	// always wins and labels match regardless of case
+	config := GetDefaultWorkflowConfig()
+
+	testCases := []struct {
+		name     string
+		labels   []string
+		ignore   bool
+		expected bool
+	}{
+		{"enable label", []string{"bug", "cowork:on"}, false, true},
+		{"enable label in other case", []string{"Cowork:On"}, false, true},
+		{"no enable label", []string{"bug"}, false, false},
+		{"no enable label with requirement skipped", []string{"bug"}, true, true},
+		{"disable label wins", []string{"cowork:on", "cowork:off"}, false, false},
+		{"disable label with requirement skipped", []string{"cowork:off"}, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config.IgnoreEnableLabels = tc.ignore
+			assert.Equal(t, tc.expected, config.AllowsIssue(tc.labels))
+		})
+	}
+
+	assert.Equal(t, "COWORK:OFF", config.DisableLabel([]string{"bug", "COWORK:OFF"}))
+	assert.Empty(t, config.DisableLabel([]string{"bug"}))
+}
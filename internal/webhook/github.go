@@ -0,0 +1,178 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// githubUser is the subset of a GitHub user used by webhooks
+type githubUser struct {
+	Login string `json:"login"`
+}
+
+// githubPayload is the subset of GitHub webhook payloads cowork reacts to
+type githubPayload struct {
+	Action string `json:"action"`
+
+	Repository struct {
+		Name  string     `json:"name"`
+		Owner githubUser `json:"owner"`
+	} `json:"repository"`
+
+	Sender githubUser `json:"sender"`
+
+	Issue *struct {
+		Number      int       `json:"number"`
+		PullRequest *struct{} `json:"pull_request"`
+	} `json:"issue"`
+
+	Comment *struct {
+		ID   int64      `json:"id"`
+		Body string     `json:"body"`
+		User githubUser `json:"user"`
+	} `json:"comment"`
+
+	PullRequest *struct {
+		Number int    `json:"number"`
+		State  string `json:"state"`
+		Merged bool   `json:"merged"`
+		Head   struct {
+			Ref string `json:"ref"`
+			SHA string `json:"sha"`
+		} `json:"head"`
+	} `json:"pull_request"`
+
+	Review *struct {
+		ID    int64      `json:"id"`
+		State string     `json:"state"`
+		Body  string     `json:"body"`
+		User  githubUser `json:"user"`
+	} `json:"review"`
+
+	CheckSuite *struct {
+		HeadBranch   string `json:"head_branch"`
+		HeadSHA      string `json:"head_sha"`
+		Status       string `json:"status"`
+		Conclusion   string `json:"conclusion"`
+		PullRequests []struct {
+			Number int `json:"number"`
+		} `json:"pull_requests"`
+	} `json:"check_suite"`
+}
+
+// verifyGitHubSignature checks the X-Hub-Signature-256 header against the payload
+func verifyGitHubSignature(secret, signature string, body []byte) error {
+	const prefix = "sha256="
+	if !strings.HasPrefix(signature, prefix) {
+		return errInvalidSignature
+	}
+
+	provided, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return errInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal(provided, mac.Sum(nil)) {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+// parseGitHubEvent converts a GitHub payload into a workflow event. It returns
+// nil for deliveries cowork does not act on.
+func parseGitHubEvent(eventType string, body []byte) (*parsedEvent, error) {
+	switch eventType {
+	case types.WorkflowEventIssues, types.WorkflowEventIssueComment, types.WorkflowEventPullRequest,
+		types.WorkflowEventPullRequestReview, types.WorkflowEventCheckSuite:
+	default:
+		return nil, nil
+	}
+
+	var payload githubPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", eventType, err)
+	}
+
+	if payload.Repository.Owner.Login == "" || payload.Repository.Name == "" {
+		return nil, fmt.Errorf("%s payload is missing repository information", eventType)
+	}
+
+	event := &parsedEvent{
+		Type:  eventType,
+		Owner: payload.Repository.Owner.Login,
+		Repo:  payload.Repository.Name,
+		Data: map[string]interface{}{
+			"action": payload.Action,
+			"sender": payload.Sender.Login,
+		},
+	}
+
+	switch eventType {
+	case types.WorkflowEventIssues:
+		if payload.Issue == nil {
+			return nil, fmt.Errorf("issues payload is missing issue")
+		}
+		event.IssueID = payload.Issue.Number
+
+	case types.WorkflowEventIssueComment:
+		if payload.Issue == nil || payload.Comment == nil {
+			return nil, fmt.Errorf("issue_comment payload is missing issue or comment")
+		}
+		event.IssueID = payload.Issue.Number
+		event.Data["pull_request"] = payload.Issue.PullRequest != nil
+		event.Data["comment_id"] = payload.Comment.ID
+		event.Data["comment_author"] = payload.Comment.User.Login
+		event.Data["comment_body"] = payload.Comment.Body
+
+	case types.WorkflowEventPullRequest:
+		if payload.PullRequest == nil {
+			return nil, fmt.Errorf("pull_request payload is missing pull request")
+		}
+		event.IssueID = payload.PullRequest.Number
+		event.Data["pull_request"] = true
+		event.Data["state"] = payload.PullRequest.State
+		event.Data["merged"] = payload.PullRequest.Merged
+		event.Data["head_branch"] = payload.PullRequest.Head.Ref
+		event.Data["head_sha"] = payload.PullRequest.Head.SHA
+
+	case types.WorkflowEventPullRequestReview:
+		if payload.PullRequest == nil || payload.Review == nil {
+			return nil, fmt.Errorf("pull_request_review payload is missing pull request or review")
+		}
+		event.IssueID = payload.PullRequest.Number
+		event.Data["pull_request"] = true
+		event.Data["head_branch"] = payload.PullRequest.Head.Ref
+		event.Data["review_id"] = payload.Review.ID
+		event.Data["review_state"] = strings.ToLower(payload.Review.State)
+		event.Data["review_author"] = payload.Review.User.Login
+
+	case types.WorkflowEventCheckSuite:
+		if payload.CheckSuite == nil {
+			return nil, fmt.Errorf("check_suite payload is missing check suite")
+		}
+		var prNumbers []int
+		for _, pr := range payload.CheckSuite.PullRequests {
+			prNumbers = append(prNumbers, pr.Number)
+		}
+		if len(prNumbers) > 0 {
+			event.IssueID = prNumbers[0]
+		}
+		event.Data["pull_request"] = len(prNumbers) > 0
+		event.Data["pr_numbers"] = prNumbers
+		event.Data["head_branch"] = payload.CheckSuite.HeadBranch
+		event.Data["head_sha"] = payload.CheckSuite.HeadSHA
+		event.Data["status"] = payload.CheckSuite.Status
+		event.Data["conclusion"] = payload.CheckSuite.Conclusion
+	}
+
+	return event, nil
+}
@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// gitlabPayload is the subset of GitLab webhook payloads cowork reacts to
+type gitlabPayload struct {
+	ObjectKind string `json:"object_kind"`
+
+	User struct {
+		Username string `json:"username"`
+	} `json:"user"`
+
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+
+	ObjectAttributes struct {
+		ID           int64  `json:"id"`
+		IID          int    `json:"iid"`
+		Action       string `json:"action"`
+		State        string `json:"state"`
+		NoteableType string `json:"noteable_type"`
+		Note         string `json:"note"`
+		Ref          string `json:"ref"`
+		SHA          string `json:"sha"`
+		Status       string `json:"status"`
+		SourceBranch string `json:"source_branch"`
+	} `json:"object_attributes"`
+
+	Issue *struct {
+		IID int `json:"iid"`
+	} `json:"issue"`
+
+	MergeRequest *struct {
+		IID          int    `json:"iid"`
+		SourceBranch string `json:"source_branch"`
+	} `json:"merge_request"`
+}
+
+// verifyGitLabToken checks the X-Gitlab-Token header. GitLab sends the
+// configured secret verbatim rather than signing the payload.
+func verifyGitLabToken(secret, token string) error {
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+		return errInvalidSignature
+	}
+
+	return nil
+}
+
+// parseGitLabEvent converts a GitLab payload into a workflow event using the
+// GitHub event names. It returns nil for deliveries cowork does not act on.
+func parseGitLabEvent(eventHeader string, body []byte) (*parsedEvent, error) {
+	switch eventHeader {
+	case "Issue Hook", "Note Hook", "Merge Request Hook", "Pipeline Hook":
+	default:
+		return nil, nil
+	}
+
+	var payload gitlabPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", eventHeader, err)
+	}
+
+	// Groups may be nested, so the project name is everything after the last slash
+	path := payload.Project.PathWithNamespace
+	slash := strings.LastIndex(path, "/")
+	if slash <= 0 || slash == len(path)-1 {
+		return nil, fmt.Errorf("%s payload has invalid project path: %q", eventHeader, path)
+	}
+
+	attrs := payload.ObjectAttributes
+	event := &parsedEvent{
+		Owner: path[:slash],
+		Repo:  path[slash+1:],
+		Data: map[string]interface{}{
+			"action": attrs.Action,
+			"sender": payload.User.Username,
+		},
+	}
+
+	switch eventHeader {
+	case "Issue Hook":
+		event.Type = types.WorkflowEventIssues
+		event.IssueID = attrs.IID
+		event.Data["state"] = attrs.State
+
+	case "Note Hook":
+		event.Type = types.WorkflowEventIssueComment
+		switch attrs.NoteableType {
+		case "Issue":
+			if payload.Issue == nil {
+				return nil, fmt.Errorf("note payload is missing issue")
+			}
+			event.IssueID = payload.Issue.IID
+			event.Data["pull_request"] = false
+		case "MergeRequest":
+			if payload.MergeRequest == nil {
+				return nil, fmt.Errorf("note payload is missing merge request")
+			}
+			event.IssueID = payload.MergeRequest.IID
+			event.Data["pull_request"] = true
+			event.Data["head_branch"] = payload.MergeRequest.SourceBranch
+		default:
+			// Commit and snippet notes don't affect workflows
+			return nil, nil
+		}
+		event.Data["comment_id"] = attrs.ID
+		event.Data["comment_author"] = payload.User.Username
+		event.Data["comment_body"] = attrs.Note
+
+	case "Merge Request Hook":
+		event.IssueID = attrs.IID
+		event.Data["pull_request"] = true
+		event.Data["head_branch"] = attrs.SourceBranch
+		if attrs.Action == "approved" || attrs.Action == "unapproved" {
+			event.Type = types.WorkflowEventPullRequestReview
+			event.Data["review_state"] = attrs.Action
+			event.Data["review_author"] = payload.User.Username
+		} else {
+			event.Type = types.WorkflowEventPullRequest
+			event.Data["state"] = attrs.State
+			event.Data["merged"] = attrs.State == "merged"
+		}
+
+	case "Pipeline Hook":
+		event.Type = types.WorkflowEventCheckSuite
+		event.Data["head_branch"] = attrs.Ref
+		event.Data["head_sha"] = attrs.SHA
+		event.Data["status"] = attrs.Status
+		event.Data["pull_request"] = payload.MergeRequest != nil
+		if payload.MergeRequest != nil {
+			event.IssueID = payload.MergeRequest.IID
+			event.Data["pr_numbers"] = []int{payload.MergeRequest.IID}
+		}
+	}
+
+	return event, nil
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// GitHubPath is the route that receives GitHub webhooks
+	GitHubPath = "/webhooks/github"
+
+	// GitLabPath is the route that receives GitLab webhooks
+	GitLabPath = "/webhooks/gitlab"
+
+	// MaxPayloadBytes is the largest webhook payload accepted
+	MaxPayloadBytes = 5 << 20
+
+	// shutdownTimeout bounds how long in-flight requests get on shutdown
+	shutdownTimeout = 10 * time.Second
+)
+
+// errInvalidSignature is returned when a payload fails signature verification
+var errInvalidSignature = errors.New("invalid webhook signature")
+
+// EventRecorder persists workflow events produced from webhooks
+type EventRecorder interface {
+	// CreateEvent records a new unprocessed workflow event
+	CreateEvent(eventType, provider, owner, repo string, issueID int, data map[string]interface{}) (*types.WorkflowEvent, error)
+}
+
+// Config contains the secrets used to verify incoming webhooks. A provider
+// without a secret has its route disabled.
+type Config struct {
+	// Secret configured on the GitHub webhook, used for HMAC-SHA256 verification
+	GitHubSecret string `json:"github_secret,omitempty"`
+
+	// Secret token configured on the GitLab webhook
+	GitLabSecret string `json:"gitlab_secret,omitempty"`
+}
+
+// parsedEvent is a provider payload normalized into workflow event fields
+type parsedEvent struct {
+	Type    string
+	Owner   string
+	Repo    string
+	IssueID int
+	Data    map[string]interface{}
+}
+
+// Server receives provider webhooks and records them as workflow events
+type Server struct {
+	recorder EventRecorder
+	config   Config
+
+	// Called after each event is recorded, e.g. to wake the workflow daemon
+	onEvent func(*types.WorkflowEvent)
+}
+
+// NewServer creates a new webhook server. onEvent may be nil.
+func NewServer(recorder EventRecorder, config Config, onEvent func(*types.WorkflowEvent)) (*Server, error) {
+	if recorder == nil {
+		return nil, fmt.Errorf("event recorder is required")
+	}
+
+	if config.GitHubSecret == "" && config.GitLabSecret == "" {
+		return nil, fmt.Errorf("at least one webhook secret is required")
+	}
+
+	return &Server{
+		recorder: recorder,
+		config:   config,
+		onEvent:  onEvent,
+	}, nil
+}
+
+// Handler returns the HTTP handler serving the webhook routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(GitHubPath, s.handleGitHub)
+	mux.HandleFunc(GitLabPath, s.handleGitLab)
+	return mux
+}
+
+// ListenAndServe serves webhooks on addr until the context is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("webhook server failed: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down webhook server: %w", err)
+		}
+		return nil
+	}
+}
+
+// handleGitHub verifies and records a GitHub webhook delivery
+func (s *Server) handleGitHub(w http.ResponseWriter, r *http.Request) {
+	if s.config.GitHubSecret == "" {
+		http.Error(w, "github webhooks are not configured", http.StatusNotFound)
+		return
+	}
+
+	body, ok := readPayload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := verifyGitHubSignature(s.config.GitHubSecret, r.Header.Get("X-Hub-Signature-256"), body); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	event, err := parseGitHubEvent(r.Header.Get("X-GitHub-Event"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event != nil {
+		event.Data["delivery_id"] = r.Header.Get("X-GitHub-Delivery")
+	}
+
+	s.record(w, "github", event)
+}
+
+// handleGitLab verifies and records a GitLab webhook delivery
+func (s *Server) handleGitLab(w http.ResponseWriter, r *http.Request) {
+	if s.config.GitLabSecret == "" {
+		http.Error(w, "gitlab webhooks are not configured", http.StatusNotFound)
+		return
+	}
+
+	body, ok := readPayload(w, r)
+	if !ok {
+		return
+	}
+
+	if err := verifyGitLabToken(s.config.GitLabSecret, r.Header.Get("X-Gitlab-Token")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	event, err := parseGitLabEvent(r.Header.Get("X-Gitlab-Event"), body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if event != nil {
+		event.Data["delivery_id"] = r.Header.Get("X-Gitlab-Event-UUID")
+	}
+
+	s.record(w, "gitlab", event)
+}
+
+// record stores a parsed event and writes the HTTP response
+func (s *Server) record(w http.ResponseWriter, provider string, event *parsedEvent) {
+	// Deliveries we don't act on (pings, unsupported events) are acknowledged and dropped
+	if event == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	recorded, err := s.recorder.CreateEvent(event.Type, provider, event.Owner, event.Repo, event.IssueID, event.Data)
+	if err != nil {
+		log.Printf("❌ Failed to record %s webhook event: %v", provider, err)
+		http.Error(w, "failed to record event", http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("📨 Recorded %s %s event for %s/%s#%d", provider, recorded.Type, recorded.Owner, recorded.Repo, recorded.IssueID)
+
+	if s.onEvent != nil {
+		s.onEvent(recorded)
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// readPayload reads a POST body, writing an error response on failure
+func readPayload(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxPayloadBytes))
+	if err != nil {
+		http.Error(w, "failed to read payload", http.StatusBadRequest)
+		return nil, false
+	}
+
+	return body, true
+}
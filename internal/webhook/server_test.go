@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// fakeRecorder captures events instead of persisting them
+type fakeRecorder struct {
+	events []*types.WorkflowEvent
+}
+
+func (r *fakeRecorder) CreateEvent(eventType, provider, owner, repo string, issueID int, data map[string]interface{}) (*types.WorkflowEvent, error) {
+	event := &types.WorkflowEvent{
+		ID:       "event-test",
+		Type:     eventType,
+		Provider: provider,
+		Owner:    owner,
+		Repo:     repo,
+		IssueID:  issueID,
+		Data:     data,
+	}
+	r.events = append(r.events, event)
+	return event, nil
+}
+
+// signGitHub computes the X-Hub-Signature-256 header for a payload
+func signGitHub(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newTestServer creates a webhook server with a recorder and a wake counter
+func newTestServer(t *testing.T) (*Server, *fakeRecorder, *int) {
+	t.Helper()
+
+	recorder := &fakeRecorder{}
+	wakes := 0
+	server, err := NewServer(recorder, Config{GitHubSecret: "gh-secret", GitLabSecret: "gl-secret"}, func(*types.WorkflowEvent) {
+		wakes++
+	})
+	require.NoError(t, err)
+
+	return server, recorder, &wakes
+}
+
+// TestNewServer_RequiresSecret tests that a server without any secret is rejected
+func TestNewServer_RequiresSecret(t *testing.T) {
+	// Test case: Accepting unsigned webhooks would let anyone inject events
+	_, err := NewServer(&fakeRecorder{}, Config{}, nil)
+	assert.Error(t, err)
+}
+
+// TestServer_GitHubReview_RecordsEvent tests that a signed review delivery becomes an event
+func TestServer_GitHubReview_RecordsEvent(t *testing.T) {
+	// Test case: A pull_request_review payload with a valid signature is recorded
+	// against the PR number and wakes the daemon
+	server, recorder, wakes := newTestServer(t)
+	body := `{"action":"submitted","repository":{"name":"repo","owner":{"login":"owner"}},
+		"sender":{"login":"alice"},"pull_request":{"number":42,"head":{"ref":"task/fix-42","sha":"abc"}},
+		"review":{"id":7,"state":"CHANGES_REQUESTED","user":{"login":"alice"}}}`
+
+	req := httptest.NewRequest(http.MethodPost, GitHubPath, strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "pull_request_review")
+	req.Header.Set("X-Hub-Signature-256", signGitHub("gh-secret", body))
+	rec := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, recorder.events, 1)
+	event := recorder.events[0]
+	assert.Equal(t, types.WorkflowEventPullRequestReview, event.Type)
+	assert.Equal(t, "github", event.Provider)
+	assert.Equal(t, "owner", event.Owner)
+	assert.Equal(t, "repo", event.Repo)
+	assert.Equal(t, 42, event.IssueID)
+	assert.Equal(t, "changes_requested", event.Data["review_state"])
+	assert.Equal(t, "task/fix-42", event.Data["head_branch"])
+	assert.Equal(t, 1, *wakes)
+}
+
+// TestServer_GitHub_RejectsBadSignature tests that tampered payloads are refused
+func TestServer_GitHub_RejectsBadSignature(t *testing.T) {
+	// Test case: Signatures made with the wrong secret or missing entirely return 401
+	server, recorder, _ := newTestServer(t)
+	body := `{"action":"opened","repository":{"name":"repo","owner":{"login":"owner"}},"issue":{"number":1}}`
+
+	for _, signature := range []string{"", "sha256=deadbeef", signGitHub("wrong", body)} {
+		req := httptest.NewRequest(http.MethodPost, GitHubPath, strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "issues")
+		req.Header.Set("X-Hub-Signature-256", signature)
+		rec := httptest.NewRecorder()
+
+		server.Handler().ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, "signature %q", signature)
+	}
+	assert.Empty(t, recorder.events)
+}
+
+// TestServer_GitHub_IgnoresUnsupportedEvents tests that pings and other events are acknowledged
+func TestServer_GitHub_IgnoresUnsupportedEvents(t *testing.T) {
+	// Test case: A ping delivery is accepted without recording an event
+	server, recorder, wakes := newTestServer(t)
+	body := `{"zen":"Keep it logically awesome."}`
+
+	req := httptest.NewRequest(http.MethodPost, GitHubPath, strings.NewReader(body))
+	req.Header.Set("X-GitHub-Event", "ping")
+	req.Header.Set("X-Hub-Signature-256", signGitHub("gh-secret", body))
+	rec := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Empty(t, recorder.events)
+	assert.Equal(t, 0, *wakes)
+}
+
+// TestServer_GitLabMergeRequestNote_RecordsEvent tests GitLab note normalization
+func TestServer_GitLabMergeRequestNote_RecordsEvent(t *testing.T) {
+	// Test case: A note on a merge request in a nested group becomes an
+	// issue_comment event flagged as a pull request
+	server, recorder, _ := newTestServer(t)
+	body := `{"object_kind":"note","user":{"username":"bob"},
+		"project":{"path_with_namespace":"group/sub/repo"},
+		"object_attributes":{"id":99,"noteable_type":"MergeRequest","note":"please rename this"},
+		"merge_request":{"iid":5,"source_branch":"task/rename-5"}}`
+
+	req := httptest.NewRequest(http.MethodPost, GitLabPath, strings.NewReader(body))
+	req.Header.Set("X-Gitlab-Event", "Note Hook")
+	req.Header.Set("X-Gitlab-Token", "gl-secret")
+	rec := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	require.Len(t, recorder.events, 1)
+	event := recorder.events[0]
+	assert.Equal(t, types.WorkflowEventIssueComment, event.Type)
+	assert.Equal(t, "group/sub", event.Owner)
+	assert.Equal(t, "repo", event.Repo)
+	assert.Equal(t, 5, event.IssueID)
+	assert.Equal(t, true, event.Data["pull_request"])
+	assert.Equal(t, "please rename this", event.Data["comment_body"])
+}
+
+// TestServer_GitLab_RejectsBadToken tests that a wrong GitLab token is refused
+func TestServer_GitLab_RejectsBadToken(t *testing.T) {
+	// Test case: The X-Gitlab-Token header must match the configured secret
+	server, recorder, _ := newTestServer(t)
+
+	req := httptest.NewRequest(http.MethodPost, GitLabPath, strings.NewReader(`{}`))
+	req.Header.Set("X-Gitlab-Event", "Issue Hook")
+	req.Header.Set("X-Gitlab-Token", "nope")
+	rec := httptest.NewRecorder()
+
+	server.Handler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, recorder.events)
+}
+
+// TestServer_RejectsNonPost tests that only POST deliveries are accepted
+func TestServer_RejectsNonPost(t *testing.T) {
+	// Test case: A GET request to a webhook route returns 405
+	server, _, _ := newTestServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, GitHubPath, nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,81 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+)
+
+// AgentRunner runs a single agent instruction against a workspace
+type AgentRunner interface {
+	// Run executes the instruction with the workspace as working directory
+	Run(ctx context.Context, workspacePath string, instruction *agent.AgentInstruction) (*agent.AgentResult, error)
+}
+
+// resultProvider is implemented by agents that expose their last execution result
+type resultProvider interface {
+	GetLastResult() *agent.AgentResult
+}
+
+// agentRunner runs instructions on a fresh agent per call, since agents are
+// single-use once they leave the idle state
+type agentRunner struct {
+	newAgent func() agent.Agent
+	config   agent.AgentConfig
+}
+
+// NewAgentRunner creates an AgentRunner that builds a new agent for each run
+// using the given factory and base configuration
+func NewAgentRunner(newAgent func() agent.Agent, config agent.AgentConfig) AgentRunner {
+	return &agentRunner{
+		newAgent: newAgent,
+		config:   config,
+	}
+}
+
+// Run initializes a new agent in the workspace and executes the instruction
+func (r *agentRunner) Run(ctx context.Context, workspacePath string, instruction *agent.AgentInstruction) (*agent.AgentResult, error) {
+	a := r.newAgent()
+
+	config := r.config
+	config.WorkingDir = workspacePath
+	if err := a.Initialize(ctx, &config); err != nil {
+		return nil, fmt.Errorf("failed to initialize agent: %w", err)
+	}
+	defer a.Cleanup(ctx)
+
+	start := time.Now()
+	execErr := a.Execute(ctx, instruction)
+	if execErr != nil {
+		execErr = fmt.Errorf("failed to execute agent: %w", execErr)
+	}
+
+	// Failed runs may still carry useful output
+	if provider, ok := a.(resultProvider); ok && provider.GetLastResult() != nil {
+		return provider.GetLastResult(), execErr
+	}
+
+	if execErr != nil {
+		return nil, execErr
+	}
+
+	return &agent.AgentResult{
+		Success:     true,
+		Summary:     fmt.Sprintf("%s completed the instruction", a.GetName()),
+		CompletedAt: time.Now(),
+		Duration:    time.Since(start),
+	}, nil
+}
+
+// runAgent runs an instruction on runner. A dry run records the run instead,
+// since agents change the workspace, and returns errDryRun.
+func (e *Engine) runAgent(ctx context.Context, runner AgentRunner, workspacePath string, instruction *agent.AgentInstruction) (*agent.AgentResult, error) {
+	if e.dryRun != nil {
+		e.dryRun.Record(ctx, MutationAgentRun, RepositoryKey(e.owner, e.repo), "run the agent for %s on task %d", instruction.Metadata["purpose"], instruction.TaskID)
+		return nil, errDryRun
+	}
+
+	return runner.Run(ctx, workspacePath, instruction)
+}
@@ -0,0 +1,46 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+)
+
+// TestEngine_GenerateBranchName tests naming workflow branches from the configured template
+func TestEngine_GenerateBranchName(t *testing.T) {
+	// Test case: The workflow's template is rendered from the issue and a
+	// name already on the remote gets a numeric suffix
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 12)
+	workflow.TaskID = 4
+	workflow.Config.BranchNamingTemplate = "{type}/{issue-key}-{slug}-{task_id}"
+
+	provider := newFakeCoworkProvider()
+	provider.branches = map[string]bool{"fix/12-crash-on-start-4": true}
+	engine := NewEngine(manager, nil, nil, provider, "owner", "repo")
+
+	issue := &git.Issue{
+		Number: 12,
+		Title:  "Crash on start",
+		Labels: []*git.Label{{Name: "bug"}},
+	}
+
+	branchName, err := engine.generateBranchName(context.Background(), workflow, issue)
+	require.NoError(t, err)
+	assert.Equal(t, "fix/12-crash-on-start-4-2", branchName)
+
+	// Without a template the provider's name is used
+	workflow.Config.BranchNamingTemplate = ""
+	branchName, err = engine.generateBranchName(context.Background(), workflow, issue)
+	require.NoError(t, err)
+	assert.Equal(t, "task-12", branchName)
+
+	// Invalid templates fail instead of producing a bad ref
+	workflow.Config.BranchNamingTemplate = "feature/{ticket}"
+	_, err = engine.generateBranchName(context.Background(), workflow, issue)
+	assert.Error(t, err)
+}
@@ -0,0 +1,167 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataChecksState holds the required checks state for the PR head
+	MetadataChecksState = "checks_state"
+
+	// MetadataChecksSHA is the head SHA the checks state refers to
+	MetadataChecksSHA = "checks_sha"
+
+	// MetadataChecksRevisedSHA is the head SHA whose failed checks already triggered a revision
+	MetadataChecksRevisedSHA = "checks_revised_sha"
+)
+
+// Required checks states
+const (
+	ChecksStatePending = "pending"
+	ChecksStatePassed  = "passed"
+	ChecksStateFailed  = "failed"
+)
+
+// RequiredChecksResult is the outcome of the required checks for a commit
+type RequiredChecksResult struct {
+	// Overall state: pending, passed or failed
+	State string
+
+	// Latest failed check for each failing required check
+	Failed []*git.CommitCheck
+
+	// Required checks that are queued, running or not reported yet
+	Pending []string
+}
+
+// EvaluateRequiredChecks decides whether the required checks passed. Any
+// failure fails the result; otherwise it is pending until every required
+// check has completed successfully.
+func EvaluateRequiredChecks(required []string, checks []*git.CommitCheck) *RequiredChecksResult {
+	result := &RequiredChecksResult{State: ChecksStatePassed}
+
+	for _, name := range required {
+		var latest *git.CommitCheck
+		running := false
+
+		for _, check := range checks {
+			if !checkMatches(name, check.Name) {
+				continue
+			}
+			if !check.IsCompleted() {
+				running = true
+				continue
+			}
+			// Re-runs report the same name again; the most recent result wins
+			if latest == nil || (check.CompletedAt != nil && (latest.CompletedAt == nil || check.CompletedAt.After(*latest.CompletedAt))) {
+				latest = check
+			}
+		}
+
+		switch {
+		case latest != nil && latest.IsFailed():
+			result.Failed = append(result.Failed, latest)
+		case running || latest == nil:
+			result.Pending = append(result.Pending, name)
+		}
+	}
+
+	if len(result.Failed) > 0 {
+		result.State = ChecksStateFailed
+	} else if len(result.Pending) > 0 {
+		result.State = ChecksStatePending
+	}
+
+	return result
+}
+
+// checkMatches reports whether a reported check satisfies a required check
+// name. Besides exact matches, status contexts ("ci/test") and matrix jobs
+// ("test (ubuntu-latest)") count as the required check.
+func checkMatches(required, name string) bool {
+	required = strings.ToLower(strings.TrimSpace(required))
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	return name == required ||
+		strings.HasSuffix(name, "/"+required) ||
+		strings.HasPrefix(name, required+" (")
+}
+
+// checkRequiredChecks evaluates the required checks on the PR head, records
+// the result on the workflow and returns blocker feedback for failures that
+// have not been sent for revision yet
+func (e *Engine) checkRequiredChecks(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest) ([]ClassifiedFeedback, error) {
+	if len(workflow.Config.RequiredChecks) == 0 {
+		return nil, nil
+	}
+
+	if pr.Head == nil || pr.Head.SHA == "" {
+		log.Printf("⚠️  PR #%d has no head SHA, cannot check required checks", pr.Number)
+		return nil, nil
+	}
+	sha := pr.Head.SHA
+
+	checks, err := e.coworkProvider.GetCommitChecks(ctx, e.owner, e.repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commit checks: %w", err)
+	}
+
+	result := EvaluateRequiredChecks(workflow.Config.RequiredChecks, checks)
+
+	if workflow.Metadata[MetadataChecksState] != result.State || workflow.Metadata[MetadataChecksSHA] != sha {
+		_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			Metadata: map[string]string{
+				MetadataChecksState: result.State,
+				MetadataChecksSHA:   sha,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to record checks state: %w", err)
+		}
+	}
+
+	switch result.State {
+	case ChecksStatePassed:
+		log.Printf("✅ Required checks passed on PR #%d", pr.Number)
+		return nil, nil
+	case ChecksStatePending:
+		log.Printf("⏳ Waiting for required checks on PR #%d: %s", pr.Number, strings.Join(result.Pending, ", "))
+		return nil, nil
+	}
+
+	// A revision is already under way for this commit
+	if workflow.Metadata[MetadataChecksRevisedSHA] == sha {
+		log.Printf("⏳ Required checks failed on PR #%d, waiting for a new commit", pr.Number)
+		return nil, nil
+	}
+
+	var feedback []ClassifiedFeedback
+	for _, check := range result.Failed {
+		log.Printf("❌ Required check %s failed on PR #%d", check.Name, pr.Number)
+
+		item := FeedbackItem{
+			Kind: FeedbackKindCheck,
+			Name: check.Name,
+			Body: check.Summary,
+			URL:  check.URL,
+		}
+		if check.CompletedAt != nil {
+			item.CreatedAt = *check.CompletedAt
+		}
+
+		feedback = append(feedback, ClassifiedFeedback{
+			Item:   item,
+			Intent: types.FeedbackIntentBlocker,
+			Reason: "required check failed",
+		})
+	}
+
+	return feedback, nil
+}
@@ -0,0 +1,100 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// completedCheck builds a completed check finished at the given offset from a fixed time
+func completedCheck(name, conclusion string, offset time.Duration) *git.CommitCheck {
+	completedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset)
+	return &git.CommitCheck{Name: name, Status: git.CheckStatusCompleted, Conclusion: conclusion, CompletedAt: &completedAt}
+}
+
+// TestEvaluateRequiredChecks tests combining reported checks into a required checks state
+func TestEvaluateRequiredChecks(t *testing.T) {
+	// Test case: Failures win over pending checks, missing checks are pending,
+	// re-runs use the latest result and non-required checks are ignored
+	running := &git.CommitCheck{Name: "test", Status: git.CheckStatusInProgress}
+
+	testCases := []struct {
+		name    string
+		checks  []*git.CommitCheck
+		state   string
+		failed  []string
+		pending []string
+	}{
+		{"all passed", []*git.CommitCheck{completedCheck("lint", "success", 0), completedCheck("test", "success", 0)}, ChecksStatePassed, nil, nil},
+		{"missing check", []*git.CommitCheck{completedCheck("lint", "success", 0)}, ChecksStatePending, nil, []string{"test"}},
+		{"still running", []*git.CommitCheck{completedCheck("lint", "success", 0), running}, ChecksStatePending, nil, []string{"test"}},
+		{"failure wins", []*git.CommitCheck{completedCheck("lint", "failure", 0), running}, ChecksStateFailed, []string{"lint"}, []string{"test"}},
+		{"rerun passed", []*git.CommitCheck{completedCheck("lint", "success", 0), completedCheck("test", "failure", 0), completedCheck("test", "success", time.Minute)}, ChecksStatePassed, nil, nil},
+		{"status context and matrix", []*git.CommitCheck{completedCheck("ci/lint", "success", 0), completedCheck("test (ubuntu)", "timed_out", 0)}, ChecksStateFailed, []string{"test (ubuntu)"}, nil},
+		{"unrelated failures", []*git.CommitCheck{completedCheck("lint", "success", 0), completedCheck("test", "skipped", 0), completedCheck("deploy", "failure", 0)}, ChecksStatePassed, nil, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := EvaluateRequiredChecks([]string{"lint", "test"}, tc.checks)
+
+			assert.Equal(t, tc.state, result.State)
+			var failed []string
+			for _, check := range result.Failed {
+				failed = append(failed, check.Name)
+			}
+			assert.Equal(t, tc.failed, failed)
+			assert.Equal(t, tc.pending, result.Pending)
+		})
+	}
+}
+
+// TestEngine_ProcessPROpen_FailedChecksTriggerRevision tests that failed required checks cause a revision
+func TestEngine_ProcessPROpen_FailedChecksTriggerRevision(t *testing.T) {
+	// Test case: A failed required check moves the workflow to revising with the
+	// check in the instruction, and the same commit never triggers a second revision
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflow.TaskID = 3
+	storeTestWorkflow(t, manager, workflow)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	failing := completedCheck("test", "failure", 0)
+	failing.Summary = "TestCache failed"
+	provider := newFakeCoworkProvider()
+	provider.pr = &git.PullRequest{Number: 42, State: "open", Head: &git.Branch{SHA: "abc123"}}
+	provider.checks = []*git.CommitCheck{completedCheck("lint", "success", 0), failing}
+
+	engine := NewEngine(manager, newFakeTaskManager(&types.Task{ID: 3}), nil, provider, "owner", "repo")
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+
+	require.NoError(t, engine.processPROpenWorkflow(context.Background(), workflow))
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+	assert.Equal(t, ChecksStateFailed, workflow.Metadata[MetadataChecksState])
+	assert.Equal(t, "abc123", workflow.Metadata[MetadataChecksRevisedSHA])
+	assert.Contains(t, workflow.Metadata[MetadataRevisionInstruction], "Required check `test` failed: TestCache failed")
+
+	// The revision has not pushed a new commit yet
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStatePROpen)
+	require.NoError(t, engine.processPROpenWorkflow(context.Background(), workflow))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+
+	// A new commit with passing checks is recorded as passed
+	provider.pr.Head.SHA = "def456"
+	provider.checks = []*git.CommitCheck{completedCheck("lint", "success", 0), completedCheck("test", "success", 0)}
+	require.NoError(t, engine.ProcessWorkflow(context.Background(), workflowID))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+	assert.Equal(t, ChecksStatePassed, workflow.Metadata[MetadataChecksState])
+	assert.Equal(t, "def456", workflow.Metadata[MetadataChecksSHA])
+}
@@ -0,0 +1,326 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataClarity holds the clarity the agent rated the issue, once it was clear enough to start
+	MetadataClarity = "clarity"
+
+	// MetadataClarificationAskedAt holds when cowork last asked the issue author to clarify
+	MetadataClarificationAskedAt = "clarification_asked_at"
+
+	// MetadataClarifications holds the JSON encoded replies the issue author gave
+	MetadataClarifications = "clarifications"
+
+	// clarificationsHeading starts the replies folded into a task description
+	clarificationsHeading = "## Clarifications from the issue author"
+)
+
+var (
+	// clarityPattern extracts the clarity rating from agent output
+	clarityPattern = regexp.MustCompile(`<clarity>\s*(\d+)\s*</clarity>`)
+
+	// questionPattern extracts each clarifying question from agent output
+	questionPattern = regexp.MustCompile(`(?s)<question>(.*?)</question>`)
+)
+
+// Clarification is a reply the issue author gave to cowork's questions
+type Clarification struct {
+	Author    string    `json:"author"`
+	Body      string    `json:"body"`
+	URL       string    `json:"url,omitempty"`
+	RepliedAt time.Time `json:"replied_at"`
+}
+
+// Clarifications returns the replies recorded on a workflow
+func Clarifications(workflow *types.Workflow) ([]Clarification, error) {
+	raw := workflow.Metadata[MetadataClarifications]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var clarifications []Clarification
+	if err := json.Unmarshal([]byte(raw), &clarifications); err != nil {
+		return nil, fmt.Errorf("failed to decode clarifications: %w", err)
+	}
+
+	return clarifications, nil
+}
+
+// triageIssue has the agent rate how clearly the issue is described. When it
+// rates below the workflow's minimum, its questions are posted on the issue
+// and the workflow waits for the author. It reports whether work may start.
+// Triage that fails is logged and skipped, since the agent would otherwise
+// have started anyway.
+func (e *Engine) triageIssue(ctx context.Context, workflow *types.Workflow, issue *git.Issue) (bool, error) {
+	if !workflow.Config.ClarityTriage || e.agentRunner == nil || workflow.Metadata[MetadataClarity] != "" {
+		return true, nil
+	}
+
+	clarifications, err := Clarifications(workflow)
+	if err != nil {
+		return false, err
+	}
+
+	clarity, questions, err := e.rateClarity(ctx, workflow, issue, clarifications)
+	if err != nil {
+		log.Printf("⚠️  Could not triage issue #%d, starting anyway: %v", issue.Number, err)
+		return true, nil
+	}
+
+	log.Printf("🔎 Agent rated issue #%d %d/5 for clarity", issue.Number, clarity)
+
+	if clarity >= workflow.Config.MinClarity || len(questions) == 0 {
+		if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			Metadata:   map[string]string{MetadataClarity: strconv.Itoa(clarity)},
+		}); err != nil {
+			return false, fmt.Errorf("failed to record clarity: %w", err)
+		}
+		return true, nil
+	}
+
+	return false, e.askForClarification(ctx, workflow, issue, clarity, questions)
+}
+
+// rateClarity asks the agent to rate the issue and list what it would need to know
+func (e *Engine) rateClarity(ctx context.Context, workflow *types.Workflow, issue *git.Issue, clarifications []Clarification) (int, []string, error) {
+	// The agent needs a working directory but not the repository itself
+	workDir, err := os.MkdirTemp("", "cw-triage-")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to create agent working directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	instruction := &agent.AgentInstruction{
+		Content:   buildTriagePrompt(issue, clarifications, workflow.Config.MinClarity),
+		TaskID:    workflow.TaskID,
+		Metadata:  map[string]string{"purpose": "clarity_triage"},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := e.runAgent(ctx, e.agentRunner, workDir, instruction)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return parseTriage(result.Output)
+}
+
+// askForClarification posts the agent's questions on the issue and moves the
+// workflow to AWAITING_CLARIFICATION
+func (e *Engine) askForClarification(ctx context.Context, workflow *types.Workflow, issue *git.Issue, clarity int, questions []string) error {
+	body := fmt.Sprintf("%s\n%s", coworkCommentMarker, formatClarificationQuestions(issue, questions))
+	if _, err := e.coworkProvider.CreateComment(ctx, e.owner, e.repo, issue.Number, &git.CreateCommentRequest{Body: body}); err != nil {
+		return fmt.Errorf("failed to post clarifying questions: %w", err)
+	}
+
+	state := types.WorkflowStateAwaitingClarification
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Metadata:   map[string]string{MetadataClarificationAskedAt: time.Now().UTC().Format(time.RFC3339)},
+		Actor:      e.processID,
+		Reason:     fmt.Sprintf("issue rated %d/5 for clarity", clarity),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to awaiting_clarification: %w", err)
+	}
+
+	log.Printf("🤔 Asked %d clarifying question(s) on issue #%d", len(questions), issue.Number)
+	return nil
+}
+
+// processAwaitingClarificationWorkflow handles workflows in
+// AWAITING_CLARIFICATION state. A reply from the issue author is recorded
+// and the workflow queued again, to be triaged with the reply in view.
+func (e *Engine) processAwaitingClarificationWorkflow(ctx context.Context, workflow *types.Workflow) error {
+	log.Printf("🤔 Processing awaiting clarification workflow %d", workflow.ID)
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	comments, err := e.coworkProvider.GetIssueComments(ctx, e.owner, e.repo, issue.Number)
+	if err != nil {
+		return fmt.Errorf("failed to get issue comments: %w", err)
+	}
+
+	askedAt, _ := time.Parse(time.RFC3339, workflow.Metadata[MetadataClarificationAskedAt])
+	replies := authorReplies(issue, comments, askedAt)
+	if len(replies) == 0 {
+		log.Printf("⏳ No reply on issue #%d yet, waiting for clarification", issue.Number)
+		return nil
+	}
+
+	clarifications, err := Clarifications(workflow)
+	if err != nil {
+		return err
+	}
+	clarifications = append(clarifications, replies...)
+
+	encoded, err := json.Marshal(clarifications)
+	if err != nil {
+		return fmt.Errorf("failed to encode clarifications: %w", err)
+	}
+
+	state := types.WorkflowStateQueued
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Metadata: map[string]string{
+			MetadataClarifications:       string(encoded),
+			MetadataClarificationAskedAt: "",
+		},
+		Actor:  e.processID,
+		Reason: fmt.Sprintf("issue author replied on #%d", issue.Number),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to queued: %w", err)
+	}
+
+	log.Printf("✅ Workflow %d queued again with %d clarification(s)", workflow.ID, len(replies))
+	return nil
+}
+
+// authorReplies returns the comments the issue author left after cowork
+// asked for clarification
+func authorReplies(issue *git.Issue, comments []*git.Comment, since time.Time) []Clarification {
+	var replies []Clarification
+	for _, comment := range comments {
+		if !comment.CreatedAt.After(since) || strings.Contains(comment.Body, coworkCommentMarker) {
+			continue
+		}
+		if comment.User == nil {
+			continue
+		}
+		if issue.Author != nil && comment.User.Login != issue.Author.Login {
+			continue
+		}
+
+		replies = append(replies, Clarification{
+			Author:    comment.User.Login,
+			Body:      strings.TrimSpace(comment.Body),
+			URL:       comment.URL,
+			RepliedAt: comment.CreatedAt,
+		})
+	}
+	return replies
+}
+
+// applyClarifications folds the author's replies into the task description
+// so the agent implements the clarified issue
+func (e *Engine) applyClarifications(workflow *types.Workflow, task *types.Task) (*types.Task, error) {
+	clarifications, err := Clarifications(workflow)
+	if err != nil || len(clarifications) == 0 {
+		return task, err
+	}
+
+	// Replace the section added for earlier replies
+	description := task.Description
+	if index := strings.Index(description, clarificationsHeading); index >= 0 {
+		description = description[:index]
+	}
+	description = strings.TrimRight(description, "\n") + "\n\n" + formatClarifications(clarifications)
+	if description == task.Description {
+		return task, nil
+	}
+	updated, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{TaskID: task.ID, Description: &description})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add clarifications to task: %w", err)
+	}
+
+	return updated, nil
+}
+
+// buildTriagePrompt builds the instruction asking the agent to rate the issue
+func buildTriagePrompt(issue *git.Issue, clarifications []Clarification, minClarity int) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Before any work starts on issue #%d, rate how clearly it describes what should change. Do not modify any files.\n\n", issue.Number)
+	fmt.Fprintf(&sb, "## %s\n\n", issue.Title)
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		fmt.Fprintf(&sb, "%s\n\n", body)
+	} else {
+		sb.WriteString("(no description)\n\n")
+	}
+
+	if len(clarifications) > 0 {
+		sb.WriteString(formatClarifications(clarifications))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("Rate the clarity from 1 (impossible to act on without guessing) to 5 (fully specified) and reply with the rating wrapped in <clarity></clarity> tags. ")
+	fmt.Fprintf(&sb, "If you rate it below %d, also list the concrete questions the author must answer, each wrapped in its own <question></question> tags.\n", minClarity)
+
+	return sb.String()
+}
+
+// parseTriage reads the clarity rating and questions from agent output
+func parseTriage(output string) (int, []string, error) {
+	match := clarityPattern.FindStringSubmatch(output)
+	if match == nil {
+		return 0, nil, fmt.Errorf("agent output contained no clarity rating")
+	}
+
+	clarity, err := strconv.Atoi(match[1])
+	if err != nil || clarity < 1 || clarity > 5 {
+		return 0, nil, fmt.Errorf("agent rated clarity %s, outside 1 to 5", match[1])
+	}
+
+	var questions []string
+	for _, question := range questionPattern.FindAllStringSubmatch(output, -1) {
+		if text := strings.TrimSpace(question[1]); text != "" {
+			questions = append(questions, text)
+		}
+	}
+
+	return clarity, questions, nil
+}
+
+// formatClarificationQuestions addresses the questions to the issue author
+func formatClarificationQuestions(issue *git.Issue, questions []string) string {
+	var sb strings.Builder
+
+	if issue.Author != nil && issue.Author.Login != "" {
+		fmt.Fprintf(&sb, "@%s ", issue.Author.Login)
+	}
+	sb.WriteString("🤔 Before I start on this issue, could you clarify the following?\n\n")
+	for i, question := range questions {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, strings.ReplaceAll(question, "\n", "\n   "))
+	}
+	sb.WriteString("\nI'll pick the issue up again once you reply here.\n")
+
+	return sb.String()
+}
+
+// formatClarifications renders the author's replies as a markdown section
+func formatClarifications(clarifications []Clarification) string {
+	var sb strings.Builder
+
+	sb.WriteString(clarificationsHeading + "\n\n")
+	for _, clarification := range clarifications {
+		fmt.Fprintf(&sb, "@%s:\n\n", clarification.Author)
+		for _, line := range strings.Split(clarification.Body, "\n") {
+			fmt.Fprintf(&sb, "> %s\n", line)
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_TriageIssue tests rating issue clarity before work starts
+func TestEngine_TriageIssue(t *testing.T) {
+	testCases := []struct {
+		name            string
+		output          string
+		runErr          error
+		expectReady     bool
+		expectState     types.WorkflowState
+		expectClarity   string
+		expectQuestions []string
+	}{
+		{
+			// Test case: A clear issue starts right away and its rating is kept
+			name:          "clear issue",
+			output:        "<clarity>4</clarity>",
+			expectReady:   true,
+			expectState:   types.WorkflowStateQueued,
+			expectClarity: "4",
+		},
+		{
+			// Test case: A vague issue gets the agent's questions and waits
+			name:            "vague issue",
+			output:          "<clarity>2</clarity>\n<question>Which page shows the widget?</question>\n<question>What should happen instead?</question>",
+			expectState:     types.WorkflowStateAwaitingClarification,
+			expectQuestions: []string{"1. Which page shows the widget?", "2. What should happen instead?"},
+		},
+		{
+			// Test case: A low rating without questions has nothing to ask
+			name:          "no questions",
+			output:        "<clarity>1</clarity>",
+			expectReady:   true,
+			expectState:   types.WorkflowStateQueued,
+			expectClarity: "1",
+		},
+		{
+			// Test case: Failed triage does not hold the issue up
+			name:        "agent failed",
+			runErr:      errors.New("agent crashed"),
+			expectReady: true,
+			expectState: types.WorkflowStateQueued,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := createTestWorkflow(t, manager, 7)
+			workflow.Config.ClarityTriage = true
+			storeTestWorkflow(t, manager, workflow)
+
+			provider := newFakeCoworkProvider()
+			issue := &git.Issue{Number: 7, Title: "Widget is broken", Author: &git.User{Login: "alice"}}
+			runner := &fakeAgentRunner{output: tc.output, err: tc.runErr}
+			engine := NewEngine(manager, newFakeTaskManager(), newFakeWorkspaceManager(), provider, "owner", "repo")
+			engine.SetAgentRunner(runner)
+
+			ready, err := engine.triageIssue(context.Background(), workflow, issue)
+			require.NoError(t, err)
+			workflow = reloadTestWorkflow(t, manager, workflow)
+			assert.Equal(t, tc.expectReady, ready)
+			assert.Equal(t, tc.expectState, workflow.State)
+			assert.Equal(t, tc.expectClarity, workflow.Metadata[MetadataClarity])
+			require.Len(t, runner.instructions, 1)
+			assert.Contains(t, runner.instructions[0].Content, "## Widget is broken")
+
+			if tc.expectQuestions == nil {
+				assert.Empty(t, provider.comments[7])
+				return
+			}
+			require.Len(t, provider.comments[7], 1)
+			body := provider.comments[7][0].Body
+			assert.Contains(t, body, coworkCommentMarker)
+			assert.Contains(t, body, "@alice")
+			for _, question := range tc.expectQuestions {
+				assert.Contains(t, body, question)
+			}
+			assert.NotEmpty(t, workflow.Metadata[MetadataClarificationAskedAt])
+		})
+	}
+}
+
+// TestEngine_ProcessAwaitingClarificationWorkflow tests picking an issue up
+// again once its author replies
+func TestEngine_ProcessAwaitingClarificationWorkflow(t *testing.T) {
+	// Test case: Comments from others and from cowork are not replies; the
+	// author's reply queues the workflow again and is folded into the task
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 7)
+	workflow.Config.ClarityTriage = true
+	storeTestWorkflow(t, manager, workflow)
+	askedAt := time.Now().Add(-time.Hour)
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		Metadata:   map[string]string{MetadataClarificationAskedAt: askedAt.UTC().Format(time.RFC3339)},
+	})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateAwaitingClarification)
+
+	provider := newFakeCoworkProvider()
+	provider.issues = map[int]*git.Issue{7: {Number: 7, Title: "Widget is broken", Author: &git.User{Login: "alice"}}}
+	provider.issueComments = map[int][]*git.Comment{7: {
+		{ID: 1, User: &git.User{Login: "alice"}, Body: "Old comment", CreatedAt: askedAt.Add(-time.Minute)},
+		{ID: 2, User: &git.User{Login: "cowork"}, Body: coworkCommentMarker + "\nQuestions", CreatedAt: askedAt},
+		{ID: 3, User: &git.User{Login: "bob"}, Body: "+1", CreatedAt: askedAt.Add(time.Minute)},
+	}}
+	task := &types.Task{ID: 3, Description: "Widget is broken"}
+	engine := NewEngine(manager, newFakeTaskManager(task), newFakeWorkspaceManager(), provider, "owner", "repo")
+
+	require.NoError(t, engine.processAwaitingClarificationWorkflow(context.Background(), workflow))
+	assert.Equal(t, types.WorkflowStateAwaitingClarification, reloadTestWorkflow(t, manager, workflow).State)
+
+	provider.issueComments[7] = append(provider.issueComments[7], &git.Comment{
+		ID: 4, User: &git.User{Login: "alice"}, Body: "It is the settings page.\nIt should save.", CreatedAt: askedAt.Add(2 * time.Minute),
+	})
+	require.NoError(t, engine.processAwaitingClarificationWorkflow(context.Background(), workflow))
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateQueued, workflow.State)
+	assert.Empty(t, workflow.Metadata[MetadataClarificationAskedAt])
+	clarifications, err := Clarifications(workflow)
+	require.NoError(t, err)
+	require.Len(t, clarifications, 1)
+	assert.Equal(t, "alice", clarifications[0].Author)
+
+	updated, err := engine.applyClarifications(workflow, task)
+	require.NoError(t, err)
+	assert.Contains(t, updated.Description, "Widget is broken\n\n## Clarifications from the issue author")
+	assert.Contains(t, updated.Description, "> It is the settings page.\n> It should save.")
+
+	// Applying them again leaves the description as it is
+	description := updated.Description
+	updated, err = engine.applyClarifications(workflow, updated)
+	require.NoError(t, err)
+	assert.Equal(t, description, updated.Description)
+}
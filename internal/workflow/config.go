@@ -0,0 +1,135 @@
+package workflow
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// WorkflowConfigFileName is the repository file holding the workflow configuration
+const WorkflowConfigFileName = "workflow.yaml"
+
+// workflowConfigFile is the layout of the repository workflow configuration.
+// Every setting is optional; settings left out keep their default.
+type workflowConfigFile struct {
+	BranchNamingTemplate *string        `yaml:"branch_naming_template"`
+	RequiredChecks       *[]string      `yaml:"required_checks"`
+	SyncStrategy         *string        `yaml:"sync_strategy"`
+	VerifyCommands       *[]string      `yaml:"verify_commands"`
+	EnableLabels         *[]string      `yaml:"enable_labels"`
+	DisableLabels        *[]string      `yaml:"disable_labels"`
+	IgnoreEnableLabels   *bool          `yaml:"ignore_enable_labels"`
+	StackDependencies    *bool          `yaml:"stack_dependencies"`
+	DraftPullRequests    *bool          `yaml:"draft_pull_requests"`
+	SelfReview           *bool          `yaml:"self_review"`
+	SelfReviewRounds     *int           `yaml:"self_review_rounds"`
+	ReviewerModel        *string        `yaml:"reviewer_model"`
+	MaxPRLines           *int           `yaml:"max_pr_lines"`
+	ClarityTriage        *bool          `yaml:"clarity_triage"`
+	PlanFirst            *bool          `yaml:"plan_first"`
+	PlanApprovers        *[]string      `yaml:"plan_approvers"`
+	MinClarity           *int           `yaml:"min_clarity"`
+	StackSplit           *string        `yaml:"stack_split"`
+	MaxRetries           *int           `yaml:"max_retries"`
+	RetryDelay           *time.Duration `yaml:"retry_delay"`
+	JobTimeout           *time.Duration `yaml:"job_timeout"`
+	CommentTimeout       *time.Duration `yaml:"comment_timeout"`
+	ForcePushDisabled    *bool          `yaml:"force_push_disabled"`
+	OnlyFeatureBranches  *bool          `yaml:"only_feature_branches"`
+}
+
+// LoadWorkflowConfig reads a repository workflow configuration file and
+// merges it over the defaults. Unknown settings are rejected so typos do not
+// silently fall back to defaults.
+func LoadWorkflowConfig(path string) (types.WorkflowConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return types.WorkflowConfig{}, fmt.Errorf("failed to read workflow config: %w", err)
+	}
+
+	var file workflowConfigFile
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return types.WorkflowConfig{}, fmt.Errorf("failed to parse workflow config: %w", err)
+	}
+
+	config := file.mergeOver(types.GetDefaultWorkflowConfig())
+	if err := config.Validate(); err != nil {
+		return types.WorkflowConfig{}, fmt.Errorf("invalid workflow config in %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// mergeOver returns the config with every setting present in the file applied
+func (f *workflowConfigFile) mergeOver(config types.WorkflowConfig) types.WorkflowConfig {
+	setString(&config.BranchNamingTemplate, f.BranchNamingTemplate)
+	setString(&config.SyncStrategy, f.SyncStrategy)
+	setString(&config.ReviewerModel, f.ReviewerModel)
+	setString(&config.StackSplit, f.StackSplit)
+	setStrings(&config.RequiredChecks, f.RequiredChecks)
+	setStrings(&config.VerifyCommands, f.VerifyCommands)
+	setStrings(&config.EnableLabels, f.EnableLabels)
+	setStrings(&config.DisableLabels, f.DisableLabels)
+	setStrings(&config.PlanApprovers, f.PlanApprovers)
+	setBool(&config.IgnoreEnableLabels, f.IgnoreEnableLabels)
+	setBool(&config.StackDependencies, f.StackDependencies)
+	setBool(&config.DraftPullRequests, f.DraftPullRequests)
+	setBool(&config.SelfReview, f.SelfReview)
+	setBool(&config.ClarityTriage, f.ClarityTriage)
+	setBool(&config.PlanFirst, f.PlanFirst)
+	setBool(&config.ForcePushDisabled, f.ForcePushDisabled)
+	setBool(&config.OnlyFeatureBranches, f.OnlyFeatureBranches)
+	setDuration(&config.RetryDelay, f.RetryDelay)
+	setDuration(&config.JobTimeout, f.JobTimeout)
+	setDuration(&config.CommentTimeout, f.CommentTimeout)
+	if f.MaxRetries != nil {
+		config.MaxRetries = *f.MaxRetries
+	}
+	if f.MinClarity != nil {
+		config.MinClarity = *f.MinClarity
+	}
+	if f.MaxPRLines != nil {
+		config.MaxPRLines = *f.MaxPRLines
+	}
+	if f.SelfReviewRounds != nil {
+		config.SelfReviewRounds = *f.SelfReviewRounds
+	}
+	return config
+}
+
+// setString overrides a string setting when the file sets it
+func setString(target *string, value *string) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// setStrings overrides a list setting when the file sets it, even to an empty list
+func setStrings(target *[]string, value *[]string) {
+	if value != nil {
+		*target = append([]string{}, *value...)
+	}
+}
+
+// setBool overrides a boolean setting when the file sets it
+func setBool(target *bool, value *bool) {
+	if value != nil {
+		*target = *value
+	}
+}
+
+// setDuration overrides a duration setting when the file sets it
+func setDuration(target *time.Duration, value *time.Duration) {
+	if value != nil {
+		*target = *value
+	}
+}
@@ -0,0 +1,117 @@
+package workflow
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestLoadWorkflowConfig tests loading repository workflow config files
+func TestLoadWorkflowConfig(t *testing.T) {
+	// Test case: Settings in the file override the defaults, including
+	// explicit false and empty lists, and everything else is left alone
+	dir := t.TempDir()
+	path := filepath.Join(dir, WorkflowConfigFileName)
+	content := `branch_naming_template: "bot/{issue_id}"
+required_checks: [build, lint]
+sync_strategy: merge
+disable_labels: []
+force_push_disabled: false
+max_retries: 5
+job_timeout: 45m
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	config, err := LoadWorkflowConfig(path)
+	require.NoError(t, err)
+
+	defaults := types.GetDefaultWorkflowConfig()
+	assert.Equal(t, "bot/{issue_id}", config.BranchNamingTemplate)
+	assert.Equal(t, []string{"build", "lint"}, config.RequiredChecks)
+	assert.Equal(t, "merge", config.SyncStrategy)
+	assert.Empty(t, config.DisableLabels)
+	assert.False(t, config.ForcePushDisabled)
+	assert.Equal(t, 5, config.MaxRetries)
+	assert.Equal(t, 45*time.Minute, config.JobTimeout)
+	assert.Equal(t, defaults.EnableLabels, config.EnableLabels)
+	assert.Equal(t, defaults.RetryDelay, config.RetryDelay)
+	assert.Equal(t, defaults.OnlyFeatureBranches, config.OnlyFeatureBranches)
+
+	// Test case: An empty file yields the defaults
+	emptyPath := filepath.Join(dir, "empty.yaml")
+	require.NoError(t, os.WriteFile(emptyPath, nil, 0644))
+	config, err = LoadWorkflowConfig(emptyPath)
+	require.NoError(t, err)
+	assert.Equal(t, defaults, config)
+
+	invalidCases := map[string]string{
+		"unknown setting":      "sync_stratgy: merge\n",
+		"bad sync strategy":    "sync_strategy: squash\n",
+		"negative retries":     "max_retries: -1\n",
+		"bad duration":         "job_timeout: soon\n",
+		"conflicting labels":   "enable_labels: [cowork]\ndisable_labels: [cowork]\n",
+		"empty required check": "required_checks: [\"\"]\n",
+	}
+	for name, content := range invalidCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			_, err := LoadWorkflowConfig(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestWorkflowManager_CreateWorkflow_ConfigSnapshot tests the config stored on new workflows
+func TestWorkflowManager_CreateWorkflow_ConfigSnapshot(t *testing.T) {
+	// Test case: A workflow created without a config gets the manager's
+	// default, and later changes to that config do not reach it
+	manager := newTestWorkflowManager(t)
+
+	config := types.GetDefaultWorkflowConfig()
+	config.RequiredChecks = []string{"build"}
+	config.MaxRetries = 7
+	manager.SetDefaultConfig(config)
+
+	workflow, err := manager.CreateWorkflow(&types.CreateWorkflowRequest{
+		Owner:      "owner",
+		Repo:       "repo",
+		IssueID:    1,
+		BaseBranch: "main",
+		Provider:   "github",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 7, workflow.Config.MaxRetries)
+
+	config.RequiredChecks[0] = "lint"
+	manager.SetDefaultConfig(types.GetDefaultWorkflowConfig())
+
+	stored, err := manager.GetWorkflow("1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build"}, stored.Config.RequiredChecks)
+	assert.Equal(t, 7, stored.Config.MaxRetries)
+}
+
+// TestWorkflowManager_CreateWorkflow_RepositoryConfig tests per-repository configs on new workflows
+func TestWorkflowManager_CreateWorkflow_RepositoryConfig(t *testing.T) {
+	// Test case: A workflow of a repository with its own config gets that
+	// config, and workflows of other repositories keep the default
+	manager := newTestWorkflowManager(t)
+
+	config := types.GetDefaultWorkflowConfig()
+	config.MaxRetries = 7
+	manager.SetRepositoryConfig("acme", "api", config)
+
+	api := createRepoWorkflow(t, manager, "acme", "api", 1)
+	web := createRepoWorkflow(t, manager, "acme", "web", 1)
+
+	assert.Equal(t, 7, api.Config.MaxRetries)
+	assert.Equal(t, types.GetDefaultWorkflowConfig().MaxRetries, web.Config.MaxRetries)
+}
@@ -0,0 +1,211 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// RecordCreatedBranch registers a branch cowork created for a workflow so it
+// may later be deleted. Recording a branch twice keeps the first entry.
+func (wm *WorkflowManager) RecordCreatedBranch(owner, repo, branch string, workflowID int) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	return wm.withStateLock(func() error {
+		branches, err := wm.loadCreatedBranchesUnlocked()
+		if err != nil {
+			return err
+		}
+		if findCreatedBranch(branches, owner, repo, branch) >= 0 {
+			return nil
+		}
+
+		branches = append(branches, &types.CreatedBranch{
+			Owner:      owner,
+			Repo:       repo,
+			Branch:     branch,
+			WorkflowID: workflowID,
+			CreatedAt:  time.Now(),
+		})
+		return wm.saveCreatedBranchesUnlocked(branches)
+	})
+}
+
+// IsCreatedBranch reports whether cowork created the branch
+func (wm *WorkflowManager) IsCreatedBranch(owner, repo, branch string) (bool, error) {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	branches, err := wm.loadCreatedBranchesUnlocked()
+	if err != nil {
+		return false, err
+	}
+	return findCreatedBranch(branches, owner, repo, branch) >= 0, nil
+}
+
+// ForgetCreatedBranch removes a deleted branch from the registry
+func (wm *WorkflowManager) ForgetCreatedBranch(owner, repo, branch string) error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	return wm.withStateLock(func() error {
+		branches, err := wm.loadCreatedBranchesUnlocked()
+		if err != nil {
+			return err
+		}
+		index := findCreatedBranch(branches, owner, repo, branch)
+		if index < 0 {
+			return nil
+		}
+
+		branches = append(branches[:index], branches[index+1:]...)
+		return wm.saveCreatedBranchesUnlocked(branches)
+	})
+}
+
+// IsBranchInUse reports whether an active workflow works on the branch or
+// is stacked on it. Failed workflows count as active since they can be retried.
+func (wm *WorkflowManager) IsBranchInUse(owner, repo, branch string) bool {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	return wm.branchesInUseUnlocked(owner, repo)[branch]
+}
+
+// OrphanedBranches returns the branches cowork created in a repository that
+// no active workflow uses anymore
+func (wm *WorkflowManager) OrphanedBranches(owner, repo string) ([]*types.CreatedBranch, error) {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	branches, err := wm.loadCreatedBranchesUnlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := wm.branchesInUseUnlocked(owner, repo)
+	var orphaned []*types.CreatedBranch
+	for _, branch := range branches {
+		if branch.Owner == owner && branch.Repo == repo && !inUse[branch.Branch] {
+			orphaned = append(orphaned, branch)
+		}
+	}
+
+	return orphaned, nil
+}
+
+// branchesInUseUnlocked returns the branches active workflows in a repository
+// work on or are based on. Must be called with the lock held.
+func (wm *WorkflowManager) branchesInUseUnlocked(owner, repo string) map[string]bool {
+	inUse := make(map[string]bool)
+	for _, workflow := range wm.workflows {
+		if workflow.Owner != owner || workflow.Repo != repo {
+			continue
+		}
+		if workflow.State.IsTerminal() && workflow.State != types.WorkflowStateFailed {
+			continue
+		}
+		inUse[workflow.BranchName] = true
+		inUse[workflow.BaseBranch] = true
+		for _, part := range workflow.StackedPRs {
+			inUse[part.Branch] = true
+		}
+	}
+	return inUse
+}
+
+// loadCreatedBranchesUnlocked reads the branch registry from disk. The
+// registry is read on every use so that branches recorded by other cw
+// processes are seen. Must be called with the lock held.
+func (wm *WorkflowManager) loadCreatedBranchesUnlocked() ([]*types.CreatedBranch, error) {
+	data, err := os.ReadFile(wm.branchesFilePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read created branches file: %w", err)
+	}
+
+	var branches []*types.CreatedBranch
+	if err := json.Unmarshal(data, &branches); err != nil {
+		return nil, fmt.Errorf("failed to decode created branches: %w", err)
+	}
+
+	return branches, nil
+}
+
+// saveCreatedBranchesUnlocked writes the branch registry to disk. Must be
+// called with both the lock and the state lock held.
+func (wm *WorkflowManager) saveCreatedBranchesUnlocked(branches []*types.CreatedBranch) error {
+	data, err := json.MarshalIndent(branches, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode created branches: %w", err)
+	}
+
+	tempFile := wm.branchesFilePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temporary created branches file: %w", err)
+	}
+
+	if err := os.Rename(tempFile, wm.branchesFilePath); err != nil {
+		return fmt.Errorf("failed to rename temporary created branches file: %w", err)
+	}
+
+	return nil
+}
+
+// findCreatedBranch returns the index of a branch in the registry, or -1
+func findCreatedBranch(branches []*types.CreatedBranch, owner, repo, branch string) int {
+	for i, created := range branches {
+		if created.Owner == owner && created.Repo == repo && created.Branch == branch {
+			return i
+		}
+	}
+	return -1
+}
+
+// isProtectedBranchName reports whether a branch is one cowork never deletes
+// regardless of the registry
+func isProtectedBranchName(branch string) bool {
+	return branch == "" || branch == "main" || branch == "master"
+}
+
+// DeleteCreatedBranch deletes a remote branch cowork created and drops it
+// from the registry. Branches cowork did not create, protected branches and
+// branches an active workflow still uses are left alone, and false is returned.
+func DeleteCreatedBranch(ctx context.Context, wm *WorkflowManager, provider git.GitProvider, owner, repo, branch string) (bool, error) {
+	if isProtectedBranchName(branch) {
+		return false, nil
+	}
+
+	created, err := wm.IsCreatedBranch(owner, repo, branch)
+	if err != nil {
+		return false, fmt.Errorf("failed to check branch registry: %w", err)
+	}
+	if !created {
+		log.Printf("🔒 Leaving branch %s alone: cowork did not create it", branch)
+		return false, nil
+	}
+	if wm.IsBranchInUse(owner, repo, branch) {
+		log.Printf("🔒 Leaving branch %s alone: an active workflow still uses it", branch)
+		return false, nil
+	}
+
+	if err := provider.DeleteBranch(ctx, owner, repo, branch); err != nil {
+		return false, fmt.Errorf("failed to delete remote branch %s: %w", branch, err)
+	}
+
+	if err := wm.ForgetCreatedBranch(owner, repo, branch); err != nil {
+		return true, fmt.Errorf("failed to update branch registry: %w", err)
+	}
+
+	log.Printf("🗑️  Deleted remote branch %s", branch)
+	return true, nil
+}
@@ -0,0 +1,144 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestWorkflowManager_CreatedBranches tests the registry of branches cowork created
+func TestWorkflowManager_CreatedBranches(t *testing.T) {
+	// Test case: Recorded branches are visible to other managers on the same
+	// directory, recording twice keeps one entry and forgotten branches are gone
+	manager := newTestWorkflowManager(t)
+
+	require.NoError(t, manager.RecordCreatedBranch("owner", "repo", "task-1", 1))
+	require.NoError(t, manager.RecordCreatedBranch("owner", "repo", "task-1", 1))
+
+	other, err := NewWorkflowManager(manager.cwDir)
+	require.NoError(t, err)
+	defer other.Close()
+
+	created, err := other.IsCreatedBranch("owner", "repo", "task-1")
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	created, err = other.IsCreatedBranch("other", "repo", "task-1")
+	require.NoError(t, err)
+	assert.False(t, created)
+
+	orphaned, err := other.OrphanedBranches("owner", "repo")
+	require.NoError(t, err)
+	assert.Len(t, orphaned, 1)
+
+	require.NoError(t, other.ForgetCreatedBranch("owner", "repo", "task-1"))
+	created, err = manager.IsCreatedBranch("owner", "repo", "task-1")
+	require.NoError(t, err)
+	assert.False(t, created)
+}
+
+// TestWorkflowManager_OrphanedBranches tests which created branches are orphaned
+func TestWorkflowManager_OrphanedBranches(t *testing.T) {
+	// Test case: Branches of active and failed workflows and branches others
+	// are stacked on are kept; only the merged workflow's branch is orphaned
+	manager := newTestWorkflowManager(t)
+
+	branchFor := func(issueID int, branch string, states ...types.WorkflowState) *types.Workflow {
+		workflow := createTestWorkflow(t, manager, issueID)
+		_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch})
+		require.NoError(t, err)
+		require.NoError(t, manager.RecordCreatedBranch("owner", "repo", branch, workflow.ID))
+		transitionTestWorkflow(t, manager, workflow, states...)
+		return workflow
+	}
+
+	branchFor(1, "task-1", types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing)
+	branchFor(2, "task-2", types.WorkflowStateFailed)
+	branchFor(3, "task-3", types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing,
+		types.WorkflowStatePROpen, types.WorkflowStateMerged)
+	stackedOn := "task-4"
+	branchFor(4, "task-4", types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing,
+		types.WorkflowStatePROpen, types.WorkflowStateMerged)
+	stacked := createTestWorkflow(t, manager, 5)
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: stacked.ID, BaseBranch: &stackedOn})
+	require.NoError(t, err)
+
+	orphaned, err := manager.OrphanedBranches("owner", "repo")
+	require.NoError(t, err)
+	require.Len(t, orphaned, 1)
+	assert.Equal(t, "task-3", orphaned[0].Branch)
+}
+
+// TestDeleteCreatedBranch tests which branches cowork agrees to delete
+func TestDeleteCreatedBranch(t *testing.T) {
+	// Test case: Only registered, unprotected branches no active workflow
+	// uses are deleted, and deleted branches leave the registry
+	testCases := []struct {
+		name       string
+		branch     string
+		register   bool
+		inUse      bool
+		wantDelete bool
+	}{
+		{"created and orphaned", "task-1", true, false, true},
+		{"not created by cowork", "feature", false, false, false},
+		{"protected branch", "main", true, false, false},
+		{"used by an active workflow", "task-1", true, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			provider := newFakeCoworkProvider()
+
+			if tc.register {
+				require.NoError(t, manager.RecordCreatedBranch("owner", "repo", tc.branch, 1))
+			}
+			if tc.inUse {
+				workflow := createTestWorkflow(t, manager, 1)
+				_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &tc.branch})
+				require.NoError(t, err)
+			}
+
+			deleted, err := DeleteCreatedBranch(context.Background(), manager, provider, "owner", "repo", tc.branch)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantDelete, deleted)
+
+			if tc.wantDelete {
+				assert.Equal(t, []string{tc.branch}, provider.deleted)
+				created, err := manager.IsCreatedBranch("owner", "repo", tc.branch)
+				require.NoError(t, err)
+				assert.False(t, created)
+			} else {
+				assert.Empty(t, provider.deleted)
+			}
+		})
+	}
+}
+
+// TestEngine_HandlePRCompleted_DeletesBranch tests branch deletion once a PR is closed
+func TestEngine_HandlePRCompleted_DeletesBranch(t *testing.T) {
+	// Test case: Closing the PR deletes the branch cowork created for it
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 7)
+	branch := "task-7"
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch})
+	require.NoError(t, err)
+	require.NoError(t, manager.RecordCreatedBranch("owner", "repo", branch, workflow.ID))
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady,
+		types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	provider := newFakeCoworkProvider()
+	engine := NewEngine(manager, nil, nil, provider, "owner", "repo")
+
+	workflow, err = manager.GetWorkflow("1")
+	require.NoError(t, err)
+	require.NoError(t, engine.handlePRCompleted(context.Background(), workflow, &git.PullRequest{Number: 42}))
+
+	assert.Equal(t, []string{branch}, provider.deleted)
+}
@@ -0,0 +1,375 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// DefaultPollInterval is the default interval between daemon polling passes
+	DefaultPollInterval = 30 * time.Second
+
+	// DefaultMaxConcurrent is the default number of workflows processed in parallel
+	DefaultMaxConcurrent = 1
+)
+
+// WorkflowProcessor advances a single workflow through its next lifecycle step
+type WorkflowProcessor interface {
+	// ProcessWorkflow processes the workflow with the given ID
+	ProcessWorkflow(ctx context.Context, workflowID string) error
+}
+
+// DaemonConfig contains the settings for the workflow daemon
+type DaemonConfig struct {
+	// Maximum number of workflows processed concurrently
+	MaxConcurrent int `json:"max_concurrent" default:"1"`
+
+	// Interval between polling passes
+	PollInterval time.Duration `json:"poll_interval" default:"30s"`
+
+	// Only process workflows for this repository (empty matches all)
+	Owner string `json:"owner,omitempty"`
+	Repo  string `json:"repo,omitempty"`
+
+	// Only process workflows for these repositories, as owner/repo (empty matches all)
+	Repositories []string `json:"repositories,omitempty"`
+
+	// Per-repository limits on concurrent workflows keyed by owner/repo,
+	// applied within the MaxConcurrent budget
+	RepoMaxConcurrent map[string]int `json:"repo_max_concurrent,omitempty"`
+}
+
+// Validate checks if the daemon config is valid
+func (dc *DaemonConfig) Validate() error {
+	if dc.MaxConcurrent <= 0 {
+		return fmt.Errorf("max concurrent must be positive")
+	}
+
+	if dc.PollInterval <= 0 {
+		return fmt.Errorf("poll interval must be positive")
+	}
+
+	for repository, limit := range dc.RepoMaxConcurrent {
+		if limit <= 0 {
+			return fmt.Errorf("max concurrent for %s must be positive", repository)
+		}
+	}
+
+	return nil
+}
+
+// Daemon is a long-running supervisor that repeatedly picks up every
+// non-terminal workflow and hands it to a bounded pool of workers
+type Daemon struct {
+	workflowManager *WorkflowManager
+	processor       WorkflowProcessor
+	config          DaemonConfig
+
+	// Slots available to workers, sized by MaxConcurrent
+	slots chan struct{}
+
+	// Workflows currently being processed, mapped to their owner/repo
+	inFlight map[string]string
+
+	// Number of workflows in flight per owner/repo
+	inFlightByRepo map[string]int
+
+	// Workflows with new provider events that have not been dispatched yet
+	pending map[string]bool
+	mu      sync.Mutex
+
+	// Signals the run loop to poll immediately
+	wake chan struct{}
+
+	// Tracks running workers so shutdown can wait for them
+	wg sync.WaitGroup
+}
+
+// NewDaemon creates a new workflow daemon
+func NewDaemon(workflowManager *WorkflowManager, processor WorkflowProcessor, config DaemonConfig) (*Daemon, error) {
+	if workflowManager == nil {
+		return nil, fmt.Errorf("workflow manager is required")
+	}
+
+	if processor == nil {
+		return nil, fmt.Errorf("workflow processor is required")
+	}
+
+	if config.MaxConcurrent == 0 {
+		config.MaxConcurrent = DefaultMaxConcurrent
+	}
+
+	if config.PollInterval == 0 {
+		config.PollInterval = DefaultPollInterval
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid daemon config: %w", err)
+	}
+
+	return &Daemon{
+		workflowManager: workflowManager,
+		processor:       processor,
+		config:          config,
+		slots:           make(chan struct{}, config.MaxConcurrent),
+		inFlight:        make(map[string]string),
+		inFlightByRepo:  make(map[string]int),
+		pending:         make(map[string]bool),
+		wake:            make(chan struct{}, 1),
+	}, nil
+}
+
+// Run polls for active workflows until the context is cancelled, then waits
+// for in-flight workflows to finish before returning
+func (d *Daemon) Run(ctx context.Context) error {
+	log.Printf("🚀 Workflow daemon started (max concurrent: %d, poll interval: %s)", d.config.MaxConcurrent, d.config.PollInterval)
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.dispatch(ctx); err != nil {
+			log.Printf("⚠️  Workflow daemon poll failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("🛑 Workflow daemon stopping, waiting for %d in-flight workflow(s)", d.InFlightCount())
+			d.wg.Wait()
+			log.Printf("✅ Workflow daemon stopped")
+			return nil
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// Wake triggers an immediate polling pass, e.g. after a webhook event arrives
+func (d *Daemon) Wake() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+		// A wake-up is already queued
+	}
+}
+
+// RunOnce performs a single polling pass and waits for every dispatched
+// workflow to finish
+func (d *Daemon) RunOnce(ctx context.Context) error {
+	err := d.dispatch(ctx)
+	d.wg.Wait()
+	return err
+}
+
+// InFlightCount returns the number of workflows currently being processed
+func (d *Daemon) InFlightCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return len(d.inFlight)
+}
+
+// dispatch hands every eligible workflow to a worker while slots are available
+func (d *Daemon) dispatch(ctx context.Context) error {
+	// Pick up workflows created or changed by other cw processes
+	if err := d.workflowManager.Reload(); err != nil {
+		return fmt.Errorf("failed to reload workflows: %w", err)
+	}
+
+	// Workflows with fresh provider events jump the queue
+	if consumer, ok := d.processor.(EventConsumer); ok {
+		workflowIDs, err := consumer.ConsumeEvents(ctx)
+		if err != nil {
+			log.Printf("⚠️  Failed to consume workflow events: %v", err)
+		}
+		d.markPending(workflowIDs)
+	}
+
+	workflows, err := d.activeWorkflows()
+	if err != nil {
+		return err
+	}
+
+	repositories := make(map[string]string, len(workflows))
+	for _, workflow := range workflows {
+		repositories[fmt.Sprintf("%d", workflow.ID)] = RepositoryKey(workflow.Owner, workflow.Repo)
+	}
+
+	for _, workflowID := range d.prioritize(workflows) {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// Busy workflows and repositories at their limit wait for the next poll
+		if !d.markInFlight(workflowID, repositories[workflowID]) {
+			continue
+		}
+
+		// Acquire a worker slot without blocking; remaining workflows wait for the next poll
+		select {
+		case d.slots <- struct{}{}:
+		default:
+			d.clearInFlight(workflowID)
+			return nil
+		}
+
+		d.clearPending(workflowID)
+		d.wg.Add(1)
+		go d.work(ctx, workflowID)
+	}
+
+	return nil
+}
+
+// work processes a single workflow and releases its slot when done
+func (d *Daemon) work(ctx context.Context, workflowID string) {
+	defer d.wg.Done()
+	defer func() {
+		<-d.slots
+		d.clearInFlight(workflowID)
+
+		// Events that arrived while this workflow was busy shouldn't wait for the next poll
+		if d.isPending(workflowID) {
+			d.Wake()
+		}
+	}()
+
+	if err := d.processor.ProcessWorkflow(ctx, workflowID); err != nil {
+		log.Printf("❌ Workflow %s failed: %v", workflowID, err)
+	}
+}
+
+// activeWorkflows returns the non-terminal, unpaused workflows this daemon is
+// responsible for, least recently updated first
+func (d *Daemon) activeWorkflows() ([]*types.Workflow, error) {
+	workflows, err := d.workflowManager.ListWorkflows()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	var active []*types.Workflow
+	for _, workflow := range workflows {
+		if workflow.State.IsTerminal() || workflow.State == types.WorkflowStatePaused {
+			continue
+		}
+		if d.config.Owner != "" && workflow.Owner != d.config.Owner {
+			continue
+		}
+		if d.config.Repo != "" && workflow.Repo != d.config.Repo {
+			continue
+		}
+		if len(d.config.Repositories) > 0 && !containsString(d.config.Repositories, RepositoryKey(workflow.Owner, workflow.Repo)) {
+			continue
+		}
+		active = append(active, workflow)
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].UpdatedAt.Before(active[j].UpdatedAt)
+	})
+
+	return active, nil
+}
+
+// prioritize orders workflow IDs so that workflows with pending events come first
+func (d *Daemon) prioritize(workflows []*types.Workflow) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var urgent, rest []string
+	active := make(map[string]bool)
+	for _, workflow := range workflows {
+		workflowID := fmt.Sprintf("%d", workflow.ID)
+		active[workflowID] = true
+		if d.pending[workflowID] {
+			urgent = append(urgent, workflowID)
+		} else {
+			rest = append(rest, workflowID)
+		}
+	}
+
+	// Drop pending entries for workflows that have since finished
+	for workflowID := range d.pending {
+		if !active[workflowID] {
+			delete(d.pending, workflowID)
+		}
+	}
+
+	return append(urgent, rest...)
+}
+
+// markPending records workflows that have new events to act on
+func (d *Daemon) markPending(workflowIDs []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, workflowID := range workflowIDs {
+		d.pending[workflowID] = true
+	}
+}
+
+// clearPending removes a workflow from the pending set
+func (d *Daemon) clearPending(workflowID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.pending, workflowID)
+}
+
+// isPending reports whether a workflow has undispatched events
+func (d *Daemon) isPending(workflowID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.pending[workflowID]
+}
+
+// markInFlight records a workflow of the given owner/repo as in flight,
+// returning false if it already is or its repository is at its limit
+func (d *Daemon) markInFlight(workflowID, repository string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, busy := d.inFlight[workflowID]; busy {
+		return false
+	}
+	if limit, ok := d.config.RepoMaxConcurrent[repository]; ok && d.inFlightByRepo[repository] >= limit {
+		return false
+	}
+
+	d.inFlight[workflowID] = repository
+	d.inFlightByRepo[repository]++
+	return true
+}
+
+// clearInFlight removes a workflow from the in-flight set
+func (d *Daemon) clearInFlight(workflowID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	repository, busy := d.inFlight[workflowID]
+	if !busy {
+		return
+	}
+
+	delete(d.inFlight, workflowID)
+	if d.inFlightByRepo[repository]--; d.inFlightByRepo[repository] <= 0 {
+		delete(d.inFlightByRepo, repository)
+	}
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// newTestWorkflowManager creates a workflow manager in an initialized temporary directory
+func newTestWorkflowManager(t *testing.T) *WorkflowManager {
+	t.Helper()
+
+	types.ResetIDGenerators()
+	cwDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(cwDir, "tasks.json"), []byte("[]"), 0644))
+
+	manager, err := NewWorkflowManager(cwDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { manager.Close() })
+
+	return manager
+}
+
+// createTestWorkflow creates a queued workflow for the given issue
+func createTestWorkflow(t *testing.T, manager *WorkflowManager, issueID int) *types.Workflow {
+	t.Helper()
+
+	workflow, err := manager.CreateWorkflow(&types.CreateWorkflowRequest{
+		Owner:      "owner",
+		Repo:       "repo",
+		IssueID:    issueID,
+		BaseBranch: "main",
+		Provider:   "github",
+		Config:     types.GetDefaultWorkflowConfig(),
+	})
+	require.NoError(t, err)
+
+	return workflow
+}
+
+// transitionTestWorkflow walks a workflow through the given states in order,
+// refreshing the caller's copy with the result
+func transitionTestWorkflow(t *testing.T, manager *WorkflowManager, workflow *types.Workflow, states ...types.WorkflowState) {
+	t.Helper()
+
+	for _, state := range states {
+		state := state
+		updated, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, State: &state})
+		require.NoError(t, err)
+		*workflow = *updated
+	}
+}
+
+// storeTestWorkflow replaces the manager's copy of a workflow, for fields no
+// update request sets
+func storeTestWorkflow(t *testing.T, manager *WorkflowManager, workflow *types.Workflow) {
+	t.Helper()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+	manager.workflows[fmt.Sprintf("%d", workflow.ID)] = workflow.Clone()
+}
+
+// reloadTestWorkflow reads the manager's current copy of a workflow
+func reloadTestWorkflow(t *testing.T, manager *WorkflowManager, workflow *types.Workflow) *types.Workflow {
+	t.Helper()
+
+	current, err := manager.GetWorkflow(fmt.Sprintf("%d", workflow.ID))
+	require.NoError(t, err)
+	return current
+}
+
+// recordingProcessor records processed workflows and the peak concurrency observed
+type recordingProcessor struct {
+	mu        sync.Mutex
+	delay     time.Duration
+	running   int
+	peak      int
+	processed []string
+}
+
+func (p *recordingProcessor) ProcessWorkflow(ctx context.Context, workflowID string) error {
+	p.mu.Lock()
+	p.running++
+	if p.running > p.peak {
+		p.peak = p.running
+	}
+	p.processed = append(p.processed, workflowID)
+	p.mu.Unlock()
+
+	time.Sleep(p.delay)
+
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	return nil
+}
+
+// TestNewDaemon_AppliesDefaults tests that zero config values fall back to defaults
+func TestNewDaemon_AppliesDefaults(t *testing.T) {
+	// Test case: An empty daemon config should use the default concurrency and poll interval
+	manager := newTestWorkflowManager(t)
+
+	daemon, err := NewDaemon(manager, &recordingProcessor{}, DaemonConfig{})
+
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxConcurrent, daemon.config.MaxConcurrent)
+	assert.Equal(t, DefaultPollInterval, daemon.config.PollInterval)
+}
+
+// TestNewDaemon_RejectsInvalidConfig tests that invalid daemon settings are rejected
+func TestNewDaemon_RejectsInvalidConfig(t *testing.T) {
+	// Test case: Negative concurrency and missing dependencies should fail
+	manager := newTestWorkflowManager(t)
+
+	_, err := NewDaemon(manager, &recordingProcessor{}, DaemonConfig{MaxConcurrent: -1})
+	assert.Error(t, err)
+
+	_, err = NewDaemon(nil, &recordingProcessor{}, DaemonConfig{})
+	assert.Error(t, err)
+
+	_, err = NewDaemon(manager, nil, DaemonConfig{})
+	assert.Error(t, err)
+}
+
+// TestDaemon_RunOnce_SkipsTerminalWorkflows tests that only active workflows are processed
+func TestDaemon_RunOnce_SkipsTerminalWorkflows(t *testing.T) {
+	// Test case: A single pass should process queued workflows and ignore terminal ones
+	manager := newTestWorkflowManager(t)
+	active := createTestWorkflow(t, manager, 1)
+	aborted := createTestWorkflow(t, manager, 2)
+
+	state := types.WorkflowStateAborted
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: aborted.ID, State: &state})
+	require.NoError(t, err)
+
+	processor := &recordingProcessor{}
+	daemon, err := NewDaemon(manager, processor, DaemonConfig{MaxConcurrent: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, daemon.RunOnce(context.Background()))
+
+	assert.Equal(t, []string{fmt.Sprintf("%d", active.ID)}, processor.processed)
+	assert.Equal(t, 0, daemon.InFlightCount())
+}
+
+// TestDaemon_RunOnce_BoundsConcurrency tests that the worker pool never exceeds MaxConcurrent
+func TestDaemon_RunOnce_BoundsConcurrency(t *testing.T) {
+	// Test case: With five workflows and two slots, at most two run at once and
+	// the rest wait for a later pass
+	manager := newTestWorkflowManager(t)
+	for issueID := 1; issueID <= 5; issueID++ {
+		createTestWorkflow(t, manager, issueID)
+	}
+
+	processor := &recordingProcessor{delay: 20 * time.Millisecond}
+	daemon, err := NewDaemon(manager, processor, DaemonConfig{MaxConcurrent: 2})
+	require.NoError(t, err)
+
+	require.NoError(t, daemon.RunOnce(context.Background()))
+
+	assert.Equal(t, 2, processor.peak)
+	assert.Len(t, processor.processed, 2)
+}
+
+// TestDaemon_Run_StopsOnCancel tests that the daemon exits cleanly when its context is cancelled
+func TestDaemon_Run_StopsOnCancel(t *testing.T) {
+	// Test case: Cancelling the context should stop polling and wait for in-flight work
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+
+	processor := &recordingProcessor{delay: 10 * time.Millisecond}
+	daemon, err := NewDaemon(manager, processor, DaemonConfig{PollInterval: 5 * time.Millisecond})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, daemon.Run(ctx))
+
+	assert.NotEmpty(t, processor.processed)
+	assert.Equal(t, 0, daemon.InFlightCount())
+}
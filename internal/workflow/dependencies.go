@@ -0,0 +1,375 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// Workflow metadata keys for issue dependencies
+const (
+	// MetadataBlockedBy lists the blockers a queued workflow is waiting on
+	MetadataBlockedBy = "blocked_by"
+
+	// MetadataStackedOn records the issue whose branch a stacked workflow is based on
+	MetadataStackedOn = "stacked_on"
+)
+
+var (
+	// dependencyPattern matches "depends on #12" and "blocked by #12, #13 and #14"
+	dependencyPattern = regexp.MustCompile(`(?i)\b(?:depends\s+on|blocked\s+by)\b:?((?:(?:\s*,\s*|\s+and\s+|\s+)#\d+\b)+)`)
+
+	// issueRefPattern matches a single issue reference
+	issueRefPattern = regexp.MustCompile(`#(\d+)`)
+)
+
+// ParseDependencies returns the issues an issue body says it depends on,
+// from "depends on #12" or "blocked by #12" phrases, sorted and without
+// duplicates. Task list items are not blockers: a parent issue tracking
+// sub-issues does not wait on them. IssueDependencies adds the issues the
+// provider reports as tracking the issue.
+func ParseDependencies(issueNumber int, body string) []int {
+	seen := make(map[int]bool)
+	add := func(ref string) {
+		if number, err := strconv.Atoi(ref); err == nil && number > 0 && number != issueNumber {
+			seen[number] = true
+		}
+	}
+
+	for _, match := range dependencyPattern.FindAllStringSubmatch(body, -1) {
+		for _, ref := range issueRefPattern.FindAllStringSubmatch(match[1], -1) {
+			add(ref[1])
+		}
+	}
+
+	dependencies := make([]int, 0, len(seen))
+	for number := range seen {
+		dependencies = append(dependencies, number)
+	}
+	sort.Ints(dependencies)
+	return dependencies
+}
+
+// IssueDependencies returns the issues an issue depends on: those its body
+// names and those tracking it on the provider, sorted and without duplicates
+func IssueDependencies(ctx context.Context, provider git.CoworkProvider, owner, repo string, issue *git.Issue) ([]int, error) {
+	trackers, err := provider.GetTrackingIssues(ctx, owner, repo, issue.Number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issues tracking #%d: %w", issue.Number, err)
+	}
+
+	dependencies := ParseDependencies(issue.Number, issue.Body)
+	for _, tracker := range trackers {
+		if tracker > 0 && tracker != issue.Number && !containsInt(dependencies, tracker) {
+			dependencies = append(dependencies, tracker)
+		}
+	}
+	sort.Ints(dependencies)
+	return dependencies, nil
+}
+
+// DependencyGraph links the workflows of one repository by the issues they depend on
+type DependencyGraph struct {
+	workflows  map[int]*types.Workflow
+	dependents map[int][]int
+}
+
+// BuildDependencyGraph builds the dependency graph of workflows from a single repository
+func BuildDependencyGraph(workflows []*types.Workflow) *DependencyGraph {
+	graph := &DependencyGraph{
+		workflows:  make(map[int]*types.Workflow),
+		dependents: make(map[int][]int),
+	}
+
+	for _, workflow := range workflows {
+		graph.workflows[workflow.IssueID] = workflow
+		for _, blocker := range workflow.DependsOn {
+			graph.dependents[blocker] = append(graph.dependents[blocker], workflow.IssueID)
+		}
+	}
+	for _, dependents := range graph.dependents {
+		sort.Ints(dependents)
+	}
+
+	return graph
+}
+
+// Workflow returns the workflow for an issue, or nil if cowork is not working on it
+func (g *DependencyGraph) Workflow(issueID int) *types.Workflow {
+	return g.workflows[issueID]
+}
+
+// Blockers returns the issues an issue depends on
+func (g *DependencyGraph) Blockers(issueID int) []int {
+	if workflow := g.workflows[issueID]; workflow != nil {
+		return workflow.DependsOn
+	}
+	return nil
+}
+
+// Dependents returns the issues depending on an issue
+func (g *DependencyGraph) Dependents(issueID int) []int {
+	return g.dependents[issueID]
+}
+
+// Roots returns the issues that depend on nothing, including blockers cowork
+// is not working on, in issue order
+func (g *DependencyGraph) Roots() []int {
+	seen := make(map[int]bool)
+	var roots []int
+	add := func(issueID int) {
+		if !seen[issueID] && len(g.Blockers(issueID)) == 0 {
+			seen[issueID] = true
+			roots = append(roots, issueID)
+		}
+	}
+
+	for issueID := range g.workflows {
+		add(issueID)
+	}
+	for issueID := range g.dependents {
+		add(issueID)
+	}
+
+	sort.Ints(roots)
+	return roots
+}
+
+// FindCycle returns a dependency path leading from the issue back to itself,
+// or nil if the issue is not part of a cycle
+func (g *DependencyGraph) FindCycle(issueID int) []int {
+	visited := make(map[int]bool)
+
+	var walk func(current int, path []int) []int
+	walk = func(current int, path []int) []int {
+		for _, blocker := range g.Blockers(current) {
+			if blocker == issueID {
+				return append(path, blocker)
+			}
+			if visited[blocker] {
+				continue
+			}
+			visited[blocker] = true
+			if cycle := walk(blocker, append(path, blocker)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+
+	return walk(issueID, []int{issueID})
+}
+
+// resolveDependencies records the issue's dependencies on a queued workflow
+// and reports whether it may start. Blockers must be merged, or closed if
+// cowork is not working on them. When stacking, a single blocker with an open
+// PR is enough and the workflow branches from the blocker's branch.
+func (e *Engine) resolveDependencies(ctx context.Context, workflow *types.Workflow, issue *git.Issue) (bool, error) {
+	dependencies, err := IssueDependencies(ctx, e.coworkProvider, e.owner, e.repo, issue)
+	if err != nil {
+		return false, err
+	}
+	if !equalInts(dependencies, workflow.DependsOn) {
+		if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			DependsOn:  &dependencies,
+		}); err != nil {
+			return false, fmt.Errorf("failed to record dependencies: %w", err)
+		}
+	}
+
+	if len(dependencies) == 0 {
+		return true, e.setBlockedBy(workflow, nil)
+	}
+
+	workflows, err := e.workflowManager.ListWorkflows()
+	if err != nil {
+		return false, fmt.Errorf("failed to list workflows: %w", err)
+	}
+	var repoWorkflows []*types.Workflow
+	for _, candidate := range workflows {
+		if candidate.Owner == e.owner && candidate.Repo == e.repo {
+			repoWorkflows = append(repoWorkflows, candidate)
+		}
+	}
+
+	graph := BuildDependencyGraph(repoWorkflows)
+	if cycle := graph.FindCycle(workflow.IssueID); cycle != nil {
+		return false, fmt.Errorf("issue dependencies form a cycle: %s", formatIssueRefs(cycle, " → "))
+	}
+
+	var open []int
+	var stackable []*types.Workflow
+	for _, dependency := range dependencies {
+		blocker := graph.Workflow(dependency)
+
+		switch {
+		case blocker != nil && blocker.State == types.WorkflowStateMerged:
+			continue
+		case blocker != nil && workflow.Config.StackDependencies && blocker.BranchName != "" &&
+			(blocker.State == types.WorkflowStatePROpen || blocker.State == types.WorkflowStateRevising):
+			stackable = append(stackable, blocker)
+			continue
+		case blocker == nil || blocker.State.IsTerminal():
+			// Work done outside cowork counts once the issue is closed
+			blockerIssue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, dependency)
+			if err != nil {
+				return false, fmt.Errorf("failed to get blocking issue #%d: %w", dependency, err)
+			}
+			if blockerIssue.State == "closed" {
+				continue
+			}
+		}
+
+		open = append(open, dependency)
+	}
+
+	// A branch can only be stacked on one blocker
+	if len(stackable) > 1 {
+		for _, blocker := range stackable {
+			open = append(open, blocker.IssueID)
+		}
+		stackable = nil
+	}
+
+	if len(open) > 0 {
+		sort.Ints(open)
+		log.Printf("⏸️  Workflow %d is waiting on %s", workflow.ID, formatIssueRefs(open, ", "))
+		return false, e.setBlockedBy(workflow, open)
+	}
+
+	if len(stackable) == 1 {
+		return true, e.stackOn(workflow, stackable[0])
+	}
+
+	return true, e.setBlockedBy(workflow, nil)
+}
+
+// stackOn bases a workflow on its blocker's branch
+func (e *Engine) stackOn(workflow *types.Workflow, blocker *types.Workflow) error {
+	log.Printf("🥞 Stacking workflow %d on %s from #%d", workflow.ID, blocker.BranchName, blocker.IssueID)
+
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		BaseBranch: &blocker.BranchName,
+		Metadata: map[string]string{
+			MetadataBlockedBy: "",
+			MetadataStackedOn: fmt.Sprintf("%d", blocker.IssueID),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to stack workflow on #%d: %w", blocker.IssueID, err)
+	}
+
+	return nil
+}
+
+// retargetDependents moves the workflows stacked on a merged blocker onto
+// the base branch the blocker merged into, so their PRs stay open once the
+// blocker's branch is deleted
+func (e *Engine) retargetDependents(ctx context.Context, blocker *types.Workflow) error {
+	workflows, err := e.workflowManager.ListWorkflows()
+	if err != nil {
+		return fmt.Errorf("failed to list workflows: %w", err)
+	}
+
+	stackedOn := fmt.Sprintf("%d", blocker.IssueID)
+	for _, dependent := range workflows {
+		if dependent.Owner != e.owner || dependent.Repo != e.repo || dependent.State.IsTerminal() ||
+			dependent.Metadata[MetadataStackedOn] != stackedOn {
+			continue
+		}
+		if err := e.retargetDependent(ctx, dependent, blocker); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// retargetDependent moves one stacked workflow and its PRs onto its merged blocker's base branch
+func (e *Engine) retargetDependent(ctx context.Context, dependent *types.Workflow, blocker *types.Workflow) error {
+	base := blocker.BaseBranch
+	update := &types.UpdateWorkflowRequest{
+		WorkflowID: dependent.ID,
+		BaseBranch: &base,
+		Metadata:   map[string]string{MetadataStackedOn: ""},
+	}
+
+	// Only the PRs based on the blocker's branch move; upper stack parts stay on the parts below them
+	var prs []int
+	if len(dependent.StackedPRs) > 0 {
+		stack := cloneStackedPRs(dependent.StackedPRs)
+		for i := range stack {
+			if stack[i].Base == dependent.BaseBranch && stack[i].Number != 0 && !stack[i].Merged {
+				stack[i].Base = base
+				prs = append(prs, stack[i].Number)
+			}
+		}
+		update.StackedPRs = &stack
+	} else if dependent.PRNumber != nil {
+		prs = append(prs, *dependent.PRNumber)
+	}
+
+	for _, number := range prs {
+		if _, err := e.coworkProvider.UpdatePullRequest(ctx, e.owner, e.repo, number, &git.UpdatePullRequestRequest{
+			Base: &base,
+		}); err != nil {
+			return fmt.Errorf("failed to retarget PR #%d to %s: %w", number, base, err)
+		}
+	}
+
+	if _, err := e.workflowManager.UpdateWorkflow(update); err != nil {
+		return fmt.Errorf("failed to move workflow %d onto %s: %w", dependent.ID, base, err)
+	}
+
+	log.Printf("🥞 Moved workflow %d onto %s now that #%d is merged", dependent.ID, base, blocker.IssueID)
+	return nil
+}
+
+// setBlockedBy records the blockers a workflow is waiting on, clearing them when there are none
+func (e *Engine) setBlockedBy(workflow *types.Workflow, blockers []int) error {
+	blockedBy := formatIssueRefs(blockers, ", ")
+	if workflow.Metadata[MetadataBlockedBy] == blockedBy {
+		return nil
+	}
+
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		Metadata:   map[string]string{MetadataBlockedBy: blockedBy},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record blockers: %w", err)
+	}
+
+	return nil
+}
+
+// formatIssueRefs renders issue numbers as "#1, #2"
+func formatIssueRefs(issues []int, separator string) string {
+	refs := make([]string, len(issues))
+	for i, issue := range issues {
+		refs[i] = fmt.Sprintf("#%d", issue)
+	}
+	return strings.Join(refs, separator)
+}
+
+// equalInts reports whether two int slices hold the same values in order
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,204 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestParseDependencies tests extracting blockers from issue bodies
+func TestParseDependencies(t *testing.T) {
+	// Test case: Dependency phrases are found; task list items tracking
+	// sub-issues, other references and the issue itself are ignored
+	testCases := []struct {
+		body     string
+		expected []int
+	}{
+		{"Depends on #12", []int{12}},
+		{"This is blocked by #3, #4 and #5.", []int{3, 4, 5}},
+		{"depends on: #9\nSee also #10", []int{9}},
+		{"## Tasks\n- [ ] #21\n- [x] #20\n* [ ] #22 polish", nil},
+		{"## Tasks\n- [ ] #21\n\nBlocked by #20", []int{20}},
+		{"Depends on #7 and is blocked by #7", []int{7}},
+		{"Depends on #1", nil},
+		{"Related to #30, fixes #31", nil},
+		{"depends on owner/other#5", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.body, func(t *testing.T) {
+			dependencies := ParseDependencies(1, tc.body)
+			if tc.expected == nil {
+				assert.Empty(t, dependencies)
+				return
+			}
+			assert.Equal(t, tc.expected, dependencies)
+		})
+	}
+}
+
+// TestDependencyGraph tests navigating workflow dependencies
+func TestDependencyGraph(t *testing.T) {
+	// Test case: Roots include untracked blockers, dependents are indexed and
+	// cycles are reported with their path
+	graph := BuildDependencyGraph([]*types.Workflow{
+		{IssueID: 1},
+		{IssueID: 2, DependsOn: []int{1, 9}},
+		{IssueID: 3, DependsOn: []int{2}},
+		{IssueID: 4, DependsOn: []int{5}},
+		{IssueID: 5, DependsOn: []int{4}},
+	})
+
+	assert.Equal(t, []int{1, 9}, graph.Roots())
+	assert.Equal(t, []int{2}, graph.Dependents(9))
+	assert.Equal(t, []int{1, 9}, graph.Blockers(2))
+	assert.Nil(t, graph.Workflow(9))
+
+	assert.Nil(t, graph.FindCycle(3))
+	assert.Equal(t, []int{4, 5, 4}, graph.FindCycle(4))
+}
+
+// TestEngine_ResolveDependencies tests parking queued workflows on their blockers
+func TestEngine_ResolveDependencies(t *testing.T) {
+	// Test case: A workflow waits until its blockers are merged or closed and,
+	// when stacking, starts from the branch of a blocker with an open PR
+	testCases := []struct {
+		name        string
+		stack       bool
+		blocker     []types.WorkflowState
+		closedIssue bool
+		ready       bool
+		blockedBy   string
+		baseBranch  string
+	}{
+		{"blocker still implementing", false, []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing}, false, false, "#2", "main"},
+		{"blocker PR open without stacking", false, []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen}, false, false, "#2", "main"},
+		{"blocker merged", false, []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen, types.WorkflowStateMerged}, false, true, "", "main"},
+		{"blocker PR open with stacking", true, []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen}, false, true, "", "feature/2"},
+		{"untracked blocker closed", false, nil, true, true, "", "main"},
+		{"untracked blocker open", false, nil, false, false, "#2", "main"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := createTestWorkflow(t, manager, 1)
+			workflow.Config.StackDependencies = tc.stack
+			storeTestWorkflow(t, manager, workflow)
+
+			if tc.blocker != nil {
+				blocker := createTestWorkflow(t, manager, 2)
+				branch := "feature/2"
+				_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: blocker.ID, BranchName: &branch})
+				require.NoError(t, err)
+				transitionTestWorkflow(t, manager, blocker, tc.blocker...)
+			}
+
+			provider := newFakeCoworkProvider()
+			blockerState := "open"
+			if tc.closedIssue {
+				blockerState = "closed"
+			}
+			provider.issues = map[int]*git.Issue{2: {Number: 2, State: blockerState}}
+			engine := NewEngine(manager, nil, nil, provider, "owner", "repo")
+
+			issue := &git.Issue{Number: 1, Body: "Depends on #2"}
+			ready, err := engine.resolveDependencies(context.Background(), workflow, issue)
+			require.NoError(t, err)
+
+			workflow = reloadTestWorkflow(t, manager, workflow)
+			assert.Equal(t, tc.ready, ready)
+			assert.Equal(t, []int{2}, workflow.DependsOn)
+			assert.Equal(t, tc.blockedBy, workflow.Metadata[MetadataBlockedBy])
+			assert.Equal(t, tc.baseBranch, workflow.BaseBranch)
+		})
+	}
+}
+
+// TestEngine_ResolveDependencies_Cycle tests rejecting circular dependencies
+func TestEngine_ResolveDependencies_Cycle(t *testing.T) {
+	// Test case: Two issues depending on each other fail instead of waiting forever
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	blocker := createTestWorkflow(t, manager, 2)
+	dependsOn := []int{1}
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: blocker.ID, DependsOn: &dependsOn})
+	require.NoError(t, err)
+
+	engine := NewEngine(manager, nil, nil, newFakeCoworkProvider(), "owner", "repo")
+
+	_, err = engine.resolveDependencies(context.Background(), workflow, &git.Issue{Number: 1, Body: "Blocked by #2"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "#1 → #2 → #1")
+}
+
+// TestManager_CreateWorkflow_Dependencies tests recording dependencies when scanning issues
+func TestManager_CreateWorkflow_Dependencies(t *testing.T) {
+	// Test case: A scanned issue's workflow depends on the issues its body
+	// names as blockers and the issue tracking it, and a rescan refreshes
+	// them while it is queued
+	workflows := newTestWorkflowManager(t)
+	provider := newFakeCoworkProvider()
+	manager := NewManager(provider, nil, nil, "owner", "repo")
+	manager.SetWorkflowManager(workflows)
+
+	issue := &git.Issue{Number: 3, Body: "Depends on #1.\n\n## Tasks\n- [ ] #2"}
+	require.NoError(t, manager.createWorkflow(context.Background(), issue, &types.Task{ID: 1}, "main"))
+
+	workflow, err := workflows.GetWorkflowByIssue("owner", "repo", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, workflow.DependsOn)
+	assert.Equal(t, []int{3}, BuildDependencyGraph([]*types.Workflow{workflow}).Dependents(1))
+
+	issue.Body = "Depends on #1 and blocked by #4"
+	provider.trackers = map[int][]int{3: {5, 3}}
+	require.NoError(t, manager.createWorkflow(context.Background(), issue, &types.Task{ID: 1}, "main"))
+
+	workflow, err = workflows.GetWorkflowByIssue("owner", "repo", 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4, 5}, workflow.DependsOn)
+}
+
+// TestEngine_HandlePRCompleted_RetargetsDependents tests moving stacked workflows off a merged blocker
+func TestEngine_HandlePRCompleted_RetargetsDependents(t *testing.T) {
+	// Test case: When a blocker merges, the PR of the workflow stacked on it is
+	// retargeted to the blocker's base branch before the blocker's branch is
+	// deleted, and the workflow is no longer stacked
+	manager := newTestWorkflowManager(t)
+	blocker := createTestWorkflow(t, manager, 2)
+	dependent := createTestWorkflow(t, manager, 1)
+	blockerBranch, dependentBranch, prNumber := "task-2", "task-1", 43
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: blocker.ID, BranchName: &blockerBranch})
+	require.NoError(t, err)
+	require.NoError(t, manager.RecordCreatedBranch("owner", "repo", blockerBranch, blocker.ID))
+	transitionTestWorkflow(t, manager, blocker, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: dependent.ID,
+		BranchName: &dependentBranch,
+		BaseBranch: &blockerBranch,
+		PRNumber:   &prNumber,
+		Metadata:   map[string]string{MetadataStackedOn: "2"},
+	})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, dependent, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	provider := newFakeCoworkProvider()
+	provider.pr = &git.PullRequest{Number: prNumber, State: "open", Base: &git.Branch{Ref: blockerBranch}}
+	engine := NewEngine(manager, nil, newFakeWorkspaceManager(), provider, "owner", "repo")
+
+	require.NoError(t, engine.handlePRCompleted(context.Background(), blocker, &git.PullRequest{Number: 42, State: "closed", Merged: true}))
+
+	assert.Equal(t, types.WorkflowStateMerged, reloadTestWorkflow(t, manager, blocker).State)
+	require.Len(t, provider.edits, 1)
+	assert.Equal(t, "main", *provider.edits[0].Base)
+	assert.Equal(t, "main", provider.pr.Base.Ref)
+	dependent = reloadTestWorkflow(t, manager, dependent)
+	assert.Equal(t, "main", dependent.BaseBranch)
+	assert.Empty(t, dependent.Metadata[MetadataStackedOn])
+	assert.Equal(t, []string{blockerBranch}, provider.deleted)
+}
@@ -0,0 +1,147 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// MetadataDraftPR marks a workflow whose PR cowork opened as a draft and has
+// not promoted to ready for review yet
+const MetadataDraftPR = "draft_pr"
+
+// openDraftPullRequest opens a draft PR for an implementing workflow as soon
+// as the agent has committed to the branch, so reviewers can follow along
+func (e *Engine) openDraftPullRequest(ctx context.Context, workflow *types.Workflow, task *types.Task) error {
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	committed, err := hasNewCommits(workspace.Path, workflow.BaseBranch)
+	if err != nil {
+		return err
+	}
+	if !committed {
+		log.Printf("⏳ Task %d has no commits yet, waiting before opening a draft PR", task.ID)
+		return nil
+	}
+
+	if err := e.pushBranch(ctx, workspace.Path, workflow.BranchName); err != nil {
+		return fmt.Errorf("failed to push branch: %w", err)
+	}
+
+	pr, err := e.openPullRequest(ctx, workflow, task, workspace, true)
+	if err != nil {
+		return fmt.Errorf("failed to open draft pull request: %w", err)
+	}
+
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		PRNumber:   &pr.Number,
+		Metadata:   map[string]string{MetadataDraftPR: strconv.FormatBool(pr.Draft)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update workflow with PR number: %w", err)
+	}
+
+	log.Printf("📝 Opened draft PR #%d for workflow %d", pr.Number, workflow.ID)
+	return nil
+}
+
+// openPullRequest opens the PR for a workflow, as a draft if requested. A PR
+// that already exists for the task is returned as is.
+func (e *Engine) openPullRequest(ctx context.Context, workflow *types.Workflow, task *types.Task, workspace *types.Workspace, draft bool) (*git.PullRequest, error) {
+	if !draft {
+		return e.coworkProvider.CreatePullRequestForTask(ctx, task, e.owner, e.repo, workspace)
+	}
+
+	existing, err := e.coworkProvider.GetPullRequestForTask(ctx, task, e.owner, e.repo)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	pr, err := e.coworkProvider.CreatePullRequest(ctx, e.owner, e.repo, &git.CreatePullRequestRequest{
+		Title: fmt.Sprintf("Fix #%d: %s", issue.Number, issue.Title),
+		Body:  prbody.Build(e.prTemplate, issue, task, workspace.Path, workflow.BaseBranch),
+		Head:  workflow.BranchName,
+		Base:  workflow.BaseBranch,
+		Draft: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := e.coworkProvider.LinkPullRequestToIssue(ctx, e.owner, e.repo, pr, issue); err != nil {
+		return nil, fmt.Errorf("failed to link PR to issue: %w", err)
+	}
+
+	return pr, nil
+}
+
+// promoteDraftPullRequest marks a draft PR cowork opened as ready for review
+// once the required checks on it passed, refreshing the body it was opened
+// with early on
+func (e *Engine) promoteDraftPullRequest(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest) error {
+	if workflow.Metadata[MetadataDraftPR] != "true" {
+		return nil
+	}
+
+	// A human may have marked it ready already
+	if pr.Draft {
+		// Checks are only recorded when the workflow requires some
+		if len(workflow.Config.RequiredChecks) > 0 {
+			current, err := e.reloadWorkflow(workflow)
+			if err != nil {
+				return err
+			}
+			if current.Metadata[MetadataChecksState] != ChecksStatePassed {
+				return nil
+			}
+		}
+
+		ready := false
+		update := &git.UpdatePullRequestRequest{Draft: &ready}
+		if body, err := e.currentPullRequestBody(ctx, workflow); err != nil {
+			log.Printf("⚠️  Keeping the draft body of PR #%d: %v", pr.Number, err)
+		} else {
+			update.Body = &body
+		}
+
+		if _, err := e.coworkProvider.UpdatePullRequest(ctx, e.owner, e.repo, pr.Number, update); err != nil {
+			return fmt.Errorf("failed to mark PR #%d ready for review: %w", pr.Number, err)
+		}
+		log.Printf("🚀 Marked PR #%d ready for review", pr.Number)
+	}
+
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		Metadata:   map[string]string{MetadataDraftPR: ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update workflow: %w", err)
+	}
+
+	return nil
+}
+
+// hasNewCommits reports whether the workspace branch has commits that are
+// not on the base branch
+func hasNewCommits(workspacePath, baseBranch string) (bool, error) {
+	count, err := gitOutput(workspacePath, "rev-list", "--count", "origin/"+baseBranch+"..HEAD")
+	if err != nil {
+		return false, fmt.Errorf("failed to count new commits: %w", err)
+	}
+
+	return count != "0", nil
+}
@@ -0,0 +1,164 @@
+package workflow
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// newDraftWorkspace creates an origin repository and a workspace on a fresh
+// task branch without commits of its own, returning both paths
+func newDraftWorkspace(t *testing.T) (string, string) {
+	t.Helper()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	workspace := filepath.Join(root, "workspace")
+
+	gitTest(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	gitTest(t, root, "clone", "-q", origin, workspace)
+	gitTest(t, workspace, "checkout", "-q", "-b", "main")
+	commitFile(t, workspace, "file.txt", "original\n", "initial")
+	gitTest(t, workspace, "push", "-q", "origin", "main")
+	gitTest(t, workspace, "checkout", "-q", "-b", "task-7")
+
+	return origin, workspace
+}
+
+// TestEngine_OpenDraftPullRequest tests opening a draft PR once the agent commits
+func TestEngine_OpenDraftPullRequest(t *testing.T) {
+	// Test case: No PR is opened before the first commit; after it the branch
+	// is pushed and a draft PR is opened and remembered on the workflow
+	origin, workspacePath := newDraftWorkspace(t)
+
+	manager := newTestWorkflowManager(t)
+	workflow := newImplementingWorkflow(t, manager, 0)
+	workflow.BranchName = "task-7"
+	workflow.Config.DraftPullRequests = true
+	storeTestWorkflow(t, manager, workflow)
+
+	provider := newFakeCoworkProvider()
+	provider.issues = map[int]*git.Issue{7: {Number: 7, Title: "Fix the widget"}}
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusInProgress})
+	workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath})
+	engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+
+	require.NoError(t, engine.processImplementingWorkflow(context.Background(), workflow))
+	assert.Empty(t, provider.created)
+	assert.Nil(t, reloadTestWorkflow(t, manager, workflow).PRNumber)
+
+	commitFile(t, workspacePath, "file.txt", "fixed\n", "fix the widget")
+	require.NoError(t, engine.processImplementingWorkflow(context.Background(), workflow))
+
+	require.Len(t, provider.created, 1)
+	assert.True(t, provider.created[0].Draft)
+	assert.Equal(t, "task-7", provider.created[0].Head)
+	assert.Equal(t, "main", provider.created[0].Base)
+	assert.Equal(t, "Fix #7: Fix the widget", provider.created[0].Title)
+	assert.Contains(t, provider.created[0].Body, "`file.txt`")
+	assert.Contains(t, provider.created[0].Body, "Closes #7")
+	assert.NotEmpty(t, gitTest(t, origin, "rev-parse", "--verify", "task-7"))
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	require.NotNil(t, workflow.PRNumber)
+	assert.Equal(t, provider.pr.Number, *workflow.PRNumber)
+	assert.Equal(t, "true", workflow.Metadata[MetadataDraftPR])
+	assert.Equal(t, types.WorkflowStateImplementing, workflow.State)
+}
+
+// TestEngine_PromoteDraftPullRequest tests marking cowork's draft PRs ready for review
+func TestEngine_PromoteDraftPullRequest(t *testing.T) {
+	testCases := []struct {
+		name           string
+		draftMetadata  string
+		requiredChecks []string
+		checksState    string
+		draft          bool
+		expectPromoted bool
+		expectMetadata string
+	}{
+		{
+			// Test case: Without required checks the draft is promoted right
+			// away, with a body describing the finished work
+			name:           "no required checks",
+			draftMetadata:  "true",
+			draft:          true,
+			expectPromoted: true,
+		},
+		{
+			// Test case: The draft waits for pending required checks
+			name:           "checks pending",
+			draftMetadata:  "true",
+			requiredChecks: []string{"test"},
+			checksState:    ChecksStatePending,
+			draft:          true,
+			expectMetadata: "true",
+		},
+		{
+			// Test case: Passed required checks promote the draft
+			name:           "checks passed",
+			draftMetadata:  "true",
+			requiredChecks: []string{"test"},
+			checksState:    ChecksStatePassed,
+			draft:          true,
+			expectPromoted: true,
+		},
+		{
+			// Test case: A draft someone already marked ready is only forgotten
+			name:          "already ready",
+			draftMetadata: "true",
+			draft:         false,
+		},
+		{
+			// Test case: Drafts cowork did not open are left alone
+			name:  "not opened by cowork",
+			draft: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := newImplementingWorkflow(t, manager, 0)
+			workflow.Config.RequiredChecks = tc.requiredChecks
+			storeTestWorkflow(t, manager, workflow)
+			workflow, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+				WorkflowID: workflow.ID,
+				Metadata:   map[string]string{MetadataDraftPR: tc.draftMetadata, MetadataChecksState: tc.checksState},
+			})
+			require.NoError(t, err)
+
+			provider := newFakeCoworkProvider()
+			provider.pr = &git.PullRequest{Number: 42, Draft: tc.draft}
+			tasks := newFakeTaskManager(&types.Task{ID: 3, Metadata: map[string]string{prbody.MetadataAgentSummary: "Fixed it."}})
+			workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5})
+			engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+
+			require.NoError(t, engine.promoteDraftPullRequest(context.Background(), workflow, provider.pr))
+
+			if tc.expectPromoted {
+				require.Len(t, provider.edits, 1)
+				require.NotNil(t, provider.edits[0].Draft)
+				assert.False(t, *provider.edits[0].Draft)
+				require.NotNil(t, provider.edits[0].Body)
+				assert.Contains(t, *provider.edits[0].Body, "Fixed it.")
+				assert.False(t, provider.pr.Draft)
+			} else {
+				assert.Empty(t, provider.edits)
+			}
+			assert.Equal(t, tc.expectMetadata, reloadTestWorkflow(t, manager, workflow).Metadata[MetadataDraftPR])
+		})
+	}
+}
@@ -0,0 +1,486 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// MutationKind identifies a change a dry run would have made on the provider
+type MutationKind string
+
+const (
+	MutationComment      MutationKind = "comment"
+	MutationIssueCreate  MutationKind = "issue_create"
+	MutationIssueUpdate  MutationKind = "issue_update"
+	MutationPullRequest  MutationKind = "pull_request"
+	MutationPRUpdate     MutationKind = "pull_request_update"
+	MutationPush         MutationKind = "push"
+	MutationForcePush    MutationKind = "force_push"
+	MutationBranchDelete MutationKind = "branch_delete"
+	MutationStatusSync   MutationKind = "status_sync"
+	MutationWorkspace    MutationKind = "workspace_create"
+	MutationAgentRun     MutationKind = "agent_run"
+	MutationAgentStop    MutationKind = "agent_stop"
+	MutationJobTimeout   MutationKind = "job_timeout"
+)
+
+// errDryRun is returned in place of work a dry run records but cannot stand in for
+var errDryRun = errors.New("not performed in a dry run")
+
+// dryRunPullRequestBase is where synthetic pull request numbers start, far
+// above the numbers of real pull requests
+const dryRunPullRequestBase = 900000
+
+// Mutation is a change a dry run recorded instead of performing
+type Mutation struct {
+	// Workflow the change belongs to, empty when made outside a workflow step
+	WorkflowID string `json:"workflow_id,omitempty"`
+
+	Kind MutationKind `json:"kind"`
+
+	// Repository the change targets, as owner/repo
+	Repository string `json:"repository"`
+
+	Description string    `json:"description"`
+	RecordedAt  time.Time `json:"recorded_at"`
+}
+
+// DryRunRecorder collects the mutations of a dry run
+type DryRunRecorder struct {
+	mutations []Mutation
+	mu        sync.Mutex
+}
+
+// NewDryRunRecorder creates an empty recorder
+func NewDryRunRecorder() *DryRunRecorder {
+	return &DryRunRecorder{}
+}
+
+// Record logs a mutation that was not performed, attributing it to the
+// workflow being processed in ctx
+func (r *DryRunRecorder) Record(ctx context.Context, kind MutationKind, repository, format string, args ...interface{}) {
+	mutation := Mutation{
+		WorkflowID:  workflowIDFromContext(ctx),
+		Kind:        kind,
+		Repository:  repository,
+		Description: fmt.Sprintf(format, args...),
+		RecordedAt:  time.Now(),
+	}
+
+	r.mu.Lock()
+	r.mutations = append(r.mutations, mutation)
+	r.mu.Unlock()
+
+	log.Printf("📝 [dry-run] Would %s on %s", mutation.Description, repository)
+}
+
+// Mutations returns every recorded mutation in the order it was recorded
+func (r *DryRunRecorder) Mutations() []Mutation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Mutation(nil), r.mutations...)
+}
+
+// Plan groups the recorded mutations by workflow ID
+func (r *DryRunRecorder) Plan() map[string][]Mutation {
+	plan := make(map[string][]Mutation)
+	for _, mutation := range r.Mutations() {
+		plan[mutation.WorkflowID] = append(plan[mutation.WorkflowID], mutation)
+	}
+	return plan
+}
+
+// workflowIDKey is the context key carrying the workflow being processed
+type workflowIDKey struct{}
+
+// withWorkflowID returns a context recording the workflow being processed
+func withWorkflowID(ctx context.Context, workflowID string) context.Context {
+	return context.WithValue(ctx, workflowIDKey{}, workflowID)
+}
+
+// workflowIDFromContext returns the workflow being processed, if any
+func workflowIDFromContext(ctx context.Context) string {
+	workflowID, _ := ctx.Value(workflowIDKey{}).(string)
+	return workflowID
+}
+
+// DryRunProvider wraps a CoworkProvider, passing reads through and recording
+// every change to issues, pull requests and branches instead of making it.
+// Every method is spelled out, so a method added to the provider interfaces
+// cannot reach the real provider until it is classified here.
+type DryRunProvider struct {
+	provider git.CoworkProvider
+	recorder *DryRunRecorder
+
+	// Synthetic numbers handed out for pull requests that were not opened
+	nextPRNumber int
+	mu           sync.Mutex
+}
+
+// NewDryRunProvider wraps provider so that its mutations go to recorder
+func NewDryRunProvider(provider git.CoworkProvider, recorder *DryRunRecorder) *DryRunProvider {
+	return &DryRunProvider{
+		provider:     provider,
+		recorder:     recorder,
+		nextPRNumber: dryRunPullRequestBase,
+	}
+}
+
+// GetProviderType returns the type of the wrapped provider
+func (p *DryRunProvider) GetProviderType() git.ProviderType {
+	return p.provider.GetProviderType()
+}
+
+// TestAuth verifies the wrapped provider's credentials
+func (p *DryRunProvider) TestAuth(ctx context.Context) error {
+	return p.provider.TestAuth(ctx)
+}
+
+// GetRepositoryInfo reads repository information from the wrapped provider
+func (p *DryRunProvider) GetRepositoryInfo(ctx context.Context, owner, repo string) (*git.Repository, error) {
+	return p.provider.GetRepositoryInfo(ctx, owner, repo)
+}
+
+// GetIssues reads issues from the wrapped provider
+func (p *DryRunProvider) GetIssues(ctx context.Context, owner, repo string, options *git.IssueListOptions) ([]*git.Issue, error) {
+	return p.provider.GetIssues(ctx, owner, repo, options)
+}
+
+// GetIssue reads an issue from the wrapped provider
+func (p *DryRunProvider) GetIssue(ctx context.Context, owner, repo string, issueNumber int) (*git.Issue, error) {
+	return p.provider.GetIssue(ctx, owner, repo, issueNumber)
+}
+
+// CreateIssue records the issue instead of creating it
+func (p *DryRunProvider) CreateIssue(ctx context.Context, owner, repo string, issue *git.CreateIssueRequest) (*git.Issue, error) {
+	p.recorder.Record(ctx, MutationIssueCreate, RepositoryKey(owner, repo), "create issue %q", issue.Title)
+	return &git.Issue{Title: issue.Title, Body: issue.Body, State: "open", CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+// UpdateIssue records the issue changes instead of making them
+func (p *DryRunProvider) UpdateIssue(ctx context.Context, owner, repo string, issueNumber int, updates *git.UpdateIssueRequest) (*git.Issue, error) {
+	p.recorder.Record(ctx, MutationIssueUpdate, RepositoryKey(owner, repo), "update issue #%d (%s)", issueNumber, describeIssueUpdate(updates))
+	return p.provider.GetIssue(ctx, owner, repo, issueNumber)
+}
+
+// GetPullRequests reads pull requests from the wrapped provider
+func (p *DryRunProvider) GetPullRequests(ctx context.Context, owner, repo string, options *git.PullRequestListOptions) ([]*git.PullRequest, error) {
+	return p.provider.GetPullRequests(ctx, owner, repo, options)
+}
+
+// GetPullRequest reads a pull request from the wrapped provider
+func (p *DryRunProvider) GetPullRequest(ctx context.Context, owner, repo string, prNumber int) (*git.PullRequest, error) {
+	return p.provider.GetPullRequest(ctx, owner, repo, prNumber)
+}
+
+// GetPullRequestByBranch reads the pull request of a branch from the wrapped provider
+func (p *DryRunProvider) GetPullRequestByBranch(ctx context.Context, owner, repo string, branchName string) (*git.PullRequest, error) {
+	return p.provider.GetPullRequestByBranch(ctx, owner, repo, branchName)
+}
+
+// GetPullRequestByIssue reads the pull request closing an issue from the wrapped provider
+func (p *DryRunProvider) GetPullRequestByIssue(ctx context.Context, owner, repo string, issueNumber int) (*git.PullRequest, error) {
+	return p.provider.GetPullRequestByIssue(ctx, owner, repo, issueNumber)
+}
+
+// CreatePullRequest records the pull request instead of opening it
+func (p *DryRunProvider) CreatePullRequest(ctx context.Context, owner, repo string, pr *git.CreatePullRequestRequest) (*git.PullRequest, error) {
+	draft := ""
+	if pr.Draft {
+		draft = "draft "
+	}
+	p.recorder.Record(ctx, MutationPullRequest, RepositoryKey(owner, repo), "open %spull request %s → %s titled %q", draft, pr.Head, pr.Base, pr.Title)
+	return p.syntheticPullRequest(pr), nil
+}
+
+// UpdatePullRequest records the pull request changes instead of making them
+func (p *DryRunProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, updates *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
+	p.recorder.Record(ctx, MutationPRUpdate, RepositoryKey(owner, repo), "update pull request #%d (%s)", prNumber, describePullRequestUpdate(updates))
+	return &git.PullRequest{Number: prNumber, State: "open", UpdatedAt: time.Now()}, nil
+}
+
+// GetPullRequestReviews reads pull request reviews from the wrapped provider
+func (p *DryRunProvider) GetPullRequestReviews(ctx context.Context, owner, repo string, prNumber int) ([]*git.Review, error) {
+	return p.provider.GetPullRequestReviews(ctx, owner, repo, prNumber)
+}
+
+// GetPullRequestComments reads pull request comments from the wrapped provider
+func (p *DryRunProvider) GetPullRequestComments(ctx context.Context, owner, repo string, prNumber int) ([]*git.Comment, error) {
+	return p.provider.GetPullRequestComments(ctx, owner, repo, prNumber)
+}
+
+// GetIssueComments reads issue comments from the wrapped provider
+func (p *DryRunProvider) GetIssueComments(ctx context.Context, owner, repo string, issueNumber int) ([]*git.Comment, error) {
+	return p.provider.GetIssueComments(ctx, owner, repo, issueNumber)
+}
+
+// CreateComment records the comment instead of posting it
+func (p *DryRunProvider) CreateComment(ctx context.Context, owner, repo string, issueNumber int, comment *git.CreateCommentRequest) (*git.Comment, error) {
+	p.recorder.Record(ctx, MutationComment, RepositoryKey(owner, repo), "comment on #%d: %q", issueNumber, summarizeText(comment.Body))
+	return &git.Comment{Body: comment.Body, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+// GetLabels reads repository labels from the wrapped provider
+func (p *DryRunProvider) GetLabels(ctx context.Context, owner, repo string) ([]*git.Label, error) {
+	return p.provider.GetLabels(ctx, owner, repo)
+}
+
+// GetCommitChecks reads the checks of a commit from the wrapped provider
+func (p *DryRunProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	return p.provider.GetCommitChecks(ctx, owner, repo, ref)
+}
+
+// BranchExists checks the branch on the wrapped provider
+func (p *DryRunProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	return p.provider.BranchExists(ctx, owner, repo, branch)
+}
+
+// DeleteBranch records the branch deletion instead of deleting it
+func (p *DryRunProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	p.recorder.Record(ctx, MutationBranchDelete, RepositoryKey(owner, repo), "delete branch %s", branch)
+	return nil
+}
+
+// ScanOpenIssues reads open issues from the wrapped provider
+func (p *DryRunProvider) ScanOpenIssues(ctx context.Context, owner, repo string) ([]*git.Issue, error) {
+	return p.provider.ScanOpenIssues(ctx, owner, repo)
+}
+
+// CreateTaskFromIssue creates the task through the wrapped provider. Tasks
+// are local, and a dry run keeps them in its scratch copy of the state.
+func (p *DryRunProvider) CreateTaskFromIssue(ctx context.Context, owner, repo string, issue *git.Issue) (*types.Task, error) {
+	return p.provider.CreateTaskFromIssue(ctx, owner, repo, issue)
+}
+
+// GetTaskByIssue reads the task of an issue through the wrapped provider
+func (p *DryRunProvider) GetTaskByIssue(ctx context.Context, owner, repo string, issueNumber int) (*types.Task, error) {
+	return p.provider.GetTaskByIssue(ctx, owner, repo, issueNumber)
+}
+
+// GetTrackingIssues reads the issues tracking an issue from the wrapped provider
+func (p *DryRunProvider) GetTrackingIssues(ctx context.Context, owner, repo string, issueNumber int) ([]int, error) {
+	return p.provider.GetTrackingIssues(ctx, owner, repo, issueNumber)
+}
+
+// CreateWorkspaceForTask records the workspace instead of cloning it, since
+// workspaces live outside the dry run's scratch state
+func (p *DryRunProvider) CreateWorkspaceForTask(ctx context.Context, task *types.Task, owner, repo string) (*types.Workspace, error) {
+	p.recorder.Record(ctx, MutationWorkspace, RepositoryKey(owner, repo), "create a workspace on branch %s for task %d", task.BranchName, task.ID)
+	return nil, errDryRun
+}
+
+// GenerateBranchName generates a branch name with the wrapped provider
+func (p *DryRunProvider) GenerateBranchName(issue *git.Issue) string {
+	return p.provider.GenerateBranchName(issue)
+}
+
+// CreatePullRequestForTask records the pull request and issue link a task
+// would get, reusing a pull request that already exists
+func (p *DryRunProvider) CreatePullRequestForTask(ctx context.Context, task *types.Task, owner, repo string, workspace *types.Workspace) (*git.PullRequest, error) {
+	existing, err := p.provider.GetPullRequestForTask(ctx, task, owner, repo)
+	if err == nil && existing != nil {
+		return existing, nil
+	}
+
+	head, base := task.BranchName, task.BaseBranch
+	if workspace != nil {
+		head, base = workspace.BranchName, workspace.BaseBranch
+	}
+
+	pr, err := p.CreatePullRequest(ctx, owner, repo, &git.CreatePullRequestRequest{
+		Title: task.Name,
+		Head:  head,
+		Base:  base,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	p.recorder.Record(ctx, MutationComment, RepositoryKey(owner, repo), "link the pull request from %s", task.TicketID)
+	return pr, nil
+}
+
+// GetPullRequestForTask reads the pull request of a task from the wrapped provider
+func (p *DryRunProvider) GetPullRequestForTask(ctx context.Context, task *types.Task, owner, repo string) (*git.PullRequest, error) {
+	return p.provider.GetPullRequestForTask(ctx, task, owner, repo)
+}
+
+// LinkPullRequestToIssue records the linking comment instead of posting it
+func (p *DryRunProvider) LinkPullRequestToIssue(ctx context.Context, owner, repo string, pr *git.PullRequest, issue *git.Issue) error {
+	p.recorder.Record(ctx, MutationComment, RepositoryKey(owner, repo), "comment on #%d linking pull request #%d", issue.Number, pr.Number)
+	return nil
+}
+
+// ScanPullRequestsForTasks reads the pull requests of tasks from the wrapped provider
+func (p *DryRunProvider) ScanPullRequestsForTasks(ctx context.Context, owner, repo string, knownTaskIDs []string) ([]*git.PullRequest, error) {
+	return p.provider.ScanPullRequestsForTasks(ctx, owner, repo, knownTaskIDs)
+}
+
+// GetPullRequestUpdates reads recent pull request activity from the wrapped provider
+func (p *DryRunProvider) GetPullRequestUpdates(ctx context.Context, owner, repo string, prNumber int, since time.Time) (*git.PullRequestUpdate, error) {
+	return p.provider.GetPullRequestUpdates(ctx, owner, repo, prNumber, since)
+}
+
+// ReplyToReviewComment records the reply instead of posting it
+func (p *DryRunProvider) ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber, commentID int, body string) (*git.Comment, error) {
+	p.recorder.Record(ctx, MutationComment, RepositoryKey(owner, repo), "reply to review comment %d on #%d: %q", commentID, prNumber, summarizeText(body))
+	return &git.Comment{Body: body, InReplyTo: commentID, CreatedAt: time.Now(), UpdatedAt: time.Now()}, nil
+}
+
+// UpdateTaskFromPullRequest updates the task through the wrapped provider.
+// Tasks are local, and a dry run keeps them in its scratch copy of the state.
+func (p *DryRunProvider) UpdateTaskFromPullRequest(ctx context.Context, task *types.Task, pr *git.PullRequest, updates *git.PullRequestUpdate) error {
+	return p.provider.UpdateTaskFromPullRequest(ctx, task, pr, updates)
+}
+
+// SyncTaskStatusToProvider records the status sync instead of updating the issue
+func (p *DryRunProvider) SyncTaskStatusToProvider(ctx context.Context, task *types.Task, owner, repo string) error {
+	p.recorder.Record(ctx, MutationStatusSync, RepositoryKey(owner, repo), "sync task %d status %s to %s", task.ID, task.Status, task.TicketID)
+	return nil
+}
+
+// GetProviderMetadata reads task metadata from the wrapped provider
+func (p *DryRunProvider) GetProviderMetadata(ctx context.Context, task *types.Task, owner, repo string) (map[string]interface{}, error) {
+	return p.provider.GetProviderMetadata(ctx, task, owner, repo)
+}
+
+// syntheticPullRequest returns a stand-in for a pull request that was not opened
+func (p *DryRunProvider) syntheticPullRequest(req *git.CreatePullRequestRequest) *git.PullRequest {
+	p.mu.Lock()
+	p.nextPRNumber++
+	number := p.nextPRNumber
+	p.mu.Unlock()
+
+	now := time.Now()
+	return &git.PullRequest{
+		Number:    number,
+		Title:     req.Title,
+		Body:      req.Body,
+		State:     "open",
+		Draft:     req.Draft,
+		Head:      &git.Branch{Ref: req.Head},
+		Base:      &git.Branch{Ref: req.Base},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// describeIssueUpdate summarizes the fields an issue update changes
+func describeIssueUpdate(updates *git.UpdateIssueRequest) string {
+	var changes []string
+	if updates.Title != nil {
+		changes = append(changes, fmt.Sprintf("title %q", *updates.Title))
+	}
+	if updates.Body != nil {
+		changes = append(changes, "body")
+	}
+	if updates.State != nil {
+		changes = append(changes, "state "+*updates.State)
+	}
+	if updates.Assignees != nil {
+		changes = append(changes, "assignees ["+strings.Join(*updates.Assignees, ", ")+"]")
+	}
+	if updates.Labels != nil {
+		changes = append(changes, "labels ["+strings.Join(*updates.Labels, ", ")+"]")
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// describePullRequestUpdate summarizes the fields a pull request update changes
+func describePullRequestUpdate(updates *git.UpdatePullRequestRequest) string {
+	var changes []string
+	if updates.Title != nil {
+		changes = append(changes, fmt.Sprintf("title %q", *updates.Title))
+	}
+	if updates.Body != nil {
+		changes = append(changes, "body")
+	}
+	if updates.State != nil {
+		changes = append(changes, "state "+*updates.State)
+	}
+	if updates.Base != nil {
+		changes = append(changes, "base "+*updates.Base)
+	}
+	if updates.Draft != nil {
+		changes = append(changes, fmt.Sprintf("draft %t", *updates.Draft))
+	}
+	if updates.Labels != nil {
+		changes = append(changes, "labels ["+strings.Join(*updates.Labels, ", ")+"]")
+	}
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, ", ")
+}
+
+// summarizeText returns the first line of text, shortened for the plan
+func summarizeText(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if len(line) > 80 {
+		return line[:77] + "..."
+	}
+	return line
+}
+
+// CopyStateForDryRun copies the state files in cwDir to a new temporary
+// directory, so a dry run can advance workflows without touching the real
+// state. The caller must remove the returned directory.
+func CopyStateForDryRun(cwDir string) (string, error) {
+	scratchDir, err := os.MkdirTemp("", "cowork-dry-run-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create dry run directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(cwDir)
+	if err != nil && !os.IsNotExist(err) {
+		os.RemoveAll(scratchDir)
+		return "", fmt.Errorf("failed to read state directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || entry.Name() == StateLockFileName {
+			continue
+		}
+		if err := copyFile(filepath.Join(cwDir, entry.Name()), filepath.Join(scratchDir, entry.Name())); err != nil {
+			os.RemoveAll(scratchDir)
+			return "", fmt.Errorf("failed to copy %s: %w", entry.Name(), err)
+		}
+	}
+
+	return scratchDir, nil
+}
+
+// copyFile copies a regular file
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,262 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestDryRunProvider_RecordsMutations tests that provider changes are recorded, not made
+func TestDryRunProvider_RecordsMutations(t *testing.T) {
+	// Test case: Comments, issue and PR changes and branch deletions reach the
+	// recorder with the workflow being processed, and never the provider
+	fake := newFakeCoworkProvider()
+	recorder := NewDryRunRecorder()
+	provider := NewDryRunProvider(fake, recorder)
+	ctx := withWorkflowID(context.Background(), "3")
+
+	_, err := provider.CreateComment(ctx, "owner", "repo", 12, &git.CreateCommentRequest{Body: "On it!\nDetails follow"})
+	require.NoError(t, err)
+
+	labels := []string{"cowork", "in-progress"}
+	_, err = provider.UpdateIssue(ctx, "owner", "repo", 12, &git.UpdateIssueRequest{Labels: &labels})
+	require.NoError(t, err)
+
+	pr, err := provider.CreatePullRequest(ctx, "owner", "repo", &git.CreatePullRequestRequest{Title: "Fix #12", Head: "task-12", Base: "main"})
+	require.NoError(t, err)
+	assert.Greater(t, pr.Number, dryRunPullRequestBase)
+
+	require.NoError(t, provider.DeleteBranch(context.Background(), "owner", "repo", "task-12"))
+
+	assert.Empty(t, fake.comments)
+	assert.Empty(t, fake.deleted)
+
+	mutations := recorder.Mutations()
+	require.Len(t, mutations, 4)
+	assert.Equal(t, MutationComment, mutations[0].Kind)
+	assert.Equal(t, `comment on #12: "On it!"`, mutations[0].Description)
+	assert.Equal(t, "update issue #12 (labels [cowork, in-progress])", mutations[1].Description)
+	assert.Equal(t, MutationPullRequest, mutations[2].Kind)
+	assert.Equal(t, "owner/repo", mutations[2].Repository)
+
+	plan := recorder.Plan()
+	assert.Len(t, plan["3"], 3)
+	require.Len(t, plan[""], 1)
+	assert.Equal(t, MutationBranchDelete, plan[""][0].Kind)
+}
+
+// TestEngine_DryRun_HandlePRCompleted tests a workflow step in dry-run mode
+func TestEngine_DryRun_HandlePRCompleted(t *testing.T) {
+	// Test case: Finishing a workflow in dry-run mode plans the branch
+	// deletion for that workflow instead of deleting the branch
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 7)
+	branch := "task-7"
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch})
+	require.NoError(t, err)
+	require.NoError(t, manager.RecordCreatedBranch("owner", "repo", branch, workflow.ID))
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady,
+		types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	provider := newFakeCoworkProvider()
+	recorder := NewDryRunRecorder()
+	engine := NewEngine(manager, nil, nil, provider, "owner", "repo")
+	engine.SetDryRun(recorder)
+
+	workflow, err = manager.GetWorkflow("1")
+	require.NoError(t, err)
+	ctx := withWorkflowID(context.Background(), "1")
+	require.NoError(t, engine.handlePRCompleted(ctx, workflow, &git.PullRequest{Number: 42}))
+
+	assert.Empty(t, provider.deleted)
+	plan := recorder.Plan()
+	require.NotEmpty(t, plan["1"])
+	assert.Equal(t, MutationBranchDelete, plan["1"][len(plan["1"])-1].Kind)
+	assert.Equal(t, "delete branch task-7", plan["1"][len(plan["1"])-1].Description)
+}
+
+// TestCopyStateForDryRun tests copying the state files for a dry run
+func TestCopyStateForDryRun(t *testing.T) {
+	// Test case: State files are copied without the state lock, and changes
+	// made through the copy leave the original untouched
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+
+	scratchDir, err := CopyStateForDryRun(manager.cwDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(scratchDir) })
+
+	assert.FileExists(t, filepath.Join(scratchDir, WorkflowsFileName))
+	assert.FileExists(t, filepath.Join(scratchDir, "tasks.json"))
+	assert.NoFileExists(t, filepath.Join(scratchDir, StateLockFileName))
+
+	scratch, err := NewWorkflowManager(scratchDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { scratch.Close() })
+	transitionTestWorkflow(t, scratch, &types.Workflow{ID: 1}, types.WorkflowStateWorkspaceReady)
+
+	original := openSecondManager(t, manager)
+	workflow, err := original.GetWorkflow("1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateQueued, workflow.State)
+}
+
+// failingCoworkProvider serves reads from the fake and fails the test on any
+// change to issues, pull requests, branches or workspaces
+type failingCoworkProvider struct {
+	*fakeCoworkProvider
+	t *testing.T
+}
+
+func (p *failingCoworkProvider) fail(method string) error {
+	p.t.Errorf("%s reached the provider during a dry run", method)
+	return fmt.Errorf("%s is not allowed during a dry run", method)
+}
+
+func (p *failingCoworkProvider) CreateIssue(ctx context.Context, owner, repo string, issue *git.CreateIssueRequest) (*git.Issue, error) {
+	return nil, p.fail("CreateIssue")
+}
+
+func (p *failingCoworkProvider) UpdateIssue(ctx context.Context, owner, repo string, issueNumber int, updates *git.UpdateIssueRequest) (*git.Issue, error) {
+	return nil, p.fail("UpdateIssue")
+}
+
+func (p *failingCoworkProvider) CreatePullRequest(ctx context.Context, owner, repo string, req *git.CreatePullRequestRequest) (*git.PullRequest, error) {
+	return nil, p.fail("CreatePullRequest")
+}
+
+func (p *failingCoworkProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, req *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
+	return nil, p.fail("UpdatePullRequest")
+}
+
+func (p *failingCoworkProvider) CreateComment(ctx context.Context, owner, repo string, issueNumber int, comment *git.CreateCommentRequest) (*git.Comment, error) {
+	return nil, p.fail("CreateComment")
+}
+
+func (p *failingCoworkProvider) ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber, commentID int, body string) (*git.Comment, error) {
+	return nil, p.fail("ReplyToReviewComment")
+}
+
+func (p *failingCoworkProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	return p.fail("DeleteBranch")
+}
+
+func (p *failingCoworkProvider) CreateWorkspaceForTask(ctx context.Context, task *types.Task, owner, repo string) (*types.Workspace, error) {
+	return nil, p.fail("CreateWorkspaceForTask")
+}
+
+func (p *failingCoworkProvider) CreatePullRequestForTask(ctx context.Context, task *types.Task, owner, repo string, workspace *types.Workspace) (*git.PullRequest, error) {
+	return nil, p.fail("CreatePullRequestForTask")
+}
+
+func (p *failingCoworkProvider) LinkPullRequestToIssue(ctx context.Context, owner, repo string, pr *git.PullRequest, issue *git.Issue) error {
+	return p.fail("LinkPullRequestToIssue")
+}
+
+func (p *failingCoworkProvider) SyncTaskStatusToProvider(ctx context.Context, task *types.Task, owner, repo string) error {
+	return p.fail("SyncTaskStatusToProvider")
+}
+
+// TestDryRunProvider_NoMutationEscapes tests that no change reaches the provider
+func TestDryRunProvider_NoMutationEscapes(t *testing.T) {
+	// Test case: Every mutating method is recorded and none reaches a provider
+	// that fails the test when changed
+	fake := newFakeCoworkProvider()
+	fake.issues = map[int]*git.Issue{12: {Number: 12}}
+	recorder := NewDryRunRecorder()
+	provider := NewDryRunProvider(&failingCoworkProvider{fakeCoworkProvider: fake, t: t}, recorder)
+	ctx := context.Background()
+	task := &types.Task{ID: 3, Name: "Fix #12", TicketID: "github:owner/repo#12", BranchName: "task-12", BaseBranch: "main"}
+	title, state := "Renamed", "closed"
+
+	_, err := provider.CreateIssue(ctx, "owner", "repo", &git.CreateIssueRequest{Title: "Follow up"})
+	require.NoError(t, err)
+	_, err = provider.UpdateIssue(ctx, "owner", "repo", 12, &git.UpdateIssueRequest{Title: &title})
+	require.NoError(t, err)
+	_, err = provider.CreatePullRequest(ctx, "owner", "repo", &git.CreatePullRequestRequest{Title: "Fix #12", Head: "task-12", Base: "main"})
+	require.NoError(t, err)
+	_, err = provider.UpdatePullRequest(ctx, "owner", "repo", 42, &git.UpdatePullRequestRequest{State: &state})
+	require.NoError(t, err)
+	_, err = provider.CreateComment(ctx, "owner", "repo", 12, &git.CreateCommentRequest{Body: "On it!"})
+	require.NoError(t, err)
+	_, err = provider.ReplyToReviewComment(ctx, "owner", "repo", 42, 7, "Done.")
+	require.NoError(t, err)
+	require.NoError(t, provider.DeleteBranch(ctx, "owner", "repo", "task-12"))
+	_, err = provider.CreatePullRequestForTask(ctx, task, "owner", "repo", nil)
+	require.NoError(t, err)
+	require.NoError(t, provider.LinkPullRequestToIssue(ctx, "owner", "repo", &git.PullRequest{Number: 42}, &git.Issue{Number: 12}))
+	require.NoError(t, provider.SyncTaskStatusToProvider(ctx, task, "owner", "repo"))
+
+	_, err = provider.CreateWorkspaceForTask(ctx, task, "owner", "repo")
+	assert.ErrorIs(t, err, errDryRun)
+
+	kinds := make([]MutationKind, 0, len(recorder.Mutations()))
+	for _, mutation := range recorder.Mutations() {
+		kinds = append(kinds, mutation.Kind)
+	}
+	assert.Equal(t, []MutationKind{
+		MutationIssueCreate, MutationIssueUpdate, MutationPullRequest, MutationPRUpdate, MutationComment, MutationComment,
+		MutationBranchDelete, MutationPullRequest, MutationComment, MutationComment, MutationStatusSync, MutationWorkspace,
+	}, kinds)
+}
+
+// TestEngine_DryRun_LeavesAgentsAlone tests that a dry run neither runs nor stops agents
+func TestEngine_DryRun_LeavesAgentsAlone(t *testing.T) {
+	// Test case: A job timeout and a plan-first workflow are recorded without
+	// stopping a container, running the agent, failing the task or touching
+	// the provider
+	manager := newTestWorkflowManager(t)
+	timedOut := newImplementingWorkflow(t, manager, 3*time.Hour)
+
+	planned := createTestWorkflow(t, manager, 8)
+	planned.Config.PlanFirst = true
+	taskID, workspaceID := 4, 6
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: planned.ID, TaskID: &taskID, WorkspaceID: &workspaceID})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, planned, types.WorkflowStateWorkspaceReady)
+
+	provider := newFakeCoworkProvider()
+	provider.issues = map[int]*git.Issue{8: {Number: 8, Title: "Plan the widget"}}
+	tasks := newFakeTaskManager(
+		&types.Task{ID: 3, Status: types.TaskStatusInProgress},
+		&types.Task{ID: 4, Status: types.TaskStatusQueued},
+	)
+	workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 6, Path: t.TempDir()})
+	runner := &fakeAgentRunner{output: "<plan>- Touch widget.go</plan>"}
+	recorder := NewDryRunRecorder()
+	engine := NewEngine(manager, tasks, workspaces, &failingCoworkProvider{fakeCoworkProvider: provider, t: t}, "owner", "repo")
+	engine.SetAgentRunner(runner)
+	engine.SetDryRun(recorder)
+
+	require.NoError(t, engine.HandleJobTimeout(context.Background(), timedOut))
+	engine.stopAgent(context.Background(), timedOut)
+	require.NoError(t, engine.proposePlan(context.Background(), planned, tasks.tasks[4]))
+
+	assert.Empty(t, workspaces.stopped)
+	assert.Empty(t, runner.instructions)
+	assert.Equal(t, types.TaskStatusInProgress, tasks.tasks[3].Status)
+	assert.Empty(t, tasks.tasks[4].Metadata[MetadataImplementationPlan])
+
+	timedOut, err = manager.GetWorkflow(fmt.Sprintf("%d", timedOut.ID))
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, timedOut.State)
+	planned, err = manager.GetWorkflow(fmt.Sprintf("%d", planned.ID))
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateWorkspaceReady, planned.State)
+
+	mutations := recorder.Mutations()
+	require.Len(t, mutations, 3)
+	assert.Equal(t, MutationJobTimeout, mutations[0].Kind)
+	assert.Equal(t, MutationAgentStop, mutations[1].Kind)
+	assert.Equal(t, MutationAgentRun, mutations[2].Kind)
+	assert.Equal(t, "run the agent for implementation_plan on task 4", mutations[2].Description)
+}
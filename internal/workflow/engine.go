@@ -2,19 +2,26 @@ package workflow
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/hlfshell/cowork/internal/branchname"
 	"github.com/hlfshell/cowork/internal/git"
 	"github.com/hlfshell/cowork/internal/task"
 	"github.com/hlfshell/cowork/internal/types"
 	"github.com/hlfshell/cowork/internal/workspace"
 )
 
+// containerStopTimeoutSeconds is how long an agent container gets to shut
+// down before it is killed
+const containerStopTimeoutSeconds = 30
+
 // Engine orchestrates the complete auto-PR workflow
 type Engine struct {
 	workflowManager  *WorkflowManager
@@ -24,24 +31,76 @@ type Engine struct {
 	owner            string
 	repo             string
 	processID        string
+
+	// Classifies PR feedback; defaults to the built-in rules
+	feedbackClassifier FeedbackClassifier
+
+	// Answers reviewer questions; questions are only logged when unset
+	agentRunner AgentRunner
+
+	// Reviews branches before their PR is opened; falls back to agentRunner
+	reviewRunner AgentRunner
+
+	// How often the lease of a held workflow lock is renewed
+	lockHeartbeat time.Duration
+
+	// Records pushes and provider changes instead of making them when set
+	dryRun *DryRunRecorder
+
+	// Template for PR bodies; empty uses prbody.DefaultTemplate
+	prTemplate string
 }
 
 // NewEngine creates a new workflow engine
 func NewEngine(workflowManager *WorkflowManager, taskManager task.TaskManager, workspaceManager workspace.WorkspaceManager, coworkProvider git.CoworkProvider, owner, repo string) *Engine {
+	// The built-in rules always compile
+	classifier, _ := NewRulesFeedbackClassifier(DefaultFeedbackRules())
+
 	return &Engine{
-		workflowManager:  workflowManager,
-		taskManager:      taskManager,
-		workspaceManager: workspaceManager,
-		coworkProvider:   coworkProvider,
-		owner:            owner,
-		repo:             repo,
-		processID:        fmt.Sprintf("engine-%d", os.Getpid()),
+		workflowManager:    workflowManager,
+		taskManager:        taskManager,
+		workspaceManager:   workspaceManager,
+		coworkProvider:     coworkProvider,
+		owner:              owner,
+		repo:               repo,
+		processID:          fmt.Sprintf("engine-%d", os.Getpid()),
+		feedbackClassifier: classifier,
+		lockHeartbeat:      LockHeartbeatInterval,
 	}
 }
 
+// SetFeedbackClassifier replaces the classifier used for PR feedback
+func (e *Engine) SetFeedbackClassifier(classifier FeedbackClassifier) {
+	e.feedbackClassifier = classifier
+}
+
+// SetDryRun makes the engine record every push and provider change in
+// recorder instead of making it
+func (e *Engine) SetDryRun(recorder *DryRunRecorder) {
+	e.dryRun = recorder
+	e.coworkProvider = NewDryRunProvider(e.coworkProvider, recorder)
+}
+
+// SetPRTemplate sets the template used for the bodies of draft PRs and of
+// drafts promoted to ready for review
+func (e *Engine) SetPRTemplate(template string) {
+	e.prTemplate = template
+}
+
+// SetAgentRunner sets the runner used to answer reviewer questions
+func (e *Engine) SetAgentRunner(runner AgentRunner) {
+	e.agentRunner = runner
+}
+
+// SetReviewRunner sets the runner used to review branches before their PR is
+// opened, so the reviewer can use a different agent or model
+func (e *Engine) SetReviewRunner(runner AgentRunner) {
+	e.reviewRunner = runner
+}
+
 // ProcessWorkflow processes a workflow through its complete lifecycle
 func (e *Engine) ProcessWorkflow(ctx context.Context, workflowID string) error {
-	log.Printf("🚀 Starting workflow processing for %s", workflowID)
+	ctx = withWorkflowID(ctx, workflowID)
 
 	// Get the workflow
 	workflow, err := e.workflowManager.GetWorkflow(workflowID)
@@ -49,35 +108,132 @@ func (e *Engine) ProcessWorkflow(ctx context.Context, workflowID string) error {
 		return fmt.Errorf("failed to get workflow: %w", err)
 	}
 
+	if workflow.State.IsTerminal() {
+		return fmt.Errorf("workflow %s is in terminal state %s", workflowID, workflow.State)
+	}
+
+	if workflow.State == types.WorkflowStatePaused {
+		log.Printf("⏸️  Workflow %s is paused", workflowID)
+		return nil
+	}
+
+	// Wait out the backoff after a failed attempt
+	if workflow.NextRetryAt != nil && time.Now().Before(*workflow.NextRetryAt) {
+		log.Printf("⏳ Workflow %s is backing off until %s", workflowID, workflow.NextRetryAt.Format(time.RFC3339))
+		return nil
+	}
+
+	log.Printf("🚀 Starting workflow processing for %s", workflowID)
+
 	// Try to acquire lock
-	lock, err := e.workflowManager.LockWorkflow(workflowID, e.processID, DefaultLockTimeout)
+	lock, release, err := e.lockWorkflow(workflowID)
 	if err != nil {
 		return fmt.Errorf("failed to acquire workflow lock: %w", err)
 	}
 
 	// Ensure we release the lock when done
-	defer func() {
+	defer release()
+
+	log.Printf("🔒 Acquired lock for workflow %s (lease until: %s)", workflowID, lock.LockTimeout.Format(time.RFC3339))
+
+	// Another cw process may have advanced the workflow before we got the lock
+	workflow, err = e.workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow: %w", err)
+	}
+	if workflow.State.IsTerminal() || workflow.State == types.WorkflowStatePaused {
+		return nil
+	}
+
+	// Someone may have opted the issue out of automation since the last step
+	label, number, err := e.findDisableLabel(ctx, workflow)
+	if err != nil {
+		return e.recordFailure(workflowID, err)
+	}
+	if label != "" {
+		return e.abortDisabledWorkflow(ctx, workflow, label, number)
+	}
+
+	if err := e.processState(ctx, workflow); err != nil {
+		return e.recordFailure(workflowID, err)
+	}
+
+	return e.clearFailures(workflowID)
+}
+
+// lockWorkflow acquires a workflow lock on a short lease and keeps renewing
+// it in the background until the returned release function is called, so long
+// steps keep the lock while a crashed process loses it quickly
+func (e *Engine) lockWorkflow(workflowID string) (*types.WorkflowLock, func(), error) {
+	lock, err := e.workflowManager.LockWorkflow(workflowID, e.processID, DefaultLockLease)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+
+		ticker := time.NewTicker(e.lockHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := e.workflowManager.RenewWorkflowLock(workflowID, e.processID, DefaultLockLease); err != nil {
+					log.Printf("⚠️  Failed to renew lock for workflow %s: %v", workflowID, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	release := func() {
+		close(done)
+		<-stopped
+
 		if err := e.workflowManager.UnlockWorkflow(workflowID, e.processID); err != nil {
 			log.Printf("⚠️  Failed to release workflow lock: %v", err)
 		}
-	}()
+	}
+
+	return lock, release, nil
+}
 
-	log.Printf("🔒 Acquired lock for workflow %s (timeout: %s)", workflowID, lock.LockTimeout.Format(time.RFC3339))
+// reloadWorkflow reads the stored workflow again; the manager hands out
+// copies, so changes made through it since the workflow was read are only
+// seen this way
+func (e *Engine) reloadWorkflow(workflow *types.Workflow) (*types.Workflow, error) {
+	current, err := e.workflowManager.GetWorkflow(fmt.Sprintf("%d", workflow.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+	return current, nil
+}
 
-	// Process workflow based on current state
+// processState advances the workflow by one step based on its current state
+func (e *Engine) processState(ctx context.Context, workflow *types.Workflow) error {
 	switch workflow.State {
 	case types.WorkflowStateQueued:
 		return e.processQueuedWorkflow(ctx, workflow)
+	case types.WorkflowStateAwaitingClarification:
+		return e.processAwaitingClarificationWorkflow(ctx, workflow)
 	case types.WorkflowStateWorkspaceReady:
 		return e.processWorkspaceReadyWorkflow(ctx, workflow)
+	case types.WorkflowStatePlanReview:
+		return e.processPlanReviewWorkflow(ctx, workflow)
 	case types.WorkflowStateImplementing:
 		return e.processImplementingWorkflow(ctx, workflow)
+	case types.WorkflowStateReviewing:
+		return e.processReviewingWorkflow(ctx, workflow)
 	case types.WorkflowStatePROpen:
 		return e.processPROpenWorkflow(ctx, workflow)
 	case types.WorkflowStateRevising:
 		return e.processRevisingWorkflow(ctx, workflow)
 	default:
-		return fmt.Errorf("workflow %s is in terminal state %s", workflowID, workflow.State)
+		return fmt.Errorf("workflow %d is in unsupported state %s", workflow.ID, workflow.State)
 	}
 }
 
@@ -91,12 +247,38 @@ func (e *Engine) processQueuedWorkflow(ctx context.Context, workflow *types.Work
 		return fmt.Errorf("failed to get issue: %w", err)
 	}
 
+	// Issues wait until the issues they depend on are done
+	ready, err := e.resolveDependencies(ctx, workflow, issue)
+	if err != nil {
+		return err
+	}
+	if !ready {
+		return nil
+	}
+
+	// Vague issues wait for their author to clarify them
+	ready, err = e.triageIssue(ctx, workflow, issue)
+	if err != nil || !ready {
+		return err
+	}
+
 	// Create or get the associated task
 	task, err := e.createOrGetTask(workflow, issue)
 	if err != nil {
 		return fmt.Errorf("failed to create/get task: %w", err)
 	}
 
+	task, err = e.applyClarifications(workflow, task)
+	if err != nil {
+		return err
+	}
+
+	// Gating may have stacked the workflow on another branch
+	workflow, err = e.reloadWorkflow(workflow)
+	if err != nil {
+		return err
+	}
+
 	// Update workflow with task ID
 	updateReq := &types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
@@ -108,7 +290,7 @@ func (e *Engine) processQueuedWorkflow(ctx context.Context, workflow *types.Work
 	}
 
 	// Create workspace
-	workspace, err := e.createWorkspace(workflow, issue)
+	workspace, err := e.createWorkspace(ctx, workflow, issue)
 	if err != nil {
 		return fmt.Errorf("failed to create workspace: %w", err)
 	}
@@ -129,6 +311,8 @@ func (e *Engine) processQueuedWorkflow(ctx context.Context, workflow *types.Work
 	updateReq = &types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
 		State:      &state,
+		Actor:      e.processID,
+		Reason:     "workspace created",
 	}
 	_, err = e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
@@ -149,6 +333,16 @@ func (e *Engine) processWorkspaceReadyWorkflow(ctx context.Context, workflow *ty
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
+	// In plan-first mode the agent plans before it changes anything
+	if needsPlanApproval(workflow, task) {
+		return e.proposePlan(ctx, workflow, task)
+	}
+
+	return e.startImplementation(workflow, task, "task started")
+}
+
+// startImplementation starts the task and moves the workflow to IMPLEMENTING
+func (e *Engine) startImplementation(workflow *types.Workflow, task *types.Task, reason string) error {
 	// If task is not in progress, start it
 	if task.Status != types.TaskStatusInProgress {
 		// Update task status to in progress
@@ -157,7 +351,7 @@ func (e *Engine) processWorkspaceReadyWorkflow(ctx context.Context, workflow *ty
 			TaskID: task.ID,
 			Status: &taskStatus,
 		}
-		_, err = e.taskManager.UpdateTask(updateReq)
+		_, err := e.taskManager.UpdateTask(updateReq)
 		if err != nil {
 			return fmt.Errorf("failed to update task status: %w", err)
 		}
@@ -168,8 +362,10 @@ func (e *Engine) processWorkspaceReadyWorkflow(ctx context.Context, workflow *ty
 	updateReq := &types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
 		State:      &state,
+		Actor:      e.processID,
+		Reason:     reason,
 	}
-	_, err = e.workflowManager.UpdateWorkflow(updateReq)
+	_, err := e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
 		return fmt.Errorf("failed to transition workflow to implementing: %w", err)
 	}
@@ -188,8 +384,11 @@ func (e *Engine) processImplementingWorkflow(ctx context.Context, workflow *type
 		return fmt.Errorf("failed to get task: %w", err)
 	}
 
-	// If task is completed, create PR
+	// If task is completed, review the branch or create PR
 	if task.Status == types.TaskStatusCompleted {
+		if needsSelfReview(workflow) {
+			return e.startSelfReview(workflow, task)
+		}
 		return e.createPullRequest(ctx, workflow, task)
 	}
 
@@ -198,6 +397,11 @@ func (e *Engine) processImplementingWorkflow(ctx context.Context, workflow *type
 		return e.handleTaskFailure(ctx, workflow, task)
 	}
 
+	// Let reviewers follow the work in a draft PR
+	if workflow.Config.DraftPullRequests && workflow.PRNumber == nil {
+		return e.openDraftPullRequest(ctx, workflow, task)
+	}
+
 	// Task is still in progress, nothing to do
 	log.Printf("⏳ Task %d is still in progress, waiting for completion", task.ID)
 	return nil
@@ -228,25 +432,57 @@ func (e *Engine) processPROpenWorkflow(ctx context.Context, workflow *types.Work
 		return e.handlePRCompleted(ctx, workflow, pr)
 	}
 
+	// Move the rest of a stack onto the base branch as its lower PRs merge
+	if completed, err := e.advanceStack(ctx, workflow); err != nil || completed {
+		return err
+	}
+
+	// Retries and the checks state touch the workflow, so feedback has a
+	// cursor of its own that only moves once feedback is handled
+	since := workflow.FeedbackSince()
+	fetchedAt := time.Now()
+
+	// Failed required checks are blocking feedback
+	checkFeedback, err := e.checkRequiredChecks(ctx, workflow, pr)
+	if err != nil {
+		return fmt.Errorf("failed to check required checks: %w", err)
+	}
+
+	// A draft opened while the agent worked is ready once the checks pass
+	if err := e.promoteDraftPullRequest(ctx, workflow, pr); err != nil {
+		return err
+	}
+
 	// Check for new feedback
-	updates, err := e.coworkProvider.GetPullRequestUpdates(ctx, e.owner, e.repo, pr.Number, workflow.LastEventTS)
+	updates, err := e.coworkProvider.GetPullRequestUpdates(ctx, e.owner, e.repo, pr.Number, since)
 	if err != nil {
 		return fmt.Errorf("failed to get PR updates: %w", err)
 	}
+	partUpdates, err := e.stackUpdates(ctx, workflow, since)
+	if err != nil {
+		return err
+	}
 
 	// If there are updates, handle them
-	if len(updates.NewComments) > 0 || len(updates.NewReviews) > 0 {
-		return e.handlePRFeedback(ctx, workflow, pr, updates)
+	items := FeedbackItemsFromUpdate(append([]*git.PullRequestUpdate{updates}, partUpdates...)...)
+	if len(items) > 0 || len(checkFeedback) > 0 {
+		return e.handlePRFeedback(ctx, workflow, pr, items, checkFeedback, fetchedAt)
 	}
 
 	log.Printf("⏳ No updates for PR #%d, waiting for feedback", pr.Number)
 	return nil
 }
 
-// processRevisingWorkflow handles workflows in REVISING state
+// processRevisingWorkflow handles workflows in REVISING state. The workflow
+// stays in REVISING while the agent works, so the job timeout covers it.
 func (e *Engine) processRevisingWorkflow(ctx context.Context, workflow *types.Workflow) error {
 	log.Printf("🔄 Processing revising workflow %d", workflow.ID)
 
+	// The agent is already working on the revision
+	if workflow.Metadata[MetadataRevisionStartedAt] != "" {
+		return e.finishRevision(ctx, workflow)
+	}
+
 	// Get the workspace
 	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
 	if err != nil {
@@ -254,34 +490,103 @@ func (e *Engine) processRevisingWorkflow(ctx context.Context, workflow *types.Wo
 	}
 
 	// Sync with base branch
-	err = e.syncWithBaseBranch(workspace.Path, workflow.BaseBranch)
+	err = e.syncWithBaseBranch(ctx, workflow, workspace.Path)
 	if err != nil {
 		return fmt.Errorf("failed to sync with base branch: %w", err)
 	}
 
-	// Update task to trigger agent work
+	// Update task to trigger agent work, handing over the review feedback
+	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+
+	taskMetadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		taskMetadata[key] = value
+	}
+	if instruction := workflow.Metadata[MetadataRevisionInstruction]; instruction != "" {
+		taskMetadata[MetadataRevisionInstruction] = instruction
+	}
+
 	taskStatus := types.TaskStatusInProgress
 	updateReq := &types.UpdateTaskRequest{
-		TaskID: workflow.TaskID,
-		Status: &taskStatus,
+		TaskID:   workflow.TaskID,
+		Status:   &taskStatus,
+		Metadata: &taskMetadata,
 	}
 	_, err = e.taskManager.UpdateTask(updateReq)
 	if err != nil {
 		return fmt.Errorf("failed to update task status: %w", err)
 	}
 
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		Metadata:   map[string]string{MetadataRevisionStartedAt: time.Now().UTC().Format(time.RFC3339)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record revision start: %w", err)
+	}
+
+	log.Printf("✅ Revision of workflow %d handed to the agent", workflow.ID)
+	return nil
+}
+
+// finishRevision waits for the agent to finish a revision, then pushes it and
+// moves the workflow back to PR_OPEN
+func (e *Engine) finishRevision(ctx context.Context, workflow *types.Workflow) error {
+	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+
+	switch task.Status {
+	case types.TaskStatusCompleted:
+	case types.TaskStatusFailed:
+		return e.handleTaskFailure(ctx, workflow, task)
+	default:
+		log.Printf("⏳ Revision of workflow %d is still in progress", workflow.ID)
+		return nil
+	}
+
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	// Feedback from lower parts of the stack is told what changed for it
+	partFeedback, err := RevisionStackFeedback(workflow)
+	if err != nil {
+		log.Printf("⚠️  Discarding unreadable stack feedback for workflow %d: %v", workflow.ID, err)
+	}
+	var commits string
+	if len(partFeedback) > 0 {
+		commits, err = gitOutput(workspace.Path, "log", "--format=- %s", fmt.Sprintf("origin/%s..HEAD", workflow.BranchName))
+		if err != nil {
+			log.Printf("⚠️  Could not list the commits of the revision of workflow %d: %v", workflow.ID, err)
+		}
+	}
+
+	if err := e.pushBranch(ctx, workspace.Path, workflow.BranchName); err != nil {
+		return err
+	}
+
 	// Transition back to PR_OPEN
 	state := types.WorkflowStatePROpen
-	workflowUpdateReq := &types.UpdateWorkflowRequest{
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
 		State:      &state,
-	}
-	_, err = e.workflowManager.UpdateWorkflow(workflowUpdateReq)
+		Metadata:   map[string]string{MetadataRevisionStartedAt: "", MetadataRevisionStackFeedback: ""},
+		Actor:      e.processID,
+		Reason:     "revision pushed",
+	})
 	if err != nil {
 		return fmt.Errorf("failed to transition workflow to pr_open: %w", err)
 	}
 
-	log.Printf("✅ Workflow %d transitioned back to pr_open for revision", workflow.ID)
+	e.postStackRevisionSummaries(ctx, workflow, partFeedback, commits)
+
+	log.Printf("✅ Workflow %d transitioned back to pr_open after revision", workflow.ID)
 	return nil
 }
 
@@ -306,19 +611,45 @@ func (e *Engine) createOrGetTask(workflow *types.Workflow, issue *git.Issue) (*t
 }
 
 // createWorkspace creates a workspace for the workflow
-func (e *Engine) createWorkspace(workflow *types.Workflow, issue *git.Issue) (*types.Workspace, error) {
-	// Generate branch name
-	branchName := e.coworkProvider.GenerateBranchName(issue)
-	workflow.BranchName = branchName
-
+func (e *Engine) createWorkspace(ctx context.Context, workflow *types.Workflow, issue *git.Issue) (*types.Workspace, error) {
 	// Get the task to pass to the provider
 	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get task: %w", err)
 	}
 
+	// Keep the branch from an earlier attempt so retries reuse it
+	branchName := workflow.BranchName
+	if branchName == "" {
+		branchName = task.BranchName
+	}
+	if branchName == "" {
+		branchName, err = e.generateBranchName(ctx, workflow, issue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate branch name: %w", err)
+		}
+
+		// Only branches cowork named itself are ever deleted by it
+		err = e.workflowManager.RecordCreatedBranch(e.owner, e.repo, branchName, workflow.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record created branch: %w", err)
+		}
+	}
+	workflow.BranchName = branchName
+
+	// The provider creates the workspace on the task's branch
+	if task.BranchName != branchName {
+		task, err = e.taskManager.UpdateTask(&types.UpdateTaskRequest{
+			TaskID:     task.ID,
+			BranchName: &branchName,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update task branch name: %w", err)
+		}
+	}
+
 	// Create workspace
-	workspace, err := e.coworkProvider.CreateWorkspaceForTask(context.Background(), task, e.owner, e.repo)
+	workspace, err := e.coworkProvider.CreateWorkspaceForTask(ctx, task, e.owner, e.repo)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create workspace: %w", err)
 	}
@@ -332,54 +663,62 @@ func (e *Engine) createWorkspace(workflow *types.Workflow, issue *git.Issue) (*t
 	return workspace, nil
 }
 
-// setupBranch sets up the feature branch for the workflow
-func (e *Engine) setupBranch(workspacePath, baseBranch, branchName string) error {
-	// Change to workspace directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-	defer os.Chdir(originalDir)
+// generateBranchName renders the workflow's branch naming template for the
+// issue and picks a name that is not taken on the remote yet
+func (e *Engine) generateBranchName(ctx context.Context, workflow *types.Workflow, issue *git.Issue) (string, error) {
+	branchName := e.coworkProvider.GenerateBranchName(issue)
+
+	if template := workflow.Config.BranchNamingTemplate; template != "" {
+		data := git.NewBranchNameData(issue)
+		data.TaskID = workflow.TaskID
+		data.SlugLength = branchname.DefaultSlugLength
 
-	if err := os.Chdir(workspacePath); err != nil {
-		return fmt.Errorf("failed to change to workspace directory: %w", err)
+		rendered, err := branchname.Render(template, data)
+		if err != nil {
+			return "", err
+		}
+		branchName = rendered
 	}
 
+	return branchname.Resolve(branchName, git.RemoteBranchExists(ctx, e.coworkProvider, e.owner, e.repo))
+}
+
+// setupBranch sets up the feature branch for the workflow
+func (e *Engine) setupBranch(workspacePath, baseBranch, branchName string) error {
 	// Fetch latest changes
-	cmd := exec.Command("git", "fetch", "origin")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runGit(workspacePath, "fetch", "origin"); err != nil {
 		return fmt.Errorf("failed to fetch origin: %w", err)
 	}
 
 	// Checkout base branch
-	cmd = exec.Command("git", "checkout", baseBranch)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runGit(workspacePath, "checkout", baseBranch); err != nil {
 		return fmt.Errorf("failed to checkout base branch: %w", err)
 	}
 
 	// Pull latest changes
-	cmd = exec.Command("git", "pull", "origin", baseBranch)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runGit(workspacePath, "pull", "origin", baseBranch); err != nil {
 		return fmt.Errorf("failed to pull latest changes: %w", err)
 	}
 
-	// Create and checkout feature branch
-	cmd = exec.Command("git", "checkout", "-b", branchName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	// Create and checkout feature branch; the workspace clone may already
+	// have created it
+	if err := runGit(workspacePath, "checkout", "-B", branchName); err != nil {
 		return fmt.Errorf("failed to create feature branch: %w", err)
 	}
 
 	return nil
 }
 
+// runGit runs a git command in the given directory. Workflows are processed
+// concurrently, so commands must never rely on the process working directory.
+func runGit(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
 // createPullRequest creates a pull request for the completed task
 func (e *Engine) createPullRequest(ctx context.Context, workflow *types.Workflow, task *types.Task) error {
 	log.Printf("📝 Creating pull request for workflow %d", workflow.ID)
@@ -390,14 +729,33 @@ func (e *Engine) createPullRequest(ctx context.Context, workflow *types.Workflow
 		return fmt.Errorf("failed to get workspace: %w", err)
 	}
 
+	// Record how the branch verifies for the PR body
+	if err := e.recordVerification(ctx, workflow, task, workspace.Path); err != nil {
+		return err
+	}
+
+	// Large changes are reviewed as a stack of smaller PRs
+	if err := e.prepareStack(ctx, workflow, workspace.Path); err != nil {
+		return err
+	}
+	if workflow, err = e.reloadWorkflow(workflow); err != nil {
+		return err
+	}
+	if err := e.openStackParts(ctx, workflow, workspace.Path); err != nil {
+		return err
+	}
+	if workflow, err = e.reloadWorkflow(workflow); err != nil {
+		return err
+	}
+
 	// Push the branch
-	err = e.pushBranch(workspace.Path, workflow.BranchName)
+	err = e.pushBranch(ctx, workspace.Path, workflow.BranchName)
 	if err != nil {
 		return fmt.Errorf("failed to push branch: %w", err)
 	}
 
-	// Create pull request
-	pr, err := e.coworkProvider.CreatePullRequestForTask(ctx, task, e.owner, e.repo, workspace)
+	// Create pull request, unless a draft is already open
+	pr, err := e.openPullRequest(ctx, workflow, task, workspace, workflow.Config.DraftPullRequests)
 	if err != nil {
 		return fmt.Errorf("failed to create pull request: %w", err)
 	}
@@ -407,16 +765,25 @@ func (e *Engine) createPullRequest(ctx context.Context, workflow *types.Workflow
 		WorkflowID: workflow.ID,
 		PRNumber:   &pr.Number,
 	}
+	if workflow.PRNumber == nil && workflow.Config.DraftPullRequests {
+		updateReq.Metadata = map[string]string{MetadataDraftPR: strconv.FormatBool(pr.Draft)}
+	}
 	_, err = e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
 		return fmt.Errorf("failed to update workflow with PR number: %w", err)
 	}
 
+	if err := e.finishStack(ctx, workflow, pr); err != nil {
+		return err
+	}
+
 	// Transition to PR_OPEN
 	state := types.WorkflowStatePROpen
 	updateReq = &types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
 		State:      &state,
+		Actor:      e.processID,
+		Reason:     fmt.Sprintf("opened PR #%d", pr.Number),
 	}
 	_, err = e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
@@ -428,23 +795,13 @@ func (e *Engine) createPullRequest(ctx context.Context, workflow *types.Workflow
 }
 
 // pushBranch pushes the feature branch to origin
-func (e *Engine) pushBranch(workspacePath, branchName string) error {
-	// Change to workspace directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(workspacePath); err != nil {
-		return fmt.Errorf("failed to change to workspace directory: %w", err)
+func (e *Engine) pushBranch(ctx context.Context, workspacePath, branchName string) error {
+	if e.dryRun != nil {
+		e.dryRun.Record(ctx, MutationPush, RepositoryKey(e.owner, e.repo), "push branch %s", branchName)
+		return nil
 	}
 
-	// Push branch to origin
-	cmd := exec.Command("git", "push", "-u", "origin", branchName)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runGit(workspacePath, "push", "-u", "origin", branchName); err != nil {
 		return fmt.Errorf("failed to push branch: %w", err)
 	}
 
@@ -461,6 +818,8 @@ func (e *Engine) handleTaskFailure(ctx context.Context, workflow *types.Workflow
 		WorkflowID: workflow.ID,
 		State:      &state,
 		LastError:  &task.ErrorMessage,
+		Actor:      e.processID,
+		Reason:     "task failed",
 	}
 	_, err := e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
@@ -488,6 +847,8 @@ func (e *Engine) handlePRCompleted(ctx context.Context, workflow *types.Workflow
 	updateReq := &types.UpdateWorkflowRequest{
 		WorkflowID: workflow.ID,
 		State:      &state,
+		Actor:      e.processID,
+		Reason:     fmt.Sprintf("PR #%d %s", pr.Number, state),
 	}
 	_, err := e.workflowManager.UpdateWorkflow(updateReq)
 	if err != nil {
@@ -500,121 +861,134 @@ func (e *Engine) handlePRCompleted(ctx context.Context, workflow *types.Workflow
 		if err != nil {
 			log.Printf("⚠️  Failed to cleanup workspace: %v", err)
 		}
+
+		// Workflows stacked on this one leave its branch before it is deleted
+		err = e.retargetDependents(ctx, workflow)
+		if err != nil {
+			log.Printf("⚠️  Failed to retarget stacked workflows: %v", err)
+		}
+	}
+
+	// The branch is no longer needed either way
+	err = e.deleteRemoteBranch(ctx, workflow)
+	if err != nil {
+		log.Printf("⚠️  Failed to delete remote branch: %v", err)
 	}
 
 	log.Printf("✅ Workflow %d completed with state %s", workflow.ID, state)
 	return nil
 }
 
-// handlePRFeedback handles feedback on a pull request
-func (e *Engine) handlePRFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, updates *git.PullRequestUpdate) error {
+// handlePRFeedback handles feedback on a pull request fetched at fetchedAt,
+// moving the feedback cursor there once it is handled
+func (e *Engine) handlePRFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, items []FeedbackItem, checkFeedback []ClassifiedFeedback, fetchedAt time.Time) error {
 	log.Printf("💬 Handling PR feedback for workflow %d", workflow.ID)
 
-	// Classify feedback intent
-	intent := e.classifyFeedbackIntent(updates)
-
-	switch intent {
-	case types.FeedbackIntentAsk:
-		// Handle questions/clarifications
-		return e.handleAskFeedback(ctx, workflow, pr, updates)
-	case types.FeedbackIntentChange, types.FeedbackIntentBlocker:
-		// Handle requested changes
-		return e.handleChangeFeedback(ctx, workflow, pr, updates)
-	default:
-		log.Printf("ℹ️  No actionable feedback found for workflow %d", workflow.ID)
-		return nil
+	// Classify each comment and review on its own
+	classified, err := e.feedbackClassifier.Classify(ctx, workflow, items)
+	if err != nil {
+		return fmt.Errorf("failed to classify feedback: %w", err)
 	}
-}
 
-// classifyFeedbackIntent classifies the intent of feedback
-func (e *Engine) classifyFeedbackIntent(updates *git.PullRequestUpdate) types.FeedbackIntent {
-	// Check for blocker conditions first
-	for _, review := range updates.NewReviews {
-		if review.State == "changes_requested" {
-			return types.FeedbackIntentBlocker
+	for _, feedback := range classified {
+		if feedback.Ignored {
+			log.Printf("🙈 Ignoring %s %d from %s: %s", feedback.Item.Kind, feedback.Item.ID, feedback.Item.Author, feedback.Reason)
+			continue
 		}
+		log.Printf("🏷️  Classified %s %d from %s as %s", feedback.Item.Kind, feedback.Item.ID, feedback.Item.Author, feedback.Intent)
 	}
+	classified = append(classified, checkFeedback...)
 
-	// Check for change requests in comments
-	for _, comment := range updates.NewComments {
-		body := strings.ToLower(comment.Body)
-		if strings.Contains(body, "change") || strings.Contains(body, "fix") || strings.Contains(body, "update") {
-			return types.FeedbackIntentChange
+	groups := FeedbackByIntent(classified)
+	if questions := groups[types.FeedbackIntentAsk]; len(questions) > 0 {
+		if err := e.handleAskFeedback(ctx, workflow, pr, questions); err != nil {
+			return err
 		}
 	}
 
-	// Default to ask for questions/clarifications
-	return types.FeedbackIntentAsk
+	intent, ok := highestIntent(classified)
+	if ok && intent != types.FeedbackIntentAsk {
+		return e.handleChangeFeedback(ctx, workflow, pr, classified, fetchedAt)
+	}
+
+	if !ok {
+		log.Printf("ℹ️  No actionable feedback found for workflow %d", workflow.ID)
+	}
+
+	// Move the cursor so the same feedback is not fetched again
+	if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, LastFeedbackTS: &fetchedAt}); err != nil {
+		return fmt.Errorf("failed to mark feedback as seen: %w", err)
+	}
+
+	return nil
 }
 
 // handleAskFeedback handles questions and clarifications
-func (e *Engine) handleAskFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, updates *git.PullRequestUpdate) error {
+func (e *Engine) handleAskFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, questions []ClassifiedFeedback) error {
 	log.Printf("❓ Handling ask feedback for workflow %d", workflow.ID)
 
-	// For now, just log the questions
-	// In the future, this could trigger agent responses
-	for _, comment := range updates.NewComments {
-		log.Printf("Question on PR #%d: %s", pr.Number, comment.Body)
+	if e.agentRunner == nil {
+		for _, question := range questions {
+			log.Printf("Question on PR #%d from %s: %s", pr.Number, question.Item.Author, question.Item.Body)
+		}
+		return nil
 	}
 
-	return nil
+	return e.answerQuestions(ctx, workflow, pr, questions)
 }
 
-// handleChangeFeedback handles requested changes
-func (e *Engine) handleChangeFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, updates *git.PullRequestUpdate) error {
+// handleChangeFeedback handles requested changes from feedback fetched at fetchedAt
+func (e *Engine) handleChangeFeedback(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, classified []ClassifiedFeedback, fetchedAt time.Time) error {
 	log.Printf("🔧 Handling change feedback for workflow %d", workflow.ID)
 
-	// Transition to REVISING
-	state := types.WorkflowStateRevising
-	updateReq := &types.UpdateWorkflowRequest{
-		WorkflowID: workflow.ID,
-		State:      &state,
-	}
-	_, err := e.workflowManager.UpdateWorkflow(updateReq)
+	instruction := BuildRevisionInstruction(classified)
+
+	// Pick up answers and check results recorded while handling this feedback
+	workflow, err := e.reloadWorkflow(workflow)
 	if err != nil {
-		return fmt.Errorf("failed to transition workflow to revising: %w", err)
+		return err
 	}
 
-	log.Printf("✅ Workflow %d transitioned to revising for feedback", workflow.ID)
-	return nil
-}
-
-// syncWithBaseBranch syncs the feature branch with the base branch
-func (e *Engine) syncWithBaseBranch(workspacePath, baseBranch string) error {
-	// Change to workspace directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+	// Keep earlier answers in view so the revision does not contradict them
+	if exchanges, err := FeedbackExchanges(workflow); err == nil && len(exchanges) > 0 {
+		var sb strings.Builder
+		sb.WriteString(instruction)
+		sb.WriteString("\n## Earlier discussion on this pull request\n\n")
+		writeExchangeHistory(&sb, exchanges)
+		instruction = sb.String()
 	}
-	defer os.Chdir(originalDir)
 
-	if err := os.Chdir(workspacePath); err != nil {
-		return fmt.Errorf("failed to change to workspace directory: %w", err)
+	// Transition to REVISING, keeping the merged feedback for the agent and
+	// the feedback to report back to lower parts of the stack
+	metadata := map[string]string{MetadataRevisionInstruction: instruction, MetadataRevisionStackFeedback: ""}
+	if partFeedback := stackPartFeedback(pr, classified); len(partFeedback) > 0 {
+		encoded, err := json.Marshal(partFeedback)
+		if err != nil {
+			return fmt.Errorf("failed to encode stack feedback: %w", err)
+		}
+		metadata[MetadataRevisionStackFeedback] = string(encoded)
 	}
 
-	// Fetch latest changes
-	cmd := exec.Command("git", "fetch", "origin")
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to fetch origin: %w", err)
+	// Failing checks on this commit are part of the revision and must not trigger another
+	if workflow.Metadata[MetadataChecksState] == ChecksStateFailed {
+		metadata[MetadataChecksRevisedSHA] = workflow.Metadata[MetadataChecksSHA]
 	}
 
-	// Try rebase first
-	cmd = exec.Command("git", "rebase", "origin/"+baseBranch)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		// If rebase fails, try merge
-		log.Printf("⚠️  Rebase failed, trying merge")
-		cmd = exec.Command("git", "merge", "origin/"+baseBranch)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to sync with base branch: %w", err)
-		}
+	state := types.WorkflowStateRevising
+	updateReq := &types.UpdateWorkflowRequest{
+		WorkflowID:     workflow.ID,
+		State:          &state,
+		LastFeedbackTS: &fetchedAt,
+		Metadata:       metadata,
+		Actor:          e.processID,
+		Reason:         "changes requested on PR",
+	}
+	_, err = e.workflowManager.UpdateWorkflow(updateReq)
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to revising: %w", err)
 	}
 
+	log.Printf("✅ Workflow %d transitioned to revising for feedback", workflow.ID)
 	return nil
 }
 
@@ -628,19 +1002,52 @@ func (e *Engine) cleanupWorkspace(workflow *types.Workflow) error {
 		return fmt.Errorf("failed to delete workspace: %w", err)
 	}
 
-	// Delete the remote branch
-	err = e.deleteRemoteBranch(workflow.BranchName)
-	if err != nil {
-		log.Printf("⚠️  Failed to delete remote branch: %v", err)
+	return nil
+}
+
+// deleteRemoteBranch deletes the workflow's remote branches once its PRs are
+// done, as long as cowork created them and they are not the base branch
+func (e *Engine) deleteRemoteBranch(ctx context.Context, workflow *types.Workflow) error {
+	if workflow.BranchName == workflow.BaseBranch {
+		return nil
 	}
 
-	return nil
+	_, err := DeleteCreatedBranch(ctx, e.workflowManager, e.coworkProvider, e.owner, e.repo, workflow.BranchName)
+
+	// Lower parts of a stack are on branches of their own
+	for _, part := range workflow.StackedPRs {
+		if part.Branch == workflow.BranchName {
+			continue
+		}
+		if _, partErr := DeleteCreatedBranch(ctx, e.workflowManager, e.coworkProvider, e.owner, e.repo, part.Branch); partErr != nil && err == nil {
+			err = partErr
+		}
+	}
+
+	return err
 }
 
-// deleteRemoteBranch deletes the remote branch
-func (e *Engine) deleteRemoteBranch(branchName string) error {
-	// This would require authentication and proper Git operations
-	// For now, just log the intention
-	log.Printf("🗑️  Would delete remote branch: %s", branchName)
-	return nil
+// stopAgent stops the agent container working on a workflow, logging failures
+// since the caller is abandoning the work either way
+func (e *Engine) stopAgent(ctx context.Context, workflow *types.Workflow) {
+	if workflow.WorkspaceID == 0 {
+		return
+	}
+
+	if e.dryRun != nil {
+		e.dryRun.Record(ctx, MutationAgentStop, RepositoryKey(e.owner, e.repo), "stop the agent container of workspace %d", workflow.WorkspaceID)
+		return
+	}
+
+	if err := e.workspaceManager.StopContainer(ctx, workflow.WorkspaceID, containerStopTimeoutSeconds); err != nil {
+		log.Printf("⚠️  Failed to stop agent container for workflow %d: %v", workflow.ID, err)
+	}
+}
+
+// postComment leaves a cowork comment on an issue or pull request, logging failures
+func (e *Engine) postComment(ctx context.Context, number int, text string) {
+	body := fmt.Sprintf("%s\n%s", coworkCommentMarker, text)
+	if _, err := e.coworkProvider.CreateComment(ctx, e.owner, e.repo, number, &git.CreateCommentRequest{Body: body}); err != nil {
+		log.Printf("⚠️  Failed to comment on #%d: %v", number, err)
+	}
 }
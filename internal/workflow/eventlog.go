@@ -0,0 +1,105 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// EventRecordType is the kind of change an event log record holds
+type EventRecordType string
+
+const (
+	// EventRecordCreated records a new event in full
+	EventRecordCreated EventRecordType = "created"
+
+	// EventRecordProcessed records that an event was processed
+	EventRecordProcessed EventRecordType = "processed"
+)
+
+// EventRecord is one change in the event log. Events are the result of
+// applying the records in order on top of the events file written before the
+// log existed.
+type EventRecord struct {
+	Type      EventRecordType `json:"type"`
+	EventID   string          `json:"event_id"`
+	Timestamp time.Time       `json:"timestamp"`
+
+	// The new event, for created records
+	Event *types.WorkflowEvent `json:"event,omitempty"`
+
+	// The workflow the event was resolved to and the error processing it,
+	// for processed records
+	JobID string `json:"job_id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// appendEventRecordUnlocked appends a record to the event log and applies it
+// to the cached events. Other cw processes append too, so the caller must
+// have synced with the log first. Must be called with both locks held.
+func (wm *WorkflowManager) appendEventRecordUnlocked(record *EventRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode event record: %w", err)
+	}
+
+	size, err := appendLogLine(wm.eventLogFilePath, data)
+	if err != nil {
+		return fmt.Errorf("failed to append to event log: %w", err)
+	}
+	wm.eventLogOffset = size
+	wm.applyEventRecordUnlocked(record)
+
+	return nil
+}
+
+// syncEventsUnlocked applies the event log records other cw processes
+// appended since this one last read the log. Unreadable lines are skipped.
+// Must be called with both locks held.
+func (wm *WorkflowManager) syncEventsUnlocked() error {
+	// A log that was replaced is read from the start
+	if info, err := os.Stat(wm.eventLogFilePath); err == nil && info.Size() < wm.eventLogOffset {
+		wm.eventLogOffset = 0
+	}
+
+	offset, err := readLogLines(wm.eventLogFilePath, wm.eventLogOffset, func(line []byte, lineOffset int64) error {
+		var record EventRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Printf("⚠️  Skipping unreadable event log line at byte %d: %v", lineOffset, err)
+			return nil
+		}
+		wm.applyEventRecordUnlocked(&record)
+		return nil
+	})
+	wm.eventLogOffset = offset
+	if err != nil {
+		return fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	return nil
+}
+
+// applyEventRecordUnlocked applies one event log record to the cached events.
+// Must be called with the lock held.
+func (wm *WorkflowManager) applyEventRecordUnlocked(record *EventRecord) {
+	switch record.Type {
+	case EventRecordCreated:
+		if record.Event != nil {
+			wm.events[record.Event.ID] = record.Event
+		}
+	case EventRecordProcessed:
+		event, exists := wm.events[record.EventID]
+		if !exists {
+			return
+		}
+		event.Processed = true
+		event.JobID = record.JobID
+		if record.Error != "" {
+			event.Error = record.Error
+		}
+	}
+}
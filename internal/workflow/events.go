@@ -0,0 +1,98 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// EventConsumer turns recorded workflow events into workflows needing attention
+type EventConsumer interface {
+	// ConsumeEvents marks pending events processed and returns the IDs of the
+	// workflows they affect
+	ConsumeEvents(ctx context.Context) ([]string, error)
+}
+
+// ConsumeEvents resolves unprocessed events for the engine's repository to
+// workflows, marks them processed, and returns the affected active workflow IDs
+// in event order
+func (e *Engine) ConsumeEvents(ctx context.Context) ([]string, error) {
+	events, err := e.workflowManager.ListUnprocessedEvents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unprocessed events: %w", err)
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Timestamp.Before(events[j].Timestamp)
+	})
+
+	var workflowIDs []string
+	seen := make(map[string]bool)
+
+	for _, event := range events {
+		if ctx.Err() != nil {
+			break
+		}
+
+		// Events for other repositories belong to other engines
+		if event.Owner != e.owner || event.Repo != e.repo {
+			continue
+		}
+
+		workflow, err := e.workflowManager.FindWorkflowForEvent(event)
+		if err != nil {
+			// Most events concern issues and PRs cowork isn't working on
+			if err := e.workflowManager.MarkEventProcessed(event.ID, "", ""); err != nil {
+				return workflowIDs, fmt.Errorf("failed to mark event processed: %w", err)
+			}
+			continue
+		}
+
+		workflowID := fmt.Sprintf("%d", workflow.ID)
+		if err := e.workflowManager.MarkEventProcessed(event.ID, workflowID, ""); err != nil {
+			return workflowIDs, fmt.Errorf("failed to mark event processed: %w", err)
+		}
+
+		if workflow.State.IsTerminal() || seen[workflowID] {
+			continue
+		}
+
+		log.Printf("📨 %s event %s queued workflow %s for processing", event.Type, event.ID, workflowID)
+		seen[workflowID] = true
+		workflowIDs = append(workflowIDs, workflowID)
+	}
+
+	return workflowIDs, nil
+}
+
+// eventString reads a string value from event data
+func eventString(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+// eventBool reads a boolean value from event data
+func eventBool(data map[string]interface{}, key string) bool {
+	value, _ := data[key].(bool)
+	return value
+}
+
+// eventInts reads a list of integers from event data. Values may be native
+// ints or, once round-tripped through JSON, float64s.
+func eventInts(data map[string]interface{}, key string) []int {
+	switch values := data[key].(type) {
+	case []int:
+		return append([]int(nil), values...)
+	case []interface{}:
+		var result []int
+		for _, value := range values {
+			if number, ok := value.(float64); ok {
+				result = append(result, int(number))
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestFindWorkflowForEvent_MatchesIssueAndPullRequest tests event to workflow resolution
+func TestFindWorkflowForEvent_MatchesIssueAndPullRequest(t *testing.T) {
+	// Test case: Issue events match on issue number, PR events on PR number or
+	// head branch, and unrelated events match nothing
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 10)
+
+	prNumber := 42
+	branch := "task/fix-10"
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, PRNumber: &prNumber, BranchName: &branch})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		event   *types.WorkflowEvent
+		matches bool
+	}{
+		{"issue comment", &types.WorkflowEvent{Owner: "owner", Repo: "repo", IssueID: 10, Data: map[string]interface{}{}}, true},
+		{"pull request by number", &types.WorkflowEvent{Owner: "owner", Repo: "repo", IssueID: 42, Data: map[string]interface{}{"pull_request": true}}, true},
+		{"check suite by branch", &types.WorkflowEvent{Owner: "owner", Repo: "repo", Data: map[string]interface{}{"head_branch": "task/fix-10"}}, true},
+		{"check suite by json pr numbers", &types.WorkflowEvent{Owner: "owner", Repo: "repo", Data: map[string]interface{}{"pull_request": true, "pr_numbers": []interface{}{float64(42)}}}, true},
+		{"pull request with issue number", &types.WorkflowEvent{Owner: "owner", Repo: "repo", IssueID: 10, Data: map[string]interface{}{"pull_request": true}}, false},
+		{"other repository", &types.WorkflowEvent{Owner: "owner", Repo: "other", IssueID: 10, Data: map[string]interface{}{}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found, err := manager.FindWorkflowForEvent(tt.event)
+			if tt.matches {
+				require.NoError(t, err)
+				assert.Equal(t, workflow.ID, found.ID)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+// TestEngine_ConsumeEvents tests that events are marked processed and mapped to workflows
+func TestEngine_ConsumeEvents(t *testing.T) {
+	// Test case: Two events for the same workflow yield its ID once, events for
+	// unknown issues are consumed silently, and other repositories are left alone
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 10)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+
+	first, err := manager.CreateEvent(types.WorkflowEventIssueComment, "github", "owner", "repo", 10, map[string]interface{}{})
+	require.NoError(t, err)
+	second, err := manager.CreateEvent(types.WorkflowEventIssues, "github", "owner", "repo", 10, map[string]interface{}{})
+	require.NoError(t, err)
+	unknown, err := manager.CreateEvent(types.WorkflowEventIssues, "github", "owner", "repo", 99, map[string]interface{}{})
+	require.NoError(t, err)
+	foreign, err := manager.CreateEvent(types.WorkflowEventIssues, "github", "owner", "other", 10, map[string]interface{}{})
+	require.NoError(t, err)
+
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	workflowIDs, err := engine.ConsumeEvents(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{workflowID}, workflowIDs)
+
+	for _, event := range []*types.WorkflowEvent{first, second} {
+		stored, err := manager.GetEvent(event.ID)
+		require.NoError(t, err)
+		assert.True(t, stored.Processed)
+		assert.Equal(t, workflowID, stored.JobID)
+	}
+
+	stored, err := manager.GetEvent(unknown.ID)
+	require.NoError(t, err)
+	assert.True(t, stored.Processed)
+	assert.Empty(t, stored.JobID)
+
+	stored, err = manager.GetEvent(foreign.ID)
+	require.NoError(t, err)
+	assert.False(t, stored.Processed)
+}
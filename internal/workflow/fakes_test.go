@@ -0,0 +1,256 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/task"
+	"github.com/hlfshell/cowork/internal/types"
+	"github.com/hlfshell/cowork/internal/workspace"
+)
+
+// fakeAgentRunner returns canned agent output, optionally editing the workspace first
+type fakeAgentRunner struct {
+	output       string
+	err          error
+	edit         func(workspacePath string)
+	instructions []*agent.AgentInstruction
+}
+
+func (r *fakeAgentRunner) Run(ctx context.Context, workspacePath string, instruction *agent.AgentInstruction) (*agent.AgentResult, error) {
+	r.instructions = append(r.instructions, instruction)
+	if r.edit != nil {
+		r.edit(workspacePath)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &agent.AgentResult{Success: true, Output: r.output}, nil
+}
+
+// fakeCoworkProvider serves a single pull request and records comments;
+// unimplemented methods panic via the nil embedded interface
+type fakeCoworkProvider struct {
+	git.CoworkProvider
+	comments map[int][]*git.CreateCommentRequest
+	replies  []*git.Comment
+	pr       *git.PullRequest
+	updates  *git.PullRequestUpdate
+	since    []time.Time
+	checks   []*git.CommitCheck
+	branches map[string]bool
+	issues   map[int]*git.Issue
+	deleted  []string
+	created  []*git.CreatePullRequestRequest
+	edits    []*git.UpdatePullRequestRequest
+	opened   map[int]*git.PullRequest
+
+	issueComments map[int][]*git.Comment
+	updatesErr    error
+	trackers      map[int][]int
+}
+
+func newFakeCoworkProvider() *fakeCoworkProvider {
+	return &fakeCoworkProvider{comments: make(map[int][]*git.CreateCommentRequest)}
+}
+
+func (p *fakeCoworkProvider) CreateComment(ctx context.Context, owner, repo string, issueNumber int, comment *git.CreateCommentRequest) (*git.Comment, error) {
+	p.comments[issueNumber] = append(p.comments[issueNumber], comment)
+	id := 1000 + len(p.comments[issueNumber])
+	return &git.Comment{ID: id, Body: comment.Body, URL: fmt.Sprintf("https://example.com/comments/%d", id)}, nil
+}
+
+func (p *fakeCoworkProvider) ReplyToReviewComment(ctx context.Context, owner, repo string, prNumber, commentID int, body string) (*git.Comment, error) {
+	reply := &git.Comment{ID: 2000 + len(p.replies), Body: body, InReplyTo: commentID}
+	p.replies = append(p.replies, reply)
+	return reply, nil
+}
+
+func (p *fakeCoworkProvider) GetIssue(ctx context.Context, owner, repo string, issueNumber int) (*git.Issue, error) {
+	if issue, ok := p.issues[issueNumber]; ok {
+		return issue, nil
+	}
+	return &git.Issue{Number: issueNumber}, nil
+}
+
+func (p *fakeCoworkProvider) GetTrackingIssues(ctx context.Context, owner, repo string, issueNumber int) ([]int, error) {
+	return p.trackers[issueNumber], nil
+}
+
+func (p *fakeCoworkProvider) GetIssueComments(ctx context.Context, owner, repo string, issueNumber int) ([]*git.Comment, error) {
+	return p.issueComments[issueNumber], nil
+}
+
+func (p *fakeCoworkProvider) GetPullRequest(ctx context.Context, owner, repo string, prNumber int) (*git.PullRequest, error) {
+	if pr, ok := p.opened[prNumber]; ok {
+		return pr, nil
+	}
+	if p.pr == nil || p.pr.Number != prNumber {
+		return nil, fmt.Errorf("pull request #%d not found", prNumber)
+	}
+	return p.pr, nil
+}
+
+func (p *fakeCoworkProvider) GetPullRequestForTask(ctx context.Context, task *types.Task, owner, repo string) (*git.PullRequest, error) {
+	return p.pr, nil
+}
+
+func (p *fakeCoworkProvider) CreatePullRequest(ctx context.Context, owner, repo string, req *git.CreatePullRequestRequest) (*git.PullRequest, error) {
+	p.created = append(p.created, req)
+	p.pr = &git.PullRequest{
+		Number: 40 + len(p.created),
+		Title:  req.Title,
+		State:  "open",
+		Draft:  req.Draft,
+		Head:   &git.Branch{Ref: req.Head, SHA: "abc123"},
+		Base:   &git.Branch{Ref: req.Base},
+	}
+	if p.opened == nil {
+		p.opened = make(map[int]*git.PullRequest)
+	}
+	p.opened[p.pr.Number] = p.pr
+	return p.pr, nil
+}
+
+func (p *fakeCoworkProvider) CreatePullRequestForTask(ctx context.Context, task *types.Task, owner, repo string, workspace *types.Workspace) (*git.PullRequest, error) {
+	return p.CreatePullRequest(ctx, owner, repo, &git.CreatePullRequestRequest{
+		Title: task.Name,
+		Head:  workspace.BranchName,
+		Base:  task.BaseBranch,
+	})
+}
+
+func (p *fakeCoworkProvider) UpdatePullRequest(ctx context.Context, owner, repo string, prNumber int, req *git.UpdatePullRequestRequest) (*git.PullRequest, error) {
+	p.edits = append(p.edits, req)
+	pr, ok := p.opened[prNumber]
+	if !ok {
+		if p.pr == nil || p.pr.Number != prNumber {
+			return nil, fmt.Errorf("pull request #%d not found", prNumber)
+		}
+		pr = p.pr
+	}
+	if req.Draft != nil {
+		pr.Draft = *req.Draft
+	}
+	if req.Base != nil {
+		pr.Base = &git.Branch{Ref: *req.Base}
+	}
+	return pr, nil
+}
+
+func (p *fakeCoworkProvider) LinkPullRequestToIssue(ctx context.Context, owner, repo string, pr *git.PullRequest, issue *git.Issue) error {
+	return nil
+}
+
+func (p *fakeCoworkProvider) GetPullRequestUpdates(ctx context.Context, owner, repo string, prNumber int, since time.Time) (*git.PullRequestUpdate, error) {
+	p.since = append(p.since, since)
+	if p.updatesErr != nil {
+		return nil, p.updatesErr
+	}
+	if p.updates == nil {
+		return &git.PullRequestUpdate{PRNumber: prNumber}, nil
+	}
+	return p.updates, nil
+}
+
+func (p *fakeCoworkProvider) GetCommitChecks(ctx context.Context, owner, repo, ref string) ([]*git.CommitCheck, error) {
+	return p.checks, nil
+}
+
+func (p *fakeCoworkProvider) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	return p.branches[branch], nil
+}
+
+func (p *fakeCoworkProvider) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	p.deleted = append(p.deleted, branch)
+	return nil
+}
+
+func (p *fakeCoworkProvider) GenerateBranchName(issue *git.Issue) string {
+	return fmt.Sprintf("task-%d", issue.Number)
+}
+
+func (p *fakeCoworkProvider) GetProviderType() git.ProviderType {
+	return git.ProviderGitHub
+}
+
+// fakeTaskManager keeps tasks in memory
+type fakeTaskManager struct {
+	task.TaskManager
+	tasks map[int]*types.Task
+}
+
+func newFakeTaskManager(tasks ...*types.Task) *fakeTaskManager {
+	manager := &fakeTaskManager{tasks: make(map[int]*types.Task)}
+	for _, t := range tasks {
+		manager.tasks[t.ID] = t
+	}
+	return manager
+}
+
+func (m *fakeTaskManager) GetTask(taskID string) (*types.Task, error) {
+	for _, t := range m.tasks {
+		if fmt.Sprintf("%d", t.ID) == taskID {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("task %s not found", taskID)
+}
+
+func (m *fakeTaskManager) UpdateTask(req *types.UpdateTaskRequest) (*types.Task, error) {
+	t, ok := m.tasks[req.TaskID]
+	if !ok {
+		return nil, fmt.Errorf("task %d not found", req.TaskID)
+	}
+	if req.Status != nil {
+		t.Status = *req.Status
+	}
+	if req.Metadata != nil {
+		t.Metadata = *req.Metadata
+	}
+	if req.ErrorMessage != nil {
+		t.ErrorMessage = *req.ErrorMessage
+	}
+	if req.Description != nil {
+		t.Description = *req.Description
+	}
+	return t, nil
+}
+
+// fakeWorkspaceManager serves workspaces from memory and records stopped
+// containers and deleted workspaces
+type fakeWorkspaceManager struct {
+	workspace.WorkspaceManager
+	workspaces map[int]*types.Workspace
+	stopped    []int
+	deleted    []int
+}
+
+func newFakeWorkspaceManager(workspaces ...*types.Workspace) *fakeWorkspaceManager {
+	manager := &fakeWorkspaceManager{workspaces: make(map[int]*types.Workspace)}
+	for _, ws := range workspaces {
+		manager.workspaces[ws.ID] = ws
+	}
+	return manager
+}
+
+func (m *fakeWorkspaceManager) GetWorkspace(workspaceID int) (*types.Workspace, error) {
+	ws, ok := m.workspaces[workspaceID]
+	if !ok {
+		return nil, fmt.Errorf("workspace %d not found", workspaceID)
+	}
+	return ws, nil
+}
+
+func (m *fakeWorkspaceManager) StopContainer(ctx context.Context, workspaceID int, timeoutSeconds int) error {
+	m.stopped = append(m.stopped, workspaceID)
+	return nil
+}
+
+func (m *fakeWorkspaceManager) DeleteWorkspace(workspaceID int) error {
+	m.deleted = append(m.deleted, workspaceID)
+	return nil
+}
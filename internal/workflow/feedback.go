@@ -0,0 +1,292 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataRevisionInstruction holds the merged review feedback for the next revision
+	MetadataRevisionInstruction = "revision_instruction"
+
+	// MetadataRevisionStartedAt holds when the revision was handed to the
+	// agent; the workflow stays in REVISING until the agent finishes it
+	MetadataRevisionStartedAt = "revision_started_at"
+
+	// MetadataRevisionStackFeedback holds the JSON encoded change requests of
+	// the current revision that were left on lower parts of the stack, which
+	// are told what changed once the revision is pushed
+	MetadataRevisionStackFeedback = "revision_stack_feedback"
+
+	// coworkCommentMarker tags comments posted by cowork so they are never treated as feedback
+	coworkCommentMarker = "<!-- cowork -->"
+)
+
+// FeedbackKind identifies where a piece of PR feedback came from
+type FeedbackKind string
+
+const (
+	// FeedbackKindComment is a PR conversation comment
+	FeedbackKindComment FeedbackKind = "comment"
+
+	// FeedbackKindReview is a submitted PR review
+	FeedbackKindReview FeedbackKind = "review"
+
+	// FeedbackKindReviewComment is an inline review comment on a line of the diff
+	FeedbackKindReviewComment FeedbackKind = "review_comment"
+
+	// FeedbackKindCheck is a failed required CI check
+	FeedbackKindCheck FeedbackKind = "check"
+)
+
+// FeedbackItem is a single comment or review left on a pull request
+type FeedbackItem struct {
+	Kind        FeedbackKind `json:"kind"`
+	ID          int          `json:"id"`
+	Name        string       `json:"name,omitempty"`
+	Author      string       `json:"author"`
+	AuthorType  string       `json:"author_type,omitempty"`
+	Body        string       `json:"body"`
+	ReviewState string       `json:"review_state,omitempty"`
+	Path        string       `json:"path,omitempty"`
+	Line        int          `json:"line,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+
+	// Pull request the item was left on, which is one of the lower parts
+	// for feedback on a stack; zero when unknown
+	PRNumber int `json:"pr_number,omitempty"`
+}
+
+// Location returns the file and line an inline review comment is attached
+// to, or an empty string for other feedback
+func (item FeedbackItem) Location() string {
+	if item.Path == "" || item.Line == 0 {
+		return item.Path
+	}
+	return fmt.Sprintf("%s:%d", item.Path, item.Line)
+}
+
+// ClassifiedFeedback is a feedback item with its classified intent
+type ClassifiedFeedback struct {
+	Item   FeedbackItem         `json:"item"`
+	Intent types.FeedbackIntent `json:"intent"`
+
+	// Ignored items (bots, non-allowlisted reviewers, bare approvals) need no action
+	Ignored bool `json:"ignored,omitempty"`
+
+	// Why the item was classified this way, for logs
+	Reason string `json:"reason,omitempty"`
+}
+
+// FeedbackClassifier classifies each PR comment and review independently
+type FeedbackClassifier interface {
+	// Classify returns one classification per item, in the same order
+	Classify(ctx context.Context, workflow *types.Workflow, items []FeedbackItem) ([]ClassifiedFeedback, error)
+}
+
+// FeedbackItemsFromUpdate flattens the updates of one or more PRs into
+// feedback items ordered by time, leaving out comments and reviews cowork
+// posted itself
+func FeedbackItemsFromUpdate(updates ...*git.PullRequestUpdate) []FeedbackItem {
+	var items []FeedbackItem
+	for _, update := range updates {
+		items = append(items, feedbackItemsFromUpdate(update)...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].CreatedAt.Before(items[j].CreatedAt)
+	})
+
+	return items
+}
+
+// feedbackItemsFromUpdate flattens the updates of a single PR into feedback items
+func feedbackItemsFromUpdate(updates *git.PullRequestUpdate) []FeedbackItem {
+	var items []FeedbackItem
+
+	for _, review := range updates.NewReviews {
+		if strings.Contains(review.Body, coworkCommentMarker) {
+			continue
+		}
+
+		item := FeedbackItem{
+			Kind:        FeedbackKindReview,
+			ID:          review.ID,
+			Body:        review.Body,
+			ReviewState: strings.ToLower(review.State),
+			URL:         review.URL,
+			CreatedAt:   review.SubmittedAt,
+			PRNumber:    updates.PRNumber,
+		}
+		if review.User != nil {
+			item.Author = review.User.Login
+			item.AuthorType = review.User.Type
+		}
+		items = append(items, item)
+	}
+
+	for _, comment := range updates.NewComments {
+		if strings.Contains(comment.Body, coworkCommentMarker) {
+			continue
+		}
+
+		item := FeedbackItem{
+			Kind:      FeedbackKindComment,
+			ID:        comment.ID,
+			Body:      comment.Body,
+			URL:       comment.URL,
+			CreatedAt: comment.CreatedAt,
+			PRNumber:  updates.PRNumber,
+		}
+		if comment.User != nil {
+			item.Author = comment.User.Login
+			item.AuthorType = comment.User.Type
+		}
+		items = append(items, item)
+	}
+
+	for _, comment := range updates.NewReviewComments {
+		if strings.Contains(comment.Body, coworkCommentMarker) {
+			continue
+		}
+
+		item := FeedbackItem{
+			Kind:      FeedbackKindReviewComment,
+			ID:        comment.ID,
+			Body:      comment.Body,
+			Path:      comment.Path,
+			Line:      comment.Line,
+			URL:       comment.URL,
+			CreatedAt: comment.CreatedAt,
+			PRNumber:  updates.PRNumber,
+		}
+		if comment.User != nil {
+			item.Author = comment.User.Login
+			item.AuthorType = comment.User.Type
+		}
+		items = append(items, item)
+	}
+
+	return items
+}
+
+// FeedbackByIntent groups actionable classified feedback by intent
+func FeedbackByIntent(classified []ClassifiedFeedback) map[types.FeedbackIntent][]ClassifiedFeedback {
+	groups := make(map[types.FeedbackIntent][]ClassifiedFeedback)
+	for _, feedback := range classified {
+		if feedback.Ignored {
+			continue
+		}
+		groups[feedback.Intent] = append(groups[feedback.Intent], feedback)
+	}
+	return groups
+}
+
+// RevisionStackFeedback returns the feedback from lower parts of the stack
+// recorded for the workflow's current revision
+func RevisionStackFeedback(workflow *types.Workflow) ([]ClassifiedFeedback, error) {
+	raw := workflow.Metadata[MetadataRevisionStackFeedback]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var feedback []ClassifiedFeedback
+	if err := json.Unmarshal([]byte(raw), &feedback); err != nil {
+		return nil, fmt.Errorf("failed to decode stack feedback: %w", err)
+	}
+
+	return feedback, nil
+}
+
+// BuildRevisionInstruction merges change and blocker feedback into a single
+// instruction for the agent, blockers first. It returns an empty string when
+// nothing requires code changes.
+func BuildRevisionInstruction(classified []ClassifiedFeedback) string {
+	groups := FeedbackByIntent(classified)
+	blockers := groups[types.FeedbackIntentBlocker]
+	changes := groups[types.FeedbackIntentChange]
+	if len(blockers) == 0 && len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("Revise the pull request to address the following review feedback.\n")
+
+	if len(blockers) > 0 {
+		sb.WriteString("\n## Must fix\n\n")
+		writeFeedbackList(&sb, blockers)
+	}
+
+	if len(changes) > 0 {
+		sb.WriteString("\n## Requested changes\n\n")
+		writeFeedbackList(&sb, changes)
+	}
+
+	// Questions are answered separately, but give the agent the full picture
+	if questions := groups[types.FeedbackIntentAsk]; len(questions) > 0 {
+		sb.WriteString("\n## Related questions (for context, no change required)\n\n")
+		writeFeedbackList(&sb, questions)
+	}
+
+	return sb.String()
+}
+
+// writeFeedbackList writes feedback items as a numbered markdown list
+func writeFeedbackList(sb *strings.Builder, feedback []ClassifiedFeedback) {
+	for i, f := range feedback {
+		if f.Item.Kind == FeedbackKindCheck {
+			writeCheckFeedback(sb, i+1, f.Item)
+			continue
+		}
+
+		author := f.Item.Author
+		if author == "" {
+			author = "unknown"
+		}
+
+		body := strings.TrimSpace(f.Item.Body)
+		if body == "" && f.Item.ReviewState != "" {
+			body = fmt.Sprintf("(review %s without comment)", strings.ReplaceAll(f.Item.ReviewState, "_", " "))
+		}
+
+		kind := string(f.Item.Kind)
+		if location := f.Item.Location(); location != "" {
+			kind = fmt.Sprintf("%s on %s", kind, location)
+		}
+
+		// Indent continuation lines so multi-line comments stay inside the list item
+		body = strings.ReplaceAll(body, "\n", "\n   ")
+		fmt.Fprintf(sb, "%d. @%s (%s): %s\n", i+1, author, kind, body)
+	}
+}
+
+// writeCheckFeedback writes a failed check as a numbered markdown list item
+func writeCheckFeedback(sb *strings.Builder, number int, item FeedbackItem) {
+	fmt.Fprintf(sb, "%d. Required check `%s` failed", number, item.Name)
+	if summary := strings.TrimSpace(item.Body); summary != "" {
+		fmt.Fprintf(sb, ": %s", strings.ReplaceAll(summary, "\n", "\n   "))
+	}
+	if item.URL != "" {
+		fmt.Fprintf(sb, " (%s)", item.URL)
+	}
+	sb.WriteString("\n")
+}
+
+// highestIntent returns the most severe intent among actionable feedback
+func highestIntent(classified []ClassifiedFeedback) (types.FeedbackIntent, bool) {
+	groups := FeedbackByIntent(classified)
+	for _, intent := range []types.FeedbackIntent{types.FeedbackIntentBlocker, types.FeedbackIntentChange, types.FeedbackIntentAsk} {
+		if len(groups[intent]) > 0 {
+			return intent, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,166 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// agentClassificationPattern finds JSON arrays of objects in agent output
+var agentClassificationPattern = regexp.MustCompile(`(?s)\[\s*\{.*?\}\s*\]`)
+
+// agentClassification is a single classification returned by the agent
+type agentClassification struct {
+	Index   int                  `json:"index"`
+	Intent  types.FeedbackIntent `json:"intent"`
+	Ignored bool                 `json:"ignored"`
+	Reason  string               `json:"reason"`
+}
+
+// AgentFeedbackClassifier asks an agent to classify feedback. Items filtered
+// out by the rules (bots, allowlists) are never sent to the agent, and items
+// the agent does not classify fall back to the rules.
+type AgentFeedbackClassifier struct {
+	runner   AgentRunner
+	fallback *RulesFeedbackClassifier
+}
+
+// NewAgentFeedbackClassifier creates an agent-backed feedback classifier
+func NewAgentFeedbackClassifier(runner AgentRunner, fallback *RulesFeedbackClassifier) *AgentFeedbackClassifier {
+	return &AgentFeedbackClassifier{
+		runner:   runner,
+		fallback: fallback,
+	}
+}
+
+// Classify classifies feedback with the agent, falling back to the rules
+func (c *AgentFeedbackClassifier) Classify(ctx context.Context, workflow *types.Workflow, items []FeedbackItem) ([]ClassifiedFeedback, error) {
+	classified, err := c.fallback.Classify(ctx, workflow, items)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only items that survive the filters are worth asking the agent about
+	var pending []int
+	for i, item := range items {
+		if _, ignored := c.fallback.Filter(item); !ignored {
+			pending = append(pending, i)
+		}
+	}
+	if len(pending) == 0 {
+		return classified, nil
+	}
+
+	// The agent needs a working directory but not the repository itself
+	workDir, err := os.MkdirTemp("", "cw-feedback-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent working directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	instruction := &agent.AgentInstruction{
+		Content:   buildClassificationPrompt(items, pending),
+		TaskID:    workflow.TaskID,
+		Metadata:  map[string]string{"purpose": "feedback_classification"},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := c.runner.Run(ctx, workDir, instruction)
+	if err != nil {
+		log.Printf("⚠️  Agent feedback classification failed, using rules: %v", err)
+		return classified, nil
+	}
+
+	answers, err := parseAgentClassifications(result.Output)
+	if err != nil {
+		log.Printf("⚠️  Could not parse agent feedback classification, using rules: %v", err)
+		return classified, nil
+	}
+
+	for _, answer := range answers {
+		if !containsInt(pending, answer.Index) {
+			continue
+		}
+		if !answer.Ignored && !answer.Intent.IsValid() {
+			continue
+		}
+
+		reason := answer.Reason
+		if reason == "" {
+			reason = "classified by agent"
+		}
+		classified[answer.Index] = ClassifiedFeedback{
+			Item:    items[answer.Index],
+			Intent:  answer.Intent,
+			Ignored: answer.Ignored,
+			Reason:  reason,
+		}
+	}
+
+	return classified, nil
+}
+
+// buildClassificationPrompt builds the instruction asking the agent to classify feedback
+func buildClassificationPrompt(items []FeedbackItem, pending []int) string {
+	var sb strings.Builder
+
+	sb.WriteString("Classify each piece of pull request feedback below. Do not modify any files.\n\n")
+	sb.WriteString("Intents:\n")
+	sb.WriteString("- ask: a question that needs an answer but no code change\n")
+	sb.WriteString("- change: a request to change the code\n")
+	sb.WriteString("- blocker: a problem that must be fixed before the pull request can merge\n")
+	sb.WriteString("Mark feedback that needs no action (thanks, approvals, chatter) as ignored.\n\n")
+
+	for _, i := range pending {
+		item := items[i]
+		fmt.Fprintf(&sb, "### Feedback %d (%s by @%s", i, item.Kind, item.Author)
+		if item.ReviewState != "" {
+			fmt.Fprintf(&sb, ", %s", item.ReviewState)
+		}
+		if location := item.Location(); location != "" {
+			fmt.Fprintf(&sb, ", on %s", location)
+		}
+		sb.WriteString(")\n\n")
+		sb.WriteString(strings.TrimSpace(item.Body))
+		sb.WriteString("\n\n")
+	}
+
+	sb.WriteString("Respond with only a JSON array, one object per feedback item:\n")
+	sb.WriteString(`[{"index": 0, "intent": "ask|change|blocker", "ignored": false, "reason": "short reason"}]`)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// parseAgentClassifications extracts the last JSON classification array from agent output
+func parseAgentClassifications(output string) ([]agentClassification, error) {
+	matches := agentClassificationPattern.FindAllString(output, -1)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no classification found in agent output")
+	}
+
+	var answers []agentClassification
+	if err := json.Unmarshal([]byte(matches[len(matches)-1]), &answers); err != nil {
+		return nil, fmt.Errorf("failed to decode classification: %w", err)
+	}
+
+	return answers, nil
+}
+
+// containsInt reports whether values contains value
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
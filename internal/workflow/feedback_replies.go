@@ -0,0 +1,262 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataFeedbackExchanges holds the JSON encoded questions answered on the PR
+	MetadataFeedbackExchanges = "feedback_exchanges"
+
+	// maxAnswerDiffBytes caps how much of the PR diff is handed to the agent
+	maxAnswerDiffBytes = 64 * 1024
+)
+
+// answerPattern extracts the reply from agent output
+var answerPattern = regexp.MustCompile(`(?s)<answer>(.*?)</answer>`)
+
+// FeedbackExchange is a reviewer question and the agent's posted reply
+type FeedbackExchange struct {
+	QuestionID int       `json:"question_id"`
+	Author     string    `json:"author"`
+	Question   string    `json:"question"`
+	Answer     string    `json:"answer"`
+	ReplyID    int       `json:"reply_id"`
+	ReplyURL   string    `json:"reply_url,omitempty"`
+	AnsweredAt time.Time `json:"answered_at"`
+}
+
+// FeedbackExchanges returns the question and answer history recorded on a workflow
+func FeedbackExchanges(workflow *types.Workflow) ([]FeedbackExchange, error) {
+	raw := workflow.Metadata[MetadataFeedbackExchanges]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var exchanges []FeedbackExchange
+	if err := json.Unmarshal([]byte(raw), &exchanges); err != nil {
+		return nil, fmt.Errorf("failed to decode feedback exchanges: %w", err)
+	}
+
+	return exchanges, nil
+}
+
+// answerQuestions has the agent answer each question and posts the answers as
+// replies on the pull request, recording every exchange on the workflow.
+// Inline review comments are answered in their thread.
+func (e *Engine) answerQuestions(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest, questions []ClassifiedFeedback) error {
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	exchanges, err := FeedbackExchanges(workflow)
+	if err != nil {
+		log.Printf("⚠️  Discarding unreadable feedback history for workflow %d: %v", workflow.ID, err)
+	}
+
+	answered := make(map[int]bool, len(exchanges))
+	for _, exchange := range exchanges {
+		answered[exchange.QuestionID] = true
+	}
+
+	diff, err := pullRequestDiff(workspace.Path, workflow.BaseBranch)
+	if err != nil {
+		log.Printf("⚠️  Could not compute PR diff for workflow %d: %v", workflow.ID, err)
+	}
+
+	var postErr error
+	posted := 0
+	for _, question := range questions {
+		if answered[question.Item.ID] {
+			continue
+		}
+
+		answer, err := e.askAgent(ctx, workflow, workspace.Path, pr, diff, exchanges, question.Item)
+		if err != nil {
+			log.Printf("⚠️  Agent could not answer question %d on PR #%d: %v", question.Item.ID, feedbackPRNumber(pr, question.Item), err)
+			continue
+		}
+
+		reply, err := e.postFeedbackReply(ctx, pr, question.Item, answer)
+		if err != nil {
+			postErr = fmt.Errorf("failed to post reply to question %d: %w", question.Item.ID, err)
+			break
+		}
+
+		log.Printf("💬 Answered question from %s on PR #%d", question.Item.Author, feedbackPRNumber(pr, question.Item))
+		exchanges = append(exchanges, FeedbackExchange{
+			QuestionID: question.Item.ID,
+			Author:     question.Item.Author,
+			Question:   question.Item.Body,
+			Answer:     answer,
+			ReplyID:    reply.ID,
+			ReplyURL:   reply.URL,
+			AnsweredAt: time.Now(),
+		})
+		posted++
+	}
+
+	// Record whatever was posted, even if a later reply failed
+	if posted > 0 {
+		encoded, err := json.Marshal(exchanges)
+		if err != nil {
+			return fmt.Errorf("failed to encode feedback exchanges: %w", err)
+		}
+
+		_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			Metadata:   map[string]string{MetadataFeedbackExchanges: string(encoded)},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record feedback exchanges: %w", err)
+		}
+	}
+
+	return postErr
+}
+
+// postFeedbackReply replies on the PR the question was left on, in the thread
+// of an inline review comment, and with a comment quoting the question to
+// conversation comments and reviews, which have no thread of their own
+func (e *Engine) postFeedbackReply(ctx context.Context, pr *git.PullRequest, question FeedbackItem, answer string) (*git.Comment, error) {
+	number := feedbackPRNumber(pr, question)
+	if question.Kind == FeedbackKindReviewComment {
+		return e.coworkProvider.ReplyToReviewComment(ctx, e.owner, e.repo, number, question.ID, fmt.Sprintf("%s\n%s\n", coworkCommentMarker, answer))
+	}
+
+	return e.coworkProvider.CreateComment(ctx, e.owner, e.repo, number, &git.CreateCommentRequest{
+		Body: formatFeedbackReply(question, answer),
+	})
+}
+
+// feedbackPRNumber returns the PR a feedback item was left on, which is a
+// lower part of the stack for feedback fetched from one
+func feedbackPRNumber(pr *git.PullRequest, item FeedbackItem) int {
+	if item.PRNumber != 0 {
+		return item.PRNumber
+	}
+	return pr.Number
+}
+
+// askAgent runs the agent against the workspace to answer a single question
+func (e *Engine) askAgent(ctx context.Context, workflow *types.Workflow, workspacePath string, pr *git.PullRequest, diff string, history []FeedbackExchange, question FeedbackItem) (string, error) {
+	instruction := &agent.AgentInstruction{
+		Content: buildAnswerPrompt(pr, diff, history, question),
+		TaskID:  workflow.TaskID,
+		Metadata: map[string]string{
+			"purpose":     "answer_feedback",
+			"question_id": fmt.Sprintf("%d", question.ID),
+		},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := e.runAgent(ctx, e.agentRunner, workspacePath, instruction)
+	if err != nil {
+		return "", err
+	}
+
+	answer, ok := extractAnswer(result.Output)
+	if !ok {
+		return "", fmt.Errorf("agent output contained no answer")
+	}
+
+	return answer, nil
+}
+
+// buildAnswerPrompt builds the instruction asking the agent to answer a reviewer question
+func buildAnswerPrompt(pr *git.PullRequest, diff string, history []FeedbackExchange, question FeedbackItem) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "A reviewer asked a question on pull request #%d (%s).\n", pr.Number, pr.Title)
+	if number := feedbackPRNumber(pr, question); number != pr.Number {
+		fmt.Fprintf(&sb, "It was left on #%d, a lower part of the stack this pull request sits on.\n", number)
+	}
+	sb.WriteString("Answer it using the code in this workspace and the diff below. Do not modify any files.\n\n")
+
+	fmt.Fprintf(&sb, "## Question from @%s\n\n%s\n\n", question.Author, strings.TrimSpace(question.Body))
+
+	if len(history) > 0 {
+		sb.WriteString("## Earlier discussion\n\n")
+		writeExchangeHistory(&sb, history)
+		sb.WriteString("\n")
+	}
+
+	if diff != "" {
+		sb.WriteString("## Pull request diff\n\n```diff\n")
+		sb.WriteString(diff)
+		sb.WriteString("\n```\n\n")
+	}
+
+	sb.WriteString("Reply with a concise answer for the reviewer wrapped in <answer></answer> tags.\n")
+
+	return sb.String()
+}
+
+// extractAnswer returns the last tagged answer in the agent output
+func extractAnswer(output string) (string, bool) {
+	matches := answerPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	answer := strings.TrimSpace(matches[len(matches)-1][1])
+	return answer, answer != ""
+}
+
+// formatFeedbackReply quotes the question so the reply reads as part of its thread
+func formatFeedbackReply(question FeedbackItem, answer string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "%s\n", coworkCommentMarker)
+	for _, line := range strings.Split(strings.TrimSpace(question.Body), "\n") {
+		fmt.Fprintf(&sb, "> %s\n", line)
+	}
+
+	if question.URL != "" {
+		fmt.Fprintf(&sb, "\n@%s ([question](%s))\n\n", question.Author, question.URL)
+	} else {
+		fmt.Fprintf(&sb, "\n@%s\n\n", question.Author)
+	}
+	sb.WriteString(answer)
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// writeExchangeHistory writes previous questions and answers as markdown
+func writeExchangeHistory(sb *strings.Builder, exchanges []FeedbackExchange) {
+	for _, exchange := range exchanges {
+		fmt.Fprintf(sb, "- Q (@%s): %s\n", exchange.Author, strings.ReplaceAll(strings.TrimSpace(exchange.Question), "\n", " "))
+		fmt.Fprintf(sb, "  A: %s\n", strings.ReplaceAll(strings.TrimSpace(exchange.Answer), "\n", "\n     "))
+	}
+}
+
+// pullRequestDiff returns the diff of the feature branch against its base
+func pullRequestDiff(workspacePath, baseBranch string) (string, error) {
+	cmd := exec.Command("git", "diff", fmt.Sprintf("origin/%s...HEAD", baseBranch))
+	cmd.Dir = workspacePath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to diff against %s: %w", baseBranch, err)
+	}
+
+	diff := string(output)
+	if len(diff) > maxAnswerDiffBytes {
+		diff = diff[:maxAnswerDiffBytes] + "\n... (diff truncated)"
+	}
+
+	return diff, nil
+}
@@ -0,0 +1,139 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_HandleAskFeedback_PostsReplies tests agent-authored answers to reviewer questions
+func TestEngine_HandleAskFeedback_PostsReplies(t *testing.T) {
+	// Test case: Each new question is answered by the agent, posted as a quoted
+	// reply on the PR and recorded on the workflow; answered questions are skipped
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflow.TaskID = 3
+	workflow.WorkspaceID = 9
+	storeTestWorkflow(t, manager, workflow)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	provider := newFakeCoworkProvider()
+	runner := &fakeAgentRunner{output: "thinking...\n<answer>A map keeps lookups O(1).</answer>"}
+
+	engine := NewEngine(manager, nil, newFakeWorkspaceManager(&types.Workspace{ID: 9, Path: t.TempDir()}), provider, "owner", "repo")
+	engine.SetAgentRunner(runner)
+
+	pr := &git.PullRequest{Number: 42, Title: "Add cache"}
+	question := ClassifiedFeedback{
+		Item: FeedbackItem{
+			Kind:   FeedbackKindComment,
+			ID:     7,
+			Author: "alice",
+			Body:   "Why did you choose a map?",
+			URL:    "https://example.com/comments/7",
+		},
+		Intent: types.FeedbackIntentAsk,
+	}
+
+	require.NoError(t, engine.handleAskFeedback(context.Background(), workflow, pr, []ClassifiedFeedback{question}))
+
+	require.Len(t, runner.instructions, 1)
+	assert.Equal(t, 3, runner.instructions[0].TaskID)
+	assert.Contains(t, runner.instructions[0].Content, "Why did you choose a map?")
+
+	require.Len(t, provider.comments[42], 1)
+	reply := provider.comments[42][0].Body
+	assert.Contains(t, reply, "> Why did you choose a map?")
+	assert.Contains(t, reply, "@alice")
+	assert.Contains(t, reply, "A map keeps lookups O(1).")
+	assert.Empty(t, FeedbackItemsFromUpdate(&git.PullRequestUpdate{NewComments: []*git.Comment{{Body: reply}}}),
+		"cowork replies must not be treated as new feedback")
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	exchanges, err := FeedbackExchanges(workflow)
+	require.NoError(t, err)
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, 7, exchanges[0].QuestionID)
+	assert.Equal(t, "A map keeps lookups O(1).", exchanges[0].Answer)
+	assert.Equal(t, 1001, exchanges[0].ReplyID)
+
+	require.NoError(t, engine.handleAskFeedback(context.Background(), workflow, pr, []ClassifiedFeedback{question}))
+	assert.Len(t, runner.instructions, 1)
+	assert.Len(t, provider.comments[42], 1)
+
+	// Later revisions see the discussion
+	change := ClassifiedFeedback{Item: FeedbackItem{Kind: FeedbackKindComment, ID: 8, Author: "alice", Body: "Use a slice instead"}, Intent: types.FeedbackIntentChange}
+	require.NoError(t, engine.handleChangeFeedback(context.Background(), workflow, pr, []ClassifiedFeedback{change}, time.Now()))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+	assert.Contains(t, workflow.Metadata[MetadataRevisionInstruction], "A map keeps lookups O(1).")
+}
+
+// TestEngine_HandleAskFeedback_ThreadsReviewComments tests answering inline review comments in their thread
+func TestEngine_HandleAskFeedback_ThreadsReviewComments(t *testing.T) {
+	// Test case: A question left on a line of the diff is answered in its
+	// review thread without quoting it, and the reply is never read back as
+	// feedback
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflow.TaskID = 3
+	workflow.WorkspaceID = 9
+
+	provider := newFakeCoworkProvider()
+	engine := NewEngine(manager, nil, newFakeWorkspaceManager(&types.Workspace{ID: 9, Path: t.TempDir()}), provider, "owner", "repo")
+	engine.SetAgentRunner(&fakeAgentRunner{output: "<answer>It is cleared on every write.</answer>"})
+
+	update := &git.PullRequestUpdate{NewReviewComments: []*git.Comment{
+		{ID: 7, User: &git.User{Login: "alice"}, Body: "When is this cache invalidated?", Path: "cache.go", Line: 12},
+	}}
+	items := FeedbackItemsFromUpdate(update)
+	require.Len(t, items, 1)
+	assert.Equal(t, FeedbackKindReviewComment, items[0].Kind)
+	assert.Equal(t, "cache.go:12", items[0].Location())
+
+	questions := []ClassifiedFeedback{{Item: items[0], Intent: types.FeedbackIntentAsk}}
+	require.NoError(t, engine.handleAskFeedback(context.Background(), workflow, &git.PullRequest{Number: 42}, questions))
+
+	assert.Empty(t, provider.comments)
+	require.Len(t, provider.replies, 1)
+	assert.Equal(t, 7, provider.replies[0].InReplyTo)
+	assert.Contains(t, provider.replies[0].Body, "It is cleared on every write.")
+	assert.NotContains(t, provider.replies[0].Body, "> When is this cache invalidated?")
+
+	exchanges, err := FeedbackExchanges(reloadTestWorkflow(t, manager, workflow))
+	require.NoError(t, err)
+	require.Len(t, exchanges, 1)
+	assert.Equal(t, 2000, exchanges[0].ReplyID)
+
+	update.NewReviewComments = append(update.NewReviewComments, provider.replies[0])
+	update.NewReviews = []*git.Review{{ID: 8, Body: coworkCommentMarker + "\nSelf-review findings", State: "COMMENTED"}}
+	assert.Len(t, FeedbackItemsFromUpdate(update), 1, "cowork replies and reviews must not be treated as new feedback")
+}
+
+// TestEngine_HandleAskFeedback_AgentFailure tests that unanswered questions post nothing
+func TestEngine_HandleAskFeedback_AgentFailure(t *testing.T) {
+	// Test case: Agent errors and output without an answer are skipped without failing the workflow
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflow.TaskID = 3
+	workflow.WorkspaceID = 9
+
+	for _, runner := range []*fakeAgentRunner{{err: errors.New("agent crashed")}, {output: "I am not sure"}} {
+		provider := newFakeCoworkProvider()
+		engine := NewEngine(manager, nil, newFakeWorkspaceManager(&types.Workspace{ID: 9, Path: t.TempDir()}), provider, "owner", "repo")
+		engine.SetAgentRunner(runner)
+
+		questions := []ClassifiedFeedback{{Item: FeedbackItem{ID: 7, Author: "alice", Body: "Why?"}, Intent: types.FeedbackIntentAsk}}
+		require.NoError(t, engine.handleAskFeedback(context.Background(), workflow, &git.PullRequest{Number: 42}, questions))
+
+		assert.Empty(t, provider.comments)
+		assert.NotContains(t, workflow.Metadata, MetadataFeedbackExchanges)
+	}
+}
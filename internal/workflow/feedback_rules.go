@@ -0,0 +1,232 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// FeedbackRulesFileName is the repository file holding feedback classification rules
+	FeedbackRulesFileName = "feedback_rules.yaml"
+
+	// FeedbackClassifierRules selects the rules-based classifier
+	FeedbackClassifierRules = "rules"
+
+	// FeedbackClassifierAgent selects the agent-backed classifier
+	FeedbackClassifierAgent = "agent"
+)
+
+// FeedbackRule maps comments matching a regular expression to an intent
+type FeedbackRule struct {
+	Intent  types.FeedbackIntent `yaml:"intent"`
+	Pattern string               `yaml:"pattern"`
+}
+
+// FeedbackRules configures the rules-based feedback classifier
+type FeedbackRules struct {
+	// Classifier to use: "rules" (default) or "agent"
+	Classifier string `yaml:"classifier"`
+
+	// Only feedback from these reviewers is acted on (empty allows everyone)
+	Reviewers []string `yaml:"reviewers"`
+
+	// Feedback from these authors is always ignored
+	IgnoreAuthors []string `yaml:"ignore_authors"`
+
+	// Bot accounts are ignored unless this is set
+	IncludeBots bool `yaml:"include_bots"`
+
+	// Rules are evaluated in order and the first match wins
+	Rules []FeedbackRule `yaml:"rules"`
+
+	// Intent for feedback no rule matches (empty ignores it)
+	DefaultIntent types.FeedbackIntent `yaml:"default_intent"`
+}
+
+// DefaultFeedbackRules returns the rules used when a repository has no rules file
+func DefaultFeedbackRules() FeedbackRules {
+	return FeedbackRules{
+		Classifier: FeedbackClassifierRules,
+		Rules: []FeedbackRule{
+			{
+				Intent:  types.FeedbackIntentBlocker,
+				Pattern: `(?i)\b(blocker|blocking|must fix|security (issue|hole|vulnerability)|breaks? the build|build is broken|failing (tests?|checks?))\b`,
+			},
+			{
+				Intent:  types.FeedbackIntentAsk,
+				Pattern: `(?i)^\s*(why|what|how|when|where|which|is|are|does|do|did|was|were|can you explain|could you explain)\b[^\n]*\?\s*$`,
+			},
+			{
+				Intent:  types.FeedbackIntentChange,
+				Pattern: "(?i)(^\\s*(nit|suggestion)\\b|```suggestion|\\b(please|pls|can you|could you|should|needs? to|must)\\b.*\\b(change|fix|update|rename|remove|add|move|use|replace|refactor|extract|split|drop|revert|handle)\\b)",
+			},
+			{
+				Intent:  types.FeedbackIntentAsk,
+				Pattern: `\?`,
+			},
+		},
+	}
+}
+
+// LoadFeedbackRules reads feedback rules from a YAML file. Unset sections
+// fall back to the defaults.
+func LoadFeedbackRules(path string) (FeedbackRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return FeedbackRules{}, fmt.Errorf("failed to read feedback rules: %w", err)
+	}
+
+	var rules FeedbackRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return FeedbackRules{}, fmt.Errorf("failed to parse feedback rules: %w", err)
+	}
+
+	defaults := DefaultFeedbackRules()
+	if rules.Classifier == "" {
+		rules.Classifier = defaults.Classifier
+	}
+	if len(rules.Rules) == 0 {
+		rules.Rules = defaults.Rules
+	}
+
+	if err := rules.Validate(); err != nil {
+		return FeedbackRules{}, fmt.Errorf("invalid feedback rules in %s: %w", path, err)
+	}
+
+	return rules, nil
+}
+
+// Validate checks if the feedback rules are valid
+func (fr *FeedbackRules) Validate() error {
+	if fr.Classifier != "" && fr.Classifier != FeedbackClassifierRules && fr.Classifier != FeedbackClassifierAgent {
+		return fmt.Errorf("classifier must be '%s' or '%s'", FeedbackClassifierRules, FeedbackClassifierAgent)
+	}
+
+	if fr.DefaultIntent != "" && !fr.DefaultIntent.IsValid() {
+		return fmt.Errorf("invalid default intent: %s", fr.DefaultIntent)
+	}
+
+	for i, rule := range fr.Rules {
+		if !rule.Intent.IsValid() {
+			return fmt.Errorf("rule %d has invalid intent: %s", i+1, rule.Intent)
+		}
+		if _, err := regexp.Compile(rule.Pattern); err != nil {
+			return fmt.Errorf("rule %d has invalid pattern: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+// compiledFeedbackRule is a feedback rule with its pattern compiled
+type compiledFeedbackRule struct {
+	intent  types.FeedbackIntent
+	pattern *regexp.Regexp
+}
+
+// RulesFeedbackClassifier classifies feedback with regular expressions,
+// reviewer allowlists and bot filters
+type RulesFeedbackClassifier struct {
+	rules         []compiledFeedbackRule
+	reviewers     map[string]bool
+	ignoreAuthors map[string]bool
+	includeBots   bool
+	defaultIntent types.FeedbackIntent
+}
+
+// NewRulesFeedbackClassifier creates a rules-based feedback classifier
+func NewRulesFeedbackClassifier(rules FeedbackRules) (*RulesFeedbackClassifier, error) {
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid feedback rules: %w", err)
+	}
+
+	classifier := &RulesFeedbackClassifier{
+		reviewers:     lowerSet(rules.Reviewers),
+		ignoreAuthors: lowerSet(rules.IgnoreAuthors),
+		includeBots:   rules.IncludeBots,
+		defaultIntent: rules.DefaultIntent,
+	}
+
+	for _, rule := range rules.Rules {
+		classifier.rules = append(classifier.rules, compiledFeedbackRule{
+			intent:  rule.Intent,
+			pattern: regexp.MustCompile(rule.Pattern),
+		})
+	}
+
+	return classifier, nil
+}
+
+// Classify classifies each feedback item on its own
+func (c *RulesFeedbackClassifier) Classify(ctx context.Context, workflow *types.Workflow, items []FeedbackItem) ([]ClassifiedFeedback, error) {
+	classified := make([]ClassifiedFeedback, 0, len(items))
+	for _, item := range items {
+		classified = append(classified, c.classifyItem(item))
+	}
+	return classified, nil
+}
+
+// Filter reports whether an item should be ignored before classification,
+// returning the reason if so
+func (c *RulesFeedbackClassifier) Filter(item FeedbackItem) (string, bool) {
+	author := strings.ToLower(item.Author)
+
+	if !c.includeBots && (strings.EqualFold(item.AuthorType, "bot") || strings.HasSuffix(author, "[bot]")) {
+		return "bot author", true
+	}
+
+	if c.ignoreAuthors[author] {
+		return "ignored author", true
+	}
+
+	if len(c.reviewers) > 0 && !c.reviewers[author] {
+		return "reviewer not in allowlist", true
+	}
+
+	return "", false
+}
+
+// classifyItem classifies a single feedback item
+func (c *RulesFeedbackClassifier) classifyItem(item FeedbackItem) ClassifiedFeedback {
+	if reason, ignored := c.Filter(item); ignored {
+		return ClassifiedFeedback{Item: item, Ignored: true, Reason: reason}
+	}
+
+	// A "request changes" review is blocking regardless of its wording
+	if item.Kind == FeedbackKindReview && item.ReviewState == "changes_requested" {
+		return ClassifiedFeedback{Item: item, Intent: types.FeedbackIntentBlocker, Reason: "review requested changes"}
+	}
+
+	body := strings.TrimSpace(item.Body)
+	if body == "" {
+		return ClassifiedFeedback{Item: item, Ignored: true, Reason: "empty body"}
+	}
+
+	for _, rule := range c.rules {
+		if rule.pattern.MatchString(body) {
+			return ClassifiedFeedback{Item: item, Intent: rule.intent, Reason: fmt.Sprintf("matched %q", rule.pattern.String())}
+		}
+	}
+
+	if c.defaultIntent != "" {
+		return ClassifiedFeedback{Item: item, Intent: c.defaultIntent, Reason: "default intent"}
+	}
+
+	return ClassifiedFeedback{Item: item, Ignored: true, Reason: "no rule matched"}
+}
+
+// lowerSet builds a lowercase lookup set from a list of names
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
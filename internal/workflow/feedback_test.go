@@ -0,0 +1,222 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_ProcessRevisingWorkflow tests handing a revision to the agent and pushing it
+func TestEngine_ProcessRevisingWorkflow(t *testing.T) {
+	// Test case: The feedback is handed to the agent and the workflow waits in
+	// revising until the agent finishes; the revision is then pushed and the
+	// workflow is back in pr_open
+	manager := newTestWorkflowManager(t)
+	workflow, origin, workspacePath := newRevisingWorkflow(t, manager)
+
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusCompleted})
+	workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath})
+	engine := NewEngine(manager, tasks, workspaces, newFakeCoworkProvider(), "owner", "repo")
+
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), workflow))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+	assert.NotEmpty(t, workflow.Metadata[MetadataRevisionStartedAt])
+	assert.Equal(t, types.TaskStatusInProgress, tasks.tasks[3].Status)
+	assert.Equal(t, "Rename the widget", tasks.tasks[3].Metadata[MetadataRevisionInstruction])
+
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), workflow))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+
+	commitFile(t, workspacePath, "file.txt", "renamed\n", "rename the widget")
+	tasks.tasks[3].Status = types.TaskStatusCompleted
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), workflow))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+	assert.Empty(t, workflow.Metadata[MetadataRevisionStartedAt])
+	assert.Equal(t, gitTest(t, workspacePath, "rev-parse", "HEAD"), gitTest(t, origin, "rev-parse", "task-7"))
+}
+
+// TestRulesFeedbackClassifier_DefaultRules tests per-item classification with the built-in rules
+func TestRulesFeedbackClassifier_DefaultRules(t *testing.T) {
+	// Test case: Each comment and review is classified on its own
+	classifier, err := NewRulesFeedbackClassifier(DefaultFeedbackRules())
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		item    FeedbackItem
+		intent  types.FeedbackIntent
+		ignored bool
+	}{
+		{"question", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Why did you pick a map here?"}, types.FeedbackIntentAsk, false},
+		{"explain request", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Can you explain how the cache is invalidated?"}, types.FeedbackIntentAsk, false},
+		{"change request", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Please rename this to fetchUser."}, types.FeedbackIntentChange, false},
+		{"question asking for change", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Could you move this into its own file?"}, types.FeedbackIntentChange, false},
+		{"nit", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "nit: trailing whitespace"}, types.FeedbackIntentChange, false},
+		{"blocker", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "This is a security issue, the token is logged."}, types.FeedbackIntentBlocker, false},
+		{"changes requested review", FeedbackItem{Kind: FeedbackKindReview, Author: "alice", ReviewState: "changes_requested"}, types.FeedbackIntentBlocker, false},
+		{"bare approval", FeedbackItem{Kind: FeedbackKindReview, Author: "alice", ReviewState: "approved"}, "", true},
+		{"chatter", FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Thanks, looks good to me"}, "", true},
+		{"bot by type", FeedbackItem{Kind: FeedbackKindComment, Author: "codecov", AuthorType: "Bot", Body: "Please fix coverage"}, "", true},
+		{"bot by login", FeedbackItem{Kind: FeedbackKindComment, Author: "dependabot[bot]", Body: "Please update"}, "", true},
+	}
+
+	items := make([]FeedbackItem, 0, len(testCases))
+	for _, tc := range testCases {
+		items = append(items, tc.item)
+	}
+
+	classified, err := classifier.Classify(context.Background(), &types.Workflow{}, items)
+	require.NoError(t, err)
+	require.Len(t, classified, len(testCases))
+
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.ignored, classified[i].Ignored, classified[i].Reason)
+			if !tc.ignored {
+				assert.Equal(t, tc.intent, classified[i].Intent)
+			}
+		})
+	}
+}
+
+// TestRulesFeedbackClassifier_Filters tests reviewer allowlists, ignored authors and custom rules
+func TestRulesFeedbackClassifier_Filters(t *testing.T) {
+	// Test case: Only allowlisted reviewers are acted on, ignored authors are
+	// dropped, bots can be opted in, and unmatched feedback gets the default intent
+	classifier, err := NewRulesFeedbackClassifier(FeedbackRules{
+		Reviewers:     []string{"Alice", "renovate[bot]", "bob"},
+		IgnoreAuthors: []string{"bob"},
+		IncludeBots:   true,
+		Rules: []FeedbackRule{
+			{Intent: types.FeedbackIntentBlocker, Pattern: `(?i)^blocking:`},
+		},
+		DefaultIntent: types.FeedbackIntentChange,
+	})
+	require.NoError(t, err)
+
+	classified, err := classifier.Classify(context.Background(), &types.Workflow{}, []FeedbackItem{
+		{Author: "alice", Body: "BLOCKING: this leaks memory"},
+		{Author: "alice", Body: "Tidy this up"},
+		{Author: "mallory", Body: "BLOCKING: revert everything"},
+		{Author: "bob", Body: "Tidy this up"},
+		{Author: "renovate[bot]", AuthorType: "Bot", Body: "Bump the version"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, types.FeedbackIntentBlocker, classified[0].Intent)
+	assert.Equal(t, types.FeedbackIntentChange, classified[1].Intent)
+	assert.True(t, classified[2].Ignored)
+	assert.True(t, classified[3].Ignored)
+	assert.False(t, classified[4].Ignored)
+	assert.Equal(t, types.FeedbackIntentChange, classified[4].Intent)
+}
+
+// TestLoadFeedbackRules tests loading rules files
+func TestLoadFeedbackRules(t *testing.T) {
+	// Test case: Missing sections fall back to defaults and invalid rules are rejected
+	dir := t.TempDir()
+
+	validPath := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(validPath, []byte("classifier: agent\nreviewers: [alice]\n"), 0644))
+
+	rules, err := LoadFeedbackRules(validPath)
+	require.NoError(t, err)
+	assert.Equal(t, FeedbackClassifierAgent, rules.Classifier)
+	assert.Equal(t, []string{"alice"}, rules.Reviewers)
+	assert.Equal(t, DefaultFeedbackRules().Rules, rules.Rules)
+
+	invalidCases := map[string]string{
+		"bad pattern":    "rules:\n  - intent: change\n    pattern: \"(\"\n",
+		"bad intent":     "rules:\n  - intent: maybe\n    pattern: foo\n",
+		"bad classifier": "classifier: magic\n",
+	}
+	for name, content := range invalidCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			_, err := LoadFeedbackRules(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestBuildRevisionInstruction tests merging mixed feedback into one instruction
+func TestBuildRevisionInstruction(t *testing.T) {
+	// Test case: Blockers come before changes, questions are included for
+	// context, ignored items are dropped, and ask-only feedback yields nothing
+	classified := []ClassifiedFeedback{
+		{Item: FeedbackItem{Kind: FeedbackKindComment, Author: "alice", Body: "Please rename foo"}, Intent: types.FeedbackIntentChange},
+		{Item: FeedbackItem{Kind: FeedbackKindComment, Author: "bob", Body: "Why a mutex?"}, Intent: types.FeedbackIntentAsk},
+		{Item: FeedbackItem{Kind: FeedbackKindReview, Author: "carol", ReviewState: "changes_requested"}, Intent: types.FeedbackIntentBlocker},
+		{Item: FeedbackItem{Kind: FeedbackKindComment, Author: "ci[bot]", Body: "Coverage dropped"}, Ignored: true},
+	}
+
+	instruction := BuildRevisionInstruction(classified)
+
+	assert.Contains(t, instruction, "1. @carol (review): (review changes requested without comment)")
+	assert.Contains(t, instruction, "1. @alice (comment): Please rename foo")
+	assert.Contains(t, instruction, "1. @bob (comment): Why a mutex?")
+	assert.NotContains(t, instruction, "Coverage dropped")
+	assert.Less(t, strings.Index(instruction, "Must fix"), strings.Index(instruction, "Requested changes"))
+
+	assert.Empty(t, BuildRevisionInstruction(classified[1:2]))
+
+	intent, ok := highestIntent(classified)
+	assert.True(t, ok)
+	assert.Equal(t, types.FeedbackIntentBlocker, intent)
+}
+
+// TestAgentFeedbackClassifier tests agent-backed classification
+func TestAgentFeedbackClassifier(t *testing.T) {
+	rules, err := NewRulesFeedbackClassifier(DefaultFeedbackRules())
+	require.NoError(t, err)
+
+	items := []FeedbackItem{
+		{Kind: FeedbackKindComment, Author: "alice", Body: "Hmm, this feels off to me"},
+		{Kind: FeedbackKindComment, Author: "dependabot[bot]", Body: "Please update"},
+		{Kind: FeedbackKindComment, Author: "bob", Body: "Why a mutex?"},
+	}
+	workflow := &types.Workflow{TaskID: 7}
+
+	t.Run("uses agent answers", func(t *testing.T) {
+		// Test case: The agent classifies filtered items, and items it skips keep the rules result
+		runner := &fakeAgentRunner{output: "Sure!\n```json\n[{\"index\": 0, \"intent\": \"change\", \"reason\": \"design concern\"}, {\"index\": 1, \"intent\": \"blocker\"}]\n```"}
+		classifier := NewAgentFeedbackClassifier(runner, rules)
+
+		classified, err := classifier.Classify(context.Background(), workflow, items)
+		require.NoError(t, err)
+
+		require.Len(t, runner.instructions, 1)
+		assert.Equal(t, 7, runner.instructions[0].TaskID)
+		assert.NotContains(t, runner.instructions[0].Content, "dependabot")
+
+		assert.Equal(t, types.FeedbackIntentChange, classified[0].Intent)
+		assert.Equal(t, "design concern", classified[0].Reason)
+		assert.True(t, classified[1].Ignored, "bots are never reclassified by the agent")
+		assert.Equal(t, types.FeedbackIntentAsk, classified[2].Intent)
+	})
+
+	t.Run("falls back to rules", func(t *testing.T) {
+		// Test case: Agent failures and unparseable output fall back to the rules
+		for _, runner := range []*fakeAgentRunner{{err: errors.New("agent crashed")}, {output: "I could not decide"}} {
+			classified, err := NewAgentFeedbackClassifier(runner, rules).Classify(context.Background(), workflow, items)
+			require.NoError(t, err)
+
+			assert.True(t, classified[0].Ignored)
+			assert.True(t, classified[1].Ignored)
+			assert.Equal(t, types.FeedbackIntentAsk, classified[2].Intent)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// PhaseSpan is a stretch of time a workflow spent in one state
+type PhaseSpan struct {
+	// The transition that entered the state
+	Transition *types.WorkflowTransition
+
+	// How long the workflow stayed in the state
+	Duration time.Duration
+
+	// Whether the workflow is still in the state
+	Current bool
+}
+
+// DefaultActor identifies this process in the workflow log when a change
+// does not name its actor
+func DefaultActor() string {
+	return fmt.Sprintf("process-%d", os.Getpid())
+}
+
+// recordActor returns who a workflow log record names as making the change
+func recordActor(actor string) string {
+	if actor == "" {
+		return DefaultActor()
+	}
+	return actor
+}
+
+// ListTransitions returns the state transitions of a workflow, oldest first.
+// They are read from the workflow log, so they always agree with the state;
+// changes made before the log existed are not known.
+func (wm *WorkflowManager) ListTransitions(workflowID string) ([]*types.WorkflowTransition, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	var transitions []*types.WorkflowTransition
+	err := wm.withStateLock(func() error {
+		var workflow *types.Workflow
+		_, _, err := wm.readRecordsUnlocked(0, func(record *WorkflowRecord) error {
+			if fmt.Sprintf("%d", record.WorkflowID) != workflowID {
+				return nil
+			}
+
+			switch record.Type {
+			case WorkflowRecordCreated:
+				if record.Workflow == nil {
+					return fmt.Errorf("created record has no workflow")
+				}
+				workflow = record.Workflow
+				transitions = append(transitions, &types.WorkflowTransition{
+					WorkflowID: workflow.ID,
+					To:         workflow.State,
+					Actor:      record.Actor,
+					Reason:     "workflow created",
+					Timestamp:  record.Timestamp,
+				})
+			case WorkflowRecordUpdated:
+				if workflow == nil || record.Update == nil {
+					return nil
+				}
+				transition, err := applyWorkflowUpdate(workflow, record.Update, record.Timestamp)
+				if err != nil {
+					return err
+				}
+				if transition != nil {
+					transition.Actor = record.Actor
+					transitions = append(transitions, transition)
+				}
+			case WorkflowRecordDeleted:
+				workflow = nil
+			}
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transitions, nil
+}
+
+// PhaseSpans pairs each transition with the time spent in the state it
+// entered. The last state runs until now unless it is terminal.
+func PhaseSpans(transitions []*types.WorkflowTransition, now time.Time) []PhaseSpan {
+	spans := make([]PhaseSpan, 0, len(transitions))
+	for i, transition := range transitions {
+		span := PhaseSpan{Transition: transition}
+		switch {
+		case i+1 < len(transitions):
+			span.Duration = transitions[i+1].Timestamp.Sub(transition.Timestamp)
+		case !transition.To.IsTerminal():
+			span.Duration = now.Sub(transition.Timestamp)
+			span.Current = true
+		}
+		spans = append(spans, span)
+	}
+	return spans
+}
@@ -0,0 +1,97 @@
+package workflow
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestWorkflowManager_ListTransitions tests the state transition history
+func TestWorkflowManager_ListTransitions(t *testing.T) {
+	// Test case: Creation and every state change are listed with actor,
+	// reason and error; updates that keep the state are not
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	other := createTestWorkflow(t, manager, 2)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady)
+	transitionTestWorkflow(t, manager, other, types.WorkflowStateWorkspaceReady)
+
+	branch := "feature/1"
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch})
+	require.NoError(t, err)
+
+	failed := types.WorkflowStateFailed
+	lastError := "agent crashed"
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &failed,
+		LastError:  &lastError,
+		Actor:      "engine-42",
+		Reason:     "failed after 4 attempt(s)",
+	})
+	require.NoError(t, err)
+
+	_, err = manager.RetryWorkflow(workflowID, "user:jane")
+	require.NoError(t, err)
+
+	transitions, err := manager.ListTransitions(workflowID)
+	require.NoError(t, err)
+	require.Len(t, transitions, 4)
+
+	expected := []struct {
+		from, to types.WorkflowState
+		actor    string
+		reason   string
+	}{
+		{"", types.WorkflowStateQueued, DefaultActor(), "workflow created"},
+		{types.WorkflowStateQueued, types.WorkflowStateWorkspaceReady, DefaultActor(), ""},
+		{types.WorkflowStateWorkspaceReady, types.WorkflowStateFailed, "engine-42", "failed after 4 attempt(s)"},
+		{types.WorkflowStateFailed, types.WorkflowStateQueued, "user:jane", "retried"},
+	}
+	for i, want := range expected {
+		assert.Equal(t, workflow.ID, transitions[i].WorkflowID)
+		assert.Equal(t, want.from, transitions[i].From, "transition %d", i)
+		assert.Equal(t, want.to, transitions[i].To, "transition %d", i)
+		assert.Equal(t, want.actor, transitions[i].Actor, "transition %d", i)
+		assert.Equal(t, want.reason, transitions[i].Reason, "transition %d", i)
+	}
+	assert.Equal(t, "agent crashed", transitions[2].Error)
+
+	// The history is read from the workflow log other processes share
+	reopened, err := openSecondManager(t, manager).ListTransitions(workflowID)
+	require.NoError(t, err)
+	assert.Equal(t, transitions, reopened)
+}
+
+// TestPhaseSpans tests computing the time spent in each phase
+func TestPhaseSpans(t *testing.T) {
+	// Test case: Each state lasts until the next transition, the current
+	// state runs until now and terminal states have no duration
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	transitions := []*types.WorkflowTransition{
+		{To: types.WorkflowStateQueued, Timestamp: start},
+		{From: types.WorkflowStateQueued, To: types.WorkflowStateImplementing, Timestamp: start.Add(time.Minute)},
+		{From: types.WorkflowStateImplementing, To: types.WorkflowStatePROpen, Timestamp: start.Add(time.Hour)},
+	}
+	now := start.Add(2 * time.Hour)
+
+	spans := PhaseSpans(transitions, now)
+	require.Len(t, spans, 3)
+	assert.Equal(t, time.Minute, spans[0].Duration)
+	assert.Equal(t, 59*time.Minute, spans[1].Duration)
+	assert.Equal(t, time.Hour, spans[2].Duration)
+	assert.True(t, spans[2].Current)
+	assert.False(t, spans[1].Current)
+
+	transitions = append(transitions, &types.WorkflowTransition{From: types.WorkflowStatePROpen, To: types.WorkflowStateMerged, Timestamp: now})
+	spans = PhaseSpans(transitions, now.Add(time.Hour))
+	assert.Equal(t, time.Duration(0), spans[3].Duration)
+	assert.False(t, spans[3].Current)
+}
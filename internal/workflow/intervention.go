@@ -0,0 +1,125 @@
+package workflow
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// MetadataPausedState is the workflow metadata key recording the state a
+// paused workflow was in, so resuming can continue from there
+const MetadataPausedState = "paused_state"
+
+// PauseWorkflow pauses an active workflow on behalf of actor. The workflow is
+// locked while it is paused, so a step already running elsewhere makes the
+// pause fail instead of being overwritten by it.
+func (wm *WorkflowManager) PauseWorkflow(workflowID, actor string) (*types.Workflow, error) {
+	var paused *types.Workflow
+	err := wm.intervene(workflowID, actor, func(workflow *types.Workflow) error {
+		if workflow.State.IsTerminal() || workflow.State == types.WorkflowStatePaused {
+			return fmt.Errorf("workflow %s cannot be paused (state: %s)", workflowID, workflow.State)
+		}
+
+		state := types.WorkflowStatePaused
+		updated, err := wm.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			State:      &state,
+			Metadata:   map[string]string{MetadataPausedState: string(workflow.State)},
+			Actor:      actor,
+			Reason:     "paused",
+		})
+		paused = updated
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("⏸️  Workflow %s paused by %s", workflowID, actor)
+	return paused, nil
+}
+
+// ResumeWorkflow resumes a paused workflow on behalf of actor in the state it
+// was paused in
+func (wm *WorkflowManager) ResumeWorkflow(workflowID, actor string) (*types.Workflow, error) {
+	var resumed *types.Workflow
+	err := wm.intervene(workflowID, actor, func(workflow *types.Workflow) error {
+		if workflow.State != types.WorkflowStatePaused {
+			return fmt.Errorf("workflow %s is not paused (state: %s)", workflowID, workflow.State)
+		}
+
+		// Hand-edited workflows may not record where they were paused
+		state := types.WorkflowState(workflow.Metadata[MetadataPausedState])
+		if !state.IsValid() || state.IsTerminal() || state == types.WorkflowStatePaused {
+			state = types.WorkflowStateQueued
+		}
+
+		updated, err := wm.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			State:      &state,
+			Metadata:   map[string]string{MetadataPausedState: ""},
+			Actor:      actor,
+			Reason:     "resumed",
+		})
+		resumed = updated
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("▶️  Workflow %s resumed by %s in state %s", workflowID, actor, resumed.State)
+	return resumed, nil
+}
+
+// AbortWorkflow stops an active, paused or failed workflow for good on behalf of actor
+func (wm *WorkflowManager) AbortWorkflow(workflowID, actor string) (*types.Workflow, error) {
+	var aborted *types.Workflow
+	err := wm.intervene(workflowID, actor, func(workflow *types.Workflow) error {
+		if !workflow.State.CanTransitionTo(types.WorkflowStateAborted) {
+			return fmt.Errorf("workflow %s cannot be aborted (state: %s)", workflowID, workflow.State)
+		}
+
+		state := types.WorkflowStateAborted
+		updated, err := wm.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			State:      &state,
+			Metadata:   map[string]string{MetadataPausedState: ""},
+			Actor:      actor,
+			Reason:     "aborted",
+		})
+		aborted = updated
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🚫 Workflow %s aborted by %s", workflowID, actor)
+	return aborted, nil
+}
+
+// intervene runs a manual change on a workflow while holding its lock
+func (wm *WorkflowManager) intervene(workflowID, actor string, change func(workflow *types.Workflow) error) error {
+	if _, err := wm.GetWorkflow(workflowID); err != nil {
+		return err
+	}
+
+	if _, err := wm.LockWorkflow(workflowID, actor, DefaultLockLease); err != nil {
+		return fmt.Errorf("workflow %s is busy, try again shortly: %w", workflowID, err)
+	}
+	defer func() {
+		if err := wm.UnlockWorkflow(workflowID, actor); err != nil {
+			log.Printf("⚠️  Failed to release workflow lock: %v", err)
+		}
+	}()
+
+	// Re-read the workflow, which may have changed before the lock was taken
+	workflow, err := wm.GetWorkflow(workflowID)
+	if err != nil {
+		return err
+	}
+
+	return change(workflow)
+}
@@ -0,0 +1,102 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestWorkflowManager_PauseResume tests pausing and resuming a workflow
+func TestWorkflowManager_PauseResume(t *testing.T) {
+	// Test case: A paused workflow remembers its state, is skipped by the
+	// engine, resumes where it was and journals who intervened
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing)
+
+	paused, err := manager.PauseWorkflow("1", "user:alex")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStatePaused, paused.State)
+	assert.Equal(t, string(types.WorkflowStateImplementing), paused.Metadata[MetadataPausedState])
+
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	require.NoError(t, engine.ProcessWorkflow(context.Background(), "1"))
+	locked, _ := manager.IsWorkflowLocked("1")
+	assert.False(t, locked)
+
+	resumed, err := manager.ResumeWorkflow("1", "user:sam")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, resumed.State)
+	assert.Empty(t, resumed.Metadata[MetadataPausedState])
+
+	transitions, err := manager.ListTransitions("1")
+	require.NoError(t, err)
+	require.Len(t, transitions, 5)
+	assert.Equal(t, "user:alex", transitions[3].Actor)
+	assert.Equal(t, "paused", transitions[3].Reason)
+	assert.Equal(t, "user:sam", transitions[4].Actor)
+	assert.Equal(t, "resumed", transitions[4].Reason)
+}
+
+// TestWorkflowManager_Interventions_Rejected tests interventions that do not apply
+func TestWorkflowManager_Interventions_Rejected(t *testing.T) {
+	// Test case: Terminal or already paused workflows cannot be paused, only
+	// paused ones resumed, and a workflow being processed is left alone
+	testCases := []struct {
+		name   string
+		states []types.WorkflowState
+		locked bool
+		action func(manager *WorkflowManager) error
+		errMsg string
+	}{
+		{"pause a merged workflow", []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen, types.WorkflowStateMerged}, false,
+			func(m *WorkflowManager) error { _, err := m.PauseWorkflow("1", "user:alex"); return err }, "cannot be paused"},
+		{"pause twice", []types.WorkflowState{types.WorkflowStatePaused}, false,
+			func(m *WorkflowManager) error { _, err := m.PauseWorkflow("1", "user:alex"); return err }, "cannot be paused"},
+		{"resume an active workflow", nil, false,
+			func(m *WorkflowManager) error { _, err := m.ResumeWorkflow("1", "user:alex"); return err }, "not paused"},
+		{"abort an aborted workflow", []types.WorkflowState{types.WorkflowStateAborted}, false,
+			func(m *WorkflowManager) error { _, err := m.AbortWorkflow("1", "user:alex"); return err }, "cannot be aborted"},
+		{"pause while processing", nil, true,
+			func(m *WorkflowManager) error { _, err := m.PauseWorkflow("1", "user:alex"); return err }, "busy"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := createTestWorkflow(t, manager, 1)
+			transitionTestWorkflow(t, manager, workflow, tc.states...)
+			if tc.locked {
+				_, err := manager.LockWorkflow("1", "engine-1", time.Minute)
+				require.NoError(t, err)
+			}
+
+			err := tc.action(manager)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+		})
+	}
+}
+
+// TestWorkflowManager_AbortWorkflow tests aborting a paused workflow
+func TestWorkflowManager_AbortWorkflow(t *testing.T) {
+	// Test case: A paused workflow can be aborted, which ends it for good
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+
+	_, err := manager.PauseWorkflow("1", "user:alex")
+	require.NoError(t, err)
+
+	aborted, err := manager.AbortWorkflow("1", "user:alex")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateAborted, aborted.State)
+	assert.Empty(t, aborted.Metadata[MetadataPausedState])
+
+	_, err = manager.ResumeWorkflow("1", "user:alex")
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// FilterIssuesByLabels returns the issues the config allows cowork to pick
+// up based on their enable and disable labels
+func FilterIssuesByLabels(issues []*git.Issue, config *types.WorkflowConfig) []*git.Issue {
+	var allowed []*git.Issue
+	for _, issue := range issues {
+		if !config.AllowsIssue(labelNames(issue.Labels)) {
+			log.Printf("🏷️  Skipping issue #%d: labels do not enable cowork", issue.Number)
+			continue
+		}
+		allowed = append(allowed, issue)
+	}
+	return allowed
+}
+
+// labelNames returns the names of the given labels
+func labelNames(labels []*git.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label != nil {
+			names = append(names, label.Name)
+		}
+	}
+	return names
+}
+
+// findDisableLabel looks for a disable label on the workflow's issue and pull
+// request, returning the label and the number of the issue or PR carrying it
+func (e *Engine) findDisableLabel(ctx context.Context, workflow *types.Workflow) (string, int, error) {
+	if len(workflow.Config.DisableLabels) == 0 {
+		return "", 0, nil
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get issue labels: %w", err)
+	}
+	if label := workflow.Config.DisableLabel(labelNames(issue.Labels)); label != "" {
+		return label, workflow.IssueID, nil
+	}
+
+	if workflow.PRNumber == nil {
+		return "", 0, nil
+	}
+
+	pr, err := e.coworkProvider.GetPullRequest(ctx, e.owner, e.repo, *workflow.PRNumber)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get pull request labels: %w", err)
+	}
+	if label := workflow.Config.DisableLabel(labelNames(pr.Labels)); label != "" {
+		return label, pr.Number, nil
+	}
+
+	return "", 0, nil
+}
+
+// abortDisabledWorkflow aborts a workflow whose issue or pull request was
+// given a disable label: the agent is stopped, the task cancelled and a
+// comment left where the label was added
+func (e *Engine) abortDisabledWorkflow(ctx context.Context, workflow *types.Workflow, label string, number int) error {
+	log.Printf("🛑 Workflow %d disabled by label %q on #%d, aborting", workflow.ID, label, number)
+
+	e.stopAgent(ctx, workflow)
+
+	if workflow.TaskID != 0 {
+		status := types.TaskStatusCancelled
+		_, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{
+			TaskID: workflow.TaskID,
+			Status: &status,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to cancel task: %w", err)
+		}
+	}
+
+	state := types.WorkflowStateAborted
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Actor:      e.processID,
+		Reason:     fmt.Sprintf("%s label added to #%d", label, number),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to aborted: %w", err)
+	}
+
+	e.postComment(ctx, number, fmt.Sprintf("🛑 The `%s` label was added, so cowork stopped the agent and will not work on this any further.", label))
+	return nil
+}
@@ -0,0 +1,78 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestFilterIssuesByLabels tests which scanned issues cowork picks up
+func TestFilterIssuesByLabels(t *testing.T) {
+	// Test case: Only issues with the enable label and without the disable
+	// label are kept, unless the enable label requirement is skipped
+	issues := []*git.Issue{
+		{Number: 1, Labels: []*git.Label{{Name: "cowork:on"}}},
+		{Number: 2, Labels: []*git.Label{{Name: "bug"}}},
+		{Number: 3, Labels: []*git.Label{{Name: "cowork:on"}, {Name: "cowork:off"}}},
+	}
+	config := types.GetDefaultWorkflowConfig()
+
+	numbers := func(issues []*git.Issue) []int {
+		var result []int
+		for _, issue := range issues {
+			result = append(result, issue.Number)
+		}
+		return result
+	}
+
+	assert.Equal(t, []int{1}, numbers(FilterIssuesByLabels(issues, &config)))
+
+	config.IgnoreEnableLabels = true
+	assert.Equal(t, []int{1, 2}, numbers(FilterIssuesByLabels(issues, &config)))
+}
+
+// TestEngine_ProcessWorkflow_DisableLabel tests aborting in-flight workflows labeled off
+func TestEngine_ProcessWorkflow_DisableLabel(t *testing.T) {
+	// Test case: A disable label on the issue or the PR aborts the workflow,
+	// stops the agent, cancels the task and comments where the label is
+	testCases := []struct {
+		name        string
+		issueLabels []*git.Label
+		prLabels    []*git.Label
+		commentOn   int
+	}{
+		{"label on the issue", []*git.Label{{Name: "cowork:off"}}, nil, 7},
+		{"label on the pull request", nil, []*git.Label{{Name: "cowork:off"}}, 42},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := newImplementingWorkflow(t, manager, 0)
+			prNumber := 42
+			_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, PRNumber: &prNumber})
+			require.NoError(t, err)
+
+			provider := newFakeCoworkProvider()
+			provider.issues = map[int]*git.Issue{7: {Number: 7, Labels: tc.issueLabels}}
+			provider.pr = &git.PullRequest{Number: 42, Labels: tc.prLabels}
+			tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusInProgress})
+			workspaces := newFakeWorkspaceManager()
+			engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+
+			require.NoError(t, engine.ProcessWorkflow(context.Background(), fmt.Sprintf("%d", workflow.ID)))
+
+			assert.Equal(t, types.WorkflowStateAborted, reloadTestWorkflow(t, manager, workflow).State)
+			assert.Equal(t, []int{5}, workspaces.stopped)
+			assert.Equal(t, types.TaskStatusCancelled, tasks.tasks[3].Status)
+			require.Len(t, provider.comments[tc.commentOn], 1)
+			assert.Contains(t, provider.comments[tc.commentOn][0].Body, "`cowork:off`")
+		})
+	}
+}
@@ -17,8 +17,10 @@ type Manager struct {
 	coworkProvider   git.CoworkProvider
 	taskManager      task.TaskManager
 	workspaceManager workspace.WorkspaceManager
+	workflowManager  *WorkflowManager
 	owner            string
 	repo             string
+	config           types.WorkflowConfig
 }
 
 // NewManager creates a new workflow manager
@@ -29,50 +31,143 @@ func NewManager(coworkProvider git.CoworkProvider, taskManager task.TaskManager,
 		workspaceManager: workspaceManager,
 		owner:            owner,
 		repo:             repo,
+		config:           types.GetDefaultWorkflowConfig(),
 	}
 }
 
-// ScanAndCreateTasks scans open issues and creates tasks for assigned issues
-// This implements feature 1: scan all issues that are not in a closed state
-// This implements feature 2: create tasks for issues assigned to the current user
-func (wm *Manager) ScanAndCreateTasks(ctx context.Context) error {
+// SetConfig sets the workflow configuration, including the labels that gate
+// which issues are scanned
+func (wm *Manager) SetConfig(config types.WorkflowConfig) {
+	wm.config = config
+}
+
+// SetWorkflowManager sets where scanned issues get their workflows; without
+// one only tasks are created
+func (wm *Manager) SetWorkflowManager(workflowManager *WorkflowManager) {
+	wm.workflowManager = workflowManager
+}
+
+// ScanIssues returns the open issues assigned to the current user that the
+// config allows cowork to pick up: they need an enable label and no disable
+// label unless the config skips that check
+func (wm *Manager) ScanIssues(ctx context.Context) ([]*git.Issue, error) {
 	log.Printf("🔍 Scanning open issues for %s/%s", wm.owner, wm.repo)
 
 	// Scan open issues assigned to current user
 	issues, err := wm.coworkProvider.ScanOpenIssues(ctx, wm.owner, wm.repo)
 	if err != nil {
-		return fmt.Errorf("failed to scan open issues: %w", err)
+		return nil, fmt.Errorf("failed to scan open issues: %w", err)
 	}
 
 	log.Printf("📋 Found %d open issues assigned to current user", len(issues))
 
+	// Only issues labeled for cowork are picked up
+	return FilterIssuesByLabels(issues, &wm.config), nil
+}
+
+// ScanAndCreateTasks scans open issues and creates tasks for assigned issues
+// This implements feature 1: scan all issues that are not in a closed state
+// This implements feature 2: create tasks for issues assigned to the current user
+// With a workflow manager set, each issue also gets a queued workflow
+// keeping a snapshot of the config
+func (wm *Manager) ScanAndCreateTasks(ctx context.Context) error {
+	issues, err := wm.ScanIssues(ctx)
+	if err != nil {
+		return err
+	}
+
 	// Create tasks for each issue
+	var baseBranch string
 	for _, issue := range issues {
 		// Check if task already exists
-		existingTask, err := wm.coworkProvider.GetTaskByIssue(ctx, wm.owner, wm.repo, issue.Number)
+		task, err := wm.coworkProvider.GetTaskByIssue(ctx, wm.owner, wm.repo, issue.Number)
 		if err != nil {
 			log.Printf("⚠️  Error checking existing task for issue #%d: %v", issue.Number, err)
 			continue
 		}
 
-		if existingTask != nil {
-			log.Printf("✅ Task already exists for issue #%d: %s", issue.Number, existingTask.Name)
-			continue
+		if task != nil {
+			log.Printf("✅ Task already exists for issue #%d: %s", issue.Number, task.Name)
+		} else {
+			// Create new task
+			task, err = wm.coworkProvider.CreateTaskFromIssue(ctx, wm.owner, wm.repo, issue)
+			if err != nil {
+				log.Printf("❌ Failed to create task for issue #%d: %v", issue.Number, err)
+				continue
+			}
+
+			log.Printf("✅ Created task for issue #%d: %s (ID: %d)", issue.Number, task.Name, task.ID)
 		}
 
-		// Create new task
-		task, err := wm.coworkProvider.CreateTaskFromIssue(ctx, wm.owner, wm.repo, issue)
-		if err != nil {
-			log.Printf("❌ Failed to create task for issue #%d: %v", issue.Number, err)
+		if wm.workflowManager == nil {
 			continue
 		}
+		if baseBranch == "" {
+			baseBranch = wm.defaultBranch(ctx)
+		}
+		if err := wm.createWorkflow(ctx, issue, task, baseBranch); err != nil {
+			log.Printf("❌ Failed to create workflow for issue #%d: %v", issue.Number, err)
+		}
+	}
+
+	return nil
+}
+
+// createWorkflow queues a workflow for an issue with the dependencies its body
+// declares and the issues tracking it. An issue that already has a workflow
+// only gets its dependencies refreshed while the workflow is still queued.
+func (wm *Manager) createWorkflow(ctx context.Context, issue *git.Issue, task *types.Task, baseBranch string) error {
+	dependencies, err := IssueDependencies(ctx, wm.coworkProvider, wm.owner, wm.repo, issue)
+	if err != nil {
+		return err
+	}
+
+	if existing, err := wm.workflowManager.GetWorkflowByIssue(wm.owner, wm.repo, issue.Number); err == nil {
+		log.Printf("✅ Workflow already exists for issue #%d: %d", issue.Number, existing.ID)
+		if existing.State != types.WorkflowStateQueued || equalInts(dependencies, existing.DependsOn) {
+			return nil
+		}
+		if _, err := wm.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: existing.ID,
+			DependsOn:  &dependencies,
+		}); err != nil {
+			return fmt.Errorf("failed to record dependencies: %w", err)
+		}
+		return nil
+	}
 
-		log.Printf("✅ Created task for issue #%d: %s (ID: %d)", issue.Number, task.Name, task.ID)
+	workflow, err := wm.workflowManager.CreateWorkflow(&types.CreateWorkflowRequest{
+		Owner:      wm.owner,
+		Repo:       wm.repo,
+		IssueID:    issue.Number,
+		BaseBranch: baseBranch,
+		Provider:   string(wm.coworkProvider.GetProviderType()),
+		Config:     wm.config,
+		TaskID:     task.ID,
+		DependsOn:  dependencies,
+		Actor:      "scan",
+	})
+	if err != nil {
+		return err
 	}
 
+	log.Printf("✅ Created workflow for issue #%d (ID: %d)", issue.Number, workflow.ID)
 	return nil
 }
 
+// defaultBranch returns the repository's default branch, falling back to main
+func (wm *Manager) defaultBranch(ctx context.Context) string {
+	info, err := wm.coworkProvider.GetRepositoryInfo(ctx, wm.owner, wm.repo)
+	if err != nil {
+		log.Printf("⚠️  Failed to get default branch of %s/%s, using main: %v", wm.owner, wm.repo, err)
+		return "main"
+	}
+	if info.DefaultBranch == "" {
+		return "main"
+	}
+	return info.DefaultBranch
+}
+
 // ProcessQueuedTasks processes tasks that are queued and ready to start
 // This implements feature 3: create workspace when task is marked as running/started
 func (wm *Manager) ProcessQueuedTasks(ctx context.Context) error {
@@ -0,0 +1,244 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataImplementationPlan holds the agent's implementation plan on the task
+	MetadataImplementationPlan = "implementation_plan"
+
+	// MetadataPlanApprovedBy records on the task who approved its plan
+	MetadataPlanApprovedBy = "plan_approved_by"
+
+	// MetadataPlanPostedAt holds when the plan was posted on the issue
+	MetadataPlanPostedAt = "plan_posted_at"
+
+	// PlanApproveCommand approves a plan when commented on its issue by a plan approver
+	PlanApproveCommand = "/cowork approve"
+
+	// planHeading starts the approved plan folded into a task description
+	planHeading = "## Approved implementation plan"
+)
+
+// planPattern extracts the plan from agent output
+var planPattern = regexp.MustCompile(`(?s)<plan>(.*?)</plan>`)
+
+// needsPlanApproval reports whether a workflow must wait for its plan to be
+// approved before the agent starts implementing
+func needsPlanApproval(workflow *types.Workflow, task *types.Task) bool {
+	return workflow.Config.PlanFirst && task.Metadata[MetadataPlanApprovedBy] == ""
+}
+
+// proposePlan has the agent write an implementation plan without changing
+// code, records it on the task, posts it on the issue and moves the workflow
+// to PLAN_REVIEW
+func (e *Engine) proposePlan(ctx context.Context, workflow *types.Workflow, task *types.Task) error {
+	if e.agentRunner == nil {
+		return fmt.Errorf("plan-first mode needs an agent to write the plan")
+	}
+
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	log.Printf("📝 Asking agent for an implementation plan for workflow %d", workflow.ID)
+
+	instruction := &agent.AgentInstruction{
+		Content:   buildPlanPrompt(issue, task),
+		TaskID:    task.ID,
+		Metadata:  map[string]string{"purpose": "implementation_plan"},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := e.runAgent(ctx, e.agentRunner, workspace.Path, instruction)
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write implementation plan: %w", err)
+	}
+
+	plan, ok := extractPlan(result.Output)
+	if !ok {
+		return fmt.Errorf("agent output contained no implementation plan")
+	}
+
+	metadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		metadata[key] = value
+	}
+	metadata[MetadataImplementationPlan] = plan
+	if _, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{TaskID: task.ID, Metadata: &metadata}); err != nil {
+		return fmt.Errorf("failed to record implementation plan: %w", err)
+	}
+
+	body := fmt.Sprintf("%s\n%s", coworkCommentMarker, formatPlanComment(plan, task))
+	if _, err := e.coworkProvider.CreateComment(ctx, e.owner, e.repo, issue.Number, &git.CreateCommentRequest{Body: body}); err != nil {
+		return fmt.Errorf("failed to post implementation plan: %w", err)
+	}
+
+	state := types.WorkflowStatePlanReview
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Metadata:   map[string]string{MetadataPlanPostedAt: time.Now().UTC().Format(time.RFC3339)},
+		Actor:      e.processID,
+		Reason:     "implementation plan posted for review",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to plan_review: %w", err)
+	}
+
+	log.Printf("✅ Workflow %d is waiting for its plan to be approved", workflow.ID)
+	return nil
+}
+
+// processPlanReviewWorkflow handles workflows in PLAN_REVIEW state. Once the
+// plan is approved it is added to the task description and the agent starts.
+func (e *Engine) processPlanReviewWorkflow(ctx context.Context, workflow *types.Workflow) error {
+	log.Printf("📝 Processing plan review workflow %d", workflow.ID)
+
+	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+
+	approvedBy := task.Metadata[MetadataPlanApprovedBy]
+	if approvedBy == "" {
+		approvedBy, err = e.findPlanApproval(ctx, workflow)
+		if err != nil {
+			return err
+		}
+	}
+	if approvedBy == "" {
+		log.Printf("⏳ Plan for workflow %d is waiting for approval", workflow.ID)
+		return nil
+	}
+
+	metadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		metadata[key] = value
+	}
+	metadata[MetadataPlanApprovedBy] = approvedBy
+
+	description := task.Description
+	if index := strings.Index(description, planHeading); index >= 0 {
+		description = description[:index]
+	}
+	description = strings.TrimRight(description, "\n") + "\n\n" + planHeading + "\n\n" + task.Metadata[MetadataImplementationPlan] + "\n"
+
+	task, err = e.taskManager.UpdateTask(&types.UpdateTaskRequest{
+		TaskID:      task.ID,
+		Description: &description,
+		Metadata:    &metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add approved plan to task: %w", err)
+	}
+
+	log.Printf("👍 Plan for workflow %d approved by %s", workflow.ID, approvedBy)
+	return e.startImplementation(workflow, task, fmt.Sprintf("plan approved by %s", approvedBy))
+}
+
+// findPlanApproval returns who approved the plan with a comment on the issue
+// since it was posted. Only the workflow's plan approvers can approve.
+func (e *Engine) findPlanApproval(ctx context.Context, workflow *types.Workflow) (string, error) {
+	if len(workflow.Config.PlanApprovers) == 0 {
+		return "", nil
+	}
+
+	comments, err := e.coworkProvider.GetIssueComments(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get issue comments: %w", err)
+	}
+
+	postedAt, _ := time.Parse(time.RFC3339, workflow.Metadata[MetadataPlanPostedAt])
+	for _, comment := range comments {
+		if comment.User == nil || !comment.CreatedAt.After(postedAt) || strings.Contains(comment.Body, coworkCommentMarker) {
+			continue
+		}
+		if !containsLogin(workflow.Config.PlanApprovers, comment.User.Login) || !hasPlanApproveCommand(comment.Body) {
+			continue
+		}
+		return comment.User.Login, nil
+	}
+
+	return "", nil
+}
+
+// buildPlanPrompt builds the instruction asking the agent for an implementation plan
+func buildPlanPrompt(issue *git.Issue, task *types.Task) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Write an implementation plan for issue #%d (%s). Do not modify any files.\n\n", issue.Number, issue.Title)
+	if description := strings.TrimSpace(task.Description); description != "" {
+		fmt.Fprintf(&sb, "## Task\n\n%s\n\n", description)
+	}
+
+	sb.WriteString("The plan is reviewed by a maintainer before any code changes. Cover:\n")
+	sb.WriteString("- the files to touch and what changes in each\n")
+	sb.WriteString("- the approach, and any alternatives you rejected\n")
+	sb.WriteString("- how the change will be tested\n\n")
+	sb.WriteString("Reply with the plan in markdown wrapped in <plan></plan> tags.\n")
+
+	return sb.String()
+}
+
+// extractPlan returns the last tagged plan in the agent output
+func extractPlan(output string) (string, bool) {
+	matches := planPattern.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return "", false
+	}
+
+	plan := strings.TrimSpace(matches[len(matches)-1][1])
+	return plan, plan != ""
+}
+
+// formatPlanComment presents the plan on the issue with how to approve it
+func formatPlanComment(plan string, task *types.Task) string {
+	var sb strings.Builder
+
+	sb.WriteString("📝 **Implementation plan**\n\n")
+	sb.WriteString(plan)
+	fmt.Fprintf(&sb, "\n\n---\nWork starts once a maintainer approves this plan by commenting `%s` or running `cw task approve %d`.\n", PlanApproveCommand, task.ID)
+
+	return sb.String()
+}
+
+// hasPlanApproveCommand reports whether a comment has the approve command on a line of its own
+func hasPlanApproveCommand(body string) bool {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.EqualFold(strings.TrimSpace(line), PlanApproveCommand) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsLogin reports whether logins holds the login, ignoring case
+func containsLogin(logins []string, login string) bool {
+	for _, candidate := range logins {
+		if strings.EqualFold(strings.TrimPrefix(candidate, "@"), login) {
+			return true
+		}
+	}
+	return false
+}
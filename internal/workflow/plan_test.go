@@ -0,0 +1,153 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// newPlanReviewWorkflow creates a plan-first workflow whose plan was posted an hour ago
+func newPlanReviewWorkflow(t *testing.T, manager *WorkflowManager) (*types.Workflow, time.Time) {
+	t.Helper()
+
+	workflow := createTestWorkflow(t, manager, 7)
+	workflow.Config.PlanFirst = true
+	workflow.Config.PlanApprovers = []string{"maintainer"}
+	storeTestWorkflow(t, manager, workflow)
+	postedAt := time.Now().Add(-time.Hour)
+	taskID, workspaceID := 3, 5
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID:  workflow.ID,
+		TaskID:      &taskID,
+		WorkspaceID: &workspaceID,
+		Metadata:    map[string]string{MetadataPlanPostedAt: postedAt.UTC().Format(time.RFC3339)},
+	})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStatePlanReview)
+
+	return workflow, postedAt
+}
+
+// TestEngine_ProposePlan tests asking for a plan before implementing in plan-first mode
+func TestEngine_ProposePlan(t *testing.T) {
+	// Test case: The agent's plan is recorded on the task, posted on the
+	// issue and the workflow waits for approval instead of implementing
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 7)
+	workflow.Config.PlanFirst = true
+	storeTestWorkflow(t, manager, workflow)
+	taskID, workspaceID := 3, 5
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, TaskID: &taskID, WorkspaceID: &workspaceID})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady)
+
+	provider := newFakeCoworkProvider()
+	provider.issues = map[int]*git.Issue{7: {Number: 7, Title: "Fix the widget"}}
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusQueued, Description: "Widget is broken"})
+	runner := &fakeAgentRunner{output: "Thinking...\n<plan>\n- Touch widget.go\n- Add a test\n</plan>"}
+	engine := NewEngine(manager, tasks, newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: t.TempDir()}), provider, "owner", "repo")
+	engine.SetAgentRunner(runner)
+
+	require.NoError(t, engine.processWorkspaceReadyWorkflow(context.Background(), workflow))
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStatePlanReview, workflow.State)
+	assert.NotEmpty(t, workflow.Metadata[MetadataPlanPostedAt])
+	require.Len(t, runner.instructions, 1)
+	assert.Contains(t, runner.instructions[0].Content, "Do not modify any files")
+	assert.Contains(t, runner.instructions[0].Content, "Widget is broken")
+
+	task, err := tasks.GetTask("3")
+	require.NoError(t, err)
+	assert.Equal(t, "- Touch widget.go\n- Add a test", task.Metadata[MetadataImplementationPlan])
+	assert.Equal(t, types.TaskStatusQueued, task.Status)
+
+	require.Len(t, provider.comments[7], 1)
+	body := provider.comments[7][0].Body
+	assert.Contains(t, body, coworkCommentMarker)
+	assert.Contains(t, body, "- Touch widget.go")
+	assert.Contains(t, body, PlanApproveCommand)
+	assert.Contains(t, body, "cw task approve 3")
+}
+
+// TestEngine_ProcessPlanReviewWorkflow tests starting implementation once the plan is approved
+func TestEngine_ProcessPlanReviewWorkflow(t *testing.T) {
+	testCases := []struct {
+		name           string
+		comments       func(postedAt time.Time) []*git.Comment
+		cliApproval    string
+		expectState    types.WorkflowState
+		expectApprover string
+	}{
+		{
+			// Test case: Without approval the workflow keeps waiting
+			name:        "not approved",
+			comments:    func(time.Time) []*git.Comment { return nil },
+			expectState: types.WorkflowStatePlanReview,
+		},
+		{
+			// Test case: A plan approver commenting the command approves the plan
+			name: "approved by comment",
+			comments: func(postedAt time.Time) []*git.Comment {
+				return []*git.Comment{{ID: 1, User: &git.User{Login: "Maintainer"}, Body: "Looks good.\n/cowork approve", CreatedAt: postedAt.Add(time.Minute)}}
+			},
+			expectState:    types.WorkflowStateImplementing,
+			expectApprover: "Maintainer",
+		},
+		{
+			// Test case: The command from someone who is not a plan approver, or
+			// from before the plan was posted, is ignored
+			name: "ignored comments",
+			comments: func(postedAt time.Time) []*git.Comment {
+				return []*git.Comment{
+					{ID: 1, User: &git.User{Login: "drive-by"}, Body: "/cowork approve", CreatedAt: postedAt.Add(time.Minute)},
+					{ID: 2, User: &git.User{Login: "maintainer"}, Body: "/cowork approve", CreatedAt: postedAt.Add(-time.Minute)},
+				}
+			},
+			expectState: types.WorkflowStatePlanReview,
+		},
+		{
+			// Test case: Approval recorded by cw task approve starts implementation
+			name:           "approved from the CLI",
+			comments:       func(time.Time) []*git.Comment { return nil },
+			cliApproval:    "alice",
+			expectState:    types.WorkflowStateImplementing,
+			expectApprover: "alice",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow, postedAt := newPlanReviewWorkflow(t, manager)
+
+			provider := newFakeCoworkProvider()
+			provider.issueComments = map[int][]*git.Comment{7: tc.comments(postedAt)}
+			metadata := map[string]string{MetadataImplementationPlan: "- Touch widget.go"}
+			if tc.cliApproval != "" {
+				metadata[MetadataPlanApprovedBy] = tc.cliApproval
+			}
+			tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusQueued, Description: "Widget is broken", Metadata: metadata})
+			engine := NewEngine(manager, tasks, newFakeWorkspaceManager(), provider, "owner", "repo")
+
+			require.NoError(t, engine.processPlanReviewWorkflow(context.Background(), workflow))
+			assert.Equal(t, tc.expectState, reloadTestWorkflow(t, manager, workflow).State)
+
+			task, err := tasks.GetTask("3")
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectApprover, task.Metadata[MetadataPlanApprovedBy])
+			if tc.expectApprover == "" {
+				assert.Equal(t, "Widget is broken", task.Description)
+				return
+			}
+			assert.Equal(t, types.TaskStatusInProgress, task.Status)
+			assert.Equal(t, "Widget is broken\n\n## Approved implementation plan\n\n- Touch widget.go\n", task.Description)
+		})
+	}
+}
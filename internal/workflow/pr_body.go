@@ -0,0 +1,82 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os/exec"
+	"strings"
+
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// recordVerification runs the workflow's verification commands and records
+// their results on the task for the PR body. Failures do not stop the PR;
+// they are reported in it for reviewers and left to the required checks.
+func (e *Engine) recordVerification(ctx context.Context, workflow *types.Workflow, task *types.Task, workspacePath string) error {
+	if len(workflow.Config.VerifyCommands) == 0 {
+		return nil
+	}
+
+	results := collectVerification(ctx, workspacePath, workflow.Config.VerifyCommands)
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode verification results: %w", err)
+	}
+
+	metadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		metadata[key] = value
+	}
+	metadata[prbody.MetadataVerification] = string(encoded)
+
+	if _, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{TaskID: task.ID, Metadata: &metadata}); err != nil {
+		return fmt.Errorf("failed to record verification results: %w", err)
+	}
+	task.Metadata = metadata
+
+	return nil
+}
+
+// collectVerification runs every verification command in the workspace,
+// keeping the tail of the output of each
+func collectVerification(ctx context.Context, workspacePath string, commands []string) []prbody.Verification {
+	results := make([]prbody.Verification, 0, len(commands))
+	for _, command := range commands {
+		log.Printf("🧪 Verifying: %s", command)
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = workspacePath
+		output, err := cmd.CombinedOutput()
+		results = append(results, prbody.Verification{
+			Command: command,
+			Passed:  err == nil,
+			Output:  prbody.Tail(strings.TrimSpace(string(output)), prbody.VerificationOutputLines),
+		})
+	}
+
+	return results
+}
+
+// currentPullRequestBody renders a workflow's PR body from its task and
+// workspace as they are now
+func (e *Engine) currentPullRequestBody(ctx context.Context, workflow *types.Workflow) (string, error) {
+	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
+	if err != nil {
+		return "", fmt.Errorf("failed to get task: %w", err)
+	}
+
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	return prbody.Build(e.prTemplate, issue, task, workspace.Path, workflow.BaseBranch), nil
+}
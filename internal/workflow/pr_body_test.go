@@ -0,0 +1,35 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_RecordVerification tests recording verification results for the PR body
+func TestEngine_RecordVerification(t *testing.T) {
+	// Test case: Every command runs even after a failure, and the results are
+	// added to the task metadata next to what was already there
+	task := &types.Task{ID: 3, Metadata: map[string]string{"branch_name": "task-7"}}
+	tasks := newFakeTaskManager(task)
+	engine := NewEngine(newTestWorkflowManager(t), tasks, nil, newFakeCoworkProvider(), "owner", "repo")
+	workflow := newSyncWorkflow(func(c *types.WorkflowConfig) {
+		c.VerifyCommands = []string{"echo broken && false", "echo fine"}
+	})
+
+	require.NoError(t, engine.recordVerification(context.Background(), workflow, task, t.TempDir()))
+
+	var results []prbody.Verification
+	require.NoError(t, json.Unmarshal([]byte(tasks.tasks[3].Metadata[prbody.MetadataVerification]), &results))
+	assert.Equal(t, []prbody.Verification{
+		{Command: "echo broken && false", Passed: false, Output: "broken"},
+		{Command: "echo fine", Passed: true, Output: "fine"},
+	}, results)
+	assert.Equal(t, "task-7", tasks.tasks[3].Metadata["branch_name"])
+}
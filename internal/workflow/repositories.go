@@ -0,0 +1,263 @@
+package workflow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/hlfshell/cowork/internal/auth"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// RepositoriesFileName is the file listing the repositories one daemon orchestrates
+const RepositoriesFileName = "repositories.yaml"
+
+// RepositoryConfig describes one repository orchestrated by the daemon
+type RepositoryConfig struct {
+	Owner string `yaml:"owner"`
+	Repo  string `yaml:"repo"`
+
+	// Git provider hosting the repository (defaults to github)
+	Provider string `yaml:"provider"`
+
+	// Scope of the provider credentials to use; empty tries the project
+	// credentials first and falls back to the global ones
+	AuthScope auth.AuthScope `yaml:"auth_scope"`
+
+	// Maximum number of this repository's workflows processed concurrently;
+	// zero leaves it bounded only by the global budget
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	// Workflow settings of this repository, laid out like .cw/workflow.yaml
+	// and applied over the daemon's own workflow configuration
+	Workflow *workflowConfigFile `yaml:"workflow"`
+}
+
+// Key returns the repository as owner/repo
+func (rc *RepositoryConfig) Key() string {
+	return RepositoryKey(rc.Owner, rc.Repo)
+}
+
+// WorkflowConfig returns the repository's workflow configuration: its own
+// settings applied over base
+func (rc *RepositoryConfig) WorkflowConfig(base types.WorkflowConfig) (types.WorkflowConfig, error) {
+	if rc.Workflow == nil {
+		return base.Clone(), nil
+	}
+
+	config := rc.Workflow.mergeOver(base.Clone())
+	if err := config.Validate(); err != nil {
+		return types.WorkflowConfig{}, fmt.Errorf("invalid workflow config for %s: %w", rc.Key(), err)
+	}
+
+	return config, nil
+}
+
+// RepositoriesConfig lists the repositories orchestrated by one daemon
+type RepositoriesConfig struct {
+	// Maximum number of workflows processed concurrently across every
+	// repository; zero keeps the daemon's own setting, and an explicit
+	// command line limit takes precedence
+	MaxConcurrent int `yaml:"max_concurrent"`
+
+	Repositories []RepositoryConfig `yaml:"repositories"`
+}
+
+// Validate checks if the repositories config is valid
+func (rc *RepositoriesConfig) Validate() error {
+	if rc.MaxConcurrent < 0 {
+		return fmt.Errorf("max concurrent must be non-negative")
+	}
+
+	if len(rc.Repositories) == 0 {
+		return fmt.Errorf("at least one repository is required")
+	}
+
+	seen := make(map[string]bool)
+	for _, repository := range rc.Repositories {
+		if repository.Owner == "" || repository.Repo == "" {
+			return fmt.Errorf("repositories need an owner and a repo")
+		}
+
+		key := repository.Key()
+		if seen[key] {
+			return fmt.Errorf("repository %s is listed more than once", key)
+		}
+		seen[key] = true
+
+		switch repository.AuthScope {
+		case "", auth.AuthScopeProject, auth.AuthScopeGlobal:
+		default:
+			return fmt.Errorf("repository %s has invalid auth scope %q", key, repository.AuthScope)
+		}
+
+		if repository.MaxConcurrent < 0 {
+			return fmt.Errorf("repository %s max concurrent must be non-negative", key)
+		}
+
+		if _, err := repository.WorkflowConfig(types.GetDefaultWorkflowConfig()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RepoLimits returns the per-repository concurrency limits keyed by owner/repo
+func (rc *RepositoriesConfig) RepoLimits() map[string]int {
+	limits := make(map[string]int)
+	for _, repository := range rc.Repositories {
+		if repository.MaxConcurrent > 0 {
+			limits[repository.Key()] = repository.MaxConcurrent
+		}
+	}
+	return limits
+}
+
+// LoadRepositoriesConfig reads the list of repositories a daemon orchestrates
+func LoadRepositoriesConfig(path string) (*RepositoriesConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read repositories config: %w", err)
+	}
+
+	var config RepositoriesConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse repositories config: %w", err)
+	}
+
+	for i := range config.Repositories {
+		if config.Repositories[i].Provider == "" {
+			config.Repositories[i].Provider = "github"
+		}
+		config.Repositories[i].Provider = strings.ToLower(config.Repositories[i].Provider)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid repositories config in %s: %w", path, err)
+	}
+
+	return &config, nil
+}
+
+// RepositoryKey identifies a repository as owner/repo
+func RepositoryKey(owner, repo string) string {
+	return owner + "/" + repo
+}
+
+// RepositoryRouter hands each workflow to the processor of its repository, so
+// one daemon can drive an engine per repository
+type RepositoryRouter struct {
+	workflowManager *WorkflowManager
+
+	// Processors keyed by owner/repo
+	processors map[string]WorkflowProcessor
+	mu         sync.RWMutex
+}
+
+// NewRepositoryRouter creates a router without any repositories
+func NewRepositoryRouter(workflowManager *WorkflowManager) *RepositoryRouter {
+	return &RepositoryRouter{
+		workflowManager: workflowManager,
+		processors:      make(map[string]WorkflowProcessor),
+	}
+}
+
+// AddRepository routes the workflows of owner/repo to processor
+func (r *RepositoryRouter) AddRepository(owner, repo string, processor WorkflowProcessor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.processors[RepositoryKey(owner, repo)] = processor
+}
+
+// Repositories returns the routed repositories as sorted owner/repo keys
+func (r *RepositoryRouter) Repositories() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]string, 0, len(r.processors))
+	for key := range r.processors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ProcessWorkflow processes a workflow with its repository's processor
+func (r *RepositoryRouter) ProcessWorkflow(ctx context.Context, workflowID string) error {
+	workflow, err := r.workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	processor, err := r.processorFor(workflow)
+	if err != nil {
+		return err
+	}
+
+	return processor.ProcessWorkflow(ctx, workflowID)
+}
+
+// ConsumeEvents consumes the events of every routed repository whose
+// processor consumes events
+func (r *RepositoryRouter) ConsumeEvents(ctx context.Context) ([]string, error) {
+	var workflowIDs []string
+	var errs []error
+
+	for _, key := range r.Repositories() {
+		r.mu.RLock()
+		consumer, ok := r.processors[key].(EventConsumer)
+		r.mu.RUnlock()
+		if !ok {
+			continue
+		}
+
+		ids, err := consumer.ConsumeEvents(ctx)
+		workflowIDs = append(workflowIDs, ids...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+
+	return workflowIDs, errors.Join(errs...)
+}
+
+// HandleJobTimeout aborts a timed-out workflow through its repository's engine
+func (r *RepositoryRouter) HandleJobTimeout(ctx context.Context, workflow *types.Workflow) error {
+	processor, err := r.processorFor(workflow)
+	if err != nil {
+		return err
+	}
+
+	engine, ok := processor.(*Engine)
+	if !ok {
+		return fmt.Errorf("processor for %s cannot handle job timeouts", RepositoryKey(workflow.Owner, workflow.Repo))
+	}
+
+	return engine.HandleJobTimeout(ctx, workflow)
+}
+
+// processorFor returns the processor routed for a workflow's repository
+func (r *RepositoryRouter) processorFor(workflow *types.Workflow) (WorkflowProcessor, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key := RepositoryKey(workflow.Owner, workflow.Repo)
+	processor, ok := r.processors[key]
+	if !ok {
+		return nil, fmt.Errorf("no engine is configured for repository %s", key)
+	}
+
+	return processor, nil
+}
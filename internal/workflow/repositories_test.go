@@ -0,0 +1,150 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/auth"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// createRepoWorkflow creates a queued workflow for an issue of owner/repo
+func createRepoWorkflow(t *testing.T, manager *WorkflowManager, owner, repo string, issueID int) *types.Workflow {
+	t.Helper()
+
+	workflow, err := manager.CreateWorkflow(&types.CreateWorkflowRequest{
+		Owner:      owner,
+		Repo:       repo,
+		IssueID:    issueID,
+		BaseBranch: "main",
+		Provider:   "github",
+	})
+	require.NoError(t, err)
+
+	return workflow
+}
+
+// TestLoadRepositoriesConfig tests loading the list of orchestrated repositories
+func TestLoadRepositoriesConfig(t *testing.T) {
+	// Test case: Repositories default to GitHub and keep their own auth scope
+	// and limits; incomplete, duplicate or invalid entries are rejected
+	dir := t.TempDir()
+	path := filepath.Join(dir, RepositoriesFileName)
+	content := `max_concurrent: 4
+repositories:
+  - owner: acme
+    repo: api
+    max_concurrent: 2
+  - owner: acme
+    repo: web
+    provider: GitHub
+    auth_scope: global
+    workflow:
+      required_checks: [build]
+      draft_pull_requests: true
+`
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+	config, err := LoadRepositoriesConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, 4, config.MaxConcurrent)
+	require.Len(t, config.Repositories, 2)
+	assert.Equal(t, "github", config.Repositories[0].Provider)
+	assert.Equal(t, "github", config.Repositories[1].Provider)
+	assert.Equal(t, auth.AuthScopeGlobal, config.Repositories[1].AuthScope)
+	assert.Equal(t, map[string]int{"acme/api": 2}, config.RepoLimits())
+
+	base := types.GetDefaultWorkflowConfig()
+	base.MaxRetries = 5
+	api, err := config.Repositories[0].WorkflowConfig(base)
+	require.NoError(t, err)
+	assert.Equal(t, base, api)
+	web, err := config.Repositories[1].WorkflowConfig(base)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"build"}, web.RequiredChecks)
+	assert.True(t, web.DraftPullRequests)
+	assert.Equal(t, 5, web.MaxRetries)
+
+	invalidCases := map[string]string{
+		"no repositories":          "max_concurrent: 2\n",
+		"missing repo":             "repositories:\n  - owner: acme\n",
+		"duplicate":                "repositories:\n  - {owner: acme, repo: api}\n  - {owner: acme, repo: api}\n",
+		"bad auth scope":           "repositories:\n  - {owner: acme, repo: api, auth_scope: team}\n",
+		"negative limit":           "repositories:\n  - {owner: acme, repo: api, max_concurrent: -1}\n",
+		"unknown setting":          "repositories:\n  - {owner: acme, repo: api, token: secret}\n",
+		"unknown workflow setting": "repositories:\n  - {owner: acme, repo: api, workflow: {max_retry: 2}}\n",
+		"invalid workflow":         "repositories:\n  - {owner: acme, repo: api, workflow: {max_retries: -1}}\n",
+	}
+	for name, content := range invalidCases {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+
+			_, err := LoadRepositoriesConfig(path)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestRepositoryRouter_ProcessWorkflow tests routing workflows to their repository's processor
+func TestRepositoryRouter_ProcessWorkflow(t *testing.T) {
+	// Test case: Each workflow reaches the processor of its repository, and
+	// workflows of repositories without a processor are refused
+	manager := newTestWorkflowManager(t)
+	createRepoWorkflow(t, manager, "acme", "api", 1)
+	createRepoWorkflow(t, manager, "acme", "web", 2)
+	createRepoWorkflow(t, manager, "other", "repo", 3)
+
+	api, web := &recordingProcessor{}, &recordingProcessor{}
+	router := NewRepositoryRouter(manager)
+	router.AddRepository("acme", "web", web)
+	router.AddRepository("acme", "api", api)
+	assert.Equal(t, []string{"acme/api", "acme/web"}, router.Repositories())
+
+	require.NoError(t, router.ProcessWorkflow(context.Background(), "1"))
+	require.NoError(t, router.ProcessWorkflow(context.Background(), "2"))
+	assert.Equal(t, []string{"1"}, api.processed)
+	assert.Equal(t, []string{"2"}, web.processed)
+
+	err := router.ProcessWorkflow(context.Background(), "3")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "other/repo")
+}
+
+// TestDaemon_RunOnce_RepoLimits tests per-repository limits within the global budget
+func TestDaemon_RunOnce_RepoLimits(t *testing.T) {
+	// Test case: A repository at its own limit does not block other
+	// repositories, and repositories outside the daemon's list are ignored
+	manager := newTestWorkflowManager(t)
+	for issueID := 1; issueID <= 3; issueID++ {
+		createRepoWorkflow(t, manager, "acme", "api", issueID)
+	}
+	createRepoWorkflow(t, manager, "acme", "web", 4)
+	createRepoWorkflow(t, manager, "acme", "web", 5)
+	createRepoWorkflow(t, manager, "other", "repo", 6)
+
+	api := &recordingProcessor{delay: 20 * time.Millisecond}
+	web := &recordingProcessor{delay: 20 * time.Millisecond}
+	router := NewRepositoryRouter(manager)
+	router.AddRepository("acme", "api", api)
+	router.AddRepository("acme", "web", web)
+
+	daemon, err := NewDaemon(manager, router, DaemonConfig{
+		MaxConcurrent:     4,
+		Repositories:      router.Repositories(),
+		RepoMaxConcurrent: map[string]int{"acme/api": 1},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, daemon.RunOnce(context.Background()))
+
+	assert.Len(t, api.processed, 1)
+	assert.Len(t, web.processed, 2)
+	assert.Equal(t, 0, daemon.InFlightCount())
+}
@@ -0,0 +1,107 @@
+package workflow
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// MetadataFailedState is the workflow metadata key recording the state a
+// failed workflow was in, so a retry can resume from there
+const MetadataFailedState = "failed_state"
+
+// recordFailure counts a failed processing attempt and schedules a retry with
+// exponential backoff, or moves the workflow to failed once retries are
+// exhausted. The original error is returned for the caller to report.
+func (e *Engine) recordFailure(workflowID string, cause error) error {
+	// Re-read the workflow since the failed step may have changed its state
+	workflow, err := e.workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		log.Printf("⚠️  Failed to record failure for workflow %s: %v", workflowID, err)
+		return cause
+	}
+
+	errorCount := workflow.ErrorCount + 1
+	lastError := cause.Error()
+	updateReq := &types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		ErrorCount: &errorCount,
+		LastError:  &lastError,
+	}
+
+	if errorCount > workflow.Config.MaxRetries {
+		state := types.WorkflowStateFailed
+		updateReq.State = &state
+		updateReq.Actor = e.processID
+		updateReq.Reason = fmt.Sprintf("failed after %d attempt(s)", errorCount)
+		updateReq.NextRetryAt = &time.Time{}
+		updateReq.Metadata = map[string]string{MetadataFailedState: string(workflow.State)}
+		log.Printf("💥 Workflow %s failed after %d attempt(s): %v", workflowID, errorCount, cause)
+	} else {
+		nextRetryAt := time.Now().Add(workflow.Config.RetryBackoff(errorCount))
+		updateReq.NextRetryAt = &nextRetryAt
+		log.Printf("🔁 Workflow %s attempt %d/%d failed, retrying at %s", workflowID, errorCount, workflow.Config.MaxRetries+1, nextRetryAt.Format(time.RFC3339))
+	}
+
+	if _, err := e.workflowManager.UpdateWorkflow(updateReq); err != nil {
+		log.Printf("⚠️  Failed to record failure for workflow %s: %v", workflowID, err)
+	}
+
+	return cause
+}
+
+// clearFailures resets the retry bookkeeping after a successful step
+func (e *Engine) clearFailures(workflowID string) error {
+	workflow, err := e.workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	if workflow.ErrorCount == 0 && workflow.NextRetryAt == nil {
+		return nil
+	}
+
+	errorCount := 0
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID:  workflow.ID,
+		ErrorCount:  &errorCount,
+		NextRetryAt: &time.Time{},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reset workflow retries: %w", err)
+	}
+
+	return nil
+}
+
+// RetryWorkflow requeues a failed workflow on behalf of actor, resuming from
+// the state it failed in
+func (wm *WorkflowManager) RetryWorkflow(workflowID, actor string) (*types.Workflow, error) {
+	workflow, err := wm.GetWorkflow(workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	if workflow.State != types.WorkflowStateFailed {
+		return nil, fmt.Errorf("workflow %s is not failed (state: %s)", workflowID, workflow.State)
+	}
+
+	// Older or hand-edited workflows may not record where they failed
+	state := types.WorkflowState(workflow.Metadata[MetadataFailedState])
+	if !state.IsValid() || state.IsTerminal() {
+		state = types.WorkflowStateQueued
+	}
+
+	errorCount := 0
+	return wm.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID:  workflow.ID,
+		State:       &state,
+		ErrorCount:  &errorCount,
+		NextRetryAt: &time.Time{},
+		Metadata:    map[string]string{MetadataFailedState: ""},
+		Actor:       actor,
+		Reason:      "retried",
+	})
+}
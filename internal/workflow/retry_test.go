@@ -0,0 +1,152 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_RecordFailure_BacksOffThenFails tests retry scheduling and the failed state
+func TestEngine_RecordFailure_BacksOffThenFails(t *testing.T) {
+	// Test case: Each failure doubles the retry delay until MaxRetries is
+	// exceeded, at which point the workflow moves to failed
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+	workflow.Config.MaxRetries = 2
+	workflow.Config.RetryDelay = time.Minute
+	storeTestWorkflow(t, manager, workflow)
+
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	cause := errors.New("github returned 502")
+
+	start := time.Now()
+	assert.Equal(t, cause, engine.recordFailure(workflowID, cause))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, 1, workflow.ErrorCount)
+	assert.Equal(t, "github returned 502", workflow.LastError)
+	require.NotNil(t, workflow.NextRetryAt)
+	assert.WithinDuration(t, start.Add(time.Minute), *workflow.NextRetryAt, time.Second)
+
+	engine.recordFailure(workflowID, cause)
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, 2, workflow.ErrorCount)
+	require.NotNil(t, workflow.NextRetryAt)
+	assert.WithinDuration(t, start.Add(2*time.Minute), *workflow.NextRetryAt, time.Second)
+	assert.Equal(t, types.WorkflowStateQueued, workflow.State)
+
+	engine.recordFailure(workflowID, cause)
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateFailed, workflow.State)
+	assert.Nil(t, workflow.NextRetryAt)
+	assert.Equal(t, "queued", workflow.Metadata[MetadataFailedState])
+}
+
+// TestEngine_ClearFailures tests that a successful step resets retry bookkeeping
+func TestEngine_ClearFailures(t *testing.T) {
+	// Test case: After a failure, clearing resets the count and the backoff but keeps the last error
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	engine.recordFailure(workflowID, errors.New("boom"))
+	require.Equal(t, 1, reloadTestWorkflow(t, manager, workflow).ErrorCount)
+
+	require.NoError(t, engine.clearFailures(workflowID))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+
+	assert.Equal(t, 0, workflow.ErrorCount)
+	assert.Nil(t, workflow.NextRetryAt)
+	assert.Equal(t, "boom", workflow.LastError)
+}
+
+// TestWorkflowManager_RetryWorkflow tests requeueing failed workflows
+func TestWorkflowManager_RetryWorkflow(t *testing.T) {
+	// Test case: A failed workflow resumes in the state it failed in, and
+	// workflows that are not failed cannot be retried
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+
+	_, err := manager.RetryWorkflow(workflowID, "user:test")
+	assert.Error(t, err)
+
+	for _, state := range []types.WorkflowState{types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing} {
+		_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, State: &state})
+		require.NoError(t, err)
+	}
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	workflow.Config.MaxRetries = 0
+	storeTestWorkflow(t, manager, workflow)
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	engine.recordFailure(workflowID, errors.New("boom"))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	require.Equal(t, types.WorkflowStateFailed, workflow.State)
+	require.NotNil(t, workflow.EndedAt)
+
+	retried, err := manager.RetryWorkflow(workflowID, "user:test")
+
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, retried.State)
+	assert.Equal(t, 0, retried.ErrorCount)
+	assert.Nil(t, retried.EndedAt)
+	assert.NotContains(t, retried.Metadata, MetadataFailedState)
+}
+
+// TestEngine_ProcessPROpen_FeedbackSurvivesFailures tests that failed attempts do not skip PR feedback
+func TestEngine_ProcessPROpen_FeedbackSurvivesFailures(t *testing.T) {
+	// Test case: Fetching PR updates fails after the checks were recorded; the
+	// retry fetches from the same cursor and still handles the comment posted
+	// before the failure, and only then moves the cursor
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+	taskID := 3
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, TaskID: &taskID})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing, types.WorkflowStatePROpen)
+
+	commentedAt := time.Now()
+	provider := newFakeCoworkProvider()
+	provider.pr = &git.PullRequest{Number: 42, State: "open", Head: &git.Branch{SHA: "abc123"}}
+	provider.checks = []*git.CommitCheck{completedCheck("lint", "success", 0), completedCheck("test", "success", 0)}
+	provider.updatesErr = errors.New("github returned 502")
+
+	engine := NewEngine(manager, newFakeTaskManager(&types.Task{ID: 3}), nil, provider, "owner", "repo")
+	assert.Error(t, engine.ProcessWorkflow(context.Background(), workflowID))
+
+	workflow, err = manager.GetWorkflow(workflowID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, workflow.ErrorCount)
+	assert.Equal(t, ChecksStatePassed, workflow.Metadata[MetadataChecksState])
+	assert.Nil(t, workflow.LastFeedbackTS)
+
+	provider.updatesErr = nil
+	provider.updates = &git.PullRequestUpdate{PRNumber: 42, NewComments: []*git.Comment{
+		{ID: 7, User: &git.User{Login: "reviewer"}, Body: "Please rename the helper.", CreatedAt: commentedAt},
+	}}
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, NextRetryAt: &time.Time{}})
+	require.NoError(t, err)
+	require.NoError(t, engine.ProcessWorkflow(context.Background(), workflowID))
+
+	require.Len(t, provider.since, 2)
+	assert.Equal(t, provider.since[0], provider.since[1])
+	assert.True(t, provider.since[1].Before(commentedAt))
+
+	workflow, err = manager.GetWorkflow(workflowID)
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+	assert.Contains(t, workflow.Metadata[MetadataRevisionInstruction], "Please rename the helper.")
+	require.NotNil(t, workflow.LastFeedbackTS)
+	assert.True(t, workflow.LastFeedbackTS.After(commentedAt))
+}
@@ -0,0 +1,326 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+const (
+	// MetadataSelfReviewRound counts the self reviews whose findings were
+	// handed back to the implementing agent
+	MetadataSelfReviewRound = "self_review_round"
+
+	// MetadataSelfReviewFindings holds the JSON encoded findings of the last self review
+	MetadataSelfReviewFindings = "self_review_findings"
+
+	// MetadataSelfReviewDone marks a workflow whose self review is finished
+	MetadataSelfReviewDone = "self_review_done"
+)
+
+var (
+	// findingPattern extracts each concern from reviewer output
+	findingPattern = regexp.MustCompile(`(?s)<finding>(.*?)</finding>`)
+
+	// approvedPattern marks reviewer output that found nothing to change
+	approvedPattern = regexp.MustCompile(`<approved\s*/?>`)
+)
+
+// needsSelfReview reports whether a finished task goes to the reviewer
+// before its PR is opened
+func needsSelfReview(workflow *types.Workflow) bool {
+	return workflow.Config.SelfReview && workflow.Metadata[MetadataSelfReviewDone] != "true"
+}
+
+// SelfReviewFindings returns the findings of the last self review recorded on a workflow
+func SelfReviewFindings(workflow *types.Workflow) ([]string, error) {
+	raw := workflow.Metadata[MetadataSelfReviewFindings]
+	if raw == "" {
+		return nil, nil
+	}
+
+	var findings []string
+	if err := json.Unmarshal([]byte(raw), &findings); err != nil {
+		return nil, fmt.Errorf("failed to decode self review findings: %w", err)
+	}
+
+	return findings, nil
+}
+
+// startSelfReview moves a workflow whose task completed to the reviewer
+func (e *Engine) startSelfReview(workflow *types.Workflow, task *types.Task) error {
+	state := types.WorkflowStateReviewing
+	_, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Actor:      e.processID,
+		Reason:     fmt.Sprintf("task %d completed", task.ID),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to reviewing: %w", err)
+	}
+
+	log.Printf("✅ Workflow %d transitioned to reviewing", workflow.ID)
+	return nil
+}
+
+// processReviewingWorkflow handles workflows in REVIEWING state. The reviewer
+// checks the branch against the issue; its findings go back to the
+// implementing agent until the rounds run out, and whatever remains is
+// posted on the PR once it is opened.
+func (e *Engine) processReviewingWorkflow(ctx context.Context, workflow *types.Workflow) error {
+	log.Printf("🧐 Processing reviewing workflow %d", workflow.ID)
+
+	task, err := e.taskManager.GetTask(fmt.Sprintf("%d", workflow.TaskID))
+	if err != nil {
+		return fmt.Errorf("failed to get task: %w", err)
+	}
+
+	// A finished review only waits for its PR, so a failed attempt to open
+	// one does not run the reviewer again
+	if workflow.Metadata[MetadataSelfReviewDone] != "true" {
+		findings, err := e.selfReview(ctx, workflow)
+		if err != nil {
+			return fmt.Errorf("failed to review branch: %w", err)
+		}
+
+		round, _ := strconv.Atoi(workflow.Metadata[MetadataSelfReviewRound])
+		if len(findings) > 0 && round < workflow.Config.SelfReviewRounds {
+			return e.returnSelfReviewFindings(workflow, task, findings, round+1)
+		}
+
+		metadata := map[string]string{MetadataSelfReviewDone: "true", MetadataSelfReviewFindings: ""}
+		if len(findings) > 0 {
+			encoded, err := json.Marshal(findings)
+			if err != nil {
+				return fmt.Errorf("failed to encode self review findings: %w", err)
+			}
+			metadata[MetadataSelfReviewFindings] = string(encoded)
+		}
+		if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, Metadata: metadata}); err != nil {
+			return fmt.Errorf("failed to record self review: %w", err)
+		}
+	}
+
+	if err := e.createPullRequest(ctx, workflow, task); err != nil {
+		return err
+	}
+
+	return e.postSelfReviewFindings(ctx, workflow)
+}
+
+// selfReview has the reviewer agent check the branch diff against the issue,
+// returning its findings; none means the branch is ready for a PR
+func (e *Engine) selfReview(ctx context.Context, workflow *types.Workflow) ([]string, error) {
+	runner := e.reviewRunner
+	if runner == nil {
+		runner = e.agentRunner
+	}
+	if runner == nil {
+		log.Printf("⚠️  No reviewer agent is configured, skipping self review for workflow %d", workflow.ID)
+		return nil, nil
+	}
+
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	diff, err := pullRequestDiff(workspace.Path, workflow.BaseBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := SelfReviewFindings(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧐 Asking reviewer agent to review the branch of workflow %d", workflow.ID)
+
+	instruction := &agent.AgentInstruction{
+		Content: buildReviewPrompt(issue, diff, previous),
+		TaskID:  workflow.TaskID,
+		Metadata: map[string]string{
+			"purpose": "self_review",
+			"round":   workflow.Metadata[MetadataSelfReviewRound],
+		},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := e.runAgent(ctx, runner, workspace.Path, instruction)
+	if errors.Is(err, errDryRun) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	findings, ok := extractFindings(result.Output)
+	if !ok {
+		return nil, fmt.Errorf("reviewer output contained no findings and no approval")
+	}
+
+	log.Printf("🧐 Reviewer found %d concern(s) on workflow %d", len(findings), workflow.ID)
+	return findings, nil
+}
+
+// returnSelfReviewFindings hands the reviewer's findings to the implementing
+// agent and moves the workflow back to IMPLEMENTING
+func (e *Engine) returnSelfReviewFindings(workflow *types.Workflow, task *types.Task, findings []string, round int) error {
+	encoded, err := json.Marshal(findings)
+	if err != nil {
+		return fmt.Errorf("failed to encode self review findings: %w", err)
+	}
+
+	taskMetadata := make(map[string]string, len(task.Metadata)+1)
+	for key, value := range task.Metadata {
+		taskMetadata[key] = value
+	}
+	taskMetadata[MetadataRevisionInstruction] = buildSelfReviewInstruction(findings)
+
+	taskStatus := types.TaskStatusInProgress
+	if _, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{
+		TaskID:   task.ID,
+		Status:   &taskStatus,
+		Metadata: &taskMetadata,
+	}); err != nil {
+		return fmt.Errorf("failed to update task status: %w", err)
+	}
+
+	state := types.WorkflowStateImplementing
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		State:      &state,
+		Metadata: map[string]string{
+			MetadataSelfReviewRound:    strconv.Itoa(round),
+			MetadataSelfReviewFindings: string(encoded),
+		},
+		Actor:  e.processID,
+		Reason: fmt.Sprintf("self review round %d found %d concern(s)", round, len(findings)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to implementing: %w", err)
+	}
+
+	log.Printf("✅ Workflow %d handed %d self review finding(s) back to the agent", workflow.ID, len(findings))
+	return nil
+}
+
+// postSelfReviewFindings comments the concerns the implementing agent did not
+// get to address on the newly opened PR
+func (e *Engine) postSelfReviewFindings(ctx context.Context, workflow *types.Workflow) error {
+	// The findings and PR were recorded after the workflow was read
+	workflow, err := e.reloadWorkflow(workflow)
+	if err != nil {
+		return err
+	}
+
+	findings, err := SelfReviewFindings(workflow)
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 || workflow.PRNumber == nil {
+		return nil
+	}
+
+	e.postComment(ctx, *workflow.PRNumber, formatSelfReviewComment(findings))
+
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		Metadata:   map[string]string{MetadataSelfReviewFindings: ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to clear self review findings: %w", err)
+	}
+
+	return nil
+}
+
+// buildReviewPrompt builds the instruction asking the reviewer to check the
+// branch against the issue it implements
+func buildReviewPrompt(issue *git.Issue, diff string, previous []string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Review the changes on this branch, which implement issue #%d (%s).\n", issue.Number, issue.Title)
+	sb.WriteString("Check that they fully address the issue and look for bugs, missing tests and unclear code. Do not modify any files.\n\n")
+
+	if body := strings.TrimSpace(issue.Body); body != "" {
+		fmt.Fprintf(&sb, "## Issue\n\n%s\n\n", body)
+	}
+
+	if len(previous) > 0 {
+		sb.WriteString("## Findings from the previous review\n\n")
+		writeFindings(&sb, previous)
+		sb.WriteString("\nCheck whether these were addressed.\n\n")
+	}
+
+	sb.WriteString("## Branch diff\n\n```diff\n")
+	sb.WriteString(diff)
+	sb.WriteString("\n```\n\n")
+
+	sb.WriteString("Wrap each concern that must be fixed before the PR is opened in its own <finding></finding> tags. ")
+	sb.WriteString("If there are none, reply with <approved/>.\n")
+
+	return sb.String()
+}
+
+// buildSelfReviewInstruction builds the revision instruction handing the
+// reviewer's findings to the implementing agent
+func buildSelfReviewInstruction(findings []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("A review of your changes before opening the pull request found the following concerns. Address each of them.\n\n")
+	writeFindings(&sb, findings)
+
+	return sb.String()
+}
+
+// formatSelfReviewComment lists the findings left when the review rounds ran out
+func formatSelfReviewComment(findings []string) string {
+	var sb strings.Builder
+
+	sb.WriteString("🧐 The self review before this PR was opened left these concerns unresolved:\n\n")
+	writeFindings(&sb, findings)
+
+	return sb.String()
+}
+
+// writeFindings writes findings as a markdown list
+func writeFindings(sb *strings.Builder, findings []string) {
+	for _, finding := range findings {
+		fmt.Fprintf(sb, "- %s\n", strings.ReplaceAll(strings.TrimSpace(finding), "\n", "\n  "))
+	}
+}
+
+// extractFindings returns the tagged findings in reviewer output. It reports
+// false when the output holds neither findings nor an approval.
+func extractFindings(output string) ([]string, bool) {
+	var findings []string
+	for _, match := range findingPattern.FindAllStringSubmatch(output, -1) {
+		if finding := strings.TrimSpace(match[1]); finding != "" {
+			findings = append(findings, finding)
+		}
+	}
+
+	if len(findings) == 0 && !approvedPattern.MatchString(output) {
+		return nil, false
+	}
+
+	return findings, true
+}
@@ -0,0 +1,138 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_SelfReview tests reviewing the branch before its PR is opened
+func TestEngine_SelfReview(t *testing.T) {
+	testCases := []struct {
+		name           string
+		reviewerOutput string
+		expectRounds   int
+		expectComment  bool
+	}{
+		{
+			// Test case: An approving reviewer lets the PR open right away
+			name:           "approved",
+			reviewerOutput: "Looks good. <approved/>",
+		},
+		{
+			// Test case: Findings go back to the implementing agent until the
+			// rounds run out, then are posted on the new PR
+			name:           "findings outlast the rounds",
+			reviewerOutput: "<finding>The widget test is missing.</finding>\n<finding>Handle a nil widget.</finding>",
+			expectRounds:   2,
+			expectComment:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, workspacePath := newDraftWorkspace(t)
+			commitFile(t, workspacePath, "file.txt", "fixed\n", "fix the widget")
+
+			manager := newTestWorkflowManager(t)
+			workflow := newImplementingWorkflow(t, manager, 0)
+			workflow.BranchName = "task-7"
+			workflow.Config.SelfReview = true
+			workflow.Config.SelfReviewRounds = 2
+			storeTestWorkflow(t, manager, workflow)
+
+			provider := newFakeCoworkProvider()
+			provider.issues = map[int]*git.Issue{7: {Number: 7, Title: "Fix the widget", Body: "The widget breaks on startup."}}
+			task := &types.Task{ID: 3, Status: types.TaskStatusCompleted}
+			tasks := newFakeTaskManager(task)
+			workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath})
+			reviewer := &fakeAgentRunner{output: tc.reviewerOutput}
+			engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+			engine.SetAgentRunner(&fakeAgentRunner{})
+			engine.SetReviewRunner(reviewer)
+
+			for round := 1; round <= tc.expectRounds; round++ {
+				require.NoError(t, engine.processImplementingWorkflow(context.Background(), workflow))
+				workflow = reloadTestWorkflow(t, manager, workflow)
+				require.NoError(t, engine.processReviewingWorkflow(context.Background(), workflow))
+				workflow = reloadTestWorkflow(t, manager, workflow)
+
+				assert.Equal(t, types.WorkflowStateImplementing, workflow.State)
+				assert.Equal(t, types.TaskStatusInProgress, task.Status)
+				assert.Contains(t, task.Metadata[MetadataRevisionInstruction], "- Handle a nil widget.")
+				assert.Empty(t, provider.created)
+
+				task.Status = types.TaskStatusCompleted
+			}
+
+			require.NoError(t, engine.processImplementingWorkflow(context.Background(), workflow))
+			workflow = reloadTestWorkflow(t, manager, workflow)
+			assert.Equal(t, types.WorkflowStateReviewing, workflow.State)
+			require.NoError(t, engine.processReviewingWorkflow(context.Background(), workflow))
+			workflow = reloadTestWorkflow(t, manager, workflow)
+
+			require.Len(t, reviewer.instructions, tc.expectRounds+1)
+			assert.Contains(t, reviewer.instructions[0].Content, "The widget breaks on startup.")
+			assert.Contains(t, reviewer.instructions[0].Content, "+fixed")
+			if tc.expectRounds > 0 {
+				assert.Contains(t, reviewer.instructions[1].Content, "## Findings from the previous review")
+			}
+
+			assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+			require.Len(t, provider.created, 1)
+			require.NotNil(t, workflow.PRNumber)
+			assert.Equal(t, "true", workflow.Metadata[MetadataSelfReviewDone])
+			assert.Empty(t, workflow.Metadata[MetadataSelfReviewFindings])
+
+			comments := provider.comments[*workflow.PRNumber]
+			if tc.expectComment {
+				require.Len(t, comments, 1)
+				assert.Contains(t, comments[0].Body, "- The widget test is missing.")
+			} else {
+				assert.Empty(t, comments)
+			}
+		})
+	}
+}
+
+// TestExtractFindings tests reading findings from reviewer output
+func TestExtractFindings(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		findings []string
+		ok       bool
+	}{
+		{
+			// Test case: Each tagged finding is returned trimmed
+			name:     "findings",
+			output:   "<finding> First </finding> and <finding>Second\nline</finding>",
+			findings: []string{"First", "Second\nline"},
+			ok:       true,
+		},
+		{
+			// Test case: An approval has no findings
+			name:   "approved",
+			output: "All good <approved />",
+			ok:     true,
+		},
+		{
+			// Test case: Output without a verdict is rejected
+			name:   "no verdict",
+			output: "I had a look around.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			findings, ok := extractFindings(tc.output)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.findings, findings)
+		})
+	}
+}
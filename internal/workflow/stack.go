@@ -0,0 +1,631 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// Ways of splitting a large change into a stack of PRs
+const (
+	StackSplitFiles = "files"
+	StackSplitAgent = "agent"
+)
+
+// partPattern extracts each proposed PR from agent output
+var partPattern = regexp.MustCompile(`(?s)<part>(.*?)</part>`)
+
+// cloneStackedPRs returns a copy of a stack that shares no lists with the original
+func cloneStackedPRs(stack []types.StackedPullRequest) []types.StackedPullRequest {
+	if stack == nil {
+		return nil
+	}
+
+	clone := make([]types.StackedPullRequest, len(stack))
+	for i, pr := range stack {
+		clone[i] = pr
+		clone[i].Files = append([]string(nil), pr.Files...)
+	}
+	return clone
+}
+
+// stackPartBranch names the branch of a lower part of a workflow's stack
+func stackPartBranch(workflow *types.Workflow, part int) string {
+	return fmt.Sprintf("%s-part-%d", workflow.BranchName, part)
+}
+
+// changedLines returns the number of lines added and removed across files
+func changedLines(files []prbody.FileChange) int {
+	total := 0
+	for _, file := range files {
+		total += file.Additions + file.Deletions
+	}
+	return total
+}
+
+// prepareStack splits a change larger than the workflow's max PR lines into
+// a stack of branches and records the stack on the workflow. A stack
+// recorded by an earlier attempt is kept as is.
+func (e *Engine) prepareStack(ctx context.Context, workflow *types.Workflow, workspacePath string) error {
+	if len(workflow.StackedPRs) > 0 || workflow.Config.MaxPRLines <= 0 {
+		return nil
+	}
+
+	files, err := prbody.CollectChangesWithoutRenames(workspacePath, workflow.BaseBranch)
+	if err != nil {
+		return err
+	}
+	if len(files) < 2 || changedLines(files) <= workflow.Config.MaxPRLines {
+		return nil
+	}
+
+	parts := e.planStack(ctx, workflow, workspacePath, files)
+	if len(parts) < 2 {
+		return nil
+	}
+
+	stack, err := buildStackBranches(workspacePath, workflow, parts)
+	if err != nil {
+		return fmt.Errorf("failed to split branch into a stack: %w", err)
+	}
+
+	if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		StackedPRs: &stack,
+	}); err != nil {
+		return fmt.Errorf("failed to record stack: %w", err)
+	}
+
+	log.Printf("🥞 Split %d changed lines of workflow %d into a stack of %d PRs", changedLines(files), workflow.ID, len(stack))
+	return nil
+}
+
+// planStack groups the changed files into the parts of a stack, using the
+// agent's proposal when configured and falling back to grouping by directory
+func (e *Engine) planStack(ctx context.Context, workflow *types.Workflow, workspacePath string, files []prbody.FileChange) [][]prbody.FileChange {
+	if workflow.Config.StackSplit == StackSplitAgent && e.agentRunner != nil {
+		parts, err := e.proposeStackParts(ctx, workflow, workspacePath, files)
+		if err == nil {
+			return parts
+		}
+		log.Printf("⚠️  Agent did not propose a usable split for workflow %d, grouping by directory: %v", workflow.ID, err)
+	}
+
+	return groupFilesByDirectory(files, workflow.Config.MaxPRLines)
+}
+
+// proposeStackParts asks the agent how to split the change into parts
+func (e *Engine) proposeStackParts(ctx context.Context, workflow *types.Workflow, workspacePath string, files []prbody.FileChange) ([][]prbody.FileChange, error) {
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	instruction := &agent.AgentInstruction{
+		Content: buildStackPrompt(issue, files, workflow.Config.MaxPRLines),
+		TaskID:  workflow.TaskID,
+		Metadata: map[string]string{
+			"purpose": "split_stack",
+		},
+		CreatedAt: time.Now(),
+	}
+
+	result, err := e.runAgent(ctx, e.agentRunner, workspacePath, instruction)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseStackParts(result.Output, files)
+}
+
+// buildStackPrompt builds the instruction asking the agent to split a change
+func buildStackPrompt(issue *git.Issue, files []prbody.FileChange, maxLines int) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "The changes for issue #%d (%s) are too large to review as one pull request.\n", issue.Number, issue.Title)
+	fmt.Fprintf(&sb, "Split them into an ordered series of pull requests, each building on the one before it, with at most %d changed lines each where possible. ", maxLines)
+	sb.WriteString("Each pull request should make sense on its own. Do not modify any files.\n\n")
+
+	sb.WriteString("## Changed files\n\n")
+	for _, file := range files {
+		fmt.Fprintf(&sb, "- %s (+%d -%d)\n", file.Path, file.Additions, file.Deletions)
+	}
+
+	sb.WriteString("\nReply with the pull requests in merge order, each as the paths of its files, one per line, wrapped in <part></part> tags. ")
+	sb.WriteString("Every file must be in exactly one part.\n")
+
+	return sb.String()
+}
+
+// parseStackParts reads the agent's proposed parts, rejecting proposals that
+// do not place every changed file in exactly one part
+func parseStackParts(output string, files []prbody.FileChange) ([][]prbody.FileChange, error) {
+	byPath := make(map[string]prbody.FileChange, len(files))
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+
+	placed := make(map[string]bool, len(files))
+	var parts [][]prbody.FileChange
+	for _, match := range partPattern.FindAllStringSubmatch(output, -1) {
+		var part []prbody.FileChange
+		for _, line := range strings.Split(match[1], "\n") {
+			filePath := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "- "))
+			if filePath == "" {
+				continue
+			}
+
+			file, ok := byPath[filePath]
+			if !ok {
+				return nil, fmt.Errorf("proposed part lists unchanged file %s", filePath)
+			}
+			if placed[filePath] {
+				return nil, fmt.Errorf("file %s is in more than one part", filePath)
+			}
+			placed[filePath] = true
+			part = append(part, file)
+		}
+		if len(part) > 0 {
+			parts = append(parts, part)
+		}
+	}
+
+	if len(placed) != len(files) {
+		return nil, fmt.Errorf("proposal placed %d of %d changed files", len(placed), len(files))
+	}
+
+	return parts, nil
+}
+
+// groupFilesByDirectory splits files into parts of at most maxLines changed
+// lines, keeping the files of a directory together unless the directory
+// alone is over the limit. A single file over the limit gets its own part.
+func groupFilesByDirectory(files []prbody.FileChange, maxLines int) [][]prbody.FileChange {
+	var dirs []string
+	byDir := make(map[string][]prbody.FileChange)
+	for _, file := range files {
+		dir := path.Dir(file.Path)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], file)
+	}
+
+	var parts [][]prbody.FileChange
+	var current []prbody.FileChange
+	add := func(group []prbody.FileChange) {
+		if len(current) > 0 && changedLines(current)+changedLines(group) > maxLines {
+			parts = append(parts, current)
+			current = nil
+		}
+		current = append(current, group...)
+	}
+
+	for _, dir := range dirs {
+		group := byDir[dir]
+		if changedLines(group) <= maxLines {
+			add(group)
+			continue
+		}
+		for _, file := range group {
+			add([]prbody.FileChange{file})
+		}
+	}
+	if len(current) > 0 {
+		parts = append(parts, current)
+	}
+
+	return parts
+}
+
+// buildStackBranches commits every part but the last on its own branch,
+// each stacked on the one before, then merges the last of those into the
+// workflow branch so it sits on top of the stack with only the remaining
+// files left to review. Nothing is rewritten, so no force push is needed.
+func buildStackBranches(workspacePath string, workflow *types.Workflow, parts [][]prbody.FileChange) ([]types.StackedPullRequest, error) {
+	stack := make([]types.StackedPullRequest, 0, len(parts))
+	start := "origin/" + workflow.BaseBranch
+	base := workflow.BaseBranch
+
+	for i, part := range parts[:len(parts)-1] {
+		branch := stackPartBranch(workflow, i+1)
+		if err := runGit(workspacePath, "checkout", "-q", "-B", branch, start); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", branch, err)
+		}
+
+		paths := make([]string, len(part))
+		for j, file := range part {
+			paths[j] = file.Path
+			if err := takeFileFrom(workspacePath, workflow.BranchName, file.Path); err != nil {
+				return nil, err
+			}
+		}
+
+		message := fmt.Sprintf("Part %d/%d of the changes for #%d", i+1, len(parts), workflow.IssueID)
+		if err := runGit(workspacePath, "commit", "-q", "-m", message); err != nil {
+			return nil, fmt.Errorf("failed to commit %s: %w", branch, err)
+		}
+
+		stack = append(stack, types.StackedPullRequest{Branch: branch, Base: base, Files: paths})
+		start, base = branch, branch
+	}
+
+	if err := runGit(workspacePath, "checkout", "-q", workflow.BranchName); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", workflow.BranchName, err)
+	}
+	if err := runGit(workspacePath, "merge", "--no-edit", "-q", base); err != nil {
+		if abortErr := abortSync(workspacePath); abortErr != nil {
+			log.Printf("⚠️  Failed to abort merge of %s: %v", base, abortErr)
+		}
+		return nil, fmt.Errorf("failed to merge %s into %s: %w", base, workflow.BranchName, err)
+	}
+
+	top := parts[len(parts)-1]
+	paths := make([]string, len(top))
+	for i, file := range top {
+		paths[i] = file.Path
+	}
+	stack = append(stack, types.StackedPullRequest{Branch: workflow.BranchName, Base: base, Files: paths})
+
+	return stack, nil
+}
+
+// takeFileFrom stages a file as it is on the given branch, removing it when
+// the branch deleted it
+func takeFileFrom(workspacePath, branch, filePath string) error {
+	if _, err := gitOutput(workspacePath, "cat-file", "-e", branch+":"+filePath); err != nil {
+		if err := runGit(workspacePath, "rm", "-q", "--ignore-unmatch", "--", filePath); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", filePath, err)
+		}
+		return nil
+	}
+
+	if err := runGit(workspacePath, "checkout", branch, "--", filePath); err != nil {
+		return fmt.Errorf("failed to take %s from %s: %w", filePath, branch, err)
+	}
+	return nil
+}
+
+// openStackParts pushes the lower branches of the workflow's stack and opens
+// their PRs, recording each number as it is opened
+func (e *Engine) openStackParts(ctx context.Context, workflow *types.Workflow, workspacePath string) error {
+	if len(workflow.StackedPRs) == 0 {
+		return nil
+	}
+
+	issue, err := e.coworkProvider.GetIssue(ctx, e.owner, e.repo, workflow.IssueID)
+	if err != nil {
+		return fmt.Errorf("failed to get issue: %w", err)
+	}
+
+	stack := cloneStackedPRs(workflow.StackedPRs)
+	for i := range stack[:len(stack)-1] {
+		if stack[i].Number != 0 {
+			continue
+		}
+
+		if err := e.pushBranch(ctx, workspacePath, stack[i].Branch); err != nil {
+			return fmt.Errorf("failed to push %s: %w", stack[i].Branch, err)
+		}
+		if err := e.workflowManager.RecordCreatedBranch(e.owner, e.repo, stack[i].Branch, workflow.ID); err != nil {
+			log.Printf("⚠️  Failed to record created branch %s: %v", stack[i].Branch, err)
+		}
+
+		pr, err := e.coworkProvider.CreatePullRequest(ctx, e.owner, e.repo, &git.CreatePullRequestRequest{
+			Title: fmt.Sprintf("Fix #%d (part %d/%d): %s", issue.Number, i+1, len(stack), issue.Title),
+			Body:  buildStackPartBody(issue, stack, i),
+			Head:  stack[i].Branch,
+			Base:  stack[i].Base,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to open PR for part %d: %w", i+1, err)
+		}
+
+		stack[i].Number = pr.Number
+		if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+			WorkflowID: workflow.ID,
+			StackedPRs: &stack,
+		}); err != nil {
+			return fmt.Errorf("failed to record PR #%d of the stack: %w", pr.Number, err)
+		}
+
+		log.Printf("🥞 Opened PR #%d for part %d/%d of workflow %d", pr.Number, i+1, len(stack), workflow.ID)
+	}
+
+	return nil
+}
+
+// finishStack points the workflow's own PR at the top of its stack
+func (e *Engine) finishStack(ctx context.Context, workflow *types.Workflow, pr *git.PullRequest) error {
+	if len(workflow.StackedPRs) == 0 {
+		return nil
+	}
+
+	stack := cloneStackedPRs(workflow.StackedPRs)
+	top := len(stack) - 1
+	if pr.Base == nil || pr.Base.Ref != stack[top].Base {
+		if _, err := e.coworkProvider.UpdatePullRequest(ctx, e.owner, e.repo, pr.Number, &git.UpdatePullRequestRequest{
+			Base: &stack[top].Base,
+		}); err != nil {
+			return fmt.Errorf("failed to stack PR #%d on %s: %w", pr.Number, stack[top].Base, err)
+		}
+	}
+
+	if stack[top].Number == pr.Number {
+		return nil
+	}
+	stack[top].Number = pr.Number
+	if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		StackedPRs: &stack,
+	}); err != nil {
+		return fmt.Errorf("failed to record PR #%d of the stack: %w", pr.Number, err)
+	}
+
+	return nil
+}
+
+// buildStackPartBody describes a lower part of a stack
+func buildStackPartBody(issue *git.Issue, stack []types.StackedPullRequest, index int) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Part %d of %d of the changes for #%d: %s\n\n", index+1, len(stack), issue.Number, issue.Title)
+	if index > 0 {
+		fmt.Fprintf(&sb, "This PR is stacked on `%s`. ", stack[index].Base)
+	}
+	sb.WriteString("Merge the stack from the bottom up; the PRs above are moved onto the base branch as each one merges.\n\n")
+
+	sb.WriteString("## Files\n\n")
+	for _, file := range stack[index].Files {
+		fmt.Fprintf(&sb, "- `%s`\n", file)
+	}
+
+	return sb.String()
+}
+
+// advanceStack checks the lower PRs of the workflow's stack. Merged ones are
+// recorded and the rest of the stack is moved onto the base branch; a lower
+// PR closed without merging closes the workflow. It reports whether the
+// workflow was completed.
+func (e *Engine) advanceStack(ctx context.Context, workflow *types.Workflow) (bool, error) {
+	if len(workflow.StackedPRs) < 2 {
+		return false, nil
+	}
+
+	stack := cloneStackedPRs(workflow.StackedPRs)
+	next := len(stack) - 1
+	merged := false
+	for i := range stack[:len(stack)-1] {
+		if stack[i].Merged {
+			continue
+		}
+
+		pr, err := e.coworkProvider.GetPullRequest(ctx, e.owner, e.repo, stack[i].Number)
+		if err != nil {
+			return false, fmt.Errorf("failed to get pull request #%d: %w", stack[i].Number, err)
+		}
+		if pr.Merged {
+			log.Printf("✅ PR #%d, part %d/%d of workflow %d, was merged", pr.Number, i+1, len(stack), workflow.ID)
+			stack[i].Merged = true
+			merged = true
+			continue
+		}
+		if pr.State == "closed" {
+			return true, e.handlePRCompleted(ctx, workflow, pr)
+		}
+
+		next = i
+		break
+	}
+
+	if !merged {
+		return false, nil
+	}
+
+	workspace, err := e.workspaceManager.GetWorkspace(workflow.WorkspaceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	if err := e.restack(ctx, workflow, workspace.Path, stack, next); err != nil {
+		return false, fmt.Errorf("failed to rebase stack: %w", err)
+	}
+
+	stack[next].Base = workflow.BaseBranch
+	if _, err := e.coworkProvider.UpdatePullRequest(ctx, e.owner, e.repo, stack[next].Number, &git.UpdatePullRequestRequest{
+		Base: &workflow.BaseBranch,
+	}); err != nil {
+		return false, fmt.Errorf("failed to retarget PR #%d to %s: %w", stack[next].Number, workflow.BaseBranch, err)
+	}
+
+	if _, err := e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		StackedPRs: &stack,
+	}); err != nil {
+		return false, fmt.Errorf("failed to record stack: %w", err)
+	}
+
+	log.Printf("🥞 Moved the stack of workflow %d onto %s from PR #%d up", workflow.ID, workflow.BaseBranch, stack[next].Number)
+	return false, nil
+}
+
+// restack moves the unmerged parts of a stack, from next up, onto the base
+// branch. Parts are rebased when the workflow allows force pushes and the
+// base branch is merged into them otherwise. Any failure leaves every branch
+// as it was.
+func (e *Engine) restack(ctx context.Context, workflow *types.Workflow, workspacePath string, stack []types.StackedPullRequest, next int) error {
+	if err := abortSync(workspacePath); err != nil {
+		return err
+	}
+
+	status, err := gitOutput(workspacePath, "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("failed to check workspace status: %w", err)
+	}
+	if status != "" {
+		return fmt.Errorf("workspace has uncommitted changes, refusing to rebase the stack")
+	}
+
+	if err := runGit(workspacePath, "fetch", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch origin: %w", err)
+	}
+
+	remaining := stack[next:]
+	before := make(map[string]string, len(remaining))
+	for _, part := range remaining {
+		commit, err := gitOutput(workspacePath, "rev-parse", part.Branch)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", part.Branch, err)
+		}
+		before[part.Branch] = commit
+	}
+
+	rebase := workflow.Config.SyncStrategy != SyncStrategyMerge && !workflow.Config.ForcePushDisabled
+	newBase := "origin/" + workflow.BaseBranch
+	oldBase := stack[next-1].Branch
+	for _, part := range remaining {
+		if rebase {
+			err = runGit(workspacePath, "rebase", "-q", "--onto", newBase, oldBase, part.Branch)
+			oldBase = before[part.Branch]
+		} else if err = runGit(workspacePath, "checkout", "-q", part.Branch); err == nil {
+			err = runGit(workspacePath, "merge", "--no-edit", "-q", newBase)
+		}
+		if err != nil {
+			if restoreErr := restoreStack(workspacePath, workflow.BranchName, before); restoreErr != nil {
+				log.Printf("⚠️  Failed to restore the stack of workflow %d: %v", workflow.ID, restoreErr)
+			}
+			return fmt.Errorf("failed to move %s onto %s: %w", part.Branch, newBase, err)
+		}
+		newBase = part.Branch
+	}
+
+	if err := runGit(workspacePath, "checkout", "-q", workflow.BranchName); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", workflow.BranchName, err)
+	}
+
+	for _, part := range remaining {
+		if rebase {
+			err = e.forcePushBranch(ctx, workspacePath, part.Branch)
+		} else {
+			err = e.pushBranch(ctx, workspacePath, part.Branch)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreStack aborts a rebase or merge in progress and resets the stack's
+// branches to the given commits
+func restoreStack(workspacePath, branchName string, commits map[string]string) error {
+	if err := abortSync(workspacePath); err != nil {
+		return err
+	}
+
+	if err := runGit(workspacePath, "checkout", "-q", "--detach"); err != nil {
+		return fmt.Errorf("failed to detach HEAD: %w", err)
+	}
+	for branch, commit := range commits {
+		if err := runGit(workspacePath, "branch", "-f", branch, commit); err != nil {
+			return fmt.Errorf("failed to reset %s: %w", branch, err)
+		}
+	}
+
+	if err := runGit(workspacePath, "checkout", "-q", branchName); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", branchName, err)
+	}
+	return nil
+}
+
+// forcePushBranch pushes a rewritten branch, as long as nobody else pushed to it
+func (e *Engine) forcePushBranch(ctx context.Context, workspacePath, branchName string) error {
+	if e.dryRun != nil {
+		e.dryRun.Record(ctx, MutationForcePush, RepositoryKey(e.owner, e.repo), "force push branch %s", branchName)
+		return nil
+	}
+
+	if err := runGit(workspacePath, "push", "--force-with-lease", "origin", branchName); err != nil {
+		return fmt.Errorf("failed to force push branch: %w", err)
+	}
+
+	return nil
+}
+
+// stackUpdates fetches the feedback left on the open lower PRs of the
+// workflow's stack, which is handled together with its own PR's feedback
+func (e *Engine) stackUpdates(ctx context.Context, workflow *types.Workflow, since time.Time) ([]*git.PullRequestUpdate, error) {
+	var updates []*git.PullRequestUpdate
+	for _, part := range workflow.StackedPRs {
+		if part.Merged || part.Number == 0 || part.Branch == workflow.BranchName {
+			continue
+		}
+
+		partUpdates, err := e.coworkProvider.GetPullRequestUpdates(ctx, e.owner, e.repo, part.Number, since)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get updates for PR #%d: %w", part.Number, err)
+		}
+
+		// Answers and revision summaries go back to the part the feedback was left on
+		tagged := *partUpdates
+		tagged.PRNumber = part.Number
+		updates = append(updates, &tagged)
+	}
+
+	return updates, nil
+}
+
+// stackPartFeedback returns the change requests among classified feedback
+// that were left on lower parts of the stack rather than on the workflow's
+// own PR
+func stackPartFeedback(pr *git.PullRequest, classified []ClassifiedFeedback) []ClassifiedFeedback {
+	var part []ClassifiedFeedback
+	for _, feedback := range classified {
+		if feedback.Ignored || feedback.Item.PRNumber == 0 || feedback.Item.PRNumber == pr.Number {
+			continue
+		}
+		if feedback.Intent == types.FeedbackIntentChange || feedback.Intent == types.FeedbackIntentBlocker {
+			part = append(part, feedback)
+		}
+	}
+	return part
+}
+
+// postStackRevisionSummaries tells each lower part of the stack that had
+// feedback in a revision what was changed for it. Revisions are made on the
+// workflow's branch at the top of the stack, so the summary points there.
+func (e *Engine) postStackRevisionSummaries(ctx context.Context, workflow *types.Workflow, feedback []ClassifiedFeedback, commits string) {
+	var numbers []int
+	byPR := make(map[int][]ClassifiedFeedback)
+	for _, f := range feedback {
+		if _, ok := byPR[f.Item.PRNumber]; !ok {
+			numbers = append(numbers, f.Item.PRNumber)
+		}
+		byPR[f.Item.PRNumber] = append(byPR[f.Item.PRNumber], f)
+	}
+
+	top := fmt.Sprintf("`%s`", workflow.BranchName)
+	if len(workflow.StackedPRs) > 0 && workflow.StackedPRs[len(workflow.StackedPRs)-1].Number != 0 {
+		top = fmt.Sprintf("#%d", workflow.StackedPRs[len(workflow.StackedPRs)-1].Number)
+	}
+
+	for _, number := range numbers {
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Addressed the feedback below. Revisions are made at the top of the stack, so the changes were pushed to %s.\n\n", top)
+		writeFeedbackList(&sb, byPR[number])
+		if commits != "" {
+			fmt.Fprintf(&sb, "\n## Changes\n\n%s\n", commits)
+		}
+		e.postComment(ctx, number, sb.String())
+	}
+}
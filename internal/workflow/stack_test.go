@@ -0,0 +1,278 @@
+package workflow
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/git"
+	"github.com/hlfshell/cowork/internal/prbody"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// TestEngine_StackedPullRequests tests splitting a large change into stacked
+// PRs and moving the stack onto the base branch as its lower PR merges
+func TestEngine_StackedPullRequests(t *testing.T) {
+	testCases := []struct {
+		name              string
+		forcePushDisabled bool
+	}{
+		{
+			// Test case: Without force pushes the base branch is merged into the rest of the stack
+			name:              "merge",
+			forcePushDisabled: true,
+		},
+		{
+			// Test case: With force pushes the rest of the stack is rebased
+			name: "rebase",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			origin, workspacePath := newDraftWorkspace(t)
+			files := map[string]string{
+				"api/handler.go": "a\nb\nc\n",
+				"api/routes.go":  "d\ne\n",
+				"ui/view.go":     "f\ng\nh\ni\n",
+			}
+			for name, content := range files {
+				require.NoError(t, os.MkdirAll(filepath.Join(workspacePath, filepath.Dir(name)), 0755))
+				require.NoError(t, os.WriteFile(filepath.Join(workspacePath, name), []byte(content), 0644))
+			}
+			gitTest(t, workspacePath, "add", ".")
+			gitTest(t, workspacePath, "commit", "-q", "-m", "large change")
+
+			manager := newTestWorkflowManager(t)
+			workflow := newImplementingWorkflow(t, manager, 0)
+			workflow.BranchName = "task-7"
+			workflow.Config.MaxPRLines = 5
+			workflow.Config.ForcePushDisabled = tc.forcePushDisabled
+			storeTestWorkflow(t, manager, workflow)
+
+			provider := newFakeCoworkProvider()
+			provider.issues = map[int]*git.Issue{7: {Number: 7, Title: "Fix the widget"}}
+			tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusCompleted})
+			workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath, BranchName: "task-7"})
+			engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+
+			require.NoError(t, engine.processImplementingWorkflow(context.Background(), workflow))
+
+			require.Len(t, provider.created, 2)
+			assert.Equal(t, "Fix #7 (part 1/2): Fix the widget", provider.created[0].Title)
+			assert.Equal(t, "task-7-part-1", provider.created[0].Head)
+			assert.Equal(t, "main", provider.created[0].Base)
+			assert.Contains(t, provider.created[0].Body, "- `api/routes.go`")
+			require.Len(t, provider.edits, 1)
+			assert.Equal(t, "task-7-part-1", *provider.edits[0].Base)
+
+			workflow = reloadTestWorkflow(t, manager, workflow)
+			assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+			require.Len(t, workflow.StackedPRs, 2)
+			assert.Equal(t, types.StackedPullRequest{Number: 41, Branch: "task-7-part-1", Base: "main", Files: []string{"api/handler.go", "api/routes.go"}}, workflow.StackedPRs[0])
+			assert.Equal(t, types.StackedPullRequest{Number: 42, Branch: "task-7", Base: "task-7-part-1", Files: []string{"ui/view.go"}}, workflow.StackedPRs[1])
+			assert.Equal(t, []int{41, 42}, workflow.PRNumbers())
+			assert.Equal(t, "api/handler.go\napi/routes.go", gitTest(t, origin, "diff", "--name-only", "main...task-7-part-1"))
+			assert.Equal(t, "ui/view.go", gitTest(t, origin, "diff", "--name-only", "task-7-part-1...task-7"))
+
+			// Squash merge the lower PR on main
+			gitTest(t, workspacePath, "checkout", "-q", "main")
+			gitTest(t, workspacePath, "merge", "-q", "--squash", "task-7-part-1")
+			gitTest(t, workspacePath, "commit", "-q", "-m", "Part 1 (#41)")
+			gitTest(t, workspacePath, "push", "-q", "origin", "main")
+			gitTest(t, workspacePath, "checkout", "-q", "task-7")
+			provider.opened[41].Merged = true
+			provider.opened[41].State = "closed"
+
+			completed, err := engine.advanceStack(context.Background(), workflow)
+			require.NoError(t, err)
+			assert.False(t, completed)
+
+			workflow = reloadTestWorkflow(t, manager, workflow)
+			assert.True(t, workflow.StackedPRs[0].Merged)
+			assert.Equal(t, "main", workflow.StackedPRs[1].Base)
+			assert.Equal(t, "main", provider.opened[42].Base.Ref)
+			assert.Equal(t, "ui/view.go", gitTest(t, origin, "diff", "--name-only", "main...task-7"))
+			assert.Equal(t, "task-7", gitTest(t, workspacePath, "rev-parse", "--abbrev-ref", "HEAD"))
+		})
+	}
+}
+
+// TestEngine_StackPartFeedback tests answering feedback on the PR of the stack part it was left on
+func TestEngine_StackPartFeedback(t *testing.T) {
+	// Test case: A question on a lower part is answered on that part's PR,
+	// and once a revision for changes requested there is pushed the part is
+	// told what changed; feedback on the workflow's own PR gets no summary
+	origin, workspacePath := newDraftWorkspace(t)
+	gitTest(t, workspacePath, "push", "-q", "-u", "origin", "task-7")
+
+	manager := newTestWorkflowManager(t)
+	workflow := newImplementingWorkflow(t, manager, 0)
+	branch := "task-7"
+	stack := []types.StackedPullRequest{
+		{Number: 41, Branch: "task-7-part-1", Base: "main", Files: []string{"api/handler.go"}},
+		{Number: 42, Branch: "task-7", Base: "task-7-part-1", Files: []string{"ui/view.go"}},
+	}
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch, StackedPRs: &stack})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStatePROpen)
+
+	provider := newFakeCoworkProvider()
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusCompleted})
+	engine := NewEngine(manager, tasks, newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath}), provider, "owner", "repo")
+	engine.SetAgentRunner(&fakeAgentRunner{output: "<answer>The handler validates first.</answer>"})
+
+	provider.updates = &git.PullRequestUpdate{NewComments: []*git.Comment{{ID: 7, User: &git.User{Login: "alice"}, Body: "Why validate here?"}}}
+	partUpdates, err := engine.stackUpdates(context.Background(), workflow, time.Time{})
+	require.NoError(t, err)
+	items := FeedbackItemsFromUpdate(partUpdates...)
+	require.Len(t, items, 1)
+	assert.Equal(t, 41, items[0].PRNumber)
+
+	pr := &git.PullRequest{Number: 42, Title: "Fix #7 (part 2/2): Fix the widget"}
+	partQuestion := ClassifiedFeedback{Item: FeedbackItem{Kind: FeedbackKindComment, ID: 7, Author: "alice", Body: "Why validate here?", PRNumber: 41}, Intent: types.FeedbackIntentAsk}
+	partChange := ClassifiedFeedback{Item: FeedbackItem{Kind: FeedbackKindComment, ID: 8, Author: "alice", Body: "Rename the handler", PRNumber: 41}, Intent: types.FeedbackIntentChange}
+	topChange := ClassifiedFeedback{Item: FeedbackItem{Kind: FeedbackKindComment, ID: 9, Author: "bob", Body: "Fix the view", PRNumber: 42}, Intent: types.FeedbackIntentChange}
+
+	require.NoError(t, engine.handleAskFeedback(context.Background(), workflow, pr, []ClassifiedFeedback{partQuestion}))
+	require.Len(t, provider.comments[41], 1)
+	assert.Contains(t, provider.comments[41][0].Body, "The handler validates first.")
+	assert.Empty(t, provider.comments[42])
+
+	require.NoError(t, engine.handleChangeFeedback(context.Background(), workflow, pr, []ClassifiedFeedback{partQuestion, partChange, topChange}, time.Now()))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	feedback, err := RevisionStackFeedback(workflow)
+	require.NoError(t, err)
+	assert.Equal(t, []ClassifiedFeedback{partChange}, feedback)
+
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), workflow))
+	commitFile(t, workspacePath, "file.txt", "renamed\n", "rename the handler")
+	tasks.tasks[3].Status = types.TaskStatusCompleted
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), reloadTestWorkflow(t, manager, workflow)))
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+	assert.Empty(t, workflow.Metadata[MetadataRevisionStackFeedback])
+	assert.Equal(t, gitTest(t, workspacePath, "rev-parse", "HEAD"), gitTest(t, origin, "rev-parse", "task-7"))
+
+	require.Len(t, provider.comments[41], 2)
+	summary := provider.comments[41][1].Body
+	assert.Contains(t, summary, "pushed to #42")
+	assert.Contains(t, summary, "@alice (comment): Rename the handler")
+	assert.Contains(t, summary, "- rename the handler")
+	assert.NotContains(t, summary, "Fix the view")
+	assert.Empty(t, provider.comments[42])
+}
+
+// TestGroupFilesByDirectory tests splitting files into parts under a line limit
+func TestGroupFilesByDirectory(t *testing.T) {
+	testCases := []struct {
+		name     string
+		files    []prbody.FileChange
+		maxLines int
+		expected [][]string
+	}{
+		{
+			// Test case: Directories that fit together share a part
+			name: "directories fit",
+			files: []prbody.FileChange{
+				{Path: "api/a.go", Additions: 2},
+				{Path: "api/b.go", Additions: 2},
+				{Path: "docs/README.md", Additions: 1},
+				{Path: "ui/view.go", Additions: 4},
+			},
+			maxLines: 5,
+			expected: [][]string{{"api/a.go", "api/b.go", "docs/README.md"}, {"ui/view.go"}},
+		},
+		{
+			// Test case: A directory over the limit is split file by file, and a
+			// file over the limit gets a part of its own
+			name: "directory over the limit",
+			files: []prbody.FileChange{
+				{Path: "api/a.go", Additions: 3},
+				{Path: "api/b.go", Additions: 2, Deletions: 1},
+				{Path: "api/c.go", Additions: 9},
+				{Path: "logo.png", Binary: true},
+			},
+			maxLines: 5,
+			expected: [][]string{{"api/a.go"}, {"api/b.go"}, {"api/c.go"}, {"logo.png"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var paths [][]string
+			for _, part := range groupFilesByDirectory(tc.files, tc.maxLines) {
+				var partPaths []string
+				for _, file := range part {
+					partPaths = append(partPaths, file.Path)
+				}
+				paths = append(paths, partPaths)
+			}
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
+
+// TestParseStackParts tests reading the agent's proposed split
+func TestParseStackParts(t *testing.T) {
+	files := []prbody.FileChange{{Path: "a.go"}, {Path: "b.go"}, {Path: "c.go"}}
+
+	testCases := []struct {
+		name        string
+		output      string
+		expected    [][]string
+		expectError bool
+	}{
+		{
+			// Test case: Every file placed once gives the proposed parts in order
+			name:     "valid proposal",
+			output:   "<part>\n- b.go\n</part>\n<part>\na.go\nc.go\n</part>",
+			expected: [][]string{{"b.go"}, {"a.go", "c.go"}},
+		},
+		{
+			// Test case: A file left out is rejected
+			name:        "missing file",
+			output:      "<part>a.go</part><part>b.go</part>",
+			expectError: true,
+		},
+		{
+			// Test case: A file placed twice is rejected
+			name:        "duplicate file",
+			output:      "<part>a.go\nb.go</part><part>b.go\nc.go</part>",
+			expectError: true,
+		},
+		{
+			// Test case: A file that did not change is rejected
+			name:        "unknown file",
+			output:      "<part>a.go\nb.go\nc.go\nd.go</part>",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parts, err := parseStackParts(tc.output, files)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			var paths [][]string
+			for _, part := range parts {
+				var partPaths []string
+				for _, file := range part {
+					partPaths = append(partPaths, file.Path)
+				}
+				paths = append(paths, partPaths)
+			}
+			assert.Equal(t, tc.expected, paths)
+		})
+	}
+}
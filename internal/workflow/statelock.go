@@ -0,0 +1,178 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// withStateLock runs fn holding the advisory lock on the state files, so that
+// read-modify-write cycles of several cw processes on the same project do not
+// interleave. The lock is not reentrant: fn must not take it again.
+func (wm *WorkflowManager) withStateLock(fn func() error) error {
+	file, err := os.OpenFile(wm.stateLockFilePath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open state lock file: %w", err)
+	}
+	defer file.Close()
+
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("failed to lock state files: %w", err)
+	}
+	defer unlockFile(file)
+
+	return fn()
+}
+
+// refreshLocksUnlocked replaces the cached locks with the ones on disk, which
+// other cw processes may have changed. Must be called with both locks held.
+func (wm *WorkflowManager) refreshLocksUnlocked() error {
+	data, err := os.ReadFile(wm.locksFilePath)
+	if os.IsNotExist(err) {
+		wm.locks = make(map[string]*types.WorkflowLock)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read locks file: %w", err)
+	}
+
+	var locks []*types.WorkflowLock
+	if err := json.Unmarshal(data, &locks); err != nil {
+		return fmt.Errorf("failed to decode locks: %w", err)
+	}
+
+	wm.locks = make(map[string]*types.WorkflowLock, len(locks))
+	for _, lock := range locks {
+		wm.locks[fmt.Sprintf("%d", lock.WorkflowID)] = lock
+	}
+
+	return nil
+}
+
+// syncWorkflowsUnlocked applies the workflow log records other cw processes
+// appended since this one last read the log, so the cache holds the latest
+// state before it is changed. Only records after the last one applied are
+// read; a log that was replaced is replayed in full. Must be called with both
+// locks held.
+func (wm *WorkflowManager) syncWorkflowsUnlocked() error {
+	info, err := os.Stat(wm.logFilePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to stat workflow log: %w", err)
+	}
+
+	if info.Size() < wm.logOffset {
+		state, err := wm.replayUnlocked()
+		if err != nil {
+			return err
+		}
+		wm.workflows = state.workflows
+		wm.logOffset, wm.logSequence = state.offset, state.sequence
+		return nil
+	}
+
+	offset, _, err := wm.readRecordsUnlocked(wm.logOffset, func(record *WorkflowRecord) error {
+		if record.Sequence <= wm.logSequence {
+			return nil
+		}
+		if err := applyRecord(wm.workflows, record); err != nil {
+			return err
+		}
+		wm.logSequence = record.Sequence
+		return nil
+	})
+	wm.logOffset = offset
+	return err
+}
+
+// isStaleLock reports whether a lock no longer protects anything: its lease
+// ran out, or its holder ran on this host and has exited
+func isStaleLock(lock *types.WorkflowLock) bool {
+	if lock.IsExpired() {
+		return true
+	}
+
+	if lock.ProcessID == 0 || (lock.Hostname != "" && lock.Hostname != localHostname()) {
+		return false
+	}
+
+	return !processAlive(lock.ProcessID)
+}
+
+// localHostname returns the name of this host, or an empty string if unknown
+func localHostname() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return hostname
+}
+
+// RenewWorkflowLock extends the lease of a lock held by lockedBy in this
+// process. It fails if the lock was released or taken over in the meantime.
+func (wm *WorkflowManager) RenewWorkflowLock(workflowID, lockedBy string, lease time.Duration) (*types.WorkflowLock, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	var renewed *types.WorkflowLock
+	err := wm.withStateLock(func() error {
+		if err := wm.refreshLocksUnlocked(); err != nil {
+			return err
+		}
+
+		lock, exists := wm.locks[workflowID]
+		if !exists || lock.LockedBy != lockedBy || lock.ProcessID != os.Getpid() {
+			return fmt.Errorf("lock on workflow %s is no longer held by %s", workflowID, lockedBy)
+		}
+
+		// Callers may hold the previous lock, so renew a copy
+		now := time.Now()
+		renewed = &types.WorkflowLock{}
+		*renewed = *lock
+		renewed.LockTimeout = now.Add(lease)
+		renewed.HeartbeatAt = now
+		wm.locks[workflowID] = renewed
+
+		if err := wm.saveLocksUnlocked(); err != nil {
+			return fmt.Errorf("failed to save lock: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return renewed, nil
+}
+
+// cleanupStaleLocksUnlocked drops stale locks from the cache and the locks
+// file. Must be called with both locks held.
+func (wm *WorkflowManager) cleanupStaleLocksUnlocked() error {
+	if err := wm.refreshLocksUnlocked(); err != nil {
+		return err
+	}
+
+	var stale []string
+	for workflowID, lock := range wm.locks {
+		if isStaleLock(lock) {
+			stale = append(stale, workflowID)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	for _, workflowID := range stale {
+		log.Printf("🔓 Releasing stale lock on workflow %s held by %s", workflowID, wm.locks[workflowID].LockedBy)
+		delete(wm.locks, workflowID)
+	}
+
+	return wm.saveLocksUnlocked()
+}
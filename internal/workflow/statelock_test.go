@@ -0,0 +1,355 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// openSecondManager opens another manager on the same project, standing in
+// for a second cw process
+func openSecondManager(t *testing.T, manager *WorkflowManager) *WorkflowManager {
+	t.Helper()
+
+	other, err := NewWorkflowManager(manager.cwDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { other.Close() })
+
+	return other
+}
+
+// rewriteLock edits the lock on a workflow directly in the locks file
+func rewriteLock(t *testing.T, manager *WorkflowManager, edit func(lock *types.WorkflowLock)) {
+	t.Helper()
+
+	data, err := os.ReadFile(manager.locksFilePath)
+	require.NoError(t, err)
+
+	var locks []*types.WorkflowLock
+	require.NoError(t, json.Unmarshal(data, &locks))
+	for _, lock := range locks {
+		edit(lock)
+	}
+
+	data, err = json.Marshal(locks)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manager.locksFilePath, data, 0644))
+}
+
+// exitedPID returns the PID of a process that has already exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command("true")
+	require.NoError(t, cmd.Run())
+	return cmd.Process.Pid
+}
+
+// TestWorkflowManager_LockWorkflow_AcrossProcesses tests that locks are shared through the locks file
+func TestWorkflowManager_LockWorkflow_AcrossProcesses(t *testing.T) {
+	// Test case: A lock taken by one manager blocks another manager on the
+	// same project until it is released
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+	other := openSecondManager(t, manager)
+	workflowID := "1"
+
+	_, err := manager.LockWorkflow(workflowID, "daemon", time.Minute)
+	require.NoError(t, err)
+
+	_, err = other.LockWorkflow(workflowID, "cli", time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already locked by daemon")
+
+	require.NoError(t, manager.UnlockWorkflow(workflowID, "daemon"))
+
+	lock, err := other.LockWorkflow(workflowID, "cli", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, workflow.ID, lock.WorkflowID)
+	assert.Equal(t, os.Getpid(), lock.ProcessID)
+}
+
+// TestWorkflowManager_LockWorkflow_StaleHolder tests taking over locks whose holder is gone
+func TestWorkflowManager_LockWorkflow_StaleHolder(t *testing.T) {
+	// Test case: A lock is taken over once its lease ran out or its holder on
+	// this host exited; a holder on another host is trusted until the lease ends
+	testCases := []struct {
+		name      string
+		edit      func(t *testing.T, lock *types.WorkflowLock)
+		wantTaken bool
+	}{
+		{"live holder", func(t *testing.T, lock *types.WorkflowLock) {}, false},
+		{"expired lease", func(t *testing.T, lock *types.WorkflowLock) {
+			lock.LockTimeout = time.Now().Add(-time.Second)
+		}, true},
+		{"exited holder", func(t *testing.T, lock *types.WorkflowLock) {
+			lock.ProcessID = exitedPID(t)
+		}, true},
+		{"exited holder on another host", func(t *testing.T, lock *types.WorkflowLock) {
+			lock.ProcessID = exitedPID(t)
+			lock.Hostname = "elsewhere"
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			createTestWorkflow(t, manager, 1)
+			other := openSecondManager(t, manager)
+
+			_, err := manager.LockWorkflow("1", "daemon", time.Minute)
+			require.NoError(t, err)
+			rewriteLock(t, manager, func(lock *types.WorkflowLock) { tc.edit(t, lock) })
+
+			_, err = other.LockWorkflow("1", "cli", time.Minute)
+			if tc.wantTaken {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+// TestWorkflowManager_RenewWorkflowLock tests extending a lock's lease
+func TestWorkflowManager_RenewWorkflowLock(t *testing.T) {
+	// Test case: The holder's heartbeat keeps a short lease alive; a lock that
+	// was taken over cannot be renewed by its former holder
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+	other := openSecondManager(t, manager)
+
+	_, err := manager.LockWorkflow("1", "daemon", 50*time.Millisecond)
+	require.NoError(t, err)
+
+	renewed, err := manager.RenewWorkflowLock("1", "daemon", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, renewed.LockTimeout.After(time.Now().Add(30*time.Second)))
+
+	time.Sleep(100 * time.Millisecond)
+	_, err = other.LockWorkflow("1", "cli", time.Minute)
+	require.Error(t, err, "a renewed lease must not be taken over")
+
+	_, err = manager.RenewWorkflowLock("1", "someone-else", time.Minute)
+	require.Error(t, err)
+
+	require.NoError(t, other.ForceUnlockWorkflow("1"))
+	_, err = manager.RenewWorkflowLock("1", "daemon", time.Minute)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no longer held")
+}
+
+// TestWorkflowManager_SaveMergesOtherProcesses tests that saving keeps other processes' changes
+func TestWorkflowManager_SaveMergesOtherProcesses(t *testing.T) {
+	// Test case: Two managers updating different workflows from stale caches
+	// both keep their changes, and a workflow deleted by one stays deleted
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+	createTestWorkflow(t, manager, 2)
+	createTestWorkflow(t, manager, 3)
+	other := openSecondManager(t, manager)
+
+	transitionTestWorkflow(t, manager, &types.Workflow{ID: 1}, types.WorkflowStateWorkspaceReady)
+	transitionTestWorkflow(t, other, &types.Workflow{ID: 2}, types.WorkflowStateAborted)
+	require.NoError(t, manager.DeleteWorkflow("3"))
+
+	fresh := openSecondManager(t, manager)
+	first, err := fresh.GetWorkflow("1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateWorkspaceReady, first.State)
+
+	second, err := fresh.GetWorkflow("2")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateAborted, second.State)
+
+	_, err = fresh.GetWorkflow("3")
+	assert.Error(t, err)
+}
+
+// TestWorkflowManager_UpdateWorkflow_AcrossProcesses tests interleaved updates to one workflow
+func TestWorkflowManager_UpdateWorkflow_AcrossProcesses(t *testing.T) {
+	// Test case: Each update applies on top of the other manager's latest
+	// change, so transitions made from a stale cache are valid and no
+	// metadata is lost
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+	other := openSecondManager(t, manager)
+
+	transitionTestWorkflow(t, manager, &types.Workflow{ID: 1}, types.WorkflowStateWorkspaceReady)
+	_, err := other.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{"other": "1"}})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, other, &types.Workflow{ID: 1}, types.WorkflowStateImplementing)
+
+	updated, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{"manager": "1"}})
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, updated.State)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 10; i++ {
+			_, err := other.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{fmt.Sprintf("other-%d", i): "1"}})
+			assert.NoError(t, err)
+		}
+	}()
+	for i := 0; i < 10; i++ {
+		_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{fmt.Sprintf("manager-%d", i): "1"}})
+		assert.NoError(t, err)
+	}
+	<-done
+
+	workflow, err := openSecondManager(t, manager).GetWorkflow("1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, workflow.State)
+	assert.Len(t, workflow.Metadata, 22)
+}
+
+// TestWorkflowManager_ReturnsCopies tests that readers cannot see or make changes behind the manager's back
+func TestWorkflowManager_ReturnsCopies(t *testing.T) {
+	// Test case: Changing a returned workflow leaves the stored one alone, and
+	// a workflow read before an update keeps the state it was read with
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 1)
+
+	read, err := manager.GetWorkflow("1")
+	require.NoError(t, err)
+	read.Metadata["edited"] = "true"
+	read.Config.RequiredChecks = append(read.Config.RequiredChecks, "test")
+
+	transitionTestWorkflow(t, manager, &types.Workflow{ID: workflow.ID}, types.WorkflowStateWorkspaceReady)
+	assert.Equal(t, types.WorkflowStateQueued, read.State)
+
+	stored := reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateWorkspaceReady, stored.State)
+	assert.NotContains(t, stored.Metadata, "edited")
+	assert.Equal(t, types.GetDefaultWorkflowConfig().RequiredChecks, stored.Config.RequiredChecks)
+}
+
+// TestWorkflowManager_SyncReadsNewRecords tests catching up with records other processes appended
+func TestWorkflowManager_SyncReadsNewRecords(t *testing.T) {
+	// Test case: Only the records appended since the last sync are read, an
+	// unfinished line is left until it is completed, and a log that was
+	// replaced with a shorter one is replayed in full
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+	other := openSecondManager(t, manager)
+
+	transitionTestWorkflow(t, other, &types.Workflow{ID: 1}, types.WorkflowStateWorkspaceReady)
+	info, err := os.Stat(manager.logFilePath)
+	require.NoError(t, err)
+	assert.Less(t, manager.logOffset, info.Size())
+
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{"manager": "1"}})
+	require.NoError(t, err)
+	info, err = os.Stat(manager.logFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), manager.logOffset)
+	assert.Equal(t, int64(3), manager.logSequence)
+	assert.Equal(t, types.WorkflowStateWorkspaceReady, reloadTestWorkflow(t, manager, &types.Workflow{ID: 1}).State)
+
+	file, err := os.OpenFile(manager.logFilePath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"sequence":4,"type":"upd`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	_, err = manager.LockWorkflow("1", "daemon", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, info.Size(), manager.logOffset)
+	require.NoError(t, manager.UnlockWorkflow("1", "daemon"))
+
+	transitionTestWorkflow(t, other, &types.Workflow{ID: 1}, types.WorkflowStateImplementing)
+	_, err = manager.LockWorkflow("1", "daemon", time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStateImplementing, reloadTestWorkflow(t, manager, &types.Workflow{ID: 1}).State)
+	require.NoError(t, manager.UnlockWorkflow("1", "daemon"))
+
+	data, err := os.ReadFile(manager.logFilePath)
+	require.NoError(t, err)
+	first := data[:bytes.IndexByte(data, '\n')+1]
+	require.NoError(t, os.WriteFile(manager.logFilePath, first, 0644))
+
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: 1, Metadata: map[string]string{"replaced": "1"}})
+	require.NoError(t, err)
+	workflow := reloadTestWorkflow(t, manager, &types.Workflow{ID: 1})
+	assert.Equal(t, types.WorkflowStateQueued, workflow.State)
+	assert.Equal(t, map[string]string{"replaced": "1"}, workflow.Metadata)
+	assert.Equal(t, int64(2), manager.logSequence)
+}
+
+// TestWorkflowManager_EventLog_AcrossProcesses tests events recorded by other processes
+func TestWorkflowManager_EventLog_AcrossProcesses(t *testing.T) {
+	// Test case: Events created and processed by another manager are seen
+	// without reloading, events from the old events file are still loaded,
+	// and that file is never rewritten
+	manager := newTestWorkflowManager(t)
+	legacy := []byte(`[{"id":"event-1","type":"issues","issue_id":10,"processed":false}]`)
+	require.NoError(t, os.WriteFile(manager.eventsFilePath, legacy, 0644))
+	other := openSecondManager(t, manager)
+
+	created, err := other.CreateEvent(types.WorkflowEventIssues, "github", "owner", "repo", 11, map[string]interface{}{})
+	require.NoError(t, err)
+
+	events, err := manager.ListUnprocessedEvents()
+	require.NoError(t, err)
+	assert.Len(t, events, 1, "the old events file is only read when loading")
+
+	events, err = other.ListUnprocessedEvents()
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+
+	require.NoError(t, manager.MarkEventProcessed(created.ID, "1", ""))
+	require.NoError(t, other.MarkEventProcessed("event-1", "", "no workflow"))
+
+	events, err = manager.ListUnprocessedEvents()
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	fresh := openSecondManager(t, manager)
+	processed, err := fresh.GetEvent(created.ID)
+	require.NoError(t, err)
+	assert.True(t, processed.Processed)
+	assert.Equal(t, "1", processed.JobID)
+
+	processed, err = fresh.GetEvent("event-1")
+	require.NoError(t, err)
+	assert.True(t, processed.Processed)
+	assert.Equal(t, "no workflow", processed.Error)
+
+	data, err := os.ReadFile(manager.eventsFilePath)
+	require.NoError(t, err)
+	assert.Equal(t, legacy, data)
+}
+
+// TestEngine_LockWorkflow_Heartbeat tests lease renewal while a workflow is processed
+func TestEngine_LockWorkflow_Heartbeat(t *testing.T) {
+	// Test case: The engine renews the lease in the background and releases
+	// the lock when done
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 1)
+
+	engine := NewEngine(manager, nil, nil, nil, "owner", "repo")
+	engine.lockHeartbeat = 10 * time.Millisecond
+
+	lock, release, err := engine.lockWorkflow("1")
+	require.NoError(t, err)
+	acquired := lock.HeartbeatAt
+
+	require.Eventually(t, func() bool {
+		current, err := manager.GetWorkflowLock("1")
+		return err == nil && current.HeartbeatAt.After(acquired)
+	}, time.Second, 10*time.Millisecond)
+
+	release()
+	locked, _ := manager.IsWorkflowLocked("1")
+	assert.False(t, locked)
+}
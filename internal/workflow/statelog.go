@@ -0,0 +1,538 @@
+package workflow
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// WorkflowRecordType is the kind of change a workflow log record holds
+type WorkflowRecordType string
+
+const (
+	// WorkflowRecordCreated records a new workflow in full
+	WorkflowRecordCreated WorkflowRecordType = "created"
+
+	// WorkflowRecordUpdated records an update applied to a workflow
+	WorkflowRecordUpdated WorkflowRecordType = "updated"
+
+	// WorkflowRecordDeleted records a deleted workflow
+	WorkflowRecordDeleted WorkflowRecordType = "deleted"
+)
+
+// WorkflowRecord is one change in the workflow log. Workflow state is the
+// result of applying the records in sequence order.
+type WorkflowRecord struct {
+	Sequence   int64              `json:"sequence"`
+	Type       WorkflowRecordType `json:"type"`
+	WorkflowID int                `json:"workflow_id,string"`
+	Timestamp  time.Time          `json:"timestamp"`
+
+	// The new workflow, for created records
+	Workflow *types.Workflow `json:"workflow,omitempty"`
+
+	// The update applied, for updated records
+	Update *types.UpdateWorkflowRequest `json:"update,omitempty"`
+
+	// Who made the change, for the workflow's transition history
+	Actor string `json:"actor,omitempty"`
+}
+
+// WorkflowSnapshot is the state derived from the workflow log up to a sequence
+// number, so replay does not have to start from the first record
+type WorkflowSnapshot struct {
+	Sequence  int64             `json:"sequence"`
+	TakenAt   time.Time         `json:"taken_at"`
+	Workflows []*types.Workflow `json:"workflows"`
+}
+
+// ReplayResult compares a workflow rebuilt from the log with the stored one
+type ReplayResult struct {
+	// Workflow rebuilt from the log; nil if the log deleted it
+	Workflow *types.Workflow
+
+	// Workflow stored in the workflows file; nil if missing or unreadable
+	Stored *types.Workflow
+
+	// Fields whose replayed and stored values differ
+	Differences []string
+
+	// Sequence number of the snapshot replay started from, and the number of
+	// records applied to the workflow after it
+	SnapshotSequence int64
+	Records          int
+
+	// Log lines that could not be parsed, such as a write torn by a crash
+	SkippedLines int
+
+	// Whether the snapshot or the workflows file could not be read, and was
+	// rewritten from the replayed state
+	SnapshotRecovered bool
+	StoreRecovered    bool
+
+	// Whether the stored workflow was replaced with the replayed one
+	Repaired bool
+}
+
+// Matches reports whether the replayed workflow matches the stored one
+func (r *ReplayResult) Matches() bool {
+	return len(r.Differences) == 0
+}
+
+// replayedState is the workflow state rebuilt from a snapshot and the log
+type replayedState struct {
+	workflows map[string]*types.Workflow
+
+	// Last sequence number applied, and the log offset reached
+	sequence int64
+	offset   int64
+
+	snapshotSequence int64
+	snapshotCorrupt  bool
+
+	// Records applied per workflow, and log lines skipped
+	records map[string]int
+	skipped int
+}
+
+// appendRecordUnlocked assigns the next sequence number to a record and
+// appends it to the workflow log. Other cw processes append too, so the
+// caller must have synced with the log first. Must be called with both locks
+// held.
+func (wm *WorkflowManager) appendRecordUnlocked(record *WorkflowRecord) error {
+	record.Sequence = wm.logSequence + 1
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode workflow record: %w", err)
+	}
+
+	size, err := appendLogLine(wm.logFilePath, data)
+	if err != nil {
+		return fmt.Errorf("failed to append to workflow log: %w", err)
+	}
+	wm.logSequence = record.Sequence
+	wm.logOffset = size
+
+	return nil
+}
+
+// readRecordsUnlocked reads the workflow log from offset, passing each record
+// to fn, and returns the offset after the last complete line read, or of the
+// record fn failed on. Unreadable lines are skipped and counted; an
+// unfinished last line is left for when a later append completes it. Must be
+// called with both locks held.
+func (wm *WorkflowManager) readRecordsUnlocked(offset int64, fn func(record *WorkflowRecord) error) (int64, int, error) {
+	skipped := 0
+	var replayErr error
+	offset, err := readLogLines(wm.logFilePath, offset, func(line []byte, lineOffset int64) error {
+		var record WorkflowRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			log.Printf("⚠️  Skipping unreadable workflow log line at byte %d: %v", lineOffset, err)
+			skipped++
+			return nil
+		}
+		if err := fn(&record); err != nil {
+			replayErr = fmt.Errorf("failed to replay workflow log record %d: %w", record.Sequence, err)
+			return replayErr
+		}
+		return nil
+	})
+	if replayErr != nil {
+		return offset, skipped, replayErr
+	}
+	if err != nil {
+		return offset, skipped, fmt.Errorf("failed to read workflow log: %w", err)
+	}
+
+	return offset, skipped, nil
+}
+
+// appendLogLine appends data as one line to the append-only log at path,
+// returning the log's new size. A line a crashed writer left unfinished is
+// ended first, so it does not swallow the new one.
+func appendLogLine(path string, data []byte) (int64, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	line := append(append([]byte{}, data...), '\n')
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, info.Size()-1); err != nil {
+			return 0, err
+		}
+		if last[0] != '\n' {
+			line = append([]byte{'\n'}, line...)
+		}
+	}
+
+	if _, err := file.WriteAt(line, info.Size()); err != nil {
+		return 0, err
+	}
+
+	return info.Size() + int64(len(line)), nil
+}
+
+// readLogLines passes each complete, non-empty line of the append-only log at
+// path from offset on to fn, with the offset the line starts at. It returns
+// the offset after the last line read, or of the line fn failed on; an
+// unfinished last line is left unread. A missing log has no lines.
+func readLogLines(path string, offset int64, fn func(line []byte, lineOffset int64) error) (int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return offset, nil
+	}
+	if err != nil {
+		return offset, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, err
+	}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return offset, err
+		}
+
+		lineOffset := offset
+		offset += int64(len(line))
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		if err := fn(line, lineOffset); err != nil {
+			return lineOffset, err
+		}
+	}
+}
+
+// writeSnapshotUnlocked writes workflows as the snapshot at sequence. Must be
+// called with both locks held.
+func (wm *WorkflowManager) writeSnapshotUnlocked(workflows map[string]*types.Workflow, sequence int64) error {
+	snapshot := &WorkflowSnapshot{
+		Sequence:  sequence,
+		TakenAt:   time.Now(),
+		Workflows: sortedWorkflows(workflows),
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workflow snapshot: %w", err)
+	}
+
+	// Write a temporary file first so a crash never leaves half a snapshot
+	tempFile := wm.snapshotFilePath + ".tmp"
+	if err := os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("failed to write workflow snapshot: %w", err)
+	}
+	if err := os.Rename(tempFile, wm.snapshotFilePath); err != nil {
+		return fmt.Errorf("failed to rename temporary workflow snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// seedSnapshotUnlocked snapshots the workflows of a project that kept them
+// before the workflow log existed, so replay starts from them. Must be called
+// with both locks held.
+func (wm *WorkflowManager) seedSnapshotUnlocked() error {
+	if len(wm.workflows) == 0 || fileExists(wm.logFilePath) || fileExists(wm.snapshotFilePath) {
+		return nil
+	}
+
+	log.Printf("📸 Snapshotting %d existing workflows to start the workflow log", len(wm.workflows))
+	return wm.writeSnapshotUnlocked(wm.workflows, 0)
+}
+
+// replayUnlocked rebuilds workflow state from the snapshot and the log
+// records after it. An unreadable snapshot is skipped and the whole log
+// replayed instead. Must be called with both locks held.
+func (wm *WorkflowManager) replayUnlocked() (*replayedState, error) {
+	state := &replayedState{
+		workflows: make(map[string]*types.Workflow),
+		records:   make(map[string]int),
+	}
+
+	data, err := os.ReadFile(wm.snapshotFilePath)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read workflow snapshot: %w", err)
+	default:
+		var snapshot WorkflowSnapshot
+		if err := json.Unmarshal(data, &snapshot); err != nil {
+			log.Printf("⚠️  Workflow snapshot is corrupted, replaying the whole log: %v", err)
+			state.snapshotCorrupt = true
+			break
+		}
+		for _, workflow := range snapshot.Workflows {
+			state.workflows[fmt.Sprintf("%d", workflow.ID)] = workflow
+		}
+		state.snapshotSequence = snapshot.Sequence
+		state.sequence = snapshot.Sequence
+	}
+
+	state.offset, state.skipped, err = wm.readRecordsUnlocked(0, func(record *WorkflowRecord) error {
+		if record.Sequence <= state.snapshotSequence {
+			return nil
+		}
+		return state.apply(record)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// apply applies one log record to the replayed state
+func (s *replayedState) apply(record *WorkflowRecord) error {
+	if err := applyRecord(s.workflows, record); err != nil {
+		return err
+	}
+
+	s.sequence = record.Sequence
+	s.records[fmt.Sprintf("%d", record.WorkflowID)]++
+	return nil
+}
+
+// applyRecord applies one log record to workflows keyed by ID. Updated
+// workflows are replaced with a changed copy rather than changed in place,
+// since readers may still hold the previous one.
+func applyRecord(workflows map[string]*types.Workflow, record *WorkflowRecord) error {
+	workflowID := fmt.Sprintf("%d", record.WorkflowID)
+
+	switch record.Type {
+	case WorkflowRecordCreated:
+		if record.Workflow == nil {
+			return fmt.Errorf("created record has no workflow")
+		}
+		workflows[workflowID] = record.Workflow.Clone()
+	case WorkflowRecordUpdated:
+		workflow, exists := workflows[workflowID]
+		if !exists {
+			return fmt.Errorf("workflow %s was updated before it was created", workflowID)
+		}
+		if record.Update == nil {
+			return fmt.Errorf("updated record has no update")
+		}
+		updated := workflow.Clone()
+		if _, err := applyWorkflowUpdate(updated, record.Update, record.Timestamp); err != nil {
+			return err
+		}
+		workflows[workflowID] = updated
+	case WorkflowRecordDeleted:
+		delete(workflows, workflowID)
+	default:
+		return fmt.Errorf("unknown record type %q", record.Type)
+	}
+
+	return nil
+}
+
+// refreshWorkflowsCacheUnlocked rewrites the workflows file when it is
+// unreadable or no longer matches the cached workflows. Must be called with
+// both locks held.
+func (wm *WorkflowManager) refreshWorkflowsCacheUnlocked() error {
+	stored, err := readWorkflowsFile(wm.workflowsFilePath)
+	if err != nil {
+		log.Printf("♻️  Rebuilding the workflows file from the workflow log after: %v", err)
+		return wm.writeWorkflowsUnlocked()
+	}
+
+	if len(stored) == len(wm.workflows) {
+		current := true
+		for workflowID, workflow := range wm.workflows {
+			differences, err := diffWorkflows(workflow, stored[workflowID])
+			if err != nil {
+				return err
+			}
+			if len(differences) > 0 {
+				current = false
+				break
+			}
+		}
+		if current {
+			return nil
+		}
+	}
+
+	return wm.writeWorkflowsUnlocked()
+}
+
+// ReplayWorkflow rebuilds a workflow from the workflow log and compares it
+// with the copy cached in the workflows file. A corrupted snapshot or
+// workflows file is rewritten from the replayed state; with repair, a cached
+// workflow that differs is replaced with the replayed one too.
+func (wm *WorkflowManager) ReplayWorkflow(workflowID string, repair bool) (*ReplayResult, error) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	var result *ReplayResult
+	err := wm.withStateLock(func() error {
+		state, err := wm.replayUnlocked()
+		if err != nil {
+			return err
+		}
+
+		stored, storeErr := readWorkflowsFile(wm.workflowsFilePath)
+		if storeErr != nil {
+			log.Printf("⚠️  Workflows file is unreadable: %v", storeErr)
+		}
+
+		result = &ReplayResult{
+			Workflow:         state.workflows[workflowID],
+			Stored:           stored[workflowID],
+			SnapshotSequence: state.snapshotSequence,
+			Records:          state.records[workflowID],
+			SkippedLines:     state.skipped,
+		}
+		if result.Workflow == nil && result.Stored == nil {
+			return fmt.Errorf("workflow not found: %s", workflowID)
+		}
+
+		result.Differences, err = diffWorkflows(result.Workflow, result.Stored)
+		if err != nil {
+			return err
+		}
+
+		if state.snapshotCorrupt {
+			if err := wm.writeSnapshotUnlocked(state.workflows, state.sequence); err != nil {
+				return err
+			}
+			result.SnapshotRecovered = true
+		}
+
+		switch {
+		case storeErr != nil:
+			wm.workflows = state.workflows
+			wm.logOffset, wm.logSequence = state.offset, state.sequence
+			result.StoreRecovered = true
+		case repair && !result.Matches():
+			if result.Workflow == nil {
+				delete(wm.workflows, workflowID)
+			} else {
+				wm.workflows[workflowID] = result.Workflow
+			}
+			result.Repaired = true
+		default:
+			return nil
+		}
+
+		return wm.writeWorkflowsUnlocked()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// readWorkflowsFile reads the workflows file keyed by workflow ID
+func readWorkflowsFile(path string) (map[string]*types.Workflow, error) {
+	workflows := make(map[string]*types.Workflow)
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return workflows, nil
+	}
+	if err != nil {
+		return workflows, fmt.Errorf("failed to read workflows file: %w", err)
+	}
+
+	var list []*types.Workflow
+	if err := json.Unmarshal(data, &list); err != nil {
+		return workflows, fmt.Errorf("failed to decode workflows: %w", err)
+	}
+	for _, workflow := range list {
+		workflows[fmt.Sprintf("%d", workflow.ID)] = workflow
+	}
+
+	return workflows, nil
+}
+
+// diffWorkflows lists the JSON fields whose values differ between two
+// workflows; a missing workflow differs in every field of the other
+func diffWorkflows(a, b *types.Workflow) ([]string, error) {
+	fieldsA, err := workflowFields(a)
+	if err != nil {
+		return nil, err
+	}
+	fieldsB, err := workflowFields(b)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]bool)
+	for name := range fieldsA {
+		names[name] = true
+	}
+	for name := range fieldsB {
+		names[name] = true
+	}
+
+	var differences []string
+	for name := range names {
+		if !bytes.Equal(fieldsA[name], fieldsB[name]) {
+			differences = append(differences, name)
+		}
+	}
+	sort.Strings(differences)
+
+	return differences, nil
+}
+
+// workflowFields returns the JSON encoding of each field of a workflow
+func workflowFields(workflow *types.Workflow) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if workflow == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode workflow: %w", err)
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow fields: %w", err)
+	}
+
+	return fields, nil
+}
+
+// sortedWorkflows returns workflows ordered by ID
+func sortedWorkflows(workflows map[string]*types.Workflow) []*types.Workflow {
+	list := make([]*types.Workflow, 0, len(workflows))
+	for _, workflow := range workflows {
+		list = append(list, workflow)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// fileExists reports whether a file exists at path
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package workflow
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// advanceTestWorkflow walks a new workflow to pr_open with the kinds of
+// updates the engine makes along the way
+func advanceTestWorkflow(t *testing.T, manager *WorkflowManager, issueID int) *types.Workflow {
+	t.Helper()
+
+	workflow := createTestWorkflow(t, manager, issueID)
+	branch, prNumber := "task-7", 42
+	retryAt := time.Now().Add(time.Minute)
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, BranchName: &branch, NextRetryAt: &retryAt})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing)
+
+	cleared := time.Time{}
+	_, err = manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID:  workflow.ID,
+		PRNumber:    &prNumber,
+		NextRetryAt: &cleared,
+		Metadata:    map[string]string{MetadataDraftPR: "true"},
+	})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStatePROpen)
+
+	return workflow
+}
+
+// rewriteStoredWorkflow edits a workflow directly in the workflows file
+func rewriteStoredWorkflow(t *testing.T, manager *WorkflowManager, edit func(workflow *types.Workflow)) {
+	t.Helper()
+
+	var workflows []*types.Workflow
+	data, err := os.ReadFile(manager.workflowsFilePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &workflows))
+	for _, workflow := range workflows {
+		edit(workflow)
+	}
+	data, err = json.Marshal(workflows)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manager.workflowsFilePath, data, 0644))
+}
+
+// TestWorkflowManager_ReplayWorkflow tests rebuilding a workflow from the log
+func TestWorkflowManager_ReplayWorkflow(t *testing.T) {
+	// Test case: Replaying every recorded change rebuilds exactly the stored
+	// workflow, and an unfinished line left by a crash is skipped
+	manager := newTestWorkflowManager(t)
+	workflow := advanceTestWorkflow(t, manager, 7)
+
+	result, err := manager.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+	assert.Equal(t, 6, result.Records)
+	assert.Equal(t, types.WorkflowStatePROpen, result.Workflow.State)
+	assert.Equal(t, "true", result.Workflow.Metadata[MetadataDraftPR])
+	assert.Nil(t, result.Workflow.NextRetryAt)
+
+	file, err := os.OpenFile(manager.logFilePath, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"sequence":7,"type":"upd`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	other := openSecondManager(t, manager)
+	transitionTestWorkflow(t, other, workflow, types.WorkflowStateMerged)
+
+	result, err = other.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+	assert.Equal(t, 1, result.SkippedLines)
+	assert.Equal(t, types.WorkflowStateMerged, result.Workflow.State)
+
+	_, err = manager.ReplayWorkflow("99", false)
+	assert.Error(t, err)
+}
+
+// TestWorkflowManager_ReplayWorkflow_Repair tests restoring a stored workflow that drifted
+func TestWorkflowManager_ReplayWorkflow_Repair(t *testing.T) {
+	// Test case: A stored workflow that no longer matches its log is reported,
+	// and only replaced with the replayed one when repairing
+	manager := newTestWorkflowManager(t)
+	advanceTestWorkflow(t, manager, 7)
+	rewriteStoredWorkflow(t, manager, func(workflow *types.Workflow) {
+		workflow.State = types.WorkflowStateQueued
+		workflow.BranchName = ""
+	})
+
+	result, err := manager.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"branch_name", "state"}, result.Differences)
+	assert.False(t, result.Repaired)
+
+	result, err = manager.ReplayWorkflow("1", true)
+	require.NoError(t, err)
+	assert.True(t, result.Repaired)
+
+	reopened := openSecondManager(t, manager)
+	workflow, err := reopened.GetWorkflow("1")
+	require.NoError(t, err)
+	assert.Equal(t, types.WorkflowStatePROpen, workflow.State)
+	assert.Equal(t, "task-7", workflow.BranchName)
+
+	result, err = reopened.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+}
+
+// TestWorkflowManager_Snapshots tests periodic snapshots and recovering a corrupted one
+func TestWorkflowManager_Snapshots(t *testing.T) {
+	// Test case: A snapshot is taken every SnapshotInterval records and replay
+	// starts from it; a corrupted snapshot is rebuilt from the whole log
+	manager := newTestWorkflowManager(t)
+	workflow := createTestWorkflow(t, manager, 7)
+	for i := 1; i < SnapshotInterval+2; i++ {
+		_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, ErrorCount: &i})
+		require.NoError(t, err)
+	}
+
+	data, err := os.ReadFile(manager.snapshotFilePath)
+	require.NoError(t, err)
+	var snapshot WorkflowSnapshot
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	assert.Equal(t, int64(SnapshotInterval), snapshot.Sequence)
+	require.Len(t, snapshot.Workflows, 1)
+	assert.Equal(t, SnapshotInterval-1, snapshot.Workflows[0].ErrorCount)
+
+	result, err := manager.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+	assert.Equal(t, int64(SnapshotInterval), result.SnapshotSequence)
+	assert.Equal(t, 2, result.Records)
+
+	require.NoError(t, os.WriteFile(manager.snapshotFilePath, []byte(`{"sequence": 50, "workfl`), 0644))
+
+	result, err = manager.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.SnapshotRecovered)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+	assert.Equal(t, SnapshotInterval+2, result.Records)
+
+	data, err = os.ReadFile(manager.snapshotFilePath)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &snapshot))
+	assert.Equal(t, int64(SnapshotInterval+2), snapshot.Sequence)
+}
+
+// TestNewWorkflowManager_RecoversWorkflows tests rebuilding a corrupted workflows file
+func TestNewWorkflowManager_RecoversWorkflows(t *testing.T) {
+	// Test case: A workflows file left unreadable by a crash mid-save is
+	// rebuilt from the log when the manager opens
+	manager := newTestWorkflowManager(t)
+	advanceTestWorkflow(t, manager, 7)
+	createTestWorkflow(t, manager, 8)
+	require.NoError(t, manager.DeleteWorkflow("2"))
+	require.NoError(t, os.WriteFile(manager.workflowsFilePath, []byte(`[{"id": "1", "sta`), 0644))
+
+	reopened := openSecondManager(t, manager)
+	workflows, err := reopened.ListWorkflows()
+	require.NoError(t, err)
+	require.Len(t, workflows, 1)
+	assert.Equal(t, types.WorkflowStatePROpen, workflows[0].State)
+
+	stored, err := readWorkflowsFile(manager.workflowsFilePath)
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+}
+
+// TestNewWorkflowManager_LoadsFromLog tests that the log, not the workflows
+// file, is the source of workflow state
+func TestNewWorkflowManager_LoadsFromLog(t *testing.T) {
+	// Test case: A workflows file that drifted from the log is ignored and
+	// rewritten when the manager opens, and a workflow only found in the
+	// file is not loaded
+	manager := newTestWorkflowManager(t)
+	advanceTestWorkflow(t, manager, 7)
+	rewriteStoredWorkflow(t, manager, func(workflow *types.Workflow) {
+		workflow.State = types.WorkflowStateQueued
+		workflow.BranchName = ""
+	})
+	stored, err := readWorkflowsFile(manager.workflowsFilePath)
+	require.NoError(t, err)
+	stray := *stored["1"]
+	stray.ID, stray.IssueID = 2, 8
+	data, err := json.Marshal([]*types.Workflow{stored["1"], &stray})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manager.workflowsFilePath, data, 0644))
+
+	reopened := openSecondManager(t, manager)
+	workflows, err := reopened.ListWorkflows()
+	require.NoError(t, err)
+	require.Len(t, workflows, 1)
+	assert.Equal(t, types.WorkflowStatePROpen, workflows[0].State)
+	assert.Equal(t, "task-7", workflows[0].BranchName)
+
+	stored, err = readWorkflowsFile(manager.workflowsFilePath)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, types.WorkflowStatePROpen, stored["1"].State)
+}
+
+// TestNewWorkflowManager_SeedsSnapshot tests starting the log for existing workflows
+func TestNewWorkflowManager_SeedsSnapshot(t *testing.T) {
+	// Test case: Workflows stored before the log existed are snapshotted, so
+	// later changes to them replay from there
+	manager := newTestWorkflowManager(t)
+	createTestWorkflow(t, manager, 7)
+	require.NoError(t, os.Remove(manager.logFilePath))
+
+	legacy := openSecondManager(t, manager)
+	assert.FileExists(t, legacy.snapshotFilePath)
+
+	workflow, err := legacy.GetWorkflow("1")
+	require.NoError(t, err)
+	transitionTestWorkflow(t, legacy, workflow, types.WorkflowStateWorkspaceReady)
+
+	result, err := legacy.ReplayWorkflow("1", false)
+	require.NoError(t, err)
+	assert.True(t, result.Matches(), "differences: %v", result.Differences)
+	assert.Equal(t, int64(0), result.SnapshotSequence)
+	assert.Equal(t, 1, result.Records)
+}
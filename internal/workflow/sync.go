@@ -0,0 +1,342 @@
+package workflow
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/agent"
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// Sync strategies
+const (
+	SyncStrategyRebase = "rebase"
+	SyncStrategyMerge  = "merge"
+)
+
+// maxConflictRounds caps how many conflicted commits a single rebase may
+// hand to the agent
+const maxConflictRounds = 20
+
+// syncWithBaseBranch brings the feature branch up to date with the base branch
+// using the workflow's sync strategy. Conflicts are handed to the agent and
+// verification is re-run on the result. Any failure leaves the branch as it was.
+func (e *Engine) syncWithBaseBranch(ctx context.Context, workflow *types.Workflow, workspacePath string) error {
+	// A crashed earlier attempt may have left a rebase or merge behind
+	if err := abortSync(workspacePath); err != nil {
+		return err
+	}
+
+	// Restoring the branch on failure discards working tree changes
+	status, err := gitOutput(workspacePath, "status", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("failed to check workspace status: %w", err)
+	}
+	if status != "" {
+		return fmt.Errorf("workspace has uncommitted changes, refusing to sync")
+	}
+
+	if err := runGit(workspacePath, "fetch", "origin"); err != nil {
+		return fmt.Errorf("failed to fetch origin: %w", err)
+	}
+
+	before, err := gitOutput(workspacePath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	strategy := e.syncStrategy(workflow, workspacePath)
+	base := "origin/" + workflow.BaseBranch
+
+	resolved, err := e.runSync(ctx, workflow, workspacePath, strategy, base)
+	if err == nil && len(resolved) > 0 {
+		log.Printf("🧩 Agent resolved conflicts in %s for workflow %d", strings.Join(resolved, ", "), workflow.ID)
+		err = runVerification(ctx, workspacePath, workflow.Config.VerifyCommands)
+	}
+	if err != nil {
+		if restoreErr := restoreBranch(workspacePath, before); restoreErr != nil {
+			log.Printf("⚠️  Failed to restore workspace for workflow %d: %v", workflow.ID, restoreErr)
+		}
+		return err
+	}
+
+	after, err := gitOutput(workspacePath, "rev-parse", "HEAD")
+	if err != nil {
+		return fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+	if after == before {
+		return nil
+	}
+
+	log.Printf("🔀 Synced workflow %d with %s using %s", workflow.ID, base, strategy)
+
+	// Publish the synced branch so the PR reflects it
+	if workflow.BranchName == "" {
+		return nil
+	}
+	return e.pushSyncedBranch(ctx, workflow, workspacePath, strategy)
+}
+
+// syncStrategy returns the strategy to sync with. A rebase rewrites history,
+// so once the branch is published it needs a force push; when force pushes
+// are disabled a merge is used instead.
+func (e *Engine) syncStrategy(workflow *types.Workflow, workspacePath string) string {
+	if workflow.Config.SyncStrategy == SyncStrategyMerge {
+		return SyncStrategyMerge
+	}
+
+	if workflow.Config.ForcePushDisabled && workflow.BranchName != "" && remoteBranchExists(workspacePath, workflow.BranchName) {
+		log.Printf("⚠️  Rebasing published branch %s would need a force push, which is disabled; merging instead", workflow.BranchName)
+		return SyncStrategyMerge
+	}
+
+	return SyncStrategyRebase
+}
+
+// runSync rebases onto or merges the base branch, having the agent resolve
+// conflicts along the way, and returns the files it resolved
+func (e *Engine) runSync(ctx context.Context, workflow *types.Workflow, workspacePath, strategy, base string) ([]string, error) {
+	var resolved []string
+
+	if strategy == SyncStrategyMerge {
+		if err := runGit(workspacePath, "merge", "--no-edit", base); err == nil {
+			return nil, nil
+		}
+
+		files, err := e.resolveConflicts(ctx, workflow, workspacePath, base)
+		if err != nil {
+			return nil, err
+		}
+		if err := runGit(workspacePath, "commit", "--no-edit"); err != nil {
+			return nil, fmt.Errorf("failed to commit merge: %w", err)
+		}
+		return files, nil
+	}
+
+	err := runGit(workspacePath, "rebase", base)
+	for round := 0; err != nil; round++ {
+		if round >= maxConflictRounds {
+			return nil, fmt.Errorf("rebase onto %s still conflicting after %d rounds", base, maxConflictRounds)
+		}
+
+		files, resolveErr := e.resolveConflicts(ctx, workflow, workspacePath, base)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+		resolved = append(resolved, files...)
+
+		err = continueRebase(workspacePath)
+	}
+
+	return resolved, nil
+}
+
+// resolveConflicts hands the conflicted files to the agent and stages them
+// once every conflict marker is gone
+func (e *Engine) resolveConflicts(ctx context.Context, workflow *types.Workflow, workspacePath, base string) ([]string, error) {
+	files, err := conflictedFiles(workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("failed to sync with %s and no conflicted files were found", base)
+	}
+
+	if e.agentRunner == nil {
+		return nil, fmt.Errorf("sync with %s conflicts in %s and no agent is configured to resolve them", base, strings.Join(files, ", "))
+	}
+
+	log.Printf("🧩 Asking agent to resolve conflicts in %s for workflow %d", strings.Join(files, ", "), workflow.ID)
+
+	instruction := &agent.AgentInstruction{
+		Content: buildConflictPrompt(workflow, base, files),
+		TaskID:  workflow.TaskID,
+		Metadata: map[string]string{
+			"purpose": "resolve_conflicts",
+		},
+		CreatedAt: time.Now(),
+	}
+	if _, err := e.runAgent(ctx, e.agentRunner, workspacePath, instruction); err != nil {
+		return nil, fmt.Errorf("agent failed to resolve conflicts: %w", err)
+	}
+
+	var unresolved []string
+	for _, file := range files {
+		marked, err := hasConflictMarkers(filepath.Join(workspacePath, file))
+		if err != nil {
+			return nil, err
+		}
+		if marked {
+			unresolved = append(unresolved, file)
+		}
+	}
+	if len(unresolved) > 0 {
+		return nil, fmt.Errorf("agent left conflict markers in %s", strings.Join(unresolved, ", "))
+	}
+
+	if err := runGit(workspacePath, append([]string{"add", "--"}, files...)...); err != nil {
+		return nil, fmt.Errorf("failed to stage resolved files: %w", err)
+	}
+
+	return files, nil
+}
+
+// buildConflictPrompt builds the instruction asking the agent to resolve conflicts
+func buildConflictPrompt(workflow *types.Workflow, base string, files []string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "Syncing branch %s with %s produced merge conflicts.\n", workflow.BranchName, base)
+	sb.WriteString("Resolve the conflicts in the files below so that both the changes on this branch and the changes from the base branch are kept.\n")
+	sb.WriteString("Remove every conflict marker (<<<<<<<, =======, >>>>>>>). Do not commit, stage or touch any other files.\n\n")
+
+	sb.WriteString("## Conflicted files\n\n")
+	for _, file := range files {
+		fmt.Fprintf(&sb, "- %s\n", file)
+	}
+
+	return sb.String()
+}
+
+// runVerification runs the configured verification commands in the workspace
+func runVerification(ctx context.Context, workspacePath string, commands []string) error {
+	for _, command := range commands {
+		log.Printf("🧪 Verifying: %s", command)
+
+		cmd := exec.CommandContext(ctx, "sh", "-c", command)
+		cmd.Dir = workspacePath
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("verification %q failed: %w, output: %s", command, err, strings.TrimSpace(string(output)))
+		}
+	}
+
+	return nil
+}
+
+// pushSyncedBranch pushes the synced branch, refusing a force push when the
+// workflow disables them
+func (e *Engine) pushSyncedBranch(ctx context.Context, workflow *types.Workflow, workspacePath, strategy string) error {
+	if strategy != SyncStrategyRebase || !remoteBranchExists(workspacePath, workflow.BranchName) {
+		return e.pushBranch(ctx, workspacePath, workflow.BranchName)
+	}
+
+	if workflow.Config.ForcePushDisabled {
+		return fmt.Errorf("refusing to force push %s: force push is disabled", workflow.BranchName)
+	}
+
+	return e.forcePushBranch(ctx, workspacePath, workflow.BranchName)
+}
+
+// abortSync aborts a rebase or merge that is in progress in the workspace
+func abortSync(workspacePath string) error {
+	for _, dir := range []string{"rebase-merge", "rebase-apply"} {
+		path, err := gitOutput(workspacePath, "rev-parse", "--git-path", dir)
+		if err != nil {
+			return fmt.Errorf("failed to locate %s: %w", dir, err)
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workspacePath, path)
+		}
+		if _, err := os.Stat(path); err == nil {
+			log.Printf("⚠️  Aborting unfinished rebase in %s", workspacePath)
+			if err := runGit(workspacePath, "rebase", "--abort"); err != nil {
+				return fmt.Errorf("failed to abort rebase: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if _, err := gitOutput(workspacePath, "rev-parse", "-q", "--verify", "MERGE_HEAD"); err == nil {
+		log.Printf("⚠️  Aborting unfinished merge in %s", workspacePath)
+		if err := runGit(workspacePath, "merge", "--abort"); err != nil {
+			return fmt.Errorf("failed to abort merge: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// restoreBranch aborts any sync in progress and resets the branch to the given commit
+func restoreBranch(workspacePath, commit string) error {
+	if err := abortSync(workspacePath); err != nil {
+		return err
+	}
+
+	if err := runGit(workspacePath, "reset", "--hard", commit); err != nil {
+		return fmt.Errorf("failed to reset to %s: %w", commit, err)
+	}
+
+	return nil
+}
+
+// continueRebase continues a rebase without opening an editor
+func continueRebase(workspacePath string) error {
+	cmd := exec.Command("git", "rebase", "--continue")
+	cmd.Dir = workspacePath
+	cmd.Env = append(os.Environ(), "GIT_EDITOR=true")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// conflictedFiles lists the files with unresolved conflicts
+func conflictedFiles(workspacePath string) ([]string, error) {
+	output, err := gitOutput(workspacePath, "diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conflicted files: %w", err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// hasConflictMarkers reports whether a file still contains conflict markers
+func hasConflictMarkers(path string) (bool, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		// Resolved by deleting the file
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// remoteBranchExists reports whether origin has the branch as of the last fetch
+func remoteBranchExists(workspacePath, branchName string) bool {
+	_, err := gitOutput(workspacePath, "rev-parse", "-q", "--verify", "refs/remotes/origin/"+branchName)
+	return err == nil
+}
+
+// gitOutput runs a git command in the given directory and returns its trimmed output
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
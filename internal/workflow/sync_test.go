@@ -0,0 +1,226 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// gitTest runs a git command for a test and returns its trimmed output
+func gitTest(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, "git %s: %s", strings.Join(args, " "), output)
+	return strings.TrimSpace(string(output))
+}
+
+// commitFile writes a file and commits it
+func commitFile(t *testing.T, dir, name, content, message string) {
+	t.Helper()
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	gitTest(t, dir, "add", name)
+	gitTest(t, dir, "commit", "-q", "-m", message)
+}
+
+// newConflictingWorkspace creates an origin repository and a workspace whose
+// published feature branch conflicts with a later change on main
+func newConflictingWorkspace(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv("GIT_AUTHOR_NAME", "Test")
+	t.Setenv("GIT_AUTHOR_EMAIL", "test@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Test")
+	t.Setenv("GIT_COMMITTER_EMAIL", "test@example.com")
+
+	root := t.TempDir()
+	origin := filepath.Join(root, "origin.git")
+	seed := filepath.Join(root, "seed")
+	workspace := filepath.Join(root, "workspace")
+
+	gitTest(t, root, "init", "-q", "--bare", "-b", "main", origin)
+	gitTest(t, root, "clone", "-q", origin, seed)
+	gitTest(t, seed, "checkout", "-q", "-b", "main")
+	commitFile(t, seed, "file.txt", "original\n", "initial")
+	gitTest(t, seed, "push", "-q", "origin", "main")
+
+	gitTest(t, root, "clone", "-q", origin, workspace)
+	gitTest(t, workspace, "checkout", "-q", "-b", "feature")
+	commitFile(t, workspace, "file.txt", "feature\n", "feature change")
+	gitTest(t, workspace, "push", "-q", "-u", "origin", "feature")
+
+	commitFile(t, seed, "file.txt", "base\n", "base change")
+	gitTest(t, seed, "push", "-q", "origin", "main")
+
+	return workspace
+}
+
+// newSyncWorkflow builds a revising workflow for the feature branch
+func newSyncWorkflow(config func(*types.WorkflowConfig)) *types.Workflow {
+	workflow := &types.Workflow{
+		ID:         1,
+		TaskID:     2,
+		BaseBranch: "main",
+		BranchName: "feature",
+		Config:     types.GetDefaultWorkflowConfig(),
+	}
+	if config != nil {
+		config(&workflow.Config)
+	}
+	return workflow
+}
+
+// resolveFile is an agent edit that resolves the conflict in file.txt
+func resolveFile(workspacePath string) {
+	os.WriteFile(filepath.Join(workspacePath, "file.txt"), []byte("feature\nbase\n"), 0644)
+}
+
+// TestEngine_SyncWithBaseBranch_MergeResolvesConflicts tests agent-resolved merge conflicts
+func TestEngine_SyncWithBaseBranch_MergeResolvesConflicts(t *testing.T) {
+	// Test case: With the merge strategy the conflicted file goes to the agent,
+	// verification runs on the result and the merge is pushed
+	workspace := newConflictingWorkspace(t)
+	workflow := newSyncWorkflow(func(c *types.WorkflowConfig) {
+		c.SyncStrategy = SyncStrategyMerge
+		c.VerifyCommands = []string{"grep -q base file.txt"}
+	})
+
+	runner := &fakeAgentRunner{edit: resolveFile}
+	engine := NewEngine(nil, nil, nil, nil, "owner", "repo")
+	engine.SetAgentRunner(runner)
+
+	require.NoError(t, engine.syncWithBaseBranch(context.Background(), workflow, workspace))
+
+	require.Len(t, runner.instructions, 1)
+	assert.Contains(t, runner.instructions[0].Content, "- file.txt")
+
+	content, err := os.ReadFile(filepath.Join(workspace, "file.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "feature\nbase\n", string(content))
+
+	parents := strings.Fields(gitTest(t, workspace, "rev-list", "--parents", "-n", "1", "HEAD"))
+	assert.Len(t, parents, 3, "expected a merge commit")
+
+	gitTest(t, workspace, "fetch", "-q", "origin")
+	assert.Equal(t, gitTest(t, workspace, "rev-parse", "HEAD"), gitTest(t, workspace, "rev-parse", "origin/feature"))
+}
+
+// TestEngine_SyncWithBaseBranch_Strategy tests how the sync strategy and force push setting interact
+func TestEngine_SyncWithBaseBranch_Strategy(t *testing.T) {
+	// Test case: A rebase of the published branch is force pushed only when
+	// force pushes are allowed; otherwise the branch is merged instead
+	testCases := []struct {
+		name        string
+		forceDenied bool
+		wantParents int
+	}{
+		{"rebase with force push allowed", false, 2},
+		{"rebase falls back to merge when force push is disabled", true, 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workspace := newConflictingWorkspace(t)
+			workflow := newSyncWorkflow(func(c *types.WorkflowConfig) {
+				c.SyncStrategy = SyncStrategyRebase
+				c.ForcePushDisabled = tc.forceDenied
+			})
+
+			engine := NewEngine(nil, nil, nil, nil, "owner", "repo")
+			engine.SetAgentRunner(&fakeAgentRunner{edit: resolveFile})
+
+			require.NoError(t, engine.syncWithBaseBranch(context.Background(), workflow, workspace))
+
+			parents := strings.Fields(gitTest(t, workspace, "rev-list", "--parents", "-n", "1", "HEAD"))
+			assert.Len(t, parents, tc.wantParents)
+
+			gitTest(t, workspace, "fetch", "-q", "origin")
+			assert.Equal(t, gitTest(t, workspace, "rev-parse", "HEAD"), gitTest(t, workspace, "rev-parse", "origin/feature"))
+		})
+	}
+}
+
+// TestEngine_SyncWithBaseBranch_RestoresOnFailure tests that failed syncs leave the branch untouched
+func TestEngine_SyncWithBaseBranch_RestoresOnFailure(t *testing.T) {
+	// Test case: Unresolved markers, failing verification, agent errors and a
+	// missing agent all abort the sync and reset the branch
+	testCases := []struct {
+		name     string
+		strategy string
+		runner   *fakeAgentRunner
+		verify   []string
+		errMsg   string
+	}{
+		{"markers left behind", SyncStrategyMerge, &fakeAgentRunner{}, nil, "conflict markers"},
+		{"verification fails", SyncStrategyMerge, &fakeAgentRunner{edit: resolveFile}, []string{"false"}, "verification"},
+		{"agent error during rebase", SyncStrategyRebase, &fakeAgentRunner{err: errors.New("agent crashed")}, nil, "agent crashed"},
+		{"no agent configured", SyncStrategyRebase, nil, nil, "no agent is configured"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			workspace := newConflictingWorkspace(t)
+			before := gitTest(t, workspace, "rev-parse", "HEAD")
+			workflow := newSyncWorkflow(func(c *types.WorkflowConfig) {
+				c.SyncStrategy = tc.strategy
+				c.ForcePushDisabled = false
+				c.VerifyCommands = tc.verify
+			})
+
+			engine := NewEngine(nil, nil, nil, nil, "owner", "repo")
+			if tc.runner != nil {
+				engine.SetAgentRunner(tc.runner)
+			}
+
+			err := engine.syncWithBaseBranch(context.Background(), workflow, workspace)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.errMsg)
+
+			assert.Equal(t, before, gitTest(t, workspace, "rev-parse", "HEAD"))
+			assert.Empty(t, gitTest(t, workspace, "status", "--porcelain"))
+			assert.NoDirExists(t, filepath.Join(workspace, ".git", "rebase-merge"))
+			assert.NoFileExists(t, filepath.Join(workspace, ".git", "MERGE_HEAD"))
+		})
+	}
+}
+
+// TestEngine_SyncWithBaseBranch_AbortsUnfinishedRebase tests recovery from an interrupted sync
+func TestEngine_SyncWithBaseBranch_AbortsUnfinishedRebase(t *testing.T) {
+	// Test case: A rebase left behind by an earlier attempt is aborted before syncing again
+	workspace := newConflictingWorkspace(t)
+	gitTest(t, workspace, "fetch", "-q", "origin")
+	require.Error(t, exec.Command("git", "-C", workspace, "rebase", "origin/main").Run())
+	require.DirExists(t, filepath.Join(workspace, ".git", "rebase-merge"))
+
+	workflow := newSyncWorkflow(func(c *types.WorkflowConfig) { c.SyncStrategy = SyncStrategyMerge })
+	engine := NewEngine(nil, nil, nil, nil, "owner", "repo")
+	engine.SetAgentRunner(&fakeAgentRunner{edit: resolveFile})
+
+	require.NoError(t, engine.syncWithBaseBranch(context.Background(), workflow, workspace))
+	assert.NoDirExists(t, filepath.Join(workspace, ".git", "rebase-merge"))
+	assert.Equal(t, "feature", gitTest(t, workspace, "rev-parse", "--abbrev-ref", "HEAD"))
+}
+
+// TestEngine_PushSyncedBranch_RefusesForcePush tests the force push guard
+func TestEngine_PushSyncedBranch_RefusesForcePush(t *testing.T) {
+	// Test case: Pushing a rebased, published branch fails when force pushes are disabled
+	workspace := newConflictingWorkspace(t)
+	workflow := newSyncWorkflow(nil)
+	engine := NewEngine(nil, nil, nil, nil, "owner", "repo")
+
+	err := engine.pushSyncedBranch(context.Background(), workflow, workspace, SyncStrategyRebase)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "force push is disabled")
+}
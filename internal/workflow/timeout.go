@@ -0,0 +1,86 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// HandleJobTimeout aborts a workflow whose agent has been implementing or
+// revising for longer than the job timeout: the agent container is stopped,
+// the task is marked failed, the issue is told why and the workflow fails so
+// it can be retried
+func (e *Engine) HandleJobTimeout(ctx context.Context, workflow *types.Workflow) error {
+	if workflow.Owner != e.owner || workflow.Repo != e.repo {
+		return nil
+	}
+
+	workflowID := fmt.Sprintf("%d", workflow.ID)
+	ctx = withWorkflowID(ctx, workflowID)
+
+	_, release, err := e.lockWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to acquire workflow lock: %w", err)
+	}
+	defer release()
+
+	// The workflow may have moved on since the watchdog looked at it
+	workflow, err = e.workflowManager.GetWorkflow(workflowID)
+	if err != nil {
+		return fmt.Errorf("failed to get workflow: %w", err)
+	}
+	if !workflow.JobTimedOut(time.Now()) {
+		return nil
+	}
+
+	reason := fmt.Sprintf("%s phase exceeded the job timeout of %s", workflow.State, workflow.Config.JobTimeout)
+	log.Printf("⏰ Workflow %d: %s, aborting", workflow.ID, reason)
+
+	// A dry run leaves the agent, the task and the issue alone
+	if e.dryRun != nil {
+		e.dryRun.Record(ctx, MutationJobTimeout, RepositoryKey(e.owner, e.repo), "abort workflow %d: %s", workflow.ID, reason)
+		return nil
+	}
+
+	e.stopAgent(ctx, workflow)
+
+	if workflow.TaskID != 0 {
+		status := types.TaskStatusFailed
+		_, err := e.taskManager.UpdateTask(&types.UpdateTaskRequest{
+			TaskID:       workflow.TaskID,
+			Status:       &status,
+			ErrorMessage: &reason,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to mark task as failed: %w", err)
+		}
+	}
+
+	// Retrying an implementation restarts the agent from a ready workspace,
+	// and a retried revision is handed to the agent again
+	failedState := workflow.State
+	if failedState == types.WorkflowStateImplementing {
+		failedState = types.WorkflowStateWorkspaceReady
+	}
+
+	state := types.WorkflowStateFailed
+	_, err = e.workflowManager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID:  workflow.ID,
+		State:       &state,
+		LastError:   &reason,
+		NextRetryAt: &time.Time{},
+		Metadata:    map[string]string{MetadataFailedState: string(failedState), MetadataRevisionStartedAt: ""},
+		Actor:       e.processID,
+		Reason:      "job timeout",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to transition workflow to failed: %w", err)
+	}
+
+	e.postComment(ctx, workflow.IssueID, fmt.Sprintf("⏰ The %s, so cowork stopped the agent and marked this task as failed. Retry it with `cw workflow retry %d`.", reason, workflow.ID))
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hlfshell/cowork/internal/types"
+)
+
+// newImplementingWorkflow creates a workflow whose agent started implementing
+// the given duration ago
+func newImplementingWorkflow(t *testing.T, manager *WorkflowManager, running time.Duration) *types.Workflow {
+	t.Helper()
+
+	workflow := createTestWorkflow(t, manager, 7)
+	taskID, workspaceID := 3, 5
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{WorkflowID: workflow.ID, TaskID: &taskID, WorkspaceID: &workspaceID})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing)
+
+	enteredAt := time.Now().Add(-running)
+	workflow.StateChangedAt = &enteredAt
+	storeTestWorkflow(t, manager, workflow)
+	return workflow
+}
+
+// newRevisingWorkflow creates a workflow on branch task-7 of the returned
+// origin and workspace repositories that has been asked to revise its PR
+func newRevisingWorkflow(t *testing.T, manager *WorkflowManager) (*types.Workflow, string, string) {
+	t.Helper()
+
+	origin, workspacePath := newDraftWorkspace(t)
+	workflow := newImplementingWorkflow(t, manager, 0)
+	branch := "task-7"
+	_, err := manager.UpdateWorkflow(&types.UpdateWorkflowRequest{
+		WorkflowID: workflow.ID,
+		BranchName: &branch,
+		Metadata:   map[string]string{MetadataRevisionInstruction: "Rename the widget"},
+	})
+	require.NoError(t, err)
+	transitionTestWorkflow(t, manager, workflow, types.WorkflowStatePROpen, types.WorkflowStateRevising)
+
+	return workflow, origin, workspacePath
+}
+
+// TestWorkflowManager_TimedOutWorkflows tests finding workflows past their job timeout
+func TestWorkflowManager_TimedOutWorkflows(t *testing.T) {
+	// Test case: Only the workflow implementing for longer than the job
+	// timeout is returned and handed to the watchdog's handler
+	manager := newTestWorkflowManager(t)
+	stuck := newImplementingWorkflow(t, manager, 3*time.Hour)
+
+	healthy := createTestWorkflow(t, manager, 8)
+	transitionTestWorkflow(t, manager, healthy, types.WorkflowStateWorkspaceReady, types.WorkflowStateImplementing)
+	require.NotNil(t, healthy.StateChangedAt)
+	assert.WithinDuration(t, time.Now(), *healthy.StateChangedAt, time.Second)
+
+	timedOut := manager.TimedOutWorkflows(time.Now())
+	require.Len(t, timedOut, 1)
+	assert.Equal(t, stuck.ID, timedOut[0].ID)
+
+	var handled []int
+	manager.SetJobTimeoutHandler(func(workflow *types.Workflow) error {
+		handled = append(handled, workflow.ID)
+		return nil
+	})
+	manager.checkJobTimeouts()
+	assert.Equal(t, []int{stuck.ID}, handled)
+}
+
+// TestEngine_HandleJobTimeout tests aborting an agent that ran past the job timeout
+func TestEngine_HandleJobTimeout(t *testing.T) {
+	// Test case: The container is stopped, the task and workflow fail with the
+	// timeout reason and the issue gets a comment; a retry restarts the agent
+	manager := newTestWorkflowManager(t)
+	workflow := newImplementingWorkflow(t, manager, 3*time.Hour)
+
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusInProgress})
+	workspaces := newFakeWorkspaceManager()
+	provider := newFakeCoworkProvider()
+	engine := NewEngine(manager, tasks, workspaces, provider, "owner", "repo")
+
+	require.NoError(t, engine.HandleJobTimeout(context.Background(), workflow))
+
+	assert.Equal(t, []int{5}, workspaces.stopped)
+
+	task := tasks.tasks[3]
+	assert.Equal(t, types.TaskStatusFailed, task.Status)
+	assert.Contains(t, task.ErrorMessage, "implementing phase exceeded the job timeout of 2h0m0s")
+
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateFailed, workflow.State)
+	assert.Equal(t, task.ErrorMessage, workflow.LastError)
+	assert.Equal(t, string(types.WorkflowStateWorkspaceReady), workflow.Metadata[MetadataFailedState])
+
+	require.Len(t, provider.comments[7], 1)
+	assert.Contains(t, provider.comments[7][0].Body, coworkCommentMarker)
+	assert.Contains(t, provider.comments[7][0].Body, "job timeout")
+
+	locked, _ := manager.IsWorkflowLocked(fmt.Sprintf("%d", workflow.ID))
+	assert.False(t, locked)
+}
+
+// TestEngine_HandleJobTimeout_Skips tests workflows the timeout handler leaves alone
+func TestEngine_HandleJobTimeout_Skips(t *testing.T) {
+	// Test case: Workflows that are within the timeout or belong to another
+	// repository are not touched
+	testCases := []struct {
+		name    string
+		running time.Duration
+		repo    string
+	}{
+		{"within the job timeout", time.Hour, "repo"},
+		{"another repository", 3 * time.Hour, "other"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := newTestWorkflowManager(t)
+			workflow := newImplementingWorkflow(t, manager, tc.running)
+
+			tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusInProgress})
+			workspaces := newFakeWorkspaceManager()
+			provider := newFakeCoworkProvider()
+			engine := NewEngine(manager, tasks, workspaces, provider, "owner", tc.repo)
+
+			require.NoError(t, engine.HandleJobTimeout(context.Background(), workflow))
+
+			assert.Empty(t, workspaces.stopped)
+			assert.Equal(t, types.TaskStatusInProgress, tasks.tasks[3].Status)
+			assert.Equal(t, types.WorkflowStateImplementing, workflow.State)
+			assert.Empty(t, provider.comments)
+		})
+	}
+}
+
+// TestEngine_HandleJobTimeout_Revision tests aborting an agent that revises for too long
+func TestEngine_HandleJobTimeout_Revision(t *testing.T) {
+	// Test case: The workflow stays in revising while the agent works, so a
+	// revision past the job timeout is aborted and a retry hands it over again
+	manager := newTestWorkflowManager(t)
+	workflow, _, workspacePath := newRevisingWorkflow(t, manager)
+
+	tasks := newFakeTaskManager(&types.Task{ID: 3, Status: types.TaskStatusCompleted})
+	workspaces := newFakeWorkspaceManager(&types.Workspace{ID: 5, Path: workspacePath})
+	engine := NewEngine(manager, tasks, workspaces, newFakeCoworkProvider(), "owner", "repo")
+
+	require.NoError(t, engine.processRevisingWorkflow(context.Background(), workflow))
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateRevising, workflow.State)
+	assert.NotEmpty(t, workflow.Metadata[MetadataRevisionStartedAt])
+	assert.Equal(t, types.TaskStatusInProgress, tasks.tasks[3].Status)
+
+	enteredAt := time.Now().Add(-3 * time.Hour)
+	workflow.StateChangedAt = &enteredAt
+	storeTestWorkflow(t, manager, workflow)
+	timedOut := manager.TimedOutWorkflows(time.Now())
+	require.Len(t, timedOut, 1)
+	assert.Equal(t, workflow.ID, timedOut[0].ID)
+
+	require.NoError(t, engine.HandleJobTimeout(context.Background(), workflow))
+
+	assert.Equal(t, []int{5}, workspaces.stopped)
+	assert.Equal(t, types.TaskStatusFailed, tasks.tasks[3].Status)
+	workflow = reloadTestWorkflow(t, manager, workflow)
+	assert.Equal(t, types.WorkflowStateFailed, workflow.State)
+	assert.Contains(t, workflow.LastError, "revising phase exceeded the job timeout of 2h0m0s")
+	assert.Equal(t, string(types.WorkflowStateRevising), workflow.Metadata[MetadataFailedState])
+	assert.Empty(t, workflow.Metadata[MetadataRevisionStartedAt])
+}
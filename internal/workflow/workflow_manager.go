@@ -3,6 +3,7 @@ package workflow
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -16,15 +17,46 @@ const (
 	// WorkflowsFileName is the name of the workflows file
 	WorkflowsFileName = "workflows.json"
 
-	// WorkflowEventsFileName is the name of the workflow events file
+	// WorkflowEventsFileName is the name of the events file written before
+	// the event log existed; it is still read when loading events
 	WorkflowEventsFileName = "workflow_events.json"
 
+	// WorkflowEventLogFileName is the name of the append-only log of events
+	// and their processing, one JSON record per line
+	WorkflowEventLogFileName = "workflow_event_log.jsonl"
+
 	// WorkflowLocksFileName is the name of the workflow locks file
 	WorkflowLocksFileName = "workflow_locks.json"
 
+	// WorkflowLogFileName is the name of the append-only log of workflow
+	// changes that workflow state is derived from, one JSON record per line
+	WorkflowLogFileName = "workflow_log.jsonl"
+
+	// WorkflowSnapshotFileName is the name of the periodic snapshot of the
+	// state derived from the workflow log
+	WorkflowSnapshotFileName = "workflow_snapshot.json"
+
+	// SnapshotInterval is the number of workflow log records between snapshots
+	SnapshotInterval = 50
+
+	// CreatedBranchesFileName is the name of the registry of branches cowork created
+	CreatedBranchesFileName = "created_branches.json"
+
+	// StateLockFileName is the name of the file cw processes hold an advisory
+	// lock on while reading and writing the state files
+	StateLockFileName = "state.lock"
+
 	// DefaultLockTimeout is the default timeout for workflow locks
 	DefaultLockTimeout = 30 * time.Minute
 
+	// DefaultLockLease is how long a workflow lock held by the engine lasts
+	// without a heartbeat
+	DefaultLockLease = 2 * time.Minute
+
+	// LockHeartbeatInterval is how often the engine renews the lease of a
+	// workflow lock it holds
+	LockHeartbeatInterval = 30 * time.Second
+
 	// WatchdogInterval is the interval for the watchdog timer
 	WatchdogInterval = 5 * time.Minute
 )
@@ -37,12 +69,31 @@ type WorkflowManager struct {
 	// Path to the workflows file
 	workflowsFilePath string
 
-	// Path to the workflow events file
-	eventsFilePath string
+	// Path to the workflow events file and the event log
+	eventsFilePath   string
+	eventLogFilePath string
+
+	// How far this process has read the event log
+	eventLogOffset int64
 
 	// Path to the workflow locks file
 	locksFilePath string
 
+	// Path to the workflow log and its snapshot
+	logFilePath      string
+	snapshotFilePath string
+
+	// How far this process has read the workflow log, and the last sequence
+	// number seen there
+	logOffset   int64
+	logSequence int64
+
+	// Path to the registry of branches cowork created
+	branchesFilePath string
+
+	// Path to the advisory lock file guarding the state files
+	stateLockFilePath string
+
 	// In-memory cache of workflows
 	workflows map[string]*types.Workflow
 
@@ -55,11 +106,23 @@ type WorkflowManager struct {
 	// Mutex for thread safety
 	mu sync.RWMutex
 
-	// Watchdog timer for cleaning up expired locks
+	// Watchdog timer for cleaning up expired locks and timed out jobs
 	watchdogTicker *time.Ticker
 	watchdogDone   chan bool
+
+	// Called by the watchdog for workflows exceeding their job timeout
+	jobTimeoutHandler JobTimeoutHandler
+
+	// Config snapshotted into workflows created without one
+	defaultConfig types.WorkflowConfig
+
+	// Configs of repositories with settings of their own, keyed by owner/repo
+	repositoryConfigs map[string]types.WorkflowConfig
 }
 
+// JobTimeoutHandler handles a workflow whose agent exceeded the job timeout
+type JobTimeoutHandler func(workflow *types.Workflow) error
+
 // NewWorkflowManager creates a new workflow manager
 func NewWorkflowManager(cwDir string) (*WorkflowManager, error) {
 	if cwDir == "" {
@@ -86,11 +149,18 @@ func NewWorkflowManager(cwDir string) (*WorkflowManager, error) {
 		cwDir:             cwDir,
 		workflowsFilePath: filepath.Join(cwDir, WorkflowsFileName),
 		eventsFilePath:    filepath.Join(cwDir, WorkflowEventsFileName),
+		eventLogFilePath:  filepath.Join(cwDir, WorkflowEventLogFileName),
 		locksFilePath:     filepath.Join(cwDir, WorkflowLocksFileName),
+		logFilePath:       filepath.Join(cwDir, WorkflowLogFileName),
+		snapshotFilePath:  filepath.Join(cwDir, WorkflowSnapshotFileName),
+		branchesFilePath:  filepath.Join(cwDir, CreatedBranchesFileName),
+		stateLockFilePath: filepath.Join(cwDir, StateLockFileName),
 		workflows:         make(map[string]*types.Workflow),
 		events:            make(map[string]*types.WorkflowEvent),
 		locks:             make(map[string]*types.WorkflowLock),
 		watchdogDone:      make(chan bool),
+		defaultConfig:     types.GetDefaultWorkflowConfig(),
+		repositoryConfigs: make(map[string]types.WorkflowConfig),
 	}
 
 	// Load existing workflows, events, and locks
@@ -98,6 +168,11 @@ func NewWorkflowManager(cwDir string) (*WorkflowManager, error) {
 		return nil, fmt.Errorf("failed to load workflows: %w", err)
 	}
 
+	// Workflows are loaded from the log from now on, so it must start with them
+	if err := manager.withStateLock(manager.seedSnapshotUnlocked); err != nil {
+		return nil, fmt.Errorf("failed to snapshot existing workflows: %w", err)
+	}
+
 	if err := manager.loadEvents(); err != nil {
 		return nil, fmt.Errorf("failed to load events: %w", err)
 	}
@@ -123,7 +198,49 @@ func (wm *WorkflowManager) Close() error {
 	return nil
 }
 
-// startWatchdog starts the watchdog timer for cleaning up expired locks
+// Reload re-reads workflows, events, and locks from disk so that changes made
+// by other cw processes become visible
+func (wm *WorkflowManager) Reload() error {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	// Load into a scratch manager first so a failed read leaves the cache intact
+	fresh := &WorkflowManager{
+		workflowsFilePath: wm.workflowsFilePath,
+		eventsFilePath:    wm.eventsFilePath,
+		eventLogFilePath:  wm.eventLogFilePath,
+		locksFilePath:     wm.locksFilePath,
+		logFilePath:       wm.logFilePath,
+		snapshotFilePath:  wm.snapshotFilePath,
+		stateLockFilePath: wm.stateLockFilePath,
+		workflows:         make(map[string]*types.Workflow),
+		events:            make(map[string]*types.WorkflowEvent),
+		locks:             make(map[string]*types.WorkflowLock),
+	}
+
+	if err := fresh.loadWorkflows(); err != nil {
+		return fmt.Errorf("failed to load workflows: %w", err)
+	}
+
+	if err := fresh.loadEvents(); err != nil {
+		return fmt.Errorf("failed to load events: %w", err)
+	}
+
+	if err := fresh.loadLocks(); err != nil {
+		return fmt.Errorf("failed to load locks: %w", err)
+	}
+
+	wm.workflows = fresh.workflows
+	wm.logOffset, wm.logSequence = fresh.logOffset, fresh.logSequence
+	wm.events = fresh.events
+	wm.eventLogOffset = fresh.eventLogOffset
+	wm.locks = fresh.locks
+
+	return nil
+}
+
+// startWatchdog starts the watchdog timer for cleaning up expired locks and
+// timed out jobs
 func (wm *WorkflowManager) startWatchdog() {
 	wm.watchdogTicker = time.NewTicker(WatchdogInterval)
 	go func() {
@@ -131,6 +248,7 @@ func (wm *WorkflowManager) startWatchdog() {
 			select {
 			case <-wm.watchdogTicker.C:
 				wm.cleanupExpiredLocks()
+				wm.checkJobTimeouts()
 			case <-wm.watchdogDone:
 				return
 			}
@@ -138,32 +256,85 @@ func (wm *WorkflowManager) startWatchdog() {
 	}()
 }
 
-// cleanupExpiredLocks removes expired locks
+// cleanupExpiredLocks removes expired locks and locks whose holder has exited
 func (wm *WorkflowManager) cleanupExpiredLocks() {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	var expiredLocks []string
-	for workflowID, lock := range wm.locks {
-		if lock.IsExpired() {
-			expiredLocks = append(expiredLocks, workflowID)
+	if err := wm.withStateLock(wm.cleanupStaleLocksUnlocked); err != nil {
+		log.Printf("⚠️  Failed to clean up stale locks: %v", err)
+	}
+}
+
+// SetJobTimeoutHandler sets the handler the watchdog calls for workflows
+// exceeding their job timeout
+func (wm *WorkflowManager) SetJobTimeoutHandler(handler JobTimeoutHandler) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.jobTimeoutHandler = handler
+}
+
+// TimedOutWorkflows returns the workflows whose implementing or revising
+// phase has run longer than their job timeout
+func (wm *WorkflowManager) TimedOutWorkflows(now time.Time) []*types.Workflow {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	var workflows []*types.Workflow
+	for _, workflow := range wm.workflows {
+		if workflow.JobTimedOut(now) {
+			workflows = append(workflows, workflow.Clone())
 		}
 	}
 
-	for _, workflowID := range expiredLocks {
-		delete(wm.locks, workflowID)
+	return workflows
+}
+
+// checkJobTimeouts hands workflows exceeding their job timeout to the handler
+func (wm *WorkflowManager) checkJobTimeouts() {
+	wm.mu.RLock()
+	handler := wm.jobTimeoutHandler
+	wm.mu.RUnlock()
+
+	if handler == nil {
+		return
 	}
 
-	if len(expiredLocks) > 0 {
-		wm.saveLocksUnlocked()
+	// The handler updates workflows, so it must run without the lock held
+	for _, workflow := range wm.TimedOutWorkflows(time.Now()) {
+		if err := handler(workflow); err != nil {
+			log.Printf("⚠️  Failed to handle job timeout for workflow %d: %v", workflow.ID, err)
+		}
 	}
 }
 
-// loadWorkflows loads all workflows from the workflows file
+// loadWorkflows loads workflows by replaying the workflow log on top of its
+// latest snapshot. The workflows file is only a cache of that state: it is
+// read for projects without a log yet, and rewritten when it is out of date.
 func (wm *WorkflowManager) loadWorkflows() error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
+	if !fileExists(wm.logFilePath) && !fileExists(wm.snapshotFilePath) {
+		return wm.loadWorkflowsFileUnlocked()
+	}
+
+	return wm.withStateLock(func() error {
+		state, err := wm.replayUnlocked()
+		if err != nil {
+			return err
+		}
+
+		wm.workflows = state.workflows
+		wm.logOffset, wm.logSequence = state.offset, state.sequence
+		return wm.refreshWorkflowsCacheUnlocked()
+	})
+}
+
+// loadWorkflowsFileUnlocked loads workflows from the workflows file of a
+// project that kept them before the workflow log existed
+func (wm *WorkflowManager) loadWorkflowsFileUnlocked() error {
 	// Check if workflows file exists
 	if _, err := os.Stat(wm.workflowsFilePath); os.IsNotExist(err) {
 		// File doesn't exist, start with empty workflows
@@ -229,74 +400,31 @@ func (wm *WorkflowManager) saveWorkflows() error {
 	return nil
 }
 
-// loadEvents loads all events from the events file
+// loadEvents loads the events from the events file and the event log
 func (wm *WorkflowManager) loadEvents() error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	// Check if events file exists
-	if _, err := os.Stat(wm.eventsFilePath); os.IsNotExist(err) {
-		// File doesn't exist, start with empty events
-		return nil
-	}
-
-	// Read the events file
+	// Events recorded before the event log existed
 	file, err := os.Open(wm.eventsFilePath)
-	if err != nil {
+	if err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to open events file: %w", err)
 	}
-	defer file.Close()
+	if err == nil {
+		defer file.Close()
 
-	// Decode the events from JSON
-	var events []*types.WorkflowEvent
-	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&events); err != nil {
-		return fmt.Errorf("failed to decode events: %w", err)
-	}
-
-	// Load events into memory
-	for _, event := range events {
-		wm.events[event.ID] = event
-	}
-
-	return nil
-}
-
-// saveEvents saves all events to the events file
-func (wm *WorkflowManager) saveEvents() error {
-	wm.mu.RLock()
-	defer wm.mu.RUnlock()
-
-	// Convert events map to slice
-	var events []*types.WorkflowEvent
-	for _, event := range wm.events {
-		events = append(events, event)
-	}
-
-	// Create temporary file
-	tempFile := wm.eventsFilePath + ".tmp"
-	file, err := os.Create(tempFile)
-	if err != nil {
-		return fmt.Errorf("failed to create temporary events file: %w", err)
-	}
-	defer file.Close()
-
-	// Encode events to JSON
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(events); err != nil {
-		return fmt.Errorf("failed to encode events: %w", err)
-	}
-
-	// Close file before renaming
-	file.Close()
+		var events []*types.WorkflowEvent
+		decoder := json.NewDecoder(file)
+		if err := decoder.Decode(&events); err != nil {
+			return fmt.Errorf("failed to decode events: %w", err)
+		}
 
-	// Rename temporary file to actual file
-	if err := os.Rename(tempFile, wm.eventsFilePath); err != nil {
-		return fmt.Errorf("failed to rename temporary events file: %w", err)
+		for _, event := range events {
+			wm.events[event.ID] = event
+		}
 	}
 
-	return nil
+	return wm.withStateLock(wm.syncEventsUnlocked)
 }
 
 // loadLocks loads all locks from the locks file
@@ -369,8 +497,37 @@ func (wm *WorkflowManager) saveLocks() error {
 	return nil
 }
 
-// CreateWorkflow creates a new workflow
+// SetDefaultConfig sets the config snapshotted into workflows created without
+// one, typically the repository's workflow configuration
+func (wm *WorkflowManager) SetDefaultConfig(config types.WorkflowConfig) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.defaultConfig = config.Clone()
+}
+
+// SetRepositoryConfig sets the config snapshotted into workflows of one
+// repository created without one, in place of the default config
+func (wm *WorkflowManager) SetRepositoryConfig(owner, repo string, config types.WorkflowConfig) {
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	wm.repositoryConfigs[RepositoryKey(owner, repo)] = config.Clone()
+}
+
+// CreateWorkflow creates a new workflow. The workflow keeps a snapshot of its
+// config, so later configuration changes do not affect it.
 func (wm *WorkflowManager) CreateWorkflow(req *types.CreateWorkflowRequest) (*types.Workflow, error) {
+	if req.Config.IsZero() {
+		wm.mu.RLock()
+		if config, ok := wm.repositoryConfigs[RepositoryKey(req.Owner, req.Repo)]; ok {
+			req.Config = config
+		} else {
+			req.Config = wm.defaultConfig
+		}
+		wm.mu.RUnlock()
+	}
+
 	if err := req.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid create workflow request: %w", err)
 	}
@@ -378,54 +535,63 @@ func (wm *WorkflowManager) CreateWorkflow(req *types.CreateWorkflowRequest) (*ty
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	// Check if workflow already exists for this issue
-	for _, workflow := range wm.workflows {
-		if workflow.Owner == req.Owner && workflow.Repo == req.Repo && workflow.IssueID == req.IssueID {
-			return workflow, nil // Workflow already exists, return existing workflow
+	var workflow *types.Workflow
+	err := wm.withStateLock(func() error {
+		if err := wm.syncWorkflowsUnlocked(); err != nil {
+			return err
 		}
-	}
-
-	// Generate unique ID
-	workflowID := types.GenerateWorkflowID()
 
-	// Create the workflow
-	now := time.Now()
-	workflow := &types.Workflow{
-		ID:          workflowID,
-		Owner:       req.Owner,
-		Repo:        req.Repo,
-		IssueID:     req.IssueID,
-		BaseBranch:  req.BaseBranch,
-		State:       types.WorkflowStateQueued,
-		Provider:    req.Provider,
-		Config:      req.Config,
-		LastEventTS: now,
-		CreatedAt:   now,
-		UpdatedAt:   now,
-		ErrorCount:  0,
-		Metadata:    make(map[string]string),
-		LockTimeout: now.Add(DefaultLockTimeout),
-	}
+		// Check if workflow already exists for this issue
+		for _, cached := range wm.workflows {
+			if cached.Owner == req.Owner && cached.Repo == req.Repo && cached.IssueID == req.IssueID {
+				workflow = cached
+				return nil
+			}
+		}
 
-	// Set task ID if provided
-	if req.TaskID != 0 {
-		workflow.TaskID = req.TaskID
-	}
+		// Create the workflow
+		now := time.Now()
+		workflow = &types.Workflow{
+			ID:             types.GenerateWorkflowID(),
+			Owner:          req.Owner,
+			Repo:           req.Repo,
+			IssueID:        req.IssueID,
+			BaseBranch:     req.BaseBranch,
+			State:          types.WorkflowStateQueued,
+			Provider:       req.Provider,
+			Config:         req.Config.Clone(),
+			LastEventTS:    now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			StateChangedAt: &now,
+			ErrorCount:     0,
+			Metadata:       make(map[string]string),
+			LockTimeout:    now.Add(DefaultLockTimeout),
+		}
 
-	// Add to memory
-	wm.workflows[fmt.Sprintf("%d", workflowID)] = workflow
+		// Set task ID if provided
+		if req.TaskID != 0 {
+			workflow.TaskID = req.TaskID
+		}
+		if len(req.DependsOn) > 0 {
+			workflow.DependsOn = append([]int(nil), req.DependsOn...)
+		}
 
-	// Save to file
-	if err := wm.saveWorkflowsUnlocked(); err != nil {
-		// Remove from memory if save failed
-		delete(wm.workflows, fmt.Sprintf("%d", workflowID))
-		return nil, fmt.Errorf("failed to save workflow: %w", err)
+		record := &WorkflowRecord{Type: WorkflowRecordCreated, WorkflowID: workflow.ID, Timestamp: now, Workflow: workflow, Actor: recordActor(req.Actor)}
+		if err := wm.persistRecordUnlocked(record); err != nil {
+			return fmt.Errorf("failed to save workflow: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return workflow, nil
+	return workflow.Clone(), nil
 }
 
-// GetWorkflow retrieves a workflow by ID
+// GetWorkflow retrieves a copy of a workflow by ID; later updates are only
+// seen by reading it again
 func (wm *WorkflowManager) GetWorkflow(workflowID string) (*types.Workflow, error) {
 	wm.mu.RLock()
 	defer wm.mu.RUnlock()
@@ -435,7 +601,7 @@ func (wm *WorkflowManager) GetWorkflow(workflowID string) (*types.Workflow, erro
 		return nil, fmt.Errorf("workflow not found: %s", workflowID)
 	}
 
-	return workflow, nil
+	return workflow.Clone(), nil
 }
 
 // GetWorkflowByIssue retrieves a workflow by issue information
@@ -445,7 +611,7 @@ func (wm *WorkflowManager) GetWorkflowByIssue(owner, repo string, issueID int) (
 
 	for _, workflow := range wm.workflows {
 		if workflow.Owner == owner && workflow.Repo == repo && workflow.IssueID == issueID {
-			return workflow, nil
+			return workflow.Clone(), nil
 		}
 	}
 
@@ -459,7 +625,7 @@ func (wm *WorkflowManager) ListWorkflows() ([]*types.Workflow, error) {
 
 	var workflows []*types.Workflow
 	for _, workflow := range wm.workflows {
-		workflows = append(workflows, workflow)
+		workflows = append(workflows, workflow.Clone())
 	}
 
 	return workflows, nil
@@ -473,7 +639,7 @@ func (wm *WorkflowManager) ListWorkflowsByState(state types.WorkflowState) ([]*t
 	var workflows []*types.Workflow
 	for _, workflow := range wm.workflows {
 		if workflow.State == state {
-			workflows = append(workflows, workflow)
+			workflows = append(workflows, workflow.Clone())
 		}
 	}
 
@@ -489,25 +655,86 @@ func (wm *WorkflowManager) UpdateWorkflow(req *types.UpdateWorkflowRequest) (*ty
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	workflow, exists := wm.workflows[fmt.Sprintf("%d", req.WorkflowID)]
-	if !exists {
-		return nil, fmt.Errorf("workflow not found: %d", req.WorkflowID)
+	// Another cw process may have changed the workflow since it was cached, so
+	// catch up with the log and apply the update under the state lock
+	var workflow *types.Workflow
+	err := wm.withStateLock(func() error {
+		if err := wm.syncWorkflowsUnlocked(); err != nil {
+			return err
+		}
+
+		workflowID := fmt.Sprintf("%d", req.WorkflowID)
+		cached, exists := wm.workflows[workflowID]
+		if !exists {
+			return fmt.Errorf("workflow not found: %s", workflowID)
+		}
+
+		// Check the update on a copy before recording it
+		now := time.Now()
+		if _, err := applyWorkflowUpdate(cached.Clone(), req, now); err != nil {
+			return err
+		}
+
+		record := &WorkflowRecord{Type: WorkflowRecordUpdated, WorkflowID: cached.ID, Timestamp: now, Update: req, Actor: recordActor(req.Actor)}
+		if err := wm.persistRecordUnlocked(record); err != nil {
+			return fmt.Errorf("failed to save workflow: %w", err)
+		}
+		workflow = wm.workflows[workflowID]
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	return workflow.Clone(), nil
+}
+
+// applyWorkflowUpdate applies an update to a workflow as of now, returning
+// the state transition it made, if any. Replaying the workflow log applies
+// recorded updates the same way, so it must only depend on its arguments.
+func applyWorkflowUpdate(workflow *types.Workflow, req *types.UpdateWorkflowRequest, now time.Time) (*types.WorkflowTransition, error) {
 	// Update fields if provided
+	var transition *types.WorkflowTransition
 	if req.State != nil {
 		if !workflow.State.CanTransitionTo(*req.State) {
 			return nil, fmt.Errorf("invalid state transition from %s to %s", workflow.State, *req.State)
 		}
+		if workflow.State != *req.State {
+			changedAt := now
+			workflow.StateChangedAt = &changedAt
+			transition = &types.WorkflowTransition{
+				WorkflowID: workflow.ID,
+				From:       workflow.State,
+				To:         *req.State,
+				Actor:      req.Actor,
+				Reason:     req.Reason,
+				Timestamp:  now,
+			}
+			if req.LastError != nil {
+				transition.Error = *req.LastError
+			}
+		}
 		workflow.State = *req.State
 	}
+	if req.BaseBranch != nil {
+		workflow.BaseBranch = *req.BaseBranch
+	}
 
 	if req.BranchName != nil {
 		workflow.BranchName = *req.BranchName
 	}
 
+	if req.DependsOn != nil {
+		workflow.DependsOn = append([]int(nil), *req.DependsOn...)
+	}
+
 	if req.PRNumber != nil {
-		workflow.PRNumber = req.PRNumber
+		prNumber := *req.PRNumber
+		workflow.PRNumber = &prNumber
+	}
+
+	if req.StackedPRs != nil {
+		workflow.StackedPRs = cloneStackedPRs(*req.StackedPRs)
 	}
 
 	if req.TaskID != nil {
@@ -526,27 +753,54 @@ func (wm *WorkflowManager) UpdateWorkflow(req *types.UpdateWorkflowRequest) (*ty
 		workflow.LastError = *req.LastError
 	}
 
+	if req.NextRetryAt != nil {
+		if req.NextRetryAt.IsZero() {
+			workflow.NextRetryAt = nil
+		} else {
+			nextRetryAt := *req.NextRetryAt
+			workflow.NextRetryAt = &nextRetryAt
+		}
+	}
+
+	if req.LastFeedbackTS != nil {
+		lastFeedbackTS := *req.LastFeedbackTS
+		workflow.LastFeedbackTS = &lastFeedbackTS
+	}
+
+	if req.Metadata != nil {
+		if workflow.Metadata == nil {
+			workflow.Metadata = make(map[string]string)
+		}
+		for key, value := range req.Metadata {
+			if value == "" {
+				delete(workflow.Metadata, key)
+			} else {
+				workflow.Metadata[key] = value
+			}
+		}
+	}
+
 	// Update timestamps
-	workflow.UpdatedAt = time.Now()
-	workflow.LastEventTS = time.Now()
+	workflow.UpdatedAt = now
+	workflow.LastEventTS = now
 
 	// Set started/ended timestamps based on state
 	if workflow.StartedAt == nil && (workflow.State == types.WorkflowStateWorkspaceReady || workflow.State == types.WorkflowStateImplementing) {
-		now := time.Now()
-		workflow.StartedAt = &now
+		startedAt := now
+		workflow.StartedAt = &startedAt
 	}
 
 	if workflow.EndedAt == nil && workflow.State.IsTerminal() {
-		now := time.Now()
-		workflow.EndedAt = &now
+		endedAt := now
+		workflow.EndedAt = &endedAt
 	}
 
-	// Save to file
-	if err := wm.saveWorkflowsUnlocked(); err != nil {
-		return nil, fmt.Errorf("failed to save workflow: %w", err)
+	// A retried workflow is running again
+	if workflow.EndedAt != nil && !workflow.State.IsTerminal() {
+		workflow.EndedAt = nil
 	}
 
-	return workflow, nil
+	return transition, nil
 }
 
 // DeleteWorkflow removes a workflow
@@ -554,21 +808,28 @@ func (wm *WorkflowManager) DeleteWorkflow(workflowID string) error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	if _, exists := wm.workflows[workflowID]; !exists {
-		return fmt.Errorf("workflow not found: %s", workflowID)
-	}
-
-	delete(wm.workflows, workflowID)
+	return wm.withStateLock(func() error {
+		if err := wm.syncWorkflowsUnlocked(); err != nil {
+			return err
+		}
 
-	// Save to file
-	if err := wm.saveWorkflowsUnlocked(); err != nil {
-		return fmt.Errorf("failed to save workflows: %w", err)
-	}
+		deleted, exists := wm.workflows[workflowID]
+		if !exists {
+			return fmt.Errorf("workflow not found: %s", workflowID)
+		}
 
-	return nil
+		record := &WorkflowRecord{Type: WorkflowRecordDeleted, WorkflowID: deleted.ID, Timestamp: time.Now(), Actor: DefaultActor()}
+		if err := wm.persistRecordUnlocked(record); err != nil {
+			return fmt.Errorf("failed to save workflows: %w", err)
+		}
+		return nil
+	})
 }
 
-// LockWorkflow attempts to acquire a lock on a workflow
+// LockWorkflow attempts to acquire a lock on a workflow. The lock is shared
+// with other cw processes through the locks file; a lock whose lease ran out
+// or whose holder exited is taken over. Workflows other processes changed are
+// refreshed so the holder starts from their latest state.
 func (wm *WorkflowManager) LockWorkflow(workflowID, lockedBy string, timeout time.Duration) (*types.WorkflowLock, error) {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
@@ -578,37 +839,46 @@ func (wm *WorkflowManager) LockWorkflow(workflowID, lockedBy string, timeout tim
 		return nil, fmt.Errorf("workflow not found: %s", workflowID)
 	}
 
-	// Check if workflow is already locked
-	if existingLock, exists := wm.locks[workflowID]; exists {
-		if existingLock.IsValid() {
-			return nil, fmt.Errorf("workflow %s is already locked by %s until %s", workflowID, existingLock.LockedBy, existingLock.LockTimeout.Format(time.RFC3339))
+	var lock *types.WorkflowLock
+	err := wm.withStateLock(func() error {
+		if err := wm.refreshLocksUnlocked(); err != nil {
+			return err
 		}
-		// Lock is expired, remove it
-		delete(wm.locks, workflowID)
-	}
 
-	// Get current process ID
-	processID := os.Getpid()
+		// Check if workflow is already locked
+		if existingLock, exists := wm.locks[workflowID]; exists {
+			if !isStaleLock(existingLock) {
+				return fmt.Errorf("workflow %s is already locked by %s (pid %d) until %s", workflowID, existingLock.LockedBy, existingLock.ProcessID, existingLock.LockTimeout.Format(time.RFC3339))
+			}
+			log.Printf("🔓 Taking over stale lock on workflow %s held by %s", workflowID, existingLock.LockedBy)
+			delete(wm.locks, workflowID)
+		}
 
-	// Create new lock
-	now := time.Now()
-	workflowIDInt, _ := strconv.Atoi(workflowID)
-	lock := &types.WorkflowLock{
-		WorkflowID:  workflowIDInt,
-		LockedBy:    lockedBy,
-		LockedAt:    now,
-		LockTimeout: now.Add(timeout),
-		ProcessID:   processID,
-	}
+		// Create new lock
+		now := time.Now()
+		workflowIDInt, _ := strconv.Atoi(workflowID)
+		lock = &types.WorkflowLock{
+			WorkflowID:  workflowIDInt,
+			LockedBy:    lockedBy,
+			LockedAt:    now,
+			LockTimeout: now.Add(timeout),
+			ProcessID:   os.Getpid(),
+			Hostname:    localHostname(),
+			HeartbeatAt: now,
+		}
+		wm.locks[workflowID] = lock
 
-	// Add to memory
-	wm.locks[workflowID] = lock
+		// Save to file
+		if err := wm.saveLocksUnlocked(); err != nil {
+			// Remove from memory if save failed
+			delete(wm.locks, workflowID)
+			return fmt.Errorf("failed to save lock: %w", err)
+		}
 
-	// Save to file
-	if err := wm.saveLocksUnlocked(); err != nil {
-		// Remove from memory if save failed
-		delete(wm.locks, workflowID)
-		return nil, fmt.Errorf("failed to save lock: %w", err)
+		return wm.syncWorkflowsUnlocked()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return lock, nil
@@ -619,23 +889,29 @@ func (wm *WorkflowManager) UnlockWorkflow(workflowID, lockedBy string) error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	lock, exists := wm.locks[workflowID]
-	if !exists {
-		return fmt.Errorf("no lock found for workflow: %s", workflowID)
-	}
+	return wm.withStateLock(func() error {
+		if err := wm.refreshLocksUnlocked(); err != nil {
+			return err
+		}
 
-	if lock.LockedBy != lockedBy {
-		return fmt.Errorf("workflow %s is locked by %s, not %s", workflowID, lock.LockedBy, lockedBy)
-	}
+		lock, exists := wm.locks[workflowID]
+		if !exists {
+			return fmt.Errorf("no lock found for workflow: %s", workflowID)
+		}
 
-	delete(wm.locks, workflowID)
+		if lock.LockedBy != lockedBy {
+			return fmt.Errorf("workflow %s is locked by %s, not %s", workflowID, lock.LockedBy, lockedBy)
+		}
 
-	// Save to file
-	if err := wm.saveLocksUnlocked(); err != nil {
-		return fmt.Errorf("failed to save locks: %w", err)
-	}
+		delete(wm.locks, workflowID)
 
-	return nil
+		// Save to file
+		if err := wm.saveLocksUnlocked(); err != nil {
+			return fmt.Errorf("failed to save locks: %w", err)
+		}
+
+		return nil
+	})
 }
 
 // ForceUnlockWorkflow forcefully releases a lock on a workflow (admin override)
@@ -643,18 +919,24 @@ func (wm *WorkflowManager) ForceUnlockWorkflow(workflowID string) error {
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	if _, exists := wm.locks[workflowID]; !exists {
-		return fmt.Errorf("no lock found for workflow: %s", workflowID)
-	}
+	return wm.withStateLock(func() error {
+		if err := wm.refreshLocksUnlocked(); err != nil {
+			return err
+		}
 
-	delete(wm.locks, workflowID)
+		if _, exists := wm.locks[workflowID]; !exists {
+			return fmt.Errorf("no lock found for workflow: %s", workflowID)
+		}
 
-	// Save to file
-	if err := wm.saveLocksUnlocked(); err != nil {
-		return fmt.Errorf("failed to save locks: %w", err)
-	}
+		delete(wm.locks, workflowID)
 
-	return nil
+		// Save to file
+		if err := wm.saveLocksUnlocked(); err != nil {
+			return fmt.Errorf("failed to save locks: %w", err)
+		}
+
+		return nil
+	})
 }
 
 // GetWorkflowLock retrieves the lock for a workflow
@@ -680,7 +962,7 @@ func (wm *WorkflowManager) IsWorkflowLocked(workflowID string) (bool, *types.Wor
 		return false, nil
 	}
 
-	if lock.IsExpired() {
+	if isStaleLock(lock) {
 		return false, nil
 	}
 
@@ -709,13 +991,19 @@ func (wm *WorkflowManager) CreateEvent(eventType, provider, owner, repo string,
 		Processed: false,
 	}
 
-	// Add to memory
-	wm.events[eventID] = event
+	err := wm.withStateLock(func() error {
+		if err := wm.syncEventsUnlocked(); err != nil {
+			return err
+		}
 
-	// Save to file
-	if err := wm.saveEventsUnlocked(); err != nil {
-		// Remove from memory if save failed
-		delete(wm.events, eventID)
+		return wm.appendEventRecordUnlocked(&EventRecord{
+			Type:      EventRecordCreated,
+			EventID:   eventID,
+			Timestamp: now,
+			Event:     event,
+		})
+	})
+	if err != nil {
 		return nil, fmt.Errorf("failed to save event: %w", err)
 	}
 
@@ -735,10 +1023,15 @@ func (wm *WorkflowManager) GetEvent(eventID string) (*types.WorkflowEvent, error
 	return event, nil
 }
 
-// ListUnprocessedEvents returns all unprocessed events
+// ListUnprocessedEvents returns all unprocessed events, including those other
+// cw processes recorded since the last call
 func (wm *WorkflowManager) ListUnprocessedEvents() ([]*types.WorkflowEvent, error) {
-	wm.mu.RLock()
-	defer wm.mu.RUnlock()
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	if err := wm.withStateLock(wm.syncEventsUnlocked); err != nil {
+		return nil, err
+	}
 
 	var events []*types.WorkflowEvent
 	for _, event := range wm.events {
@@ -755,27 +1048,95 @@ func (wm *WorkflowManager) MarkEventProcessed(eventID, workflowID string, errorM
 	wm.mu.Lock()
 	defer wm.mu.Unlock()
 
-	event, exists := wm.events[eventID]
-	if !exists {
-		return fmt.Errorf("event not found: %s", eventID)
+	return wm.withStateLock(func() error {
+		if err := wm.syncEventsUnlocked(); err != nil {
+			return err
+		}
+
+		if _, exists := wm.events[eventID]; !exists {
+			return fmt.Errorf("event not found: %s", eventID)
+		}
+
+		if err := wm.appendEventRecordUnlocked(&EventRecord{
+			Type:      EventRecordProcessed,
+			EventID:   eventID,
+			Timestamp: time.Now(),
+			JobID:     workflowID,
+			Error:     errorMsg,
+		}); err != nil {
+			return fmt.Errorf("failed to save events: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// FindWorkflowForEvent resolves the workflow an event refers to. Events match
+// on head branch, then on PR number for pull request events or issue number
+// for everything else.
+func (wm *WorkflowManager) FindWorkflowForEvent(event *types.WorkflowEvent) (*types.Workflow, error) {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	isPullRequest := eventBool(event.Data, "pull_request")
+	prNumbers := eventInts(event.Data, "pr_numbers")
+	if isPullRequest && event.IssueID > 0 {
+		prNumbers = append(prNumbers, event.IssueID)
+	}
+	headBranch := eventString(event.Data, "head_branch")
+
+	for _, workflow := range wm.workflows {
+		if workflow.Owner != event.Owner || workflow.Repo != event.Repo {
+			continue
+		}
+
+		if headBranch != "" && workflow.BranchName == headBranch {
+			return workflow.Clone(), nil
+		}
+
+		if !isPullRequest {
+			if event.IssueID > 0 && workflow.IssueID == event.IssueID {
+				return workflow.Clone(), nil
+			}
+			continue
+		}
+
+		if workflow.PRNumber != nil {
+			for _, number := range prNumbers {
+				if *workflow.PRNumber == number {
+					return workflow.Clone(), nil
+				}
+			}
+		}
 	}
 
-	event.Processed = true
-	event.JobID = workflowID
-	if errorMsg != "" {
-		event.Error = errorMsg
+	return nil, fmt.Errorf("no workflow found for %s event %s", event.Type, event.ID)
+}
+
+// persistRecordUnlocked appends the change to the workflow log, applies it
+// to the cache and writes the workflows file, taking a snapshot every
+// SnapshotInterval records. Must be called with both locks held, after
+// syncing with the log.
+func (wm *WorkflowManager) persistRecordUnlocked(record *WorkflowRecord) error {
+	if err := wm.appendRecordUnlocked(record); err != nil {
+		return err
+	}
+	if err := applyRecord(wm.workflows, record); err != nil {
+		return err
 	}
 
-	// Save to file
-	if err := wm.saveEventsUnlocked(); err != nil {
-		return fmt.Errorf("failed to save events: %w", err)
+	if record.Sequence%SnapshotInterval == 0 {
+		if err := wm.writeSnapshotUnlocked(wm.workflows, record.Sequence); err != nil {
+			log.Printf("⚠️  Failed to snapshot workflow state: %v", err)
+		}
 	}
 
-	return nil
+	return wm.writeWorkflowsUnlocked()
 }
 
-// saveWorkflowsUnlocked saves workflows without acquiring the lock (assumes lock is already held)
-func (wm *WorkflowManager) saveWorkflowsUnlocked() error {
+// writeWorkflowsUnlocked writes the cached workflows to the workflows file.
+// Must be called with both locks held.
+func (wm *WorkflowManager) writeWorkflowsUnlocked() error {
 	// Convert workflows map to slice
 	var workflows []*types.Workflow
 	for _, workflow := range wm.workflows {
@@ -808,40 +1169,6 @@ func (wm *WorkflowManager) saveWorkflowsUnlocked() error {
 	return nil
 }
 
-// saveEventsUnlocked saves events without acquiring the lock (assumes lock is already held)
-func (wm *WorkflowManager) saveEventsUnlocked() error {
-	// Convert events map to slice
-	var events []*types.WorkflowEvent
-	for _, event := range wm.events {
-		events = append(events, event)
-	}
-
-	// Create temporary file
-	tempFile := wm.eventsFilePath + ".tmp"
-	file, err := os.Create(tempFile)
-	if err != nil {
-		return fmt.Errorf("failed to create temporary events file: %w", err)
-	}
-	defer file.Close()
-
-	// Encode events to JSON
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	if err := encoder.Encode(events); err != nil {
-		return fmt.Errorf("failed to encode events: %w", err)
-	}
-
-	// Close file before renaming
-	file.Close()
-
-	// Rename temporary file to actual file
-	if err := os.Rename(tempFile, wm.eventsFilePath); err != nil {
-		return fmt.Errorf("failed to rename temporary events file: %w", err)
-	}
-
-	return nil
-}
-
 // saveLocksUnlocked saves locks without acquiring the lock (assumes lock is already held)
 func (wm *WorkflowManager) saveLocksUnlocked() error {
 	// Convert locks map to slice
@@ -58,6 +58,11 @@ func NewManager(gitTimeoutSeconds int) (*Manager, error) {
 	}, nil
 }
 
+// SetBranchNamingPattern sets the template used to name workspace branches
+func (m *Manager) SetBranchNamingPattern(pattern string) {
+	m.gitOps.SetBranchTemplate(pattern)
+}
+
 // CreateWorkspace creates a new isolated workspace
 func (m *Manager) CreateWorkspace(req *types.CreateWorkspaceRequest) (*types.Workspace, error) {
 	// Validate the request